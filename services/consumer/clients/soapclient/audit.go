@@ -0,0 +1,62 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// WithActor names the caller in the provider's audit trail (sent as the X-Actor header)
+func WithActor(actor string) Option {
+	return func(sc *SOAPClient) {
+		sc.actor = actor
+	}
+}
+
+// WithCorrelationID returns a client that sends id as the X-Correlation-ID header, so the
+// provider's audit trail can be tied back to the Kafka message that caused a change. The returned
+// client shares the transport, breaker, endpoints and cache of sc.
+func (sc *SOAPClient) WithCorrelationID(id string) *SOAPClient {
+	scoped := *sc
+	scoped.correlationID = id
+	return &scoped
+}
+
+// ReadKYCAsOf performs a KYCQuery for the record as it stood at asOf. Historical reads bypass the cache.
+func (sc *SOAPClient) ReadKYCAsOf(clientID string, asOf time.Time) (models.UserData, error) {
+	return sc.readKYC(clientID, &asOf)
+}
+
+// GetKYCHistory performs a GetKYCHistory operation. The returned UserData carries only the
+// ClientID plus every recorded change of the record, oldest first.
+func (sc *SOAPClient) GetKYCHistory(clientID string) (models.UserData, error) {
+	requestBody := fmt.Sprintf(transitionTemplate, "GetKYCHistory", xmlEscape(clientID), "")
+	soapAction := "http://example.com/kyc/GetKYCHistory"
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return models.UserData{}, err
+	}
+
+	var envelope models.KYCHistoryResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC GetKYCHistory response: %v", err)
+		return models.UserData{}, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+
+	result := envelope.Body.KYCHistoryResult
+	userData := models.UserData{
+		Record:  kyc.Record{ClientID: result.ClientID},
+		Status:  result.Status,
+		Message: result.Message,
+		History: result.Events,
+	}
+	if userData.ClientID == "" {
+		userData.ClientID = clientID
+	}
+	return userData, nil
+}
@@ -0,0 +1,139 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mockKYCHistoryResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <KYCHistoryResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>2 event(s) found</Message>
+      <ClientID>client123</ClientID>
+      <Event>
+        <Sequence>1</Sequence>
+        <ClientID>client123</ClientID>
+        <Action>CREATE</Action>
+        <Actor>system</Actor>
+        <Source>FIXTURE</Source>
+        <Timestamp>2024-05-01T12:00:00Z</Timestamp>
+        <After>
+          <ClientID>client123</ClientID>
+          <Risk>0.2</Risk>
+        </After>
+      </Event>
+      <Event>
+        <Sequence>7</Sequence>
+        <ClientID>client123</ClientID>
+        <Action>UPDATE</Action>
+        <Actor>consumer-service</Actor>
+        <Source>SOAP</Source>
+        <Operation>UpdateKYC</Operation>
+        <CorrelationID>corr-42</CorrelationID>
+        <Timestamp>2024-05-01T13:00:00Z</Timestamp>
+        <Before>
+          <ClientID>client123</ClientID>
+          <Risk>0.2</Risk>
+        </Before>
+        <After>
+          <ClientID>client123</ClientID>
+          <Risk>0.9</Risk>
+        </After>
+      </Event>
+    </KYCHistoryResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+func TestGetKYCHistory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, mockKYCHistoryResponseSuccess)
+	}))
+	defer ts.Close()
+
+	userData, err := NewSOAPClient(ts.URL).GetKYCHistory("client123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "client123", userData.ClientID)
+	assert.Equal(t, "Success", userData.Status)
+	require.Len(t, userData.History, 2)
+	created, updated := userData.History[0], userData.History[1]
+	assert.Equal(t, kyc.AuditCreate, created.Action)
+	assert.Equal(t, kyc.SourceFixture, created.Source)
+	assert.Nil(t, created.Before)
+	assert.Equal(t, 0.2, created.After.Risk)
+	assert.Equal(t, int64(7), updated.Sequence)
+	assert.Equal(t, "corr-42", updated.CorrelationID)
+	assert.Equal(t, 0.2, updated.Before.Risk)
+	assert.Equal(t, 0.9, updated.After.Risk)
+	assert.Equal(t, time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC), updated.Timestamp)
+}
+
+func TestSOAPClient_AuditHeaders(t *testing.T) {
+	var headers []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL, WithActor("consumer-service"))
+	_, err := sc.ReadKYC("client123")
+	require.NoError(t, err)
+	_, err = sc.WithCorrelationID("corr-42").ReadKYC("client123")
+	require.NoError(t, err)
+
+	require.Len(t, headers, 2)
+	assert.Equal(t, "consumer-service", headers[0].Get("X-Actor"))
+	assert.Empty(t, headers[0].Get("X-Correlation-ID"))
+	assert.Equal(t, "consumer-service", headers[1].Get("X-Actor"))
+	assert.Equal(t, "corr-42", headers[1].Get("X-Correlation-ID"))
+}
+
+func TestSOAPClient_TenantHeader(t *testing.T) {
+	var headers []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	_, err := NewSOAPClient(ts.URL).ReadKYC("client123")
+	require.NoError(t, err)
+	_, err = NewSOAPClient(ts.URL, WithTenant("suite-a")).WithCorrelationID("corr-42").ReadKYC("client123")
+	require.NoError(t, err)
+
+	require.Len(t, headers, 2)
+	assert.Empty(t, headers[0].Get("X-KYC-Tenant"))
+	assert.Equal(t, "suite-a", headers[1].Get("X-KYC-Tenant"), "the tenant is kept by clients derived with WithCorrelationID")
+}
+
+func TestReadKYCAsOf(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	asOf := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	userData, err := NewSOAPClient(ts.URL).ReadKYCAsOf("client123", asOf)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Success", userData.Status)
+	assert.Contains(t, string(body), "<AsOf>2024-05-01T10:30:00Z</AsOf>")
+	root, violations, err := contract.KYCSchema().ValidateEnvelope(body)
+	require.NoError(t, err)
+	assert.Equal(t, "KYCQuery", root.Local)
+	assert.Empty(t, violations)
+}
@@ -0,0 +1,271 @@
+package soapclient
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// MaxBatchSize is the most ClientIDs the provider accepts in one BatchKYCQuery
+const MaxBatchSize = 1000
+
+const batchQueryTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
+  <soapenv:Header>
+    <KYCRequest xmlns="http://example.com/kyc"/>
+  </soapenv:Header>
+  <soapenv:Body>
+    <BatchKYCQuery xmlns="http://example.com/kyc">%s
+    </BatchKYCQuery>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+// BatchResult is the result of one ClientID of a ReadKYCBatch, as ReadKYC would have returned it
+type BatchResult struct {
+	UserData models.UserData
+	Err      error
+}
+
+// ReadKYCBatch performs a BatchKYCQuery, returning one result per ClientID in the order given.
+// A ClientID the provider did not find gets the error ReadKYC would have returned: the NOT_FOUND
+// fault in the provider's fault error style, a 404 StatusError in the legacy one. With a cache
+// configured, cached ClientIDs are not sent and the others are cached like ReadKYC results.
+func (sc *SOAPClient) ReadKYCBatch(clientIDs []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(clientIDs))
+	fetch := make(map[string][]int)        // Indexes into results of each ClientID to send
+	generations := make(map[string]uint64) // Cache fill generation of each ClientID to send
+	var ids []string
+	for i, clientID := range clientIDs {
+		if sc.cache != nil {
+			if entry, ok := sc.cache.get(cacheKey(sc.tenant, clientID)); ok {
+				userData, err := cachedRead(entry)
+				results[i] = BatchResult{UserData: userData, Err: err}
+				continue
+			}
+		}
+		if _, ok := fetch[clientID]; !ok {
+			ids = append(ids, clientID)
+			if sc.cache != nil {
+				generations[clientID] = sc.cache.beginFill(cacheKey(sc.tenant, clientID))
+			}
+		}
+		fetch[clientID] = append(fetch[clientID], i)
+	}
+	fills := make(map[string]*CacheEntry) // Results to cache, stored once the batch is done
+	if sc.cache != nil {
+		defer func() {
+			for clientID, generation := range generations {
+				sc.cache.endFill(cacheKey(sc.tenant, clientID), generation, fills[clientID])
+			}
+		}()
+	}
+	if len(ids) == 0 {
+		log.Printf("Consumer Service: KYC batch of %d served from the cache", len(clientIDs))
+		return results, nil
+	}
+
+	var elements strings.Builder
+	for _, clientID := range ids {
+		fmt.Fprintf(&elements, "\n      <ClientID>%s</ClientID>", xmlEscape(clientID))
+	}
+	requestBody := fmt.Sprintf(batchQueryTemplate, elements.String())
+	soapAction := "http://example.com/kyc/BatchKYCQuery"
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope models.BatchKYCQueryResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC BatchKYCQuery response: %v", err)
+		return nil, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+	result := envelope.Body.BatchKYCQueryResult
+	if result.Status != "Success" {
+		return nil, fmt.Errorf("BatchKYCQuery failed: %s", result.Message)
+	}
+
+	for _, item := range result.Results {
+		indexes, ok := fetch[item.ClientID]
+		if !ok {
+			continue
+		}
+		delete(fetch, item.ClientID)
+		result := batchResult(item)
+		for _, i := range indexes {
+			results[i] = result
+		}
+		if item.Status == "Success" || item.ErrorCode == "NOT_FOUND" {
+			fills[item.ClientID] = readCacheEntry(result.UserData, result.Err)
+		}
+	}
+	for clientID, indexes := range fetch { // Left out of the response
+		for _, i := range indexes {
+			results[i].UserData = models.UserData{
+				Record:  kyc.Record{ClientID: clientID},
+				Status:  "Error",
+				Message: fmt.Sprintf("no result for ClientID '%s' in BatchKYCQuery response", clientID),
+			}
+		}
+	}
+	log.Printf("Consumer Service: KYC batch of %d sent %d ClientID(s): %s", len(clientIDs), len(ids), result.Message)
+	return results, nil
+}
+
+// batchResult converts a BatchKYCQuery item to what ReadKYC would have returned. A NOT_FOUND item
+// becomes the fault a KYCQuery answers in the fault error style, which names its faultcode, and the
+// 404 of its legacy KYCResponse otherwise.
+func batchResult(item models.BatchKYCItem) BatchResult {
+	switch {
+	case item.ErrorCode == "NOT_FOUND" && item.FaultCode != "":
+		return BatchResult{Err: &FaultError{StatusCode: http.StatusInternalServerError, Code: item.FaultCode, String: item.Message, ErrorCode: item.ErrorCode}}
+	case item.ErrorCode == "NOT_FOUND":
+		return BatchResult{Err: &StatusError{StatusCode: http.StatusNotFound}}
+	}
+	userData := models.UserData{Status: item.Status, Message: item.Message}
+	if item.UserData != nil {
+		userData.Record = item.UserData.Record
+	}
+	if userData.ClientID == "" {
+		userData.ClientID = item.ClientID
+	}
+	return BatchResult{UserData: userData}
+}
+
+// ReadCoalescer gathers KYC reads that arrive within a short window into one ReadKYCBatch call and
+// hands every read its own result. A window with a single read sends a plain KYCQuery. A batch is
+// sent with the correlation IDs of all its reads, comma-separated in the order they were queued, as
+// its X-Correlation-ID, and waits while the client's circuit breaker is open.
+type ReadCoalescer struct {
+	client   *SOAPClient
+	window   time.Duration
+	maxBatch int
+
+	flushMu sync.Mutex // Held while a batch is sent, so Flush also waits for batches already taken
+	mu      sync.Mutex
+	pending []pendingRead
+	timer   *time.Timer
+	windows uint64 // Number of windows whose reads were taken, so a late timer can tell its own was sent
+}
+
+// pendingRead is a queued read and the function that receives its result
+type pendingRead struct {
+	clientID      string
+	correlationID string
+	done          func(models.UserData, error)
+}
+
+// NewReadCoalescer returns a coalescer that sends the reads queued within window, or as soon as
+// maxBatch of them are queued, through client
+func NewReadCoalescer(client *SOAPClient, window time.Duration, maxBatch int) *ReadCoalescer {
+	return &ReadCoalescer{client: client, window: window, maxBatch: max(maxBatch, 1)}
+}
+
+// Read queues a read of clientID on behalf of the request correlationID. done is called with its
+// result once the batch is answered, from the goroutine that sends it, which may be a timer's. When
+// the batch is full Read sends it before returning, as Flush does.
+func (c *ReadCoalescer) Read(clientID, correlationID string, done func(models.UserData, error)) {
+	c.mu.Lock()
+	c.pending = append(c.pending, pendingRead{clientID: clientID, correlationID: correlationID, done: done})
+	full := len(c.pending) >= c.maxBatch
+	if len(c.pending) == 1 && !full {
+		window := c.windows
+		c.timer = time.AfterFunc(c.window, func() { c.flush(&window) })
+	}
+	c.mu.Unlock()
+	if full {
+		c.Flush()
+	}
+}
+
+// Flush sends the queued reads now, after waiting while the circuit breaker is open, and returns
+// once every read queued before the call has its result or, with callbacks, once the provider has
+// acknowledged them and their callback is awaited in the background
+func (c *ReadCoalescer) Flush() {
+	c.flush(nil)
+}
+
+// flush sends the queued reads as Flush does. A timer passes the window it was started for and
+// sends nothing if that window's reads were taken while it waited, so it never cuts a later one short.
+func (c *ReadCoalescer) flush(window *uint64) {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	if err := c.client.WaitUntilReady(context.Background()); err != nil {
+		log.Printf("Consumer Service: Error while waiting for SOAP circuit breaker: %v", err)
+	}
+	c.mu.Lock()
+	if window != nil && *window != c.windows {
+		c.mu.Unlock()
+		return
+	}
+	batch := c.pending
+	c.pending = nil
+	c.windows++
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	accepted := make(chan struct{}, 1)
+	done := make(chan struct{})
+	client := c.client.WithCorrelationID(batchCorrelationID(batch)).OnAccepted(func() {
+		select {
+		case accepted <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		defer close(done)
+		c.send(client, batch)
+	}()
+	select {
+	case <-done:
+	case <-accepted:
+	}
+}
+
+// send reads batch through client and hands every read its result
+func (c *ReadCoalescer) send(client *SOAPClient, batch []pendingRead) {
+	if len(batch) == 1 {
+		userData, err := client.ReadKYC(batch[0].clientID)
+		batch[0].done(userData, err)
+		return
+	}
+
+	clientIDs := make([]string, len(batch))
+	for i, read := range batch {
+		clientIDs[i] = read.clientID
+	}
+	results, err := client.ReadKYCBatch(clientIDs)
+	for i, read := range batch {
+		if err != nil {
+			read.done(models.UserData{}, err)
+			continue
+		}
+		read.done(results[i].UserData, results[i].Err)
+	}
+}
+
+// batchCorrelationID joins the correlation IDs of batch, skipping empty ones
+func batchCorrelationID(batch []pendingRead) string {
+	ids := make([]string, 0, len(batch))
+	for _, read := range batch {
+		if read.correlationID != "" {
+			ids = append(ids, read.correlationID)
+		}
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,330 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRequest is a BatchKYCQuery request as the mock provider reads it
+type batchRequest struct {
+	ClientIDs []string `xml:"Body>BatchKYCQuery>ClientID"`
+}
+
+// batchProvider is a mock provider answering BatchKYCQuery and KYCQuery, where every ClientID
+// except "missing" is found. While failures is positive it answers 500 instead, counting down.
+type batchProvider struct {
+	mu             sync.Mutex
+	batches        [][]string
+	correlationIDs []string // The X-Correlation-ID of every request
+	reads          atomic.Int32
+	failures       atomic.Int32
+}
+
+func newBatchProvider(t *testing.T) (*httptest.Server, *batchProvider) {
+	t.Helper()
+	p := &batchProvider{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if p.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		p.mu.Lock()
+		p.correlationIDs = append(p.correlationIDs, r.Header.Get("X-Correlation-ID"))
+		p.mu.Unlock()
+		if r.Header.Get("SOAPAction") == "http://example.com/kyc/KYCQuery" {
+			p.reads.Add(1)
+			_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+			return
+		}
+		var request batchRequest
+		if err := xml.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		p.batches = append(p.batches, request.ClientIDs)
+		p.mu.Unlock()
+		_, _ = io.WriteString(w, mockBatchResponse(request.ClientIDs...))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, p
+}
+
+func (p *batchProvider) sent() [][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]string(nil), p.batches...)
+}
+
+func (p *batchProvider) correlations() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.correlationIDs...)
+}
+
+// mockBatchResponse answers a BatchKYCQuery for clientIDs, reporting "missing" as not found
+func mockBatchResponse(clientIDs ...string) string {
+	var results strings.Builder
+	for _, clientID := range clientIDs {
+		clientID = xmlEscape(clientID)
+		if clientID == "missing" {
+			fmt.Fprintf(&results, `
+      <Result><ClientID>%s</ClientID><Status>Error</Status><Message>User not found</Message><ErrorCode>NOT_FOUND</ErrorCode></Result>`, clientID)
+			continue
+		}
+		fmt.Fprintf(&results, `
+      <Result><ClientID>%[1]s</ClientID><Status>Success</Status><Message>User data retrieved</Message><UserData><ClientID>%[1]s</ClientID><Risk>0.5</Risk></UserData></Result>`, clientID)
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <BatchKYCQueryResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>%d user(s) read</Message>%s
+    </BatchKYCQueryResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`, len(clientIDs), results.String())
+}
+
+func TestReadKYCBatch(t *testing.T) {
+	ts, provider := newBatchProvider(t)
+	sc := NewSOAPClient(ts.URL)
+
+	results, err := sc.ReadKYCBatch([]string{"client2", "missing", "client1", "client2"})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, "client2", results[0].UserData.ClientID)
+	assert.Equal(t, "Success", results[0].UserData.Status)
+	assert.Equal(t, 0.5, results[0].UserData.Risk)
+	assert.Equal(t, &StatusError{StatusCode: http.StatusNotFound}, results[1].Err, "the 404 of a legacy KYCQuery")
+	assert.Equal(t, "client1", results[2].UserData.ClientID)
+	assert.Equal(t, results[0], results[3])
+	assert.Equal(t, [][]string{{"client2", "missing", "client1"}}, provider.sent(), "duplicates are sent once")
+}
+
+func TestReadKYCBatch_FaultStyleNotFound(t *testing.T) {
+	const faultString = "user with ClientID 'missing' not found"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("SOAPAction") == "http://example.com/kyc/KYCQuery" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, faultResponse("soapenv:Client", "NOT_FOUND", faultString))
+			return
+		}
+		_, _ = io.WriteString(w, strings.Replace(mockBatchResponse("missing"),
+			"<Message>User not found</Message><ErrorCode>NOT_FOUND</ErrorCode>",
+			"<Message>"+faultString+"</Message><ErrorCode>NOT_FOUND</ErrorCode><FaultCode>soapenv:Client</FaultCode>", 1))
+	}))
+	defer ts.Close()
+	sc := NewSOAPClient(ts.URL)
+
+	_, readErr := sc.ReadKYC("missing")
+	require.Error(t, readErr)
+	results, err := sc.ReadKYCBatch([]string{"missing"})
+	require.NoError(t, err)
+	assert.Equal(t, readErr, results[0].Err, "batching does not change the error")
+}
+
+func TestReadKYCBatch_MissingResult(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, mockBatchResponse("client1"))
+	}))
+	defer ts.Close()
+
+	results, err := NewSOAPClient(ts.URL).ReadKYCBatch([]string{"client1", "client2"})
+	require.NoError(t, err)
+	assert.Equal(t, "Success", results[0].UserData.Status)
+	assert.Equal(t, "client2", results[1].UserData.ClientID)
+	assert.Equal(t, "Error", results[1].UserData.Status)
+	assert.Contains(t, results[1].UserData.Message, "no result for ClientID 'client2'")
+}
+
+func TestReadKYCBatch_Fault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, faultResponse("soapenv:Client", "INVALID_REQUEST", "BatchKYCQuery ClientIDs exceed the limit of 1000"))
+	}))
+	defer ts.Close()
+
+	_, err := NewSOAPClient(ts.URL).ReadKYCBatch([]string{"client1", "client2"})
+	var fault *FaultError
+	require.ErrorAs(t, err, &fault)
+	assert.Equal(t, "INVALID_REQUEST", fault.ErrorCode)
+}
+
+func TestReadKYCBatch_WithCache(t *testing.T) {
+	ts, provider := newBatchProvider(t)
+	sc := NewSOAPClient(ts.URL, WithCache(NewLRUCache(10), time.Minute, time.Minute))
+
+	_, err := sc.ReadKYCBatch([]string{"client1", "missing"})
+	require.NoError(t, err)
+	results, err := sc.ReadKYCBatch([]string{"client1", "missing", "client2"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"client1", "missing"}, {"client2"}}, provider.sent(), "cached ClientIDs are not sent")
+	assert.Equal(t, "Success", results[0].UserData.Status)
+	assert.Equal(t, &StatusError{StatusCode: http.StatusNotFound}, results[1].Err)
+	assert.Equal(t, "client2", results[2].UserData.ClientID)
+
+	_, err = sc.ReadKYCBatch([]string{"client2"})
+	require.NoError(t, err)
+	assert.Len(t, provider.sent(), 2, "a batch served from the cache sends nothing")
+
+	userData, err := sc.ReadKYC("client1")
+	require.NoError(t, err)
+	assert.Equal(t, "client1", userData.ClientID)
+	_, err = sc.ReadKYC("missing")
+	assert.Equal(t, results[1].Err, err)
+	assert.Zero(t, provider.reads.Load(), "ReadKYC shares the entries")
+}
+
+// readResults collects the results a ReadCoalescer hands out
+type readResults struct {
+	mu      sync.Mutex
+	results map[string]models.UserData
+	wg      sync.WaitGroup
+}
+
+// read queues a read of clientID on behalf of the request "corr-{clientID}"
+func (r *readResults) read(c *ReadCoalescer, clientID string) {
+	r.wg.Add(1)
+	c.Read(clientID, "corr-"+clientID, func(userData models.UserData, err error) {
+		defer r.wg.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err != nil {
+			userData.Message = err.Error()
+		}
+		r.results[clientID] = userData
+	})
+}
+
+func TestReadCoalescer(t *testing.T) {
+	t.Run("batches the reads of a window", func(t *testing.T) {
+		ts, provider := newBatchProvider(t)
+		c := NewReadCoalescer(NewSOAPClient(ts.URL), 20*time.Millisecond, 100)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		r.read(c, "missing")
+		r.read(c, "client2")
+		r.wg.Wait()
+
+		assert.Equal(t, [][]string{{"client1", "missing", "client2"}}, provider.sent())
+		assert.Equal(t, []string{"corr-client1,corr-missing,corr-client2"}, provider.correlations(), "the batch carries the correlation ID of every read")
+		assert.Equal(t, "client1", r.results["client1"].ClientID)
+		assert.Contains(t, r.results["missing"].Message, "404", "a missing ClientID gets the error ReadKYC returns")
+		assert.Equal(t, "client2", r.results["client2"].ClientID)
+	})
+
+	t.Run("sends a full batch at once", func(t *testing.T) {
+		ts, provider := newBatchProvider(t)
+		c := NewReadCoalescer(NewSOAPClient(ts.URL), time.Hour, 2)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		r.read(c, "client2")
+		assert.Equal(t, [][]string{{"client1", "client2"}}, provider.sent())
+		assert.Len(t, r.results, 2, "the reads are answered before Read returns")
+	})
+
+	t.Run("a late timer leaves the next window alone", func(t *testing.T) {
+		ts, provider := newBatchProvider(t)
+		c := NewReadCoalescer(NewSOAPClient(ts.URL), time.Hour, 2)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		stale := c.windows
+		r.read(c, "client2") // Full: sent before the window's timer fires
+		r.read(c, "client3")
+
+		// The first window's timer fired while the full batch was being sent
+		c.flush(&stale)
+		assert.Equal(t, int32(0), provider.reads.Load(), "client3 waits for its own window")
+		c.Flush()
+		assert.Equal(t, int32(1), provider.reads.Load())
+		assert.Equal(t, []string{"corr-client1,corr-client2", "corr-client3"}, provider.correlations())
+	})
+
+	t.Run("sends a lone read as a KYCQuery", func(t *testing.T) {
+		ts, provider := newBatchProvider(t)
+		c := NewReadCoalescer(NewSOAPClient(ts.URL), time.Hour, 100)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client123")
+		c.Flush()
+		assert.Empty(t, provider.sent())
+		assert.Equal(t, int32(1), provider.reads.Load())
+		assert.Equal(t, []string{"corr-client123"}, provider.correlations())
+		assert.Equal(t, "client123", r.results["client123"].ClientID)
+	})
+
+	t.Run("waits while the breaker is open", func(t *testing.T) {
+		ts, provider := newBatchProvider(t)
+		openFor := 100 * time.Millisecond
+		client := NewSOAPClient(ts.URL, WithCircuitBreaker(BreakerConfig{WindowSize: 1, MinimumCalls: 1, FailureRateThreshold: 1, OpenDuration: openFor, HalfOpenMaxCalls: 1}))
+		provider.failures.Store(1)
+		_, err := client.ReadKYC("client1")
+		require.Error(t, err)
+		require.Equal(t, StateOpen, client.BreakerState())
+		opened := time.Now()
+
+		c := NewReadCoalescer(client, 10*time.Millisecond, 100)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		r.read(c, "client2")
+		r.wg.Wait()
+		assert.GreaterOrEqual(t, time.Since(opened), openFor-10*time.Millisecond, "the timer's batch waited for the breaker")
+		assert.Equal(t, "client1", r.results["client1"].ClientID, r.results["client1"].Message)
+		assert.Equal(t, [][]string{{"client1", "client2"}}, provider.sent())
+	})
+
+	t.Run("returns once a batch is acknowledged", func(t *testing.T) {
+		listener, replyTo := newCallbackServer(t)
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			var request addressedRequest
+			var ids batchRequest
+			_ = xml.Unmarshal(body, &request)
+			_ = xml.Unmarshal(body, &ids)
+			w.WriteHeader(http.StatusAccepted)
+			go func() {
+				<-release
+				sendCallback(request.Header.ReplyTo, http.StatusOK, callbackBody(request.Header.MessageID, mockBatchResponse(ids.ClientIDs...)))
+			}()
+		}))
+		defer ts.Close()
+		c := NewReadCoalescer(NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second)), time.Hour, 100)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		r.read(c, "client2")
+		c.Flush()
+		assert.Equal(t, 1, listener.Pending(), "Flush does not wait for the callback")
+		close(release)
+		r.wg.Wait()
+		assert.Equal(t, "client2", r.results["client2"].ClientID)
+	})
+
+	t.Run("hands every read the batch error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+		c := NewReadCoalescer(NewSOAPClient(ts.URL), time.Hour, 100)
+		r := &readResults{results: make(map[string]models.UserData)}
+		r.read(c, "client1")
+		r.read(c, "client2")
+		c.Flush()
+		assert.Contains(t, r.results["client1"].Message, "503")
+		assert.Contains(t, r.results["client2"].Message, "503")
+	})
+}
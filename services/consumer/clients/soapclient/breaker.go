@@ -0,0 +1,367 @@
+package soapclient
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState represents the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String returns the human readable name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// ErrCircuitOpen is returned when a call is rejected because the breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrBulkheadFull is returned when no concurrency slot became free in time
+	ErrBulkheadFull = errors.New("bulkhead is full")
+)
+
+// breakerVars exposes the metrics of every breaker under /debug/vars
+var breakerVars = expvar.NewMap("soapclient_breakers")
+
+// BreakerConfig holds the thresholds used by a CircuitBreaker
+type BreakerConfig struct {
+	WindowSize            int           // Number of most recent calls used to compute the rates
+	MinimumCalls          int           // Calls required in the window before rates are evaluated
+	FailureRateThreshold  float64       // Failure rate (0..1) at which the breaker opens
+	SlowCallThreshold     time.Duration // Calls taking at least this long count as slow (0 disables)
+	SlowCallRateThreshold float64       // Slow-call rate (0..1) at which the breaker opens
+	OpenDuration          time.Duration // Time spent open before trial calls are allowed
+	HalfOpenMaxCalls      int           // Trial calls allowed while half-open
+	MaxConcurrent         int           // Bulkhead size (0 disables the bulkhead)
+	MaxWait               time.Duration // Time a call may wait for a bulkhead slot
+}
+
+// DefaultBreakerConfig returns the configuration used when none is supplied
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		WindowSize:            20,
+		MinimumCalls:          10,
+		FailureRateThreshold:  0.5,
+		SlowCallThreshold:     3 * time.Second,
+		SlowCallRateThreshold: 0.5,
+		OpenDuration:          30 * time.Second,
+		HalfOpenMaxCalls:      3,
+		MaxConcurrent:         10,
+		MaxWait:               0,
+	}
+}
+
+// BreakerMetrics is a point-in-time snapshot of a breaker's counters
+type BreakerMetrics struct {
+	State            string `json:"state"`
+	Successes        uint64 `json:"successes"`
+	Failures         uint64 `json:"failures"`
+	SlowCalls        uint64 `json:"slowCalls"`
+	Rejected         uint64 `json:"rejected"`
+	BulkheadRejected uint64 `json:"bulkheadRejected"`
+	Transitions      uint64 `json:"transitions"`
+}
+
+// callOutcome is a single entry of the sliding window
+type callOutcome struct {
+	failed bool
+	slow   bool
+}
+
+// CircuitBreaker protects a dependency with closed/open/half-open states and a concurrency bulkhead
+type CircuitBreaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu               sync.Mutex
+	state            BreakerState
+	openedAt         time.Time
+	window           []callOutcome
+	next             int
+	count            int
+	halfOpenInFlight int
+	halfOpenPassed   int
+	generation       uint64 // Incremented by every transition, to tell which state a call was admitted in
+	metrics          BreakerMetrics
+
+	bulkhead chan struct{}
+	now      func() time.Time
+
+	// OnStateChange, if set, is called after every state transition.
+	// It runs while the breaker is locked and must not call back into it.
+	OnStateChange func(name string, from, to BreakerState)
+}
+
+// NewCircuitBreaker initializes a new CircuitBreaker and publishes its metrics
+func NewCircuitBreaker(name string, cfg BreakerConfig) *CircuitBreaker {
+	def := DefaultBreakerConfig()
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = def.WindowSize
+	}
+	if cfg.MinimumCalls <= 0 || cfg.MinimumCalls > cfg.WindowSize {
+		cfg.MinimumCalls = cfg.WindowSize
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = def.OpenDuration
+	}
+
+	cb := &CircuitBreaker{
+		name:   name,
+		cfg:    cfg,
+		state:  StateClosed,
+		window: make([]callOutcome, cfg.WindowSize),
+		now:    time.Now,
+	}
+	if cfg.MaxConcurrent > 0 {
+		cb.bulkhead = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	breakerVars.Set(name, expvar.Func(func() any { return cb.Metrics() }))
+	log.Printf("Consumer Service: Circuit breaker '%s' initialized (failure rate %.2f, slow call %s at rate %.2f, open for %s, bulkhead %d)",
+		name, cfg.FailureRateThreshold, cfg.SlowCallThreshold, cfg.SlowCallRateThreshold, cfg.OpenDuration, cfg.MaxConcurrent)
+	return cb
+}
+
+// State returns the current state, moving from open to half-open once the open duration has elapsed
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refreshLocked()
+	return cb.state
+}
+
+// Metrics returns a snapshot of the breaker's counters
+func (cb *CircuitBreaker) Metrics() BreakerMetrics {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refreshLocked()
+	m := cb.metrics
+	m.State = cb.state.String()
+	return m
+}
+
+// Execute runs fn if the breaker and bulkhead allow it and records the outcome
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	if err := cb.acquire(); err != nil {
+		return err
+	}
+	defer cb.release()
+
+	generation, err := cb.allow()
+	if err != nil {
+		return err
+	}
+
+	start := cb.now()
+	err = fn()
+	cb.record(generation, isBreakerFailure(err), cb.cfg.SlowCallThreshold > 0 && cb.now().Sub(start) >= cb.cfg.SlowCallThreshold)
+	return err
+}
+
+// WaitUntilReady blocks while the breaker is open, returning once trial calls are allowed or ctx is done
+func (cb *CircuitBreaker) WaitUntilReady(ctx context.Context) error {
+	for {
+		cb.mu.Lock()
+		cb.refreshLocked()
+		if cb.state != StateOpen {
+			cb.mu.Unlock()
+			return nil
+		}
+		remaining := cb.openedAt.Add(cb.cfg.OpenDuration).Sub(cb.now())
+		cb.mu.Unlock()
+
+		if remaining <= 0 {
+			continue
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire takes a bulkhead slot, waiting up to MaxWait
+func (cb *CircuitBreaker) acquire() error {
+	if cb.bulkhead == nil {
+		return nil
+	}
+	select {
+	case cb.bulkhead <- struct{}{}:
+		return nil
+	default:
+	}
+	if cb.cfg.MaxWait > 0 {
+		timer := time.NewTimer(cb.cfg.MaxWait)
+		defer timer.Stop()
+		select {
+		case cb.bulkhead <- struct{}{}:
+			return nil
+		case <-timer.C:
+		}
+	}
+	cb.mu.Lock()
+	cb.metrics.BulkheadRejected++
+	cb.mu.Unlock()
+	log.Printf("Consumer Service: Circuit breaker '%s' rejected call: %v", cb.name, ErrBulkheadFull)
+	return ErrBulkheadFull
+}
+
+// release frees a bulkhead slot
+func (cb *CircuitBreaker) release() {
+	if cb.bulkhead != nil {
+		<-cb.bulkhead
+	}
+}
+
+// allow decides whether a call may proceed and returns the generation it was admitted in
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refreshLocked()
+
+	switch cb.state {
+	case StateOpen:
+		cb.metrics.Rejected++
+		return cb.generation, ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.halfOpenInFlight+cb.halfOpenPassed >= cb.cfg.HalfOpenMaxCalls {
+			cb.metrics.Rejected++
+			return cb.generation, ErrCircuitOpen
+		}
+		cb.halfOpenInFlight++
+	}
+	return cb.generation, nil
+}
+
+// record stores the outcome of a call and applies any resulting transition
+func (cb *CircuitBreaker) record(generation uint64, failed, slow bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if failed {
+		cb.metrics.Failures++
+	} else {
+		cb.metrics.Successes++
+	}
+	if slow {
+		cb.metrics.SlowCalls++
+	}
+	if generation != cb.generation {
+		// The state changed while the call was in flight, even if it has changed back since (e.g.
+		// half-open -> open -> half-open); its outcome and trial slot belong to a reset state
+		return
+	}
+
+	switch cb.state {
+	case StateHalfOpen:
+		cb.halfOpenInFlight--
+		if failed || slow {
+			cb.transitionLocked(StateOpen)
+			return
+		}
+		cb.halfOpenPassed++
+		if cb.halfOpenPassed >= cb.cfg.HalfOpenMaxCalls {
+			cb.transitionLocked(StateClosed)
+		}
+	case StateClosed:
+		cb.window[cb.next] = callOutcome{failed: failed, slow: slow}
+		cb.next = (cb.next + 1) % len(cb.window)
+		if cb.count < len(cb.window) {
+			cb.count++
+		}
+		if cb.count < cb.cfg.MinimumCalls {
+			return
+		}
+		var failures, slowCalls int
+		for i := 0; i < cb.count; i++ {
+			if cb.window[i].failed {
+				failures++
+			}
+			if cb.window[i].slow {
+				slowCalls++
+			}
+		}
+		failureRate := float64(failures) / float64(cb.count)
+		slowRate := float64(slowCalls) / float64(cb.count)
+		if (cb.cfg.FailureRateThreshold > 0 && failureRate >= cb.cfg.FailureRateThreshold) ||
+			(cb.cfg.SlowCallRateThreshold > 0 && slowRate >= cb.cfg.SlowCallRateThreshold) {
+			log.Printf("Consumer Service: Circuit breaker '%s' thresholds exceeded (failure rate %.2f, slow call rate %.2f)", cb.name, failureRate, slowRate)
+			cb.transitionLocked(StateOpen)
+		}
+	}
+}
+
+// refreshLocked moves an expired open breaker to half-open. cb.mu must be held.
+func (cb *CircuitBreaker) refreshLocked() {
+	if cb.state == StateOpen && !cb.now().Before(cb.openedAt.Add(cb.cfg.OpenDuration)) {
+		cb.transitionLocked(StateHalfOpen)
+	}
+}
+
+// transitionLocked changes state and resets the per-state bookkeeping. cb.mu must be held.
+func (cb *CircuitBreaker) transitionLocked(to BreakerState) {
+	from := cb.state
+	if from == to {
+		return
+	}
+	cb.state = to
+	cb.generation++
+	cb.metrics.Transitions++
+	cb.halfOpenInFlight = 0
+	cb.halfOpenPassed = 0
+	switch to {
+	case StateOpen:
+		cb.openedAt = cb.now()
+	case StateClosed:
+		cb.count = 0
+		cb.next = 0
+	}
+	log.Printf("Consumer Service: Circuit breaker '%s' state changed: %s -> %s", cb.name, from, to)
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(cb.name, from, to)
+	}
+}
+
+// isBreakerFailure reports whether err should count against the dependency.
+// Client-side SOAP statuses (e.g. 404, 409) and soapenv:Client faults are business outcomes and do not.
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var faultErr *FaultError
+	if errors.As(err, &faultErr) {
+		return faultErr.IsServerFault()
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package soapclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock lets tests move the breaker's notion of time forward
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(t *testing.T, cfg BreakerConfig) (*CircuitBreaker, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	cb := NewCircuitBreaker(t.Name(), cfg)
+	cb.now = clock.Now
+	return cb, clock
+}
+
+var errBoom = errors.New("boom")
+
+func TestCircuitBreaker_OpensOnFailureRate(t *testing.T) {
+	cb, _ := newTestBreaker(t, BreakerConfig{WindowSize: 4, MinimumCalls: 4, FailureRateThreshold: 0.5, OpenDuration: time.Minute})
+
+	assert.NoError(t, cb.Execute(func() error { return nil }))
+	assert.NoError(t, cb.Execute(func() error { return nil }))
+	assert.ErrorIs(t, cb.Execute(func() error { return errBoom }), errBoom)
+	assert.Equal(t, StateClosed, cb.State(), "minimum calls not reached yet")
+	assert.ErrorIs(t, cb.Execute(func() error { return errBoom }), errBoom)
+	assert.Equal(t, StateOpen, cb.State())
+
+	called := false
+	err := cb.Execute(func() error { called = true; return nil })
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, called)
+
+	m := cb.Metrics()
+	assert.Equal(t, "open", m.State)
+	assert.Equal(t, uint64(2), m.Failures)
+	assert.Equal(t, uint64(1), m.Rejected)
+	assert.Equal(t, uint64(1), m.Transitions)
+}
+
+func TestCircuitBreaker_OpensOnSlowCallRate(t *testing.T) {
+	cb, clock := newTestBreaker(t, BreakerConfig{WindowSize: 2, MinimumCalls: 2, SlowCallThreshold: time.Second, SlowCallRateThreshold: 1, OpenDuration: time.Minute})
+
+	slow := func() error { clock.Advance(2 * time.Second); return nil }
+	assert.NoError(t, cb.Execute(slow))
+	assert.NoError(t, cb.Execute(slow))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Equal(t, uint64(2), cb.Metrics().SlowCalls)
+}
+
+func TestCircuitBreaker_HalfOpenTransitions(t *testing.T) {
+	cfg := BreakerConfig{WindowSize: 1, MinimumCalls: 1, FailureRateThreshold: 1, OpenDuration: 10 * time.Second, HalfOpenMaxCalls: 2}
+
+	t.Run("Closes after successful trial calls", func(t *testing.T) {
+		cb, clock := newTestBreaker(t, cfg)
+		var transitions []BreakerState
+		cb.OnStateChange = func(_ string, _, to BreakerState) { transitions = append(transitions, to) }
+
+		_ = cb.Execute(func() error { return errBoom })
+		require.Equal(t, StateOpen, cb.State())
+
+		clock.Advance(10 * time.Second)
+		assert.Equal(t, StateHalfOpen, cb.State())
+		assert.NoError(t, cb.Execute(func() error { return nil }))
+		assert.Equal(t, StateHalfOpen, cb.State())
+		assert.NoError(t, cb.Execute(func() error { return nil }))
+		assert.Equal(t, StateClosed, cb.State())
+		assert.Equal(t, []BreakerState{StateOpen, StateHalfOpen, StateClosed}, transitions)
+	})
+
+	t.Run("Reopens on a failed trial call", func(t *testing.T) {
+		cb, clock := newTestBreaker(t, cfg)
+		_ = cb.Execute(func() error { return errBoom })
+		clock.Advance(10 * time.Second)
+
+		_ = cb.Execute(func() error { return errBoom })
+		assert.Equal(t, StateOpen, cb.State())
+	})
+
+	t.Run("Ignores a trial call admitted before the breaker reopened", func(t *testing.T) {
+		cb, clock := newTestBreaker(t, cfg)
+		_ = cb.Execute(func() error { return errBoom })
+		clock.Advance(10 * time.Second)
+
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = cb.Execute(func() error { <-release; return nil }) // In flight across the cycle
+		}()
+		require.Eventually(t, func() bool { return cb.Metrics().Rejected == 0 && halfOpenInFlight(cb) == 1 }, time.Second, time.Millisecond)
+		_ = cb.Execute(func() error { return errBoom }) // Reopens
+		clock.Advance(10 * time.Second)
+		require.Equal(t, StateHalfOpen, cb.State())
+
+		close(release)
+		<-done
+		assert.Equal(t, 0, halfOpenInFlight(cb), "the stale call does not release a slot of the new half-open state")
+		for i := 0; i < cfg.HalfOpenMaxCalls; i++ {
+			_, err := cb.allow()
+			require.NoError(t, err)
+		}
+		_, err := cb.allow()
+		assert.ErrorIs(t, err, ErrCircuitOpen, "no extra trial call is admitted")
+	})
+}
+
+func halfOpenInFlight(cb *CircuitBreaker) int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.halfOpenInFlight
+}
+
+func TestCircuitBreaker_IgnoresClientStatuses(t *testing.T) {
+	cb, _ := newTestBreaker(t, BreakerConfig{WindowSize: 2, MinimumCalls: 2, FailureRateThreshold: 0.5})
+
+	_ = cb.Execute(func() error { return &StatusError{StatusCode: http.StatusNotFound} })
+	_ = cb.Execute(func() error { return &StatusError{StatusCode: http.StatusConflict} })
+	assert.Equal(t, StateClosed, cb.State())
+
+	_ = cb.Execute(func() error { return &StatusError{StatusCode: http.StatusServiceUnavailable} })
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestCircuitBreaker_Bulkhead(t *testing.T) {
+	cb, _ := newTestBreaker(t, BreakerConfig{MaxConcurrent: 1, MaxWait: 10 * time.Millisecond})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- cb.Execute(func() error { close(started); <-release; return nil })
+	}()
+	<-started
+
+	err := cb.Execute(func() error { return nil })
+	assert.ErrorIs(t, err, ErrBulkheadFull)
+	assert.Equal(t, uint64(1), cb.Metrics().BulkheadRejected)
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.NoError(t, cb.Execute(func() error { return nil }))
+}
+
+func TestCircuitBreaker_WaitUntilReady(t *testing.T) {
+	cb := NewCircuitBreaker(t.Name(), BreakerConfig{WindowSize: 1, MinimumCalls: 1, FailureRateThreshold: 1, OpenDuration: 50 * time.Millisecond})
+	_ = cb.Execute(func() error { return errBoom })
+	require.Equal(t, StateOpen, cb.State())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, cb.WaitUntilReady(ctx), context.DeadlineExceeded)
+
+	assert.NoError(t, cb.WaitUntilReady(context.Background()))
+	assert.Equal(t, StateHalfOpen, cb.State())
+}
+
+func TestSOAPClient_WithCircuitBreaker(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "Internal Server Error")
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL, WithCircuitBreaker(BreakerConfig{WindowSize: 2, MinimumCalls: 2, FailureRateThreshold: 1, OpenDuration: time.Minute}))
+	assert.Equal(t, StateClosed, sc.BreakerState())
+
+	for i := 0; i < 2; i++ {
+		_, err := sc.ReadKYC("client123")
+		assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 500")
+	}
+	assert.Equal(t, StateOpen, sc.BreakerState())
+
+	_, err := sc.ReadKYC("client123")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, calls, "open breaker must not reach the SOAP service")
+}
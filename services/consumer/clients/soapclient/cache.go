@@ -0,0 +1,225 @@
+package soapclient
+
+import (
+	"container/list"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+)
+
+// cacheVars exposes the read-through cache counters under /debug/vars
+var cacheVars = expvar.NewMap("soapclient_cache")
+
+// CacheEntry is a cached ReadKYC result. NotFound entries cache the absence of a ClientID,
+// together with the NOT_FOUND fault when the service reported it as one.
+type CacheEntry struct {
+	UserData models.UserData
+	NotFound bool
+	Fault    *FaultError
+}
+
+// Cache is the storage used by the ReadKYC read-through cache. Keys are ClientIDs, prefixed with the
+// tenant for a client built WithTenant.
+// Implementations must be safe for concurrent use; an external cache can be plugged in through WithCache.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry, ttl time.Duration)
+	Delete(key string)
+}
+
+// CacheMetrics is a point-in-time snapshot of the read-through cache counters
+type CacheMetrics struct {
+	Hits          uint64 `json:"hits"`
+	NegativeHits  uint64 `json:"negativeHits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// cacheKey is the cache key of clientID in tenant; the default tenant keys by ClientID alone
+func cacheKey(tenant, clientID string) string {
+	if tenant == "" {
+		return clientID
+	}
+	return tenant + "\x00" + clientID
+}
+
+// readCache wraps a Cache with the TTLs and counters used by SOAPClient.
+// A read that fills the cache is bracketed by beginFill and endFill; an invalidation while it is in
+// flight moves the key to a new generation, so the result it read before the mutation is dropped.
+type readCache struct {
+	store       Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu    sync.Mutex       // Orders fills and invalidations of a key
+	fills map[string]*fill // Keys with a read in flight
+
+	hits          atomic.Uint64
+	negativeHits  atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+// newReadCache initializes a readCache and publishes its metrics
+func newReadCache(name string, store Cache, ttl, negativeTTL time.Duration) *readCache {
+	rc := &readCache{store: store, ttl: ttl, negativeTTL: negativeTTL, fills: make(map[string]*fill)}
+	cacheVars.Set(name, expvar.Func(func() any { return rc.metrics() }))
+	return rc
+}
+
+// fill tracks the reads in flight for one key
+type fill struct {
+	readers    int
+	generation uint64
+}
+
+// get looks up key and updates the hit/miss counters
+func (rc *readCache) get(key string) (CacheEntry, bool) {
+	entry, ok := rc.store.Get(key)
+	switch {
+	case !ok:
+		rc.misses.Add(1)
+	case entry.NotFound:
+		rc.negativeHits.Add(1)
+	default:
+		rc.hits.Add(1)
+	}
+	return entry, ok
+}
+
+// beginFill registers a read of key that is about to be sent, returning the generation to pass to endFill
+func (rc *readCache) beginFill(key string) uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	f, ok := rc.fills[key]
+	if !ok {
+		f = &fill{}
+		rc.fills[key] = f
+	}
+	f.readers++
+	return f.generation
+}
+
+// endFill ends a read of key, storing entry unless it is nil, the key was invalidated since beginFill
+// or negative caching is disabled
+func (rc *readCache) endFill(key string, generation uint64, entry *CacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	f := rc.fills[key]
+	if f.readers--; f.readers == 0 {
+		delete(rc.fills, key)
+	}
+	if entry == nil || f.generation != generation {
+		return
+	}
+	ttl := rc.ttl
+	if entry.NotFound {
+		ttl = rc.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	rc.store.Set(key, *entry, ttl)
+}
+
+// invalidate drops key after a successful mutation
+func (rc *readCache) invalidate(key string) {
+	rc.invalidations.Add(1)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if f, ok := rc.fills[key]; ok {
+		f.generation++
+	}
+	rc.store.Delete(key)
+}
+
+func (rc *readCache) metrics() CacheMetrics {
+	return CacheMetrics{
+		Hits:          rc.hits.Load(),
+		NegativeHits:  rc.negativeHits.Load(),
+		Misses:        rc.misses.Load(),
+		Invalidations: rc.invalidations.Load(),
+	}
+}
+
+// lruItem is a single LRUCache element
+type lruItem struct {
+	clientID  string
+	entry     CacheEntry
+	expiresAt time.Time
+}
+
+// LRUCache is an in-memory Cache with per-entry expiry and a maximum number of entries
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is most recently used
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+// NewLRUCache initializes a new LRUCache. maxEntries <= 0 means unbounded.
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Get returns the unexpired entry for clientID
+func (c *LRUCache) Get(clientID string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[clientID]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	item := el.Value.(*lruItem)
+	if !c.now().Before(item.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, clientID)
+		return CacheEntry{}, false
+	}
+	c.order.MoveToFront(el)
+	return item.entry, true
+}
+
+// Set stores entry for ttl, evicting the least recently used entry when full
+func (c *LRUCache) Set(clientID string, entry CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := c.now().Add(ttl)
+	if el, ok := c.items[clientID]; ok {
+		el.Value = &lruItem{clientID: clientID, entry: entry, expiresAt: expiresAt}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[clientID] = c.order.PushFront(&lruItem{clientID: clientID, entry: entry, expiresAt: expiresAt})
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).clientID)
+	}
+}
+
+// Delete removes clientID from the cache
+func (c *LRUCache) Delete(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[clientID]; ok {
+		c.order.Remove(el)
+		delete(c.items, clientID)
+	}
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,197 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	t.Run("Evicts least recently used entry", func(t *testing.T) {
+		c := NewLRUCache(2)
+		c.Set("a", CacheEntry{UserData: models.UserData{Record: kyc.Record{ClientID: "a"}}}, time.Minute)
+		c.Set("b", CacheEntry{UserData: models.UserData{Record: kyc.Record{ClientID: "b"}}}, time.Minute)
+		_, _ = c.Get("a") // a becomes most recently used
+		c.Set("c", CacheEntry{UserData: models.UserData{Record: kyc.Record{ClientID: "c"}}}, time.Minute)
+
+		_, ok := c.Get("b")
+		assert.False(t, ok)
+		_, ok = c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("Expires entries after their TTL", func(t *testing.T) {
+		c := NewLRUCache(0)
+		now := time.Unix(0, 0)
+		c.now = func() time.Time { return now }
+		c.Set("a", CacheEntry{}, time.Second)
+
+		_, ok := c.Get("a")
+		assert.True(t, ok)
+		now = now.Add(time.Second)
+		_, ok = c.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("Delete removes entry", func(t *testing.T) {
+		c := NewLRUCache(0)
+		c.Set("a", CacheEntry{}, time.Minute)
+		c.Delete("a")
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+	})
+}
+
+func TestReadKYC_WithCache(t *testing.T) {
+	var reads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "<KYCQuery") && strings.Contains(string(body), "missing"):
+			reads.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(string(body), "<KYCQuery"):
+			reads.Add(1)
+			_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+		case strings.Contains(string(body), "<UpdateKYC"):
+			_, _ = io.WriteString(w, mockUpdateKYCResponseSuccess)
+		case strings.Contains(string(body), "<DeleteKYC"):
+			_, _ = io.WriteString(w, mockDeleteKYCResponseSuccess)
+		}
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL, WithCache(NewLRUCache(10), time.Minute, time.Minute))
+
+	t.Run("Caches found records", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			userData, err := sc.ReadKYC("client123")
+			require.NoError(t, err)
+			assert.Equal(t, "client123", userData.ClientID)
+		}
+		assert.Equal(t, int32(1), reads.Load())
+	})
+
+	t.Run("Caches not-found results", func(t *testing.T) {
+		reads.Store(0)
+		for i := 0; i < 2; i++ {
+			_, err := sc.ReadKYC("missing")
+			assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 404")
+		}
+		assert.Equal(t, int32(1), reads.Load())
+	})
+
+	t.Run("Update and delete invalidate the entry", func(t *testing.T) {
+		reads.Store(0)
+		_, err := sc.UpdateKYC(models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}})
+		require.NoError(t, err)
+		_, err = sc.ReadKYC("client123")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), reads.Load())
+
+		_, err = sc.DeleteKYC("client123")
+		require.NoError(t, err)
+		_, err = sc.ReadKYC("client123")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), reads.Load())
+	})
+
+	m := sc.CacheMetrics()
+	assert.Equal(t, uint64(2), m.Hits)
+	assert.Equal(t, uint64(1), m.NegativeHits)
+	assert.Equal(t, uint64(4), m.Misses)
+	assert.Equal(t, uint64(2), m.Invalidations)
+}
+
+func TestReadKYC_NegativeCachingDisabled(t *testing.T) {
+	var reads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads.Add(1)
+		_, _ = io.WriteString(w, mockReadKYCResponseError)
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL, WithCache(NewLRUCache(10), time.Minute, 0))
+	for i := 0; i < 2; i++ {
+		userData, err := sc.ReadKYC("nonexistent")
+		require.NoError(t, err)
+		assert.Equal(t, "Error", userData.Status)
+	}
+	assert.Equal(t, int32(2), reads.Load())
+}
+
+func TestReadKYC_CacheKeyedByTenant(t *testing.T) {
+	var reads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads.Add(1)
+		if r.Header.Get("X-KYC-Tenant") == "suite-b" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	store := NewLRUCache(10)
+	tenantA := NewSOAPClient(ts.URL, WithTenant("suite-a"), WithCache(store, time.Minute, time.Minute))
+	tenantB := NewSOAPClient(ts.URL, WithTenant("suite-b"), WithCache(store, time.Minute, time.Minute))
+
+	_, err := tenantA.ReadKYC("client123")
+	require.NoError(t, err)
+	_, err = tenantB.ReadKYC("client123")
+	assert.Contains(t, err.Error(), "404", "suite-b is not served suite-a's record")
+	_, err = tenantA.WithCorrelationID("corr-1").ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reads.Load())
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestReadKYC_InvalidationDuringRead(t *testing.T) {
+	reading, release := make(chan struct{}), make(chan struct{})
+	var reads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "<UpdateKYC"):
+			_, _ = io.WriteString(w, mockUpdateKYCResponseSuccess)
+		case reads.Add(1) == 1:
+			close(reading)
+			<-release
+			_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+		default:
+			_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+		}
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL, WithCache(NewLRUCache(10), time.Minute, time.Minute))
+	done := make(chan error)
+	go func() {
+		_, err := sc.ReadKYC("client123")
+		done <- err
+	}()
+	<-reading
+	_, err := sc.UpdateKYC(models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}})
+	require.NoError(t, err)
+	close(release)
+	require.NoError(t, <-done)
+
+	_, err = sc.ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reads.Load(), "the result read before the update is not cached")
+	_, err = sc.ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reads.Load())
+}
@@ -0,0 +1,198 @@
+package soapclient
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wsaNamespace is the WS-Addressing 1.0 namespace of MessageID, ReplyTo and RelatesTo
+const wsaNamespace = "http://www.w3.org/2005/08/addressing"
+
+// callbackStatusHeader carries the HTTP status the provider would have answered a synchronous call with
+const callbackStatusHeader = "X-KYC-Response-Status"
+
+// callbackResponse is a response delivered to the CallbackListener
+type callbackResponse struct {
+	status int
+	body   []byte
+}
+
+// CallbackListener receives the responses of asynchronous SOAP calls. The provider POSTs each one
+// to the wsa:ReplyTo address of its request, and the listener hands it to the call waiting for the
+// wsa:MessageID it RelatesTo. Serve it on the ReplyTo address given to WithCallbacks.
+type CallbackListener struct {
+	mu      sync.Mutex
+	pending map[string]chan callbackResponse
+}
+
+// NewCallbackListener returns a listener with no pending calls
+func NewCallbackListener() *CallbackListener {
+	return &CallbackListener{pending: make(map[string]chan callbackResponse)}
+}
+
+// Pending returns the number of calls waiting for their callback
+func (l *CallbackListener) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.pending)
+}
+
+// expect registers a call waiting for the response to messageID
+func (l *CallbackListener) expect(messageID string) <-chan callbackResponse {
+	ch := make(chan callbackResponse, 1)
+	l.mu.Lock()
+	l.pending[messageID] = ch
+	l.mu.Unlock()
+	return ch
+}
+
+// forget stops waiting for the response to messageID
+func (l *CallbackListener) forget(messageID string) {
+	l.mu.Lock()
+	delete(l.pending, messageID)
+	l.mu.Unlock()
+}
+
+// ServeHTTP accepts a callback with 202 Accepted, or answers 404 Not Found when no call is waiting
+// for the message it relates to, e.g. because the call timed out
+func (l *CallbackListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read callback: %v", err), http.StatusBadRequest)
+		return
+	}
+	var envelope struct {
+		Header struct {
+			RelatesTo string `xml:"http://www.w3.org/2005/08/addressing RelatesTo"`
+		} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Header.RelatesTo == "" {
+		http.Error(w, "Callback has no wsa:RelatesTo header", http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	if s := r.Header.Get(callbackStatusHeader); s != "" {
+		if status, err = strconv.Atoi(s); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s '%s'", callbackStatusHeader, s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	messageID := strings.TrimSpace(envelope.Header.RelatesTo)
+	l.mu.Lock()
+	ch, ok := l.pending[messageID]
+	delete(l.pending, messageID)
+	l.mu.Unlock()
+	if !ok {
+		log.Printf("Consumer Service: Dropped a callback for unknown message %s", messageID)
+		http.Error(w, fmt.Sprintf("No call is waiting for message %s", messageID), http.StatusNotFound)
+		return
+	}
+	ch <- callbackResponse{status: status, body: body}
+	log.Printf("Consumer Service: Received callback for message %s (status %d)", messageID, status)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// callbacks is the asynchronous mode configured by WithCallbacks
+type callbacks struct {
+	replyTo  string
+	listener *CallbackListener
+	timeout  time.Duration
+}
+
+// WithCallbacks asks the provider to answer every call asynchronously by POSTing the response to
+// replyTo, where listener must be served. A call waits up to timeout for its callback; a provider
+// that answers synchronously anyway is handled as without this option.
+func WithCallbacks(replyTo string, listener *CallbackListener, timeout time.Duration) Option {
+	return func(sc *SOAPClient) {
+		sc.callbacks = &callbacks{replyTo: replyTo, listener: listener, timeout: timeout}
+	}
+}
+
+// CallbackTimeoutError is returned when the callback of an acknowledged call does not arrive in time
+type CallbackTimeoutError struct {
+	MessageID string
+	Timeout   time.Duration
+}
+
+func (e *CallbackTimeoutError) Error() string {
+	return fmt.Sprintf("no callback for SOAP message %s within %s", e.MessageID, e.Timeout)
+}
+
+// OnAccepted returns a client that calls fn when the provider acknowledges one of its calls with
+// 202 Accepted, before it starts waiting for the callback. It lets a caller move on once a call is
+// under way without waiting for its response. The returned client shares the transport, breaker,
+// endpoints and cache of sc.
+func (sc *SOAPClient) OnAccepted(fn func()) *SOAPClient {
+	scoped := *sc
+	scoped.accepted = fn
+	return &scoped
+}
+
+// acknowledgement is a call the provider has accepted, waiting for its callback
+type acknowledgement struct {
+	messageID string
+	reply     <-chan callbackResponse
+}
+
+// withAddressing adds the WS-Addressing headers of an asynchronous call to a SOAP envelope,
+// into its soapenv:Header or a new one when it has none
+func withAddressing(envelope []byte, messageID, replyTo, soapAction, to string) []byte {
+	var headers bytes.Buffer
+	fmt.Fprintf(&headers, `<wsa:MessageID xmlns:wsa="%s">%s</wsa:MessageID>`, wsaNamespace, xmlEscape(messageID))
+	fmt.Fprintf(&headers, `<wsa:ReplyTo xmlns:wsa="%s"><wsa:Address>%s</wsa:Address></wsa:ReplyTo>`, wsaNamespace, xmlEscape(replyTo))
+	fmt.Fprintf(&headers, `<wsa:Action xmlns:wsa="%s">%s</wsa:Action>`, wsaNamespace, xmlEscape(soapAction))
+	fmt.Fprintf(&headers, `<wsa:To xmlns:wsa="%s">%s</wsa:To>`, wsaNamespace, xmlEscape(to))
+
+	if i := bytes.Index(envelope, []byte("</soapenv:Header>")); i >= 0 {
+		return append(append(envelope[:i:i], headers.Bytes()...), envelope[i:]...)
+	}
+	i := bytes.Index(envelope, []byte("<soapenv:Body"))
+	if i < 0 {
+		return envelope
+	}
+	header := append(append([]byte("<soapenv:Header>"), headers.Bytes()...), "</soapenv:Header>"...)
+	return append(append(envelope[:i:i], header...), envelope[i:]...)
+}
+
+// awaitCallback waits for the callback of an acknowledged call and returns the response it carries.
+// A callback that does not arrive in time is reported to the caller only: the call was delivered,
+// so it counts against neither the breaker nor the endpoint.
+func (sc *SOAPClient) awaitCallback(ack *acknowledgement) ([]byte, error) {
+	defer sc.callbacks.listener.forget(ack.messageID)
+	log.Printf("Consumer Service: SOAP service accepted message %s, waiting for its callback", ack.messageID)
+	if sc.accepted != nil {
+		sc.accepted()
+	}
+	timer := time.NewTimer(sc.callbacks.timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ack.reply:
+		return responseBody(resp.status, resp.body)
+	case <-timer.C:
+		return nil, &CallbackTimeoutError{MessageID: ack.messageID, Timeout: sc.callbacks.timeout}
+	}
+}
+
+// newMessageID returns a random (version 4) urn:uuid message ID
+func newMessageID() string {
+	b := make([]byte, 16)
+	rand.Read(b) // Never fails, see crypto/rand.Read
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
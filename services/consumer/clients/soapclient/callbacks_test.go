@@ -0,0 +1,224 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/clients/soapclient/kycsoap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addressedRequest is the WS-Addressing header of a request as the mock provider reads it
+type addressedRequest struct {
+	Header struct {
+		MessageID string `xml:"http://www.w3.org/2005/08/addressing MessageID"`
+		ReplyTo   string `xml:"http://www.w3.org/2005/08/addressing ReplyTo>Address"`
+		Action    string `xml:"http://www.w3.org/2005/08/addressing Action"`
+		To        string `xml:"http://www.w3.org/2005/08/addressing To"`
+	} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+}
+
+// callbackBody adds a wsa:RelatesTo header for messageID to a mock response
+func callbackBody(messageID, response string) string {
+	header := fmt.Sprintf(`<soapenv:Header><wsa:RelatesTo xmlns:wsa="%s">%s</wsa:RelatesTo></soapenv:Header>`, wsaNamespace, messageID)
+	return strings.Replace(response, "<soapenv:Body>", header+"<soapenv:Body>", 1)
+}
+
+// sendCallback POSTs a callback the way the provider does, returning the listener's status
+func sendCallback(replyTo string, status int, body string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, replyTo, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(callbackStatusHeader, strconv.Itoa(status))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func postCallback(t *testing.T, replyTo string, status int, body string) int {
+	t.Helper()
+	code, err := sendCallback(replyTo, status, body)
+	require.NoError(t, err)
+	return code
+}
+
+// newAsyncProvider returns a mock provider that acknowledges every request and calls back with
+// the given status and response
+func newAsyncProvider(t *testing.T, status int, response string) (*httptest.Server, *atomic.Value) {
+	t.Helper()
+	requests := &atomic.Value{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests.Store(string(body))
+		var request addressedRequest
+		if err := xml.Unmarshal(body, &request); err != nil || request.Header.ReplyTo == "" {
+			http.Error(w, "not an asynchronous request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		go sendCallback(request.Header.ReplyTo, status, callbackBody(request.Header.MessageID, response))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, requests
+}
+
+func newCallbackServer(t *testing.T) (*CallbackListener, string) {
+	t.Helper()
+	listener := NewCallbackListener()
+	ts := httptest.NewServer(listener)
+	t.Cleanup(ts.Close)
+	return listener, ts.URL + "/callback"
+}
+
+func TestCallbacks_ReadKYC(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	ts, requests := newAsyncProvider(t, http.StatusOK, mockReadKYCResponseSuccess)
+	client := NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second))
+
+	userData, err := client.ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, "client123", userData.ClientID)
+	assert.Equal(t, 0.5, userData.Risk)
+	assert.Zero(t, listener.Pending())
+
+	var request addressedRequest
+	require.NoError(t, xml.Unmarshal([]byte(requests.Load().(string)), &request))
+	assert.True(t, strings.HasPrefix(request.Header.MessageID, "urn:uuid:"))
+	assert.Equal(t, replyTo, request.Header.ReplyTo)
+	assert.Equal(t, "http://example.com/kyc/KYCQuery", request.Header.Action)
+	assert.Equal(t, ts.URL, request.Header.To)
+	assert.Contains(t, requests.Load(), `<KYCRequest xmlns="http://example.com/kyc"/>`, "the existing header is kept")
+}
+
+func TestCallbacks_GeneratedClient(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	ts, requests := newAsyncProvider(t, http.StatusOK, mockReadKYCResponseSuccess)
+	client := kycsoap.NewKYCPortTypeClient(NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second)))
+
+	resp, err := client.KYCQuery(&kycsoap.KYCQuery{ClientID: "client123"})
+	require.NoError(t, err)
+	assert.Equal(t, "Success", resp.Status)
+	assert.Contains(t, requests.Load(), "<soapenv:Header><wsa:MessageID", "a header is added to envelopes without one")
+}
+
+func TestCallbacks_Fault(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	ts, _ := newAsyncProvider(t, http.StatusInternalServerError, faultResponse("soapenv:Client", "NOT_FOUND", "user not found"))
+	client := NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second))
+
+	_, err := client.ReadKYC("client123")
+	var fault *FaultError
+	require.ErrorAs(t, err, &fault)
+	assert.Equal(t, http.StatusInternalServerError, fault.StatusCode)
+	assert.Equal(t, "NOT_FOUND", fault.ErrorCode)
+}
+
+func TestCallbacks_Timeout(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	var messageID atomic.Value
+	var requests atomic.Int32
+	acknowledge := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request addressedRequest
+		body, _ := io.ReadAll(r.Body)
+		_ = xml.Unmarshal(body, &request)
+		messageID.Store(request.Header.MessageID)
+		requests.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ts, standby := httptest.NewServer(acknowledge), httptest.NewServer(acknowledge)
+	defer ts.Close()
+	defer standby.Close()
+	cfg := DefaultBreakerConfig()
+	cfg.MinimumCalls, cfg.WindowSize = 1, 1
+	client := NewSOAPClient(ts.URL, WithEndpoints(standby.URL), WithCircuitBreaker(cfg), WithCallbacks(replyTo, listener, 20*time.Millisecond))
+	defer client.Close()
+
+	_, err := client.ReadKYC("client123")
+	var timeout *CallbackTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, messageID.Load(), timeout.MessageID)
+	assert.Equal(t, int32(1), requests.Load(), "an acknowledged call is not sent to another endpoint")
+	assert.Equal(t, StateClosed, client.BreakerState(), "a missing callback does not count against the breaker")
+	assert.Zero(t, listener.Pending())
+
+	late := callbackBody(timeout.MessageID, mockReadKYCResponseSuccess)
+	assert.Equal(t, http.StatusNotFound, postCallback(t, replyTo, http.StatusOK, late), "a late callback is refused")
+}
+
+func TestCallbacks_OnAccepted(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	ts, _ := newAsyncProvider(t, http.StatusOK, mockReadKYCResponseSuccess)
+	client := NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second))
+
+	var accepted atomic.Int32
+	userData, err := client.OnAccepted(func() { accepted.Add(1) }).ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, "client123", userData.ClientID)
+	assert.Equal(t, int32(1), accepted.Load())
+
+	sync := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer sync.Close()
+	_, err = NewSOAPClient(sync.URL, WithCallbacks(replyTo, listener, time.Second)).OnAccepted(func() { accepted.Add(1) }).ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), accepted.Load(), "a synchronous answer is not an acknowledgement")
+}
+
+func TestCallbacks_SynchronousAnswer(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+	client := NewSOAPClient(ts.URL, WithCallbacks(replyTo, listener, time.Second))
+
+	userData, err := client.ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, "client123", userData.ClientID)
+	assert.Zero(t, listener.Pending())
+}
+
+func TestCallbackListener(t *testing.T) {
+	listener, replyTo := newCallbackServer(t)
+
+	resp, err := http.Get(replyTo)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, http.StatusBadRequest, postCallback(t, replyTo, http.StatusOK, mockReadKYCResponseSuccess), "RelatesTo is required")
+	assert.Equal(t, http.StatusNotFound, postCallback(t, replyTo, http.StatusOK, callbackBody("urn:uuid:unknown", mockReadKYCResponseSuccess)))
+
+	reply := listener.expect("urn:uuid:msg-1")
+	assert.Equal(t, 1, listener.Pending())
+	assert.Equal(t, http.StatusAccepted, postCallback(t, replyTo, http.StatusNotFound, callbackBody("urn:uuid:msg-1", mockReadKYCResponseError)))
+	response := <-reply
+	assert.Equal(t, http.StatusNotFound, response.status)
+	assert.Contains(t, string(response.body), "<Status>Error</Status>")
+	assert.Zero(t, listener.Pending())
+	assert.Equal(t, http.StatusNotFound, postCallback(t, replyTo, http.StatusOK, callbackBody("urn:uuid:msg-1", mockReadKYCResponseSuccess)), "a message is completed once")
+}
+
+func TestWithAddressing(t *testing.T) {
+	envelope := []byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body/></soapenv:Envelope>`)
+	addressed := withAddressing(envelope, "urn:uuid:msg-1", "http://consumer/callback?a=1&b=2", "http://example.com/kyc/KYCQuery", "http://provider/soap")
+
+	var request addressedRequest
+	require.NoError(t, xml.Unmarshal(addressed, &request), string(addressed))
+	assert.Equal(t, "urn:uuid:msg-1", request.Header.MessageID)
+	assert.Equal(t, "http://consumer/callback?a=1&b=2", request.Header.ReplyTo, "addresses are escaped")
+}
@@ -0,0 +1,112 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSOAPClient_RequestsMatchContract checks every envelope the client sends against the provider's WSDL and XSD
+func TestSOAPClient_RequestsMatchContract(t *testing.T) {
+	var action string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action = r.Header.Get("SOAPAction")
+		body, _ = io.ReadAll(r.Body)
+		switch action {
+		case "http://example.com/kyc/DeleteKYC":
+			_, _ = io.WriteString(w, mockDeleteKYCResponseSuccess)
+			return
+		case "http://example.com/kyc/GetKYCStatus":
+			_, _ = io.WriteString(w, mockKYCStatusResponseSuccess)
+			return
+		case "http://example.com/kyc/ScoreKYC":
+			_, _ = io.WriteString(w, mockScoreKYCResponseSuccess)
+			return
+		case "http://example.com/kyc/GetKYCHistory":
+			_, _ = io.WriteString(w, mockKYCHistoryResponseSuccess)
+			return
+		case "http://example.com/kyc/SearchKYC":
+			_, _ = io.WriteString(w, mockSearchKYCResponseSuccess)
+			return
+		case "http://example.com/kyc/BatchKYCQuery":
+			_, _ = io.WriteString(w, mockBatchResponse("client1", "client<2>&"))
+			return
+		}
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	defer ts.Close()
+	sc := NewSOAPClient(ts.URL)
+
+	calls := map[string]func() error{
+		"KYCQuery": func() error { _, err := sc.ReadKYC("client<1>&"); return err },
+		"CreateKYC": func() error {
+			_, err := sc.CreateKYC(models.UserData{Record: kyc.Record{
+				ClientID:     "client1",
+				Risk:         0.25,
+				LegalName:    "O'Brien & Sons <Ltd>",
+				DateOfBirth:  "1980-01-31",
+				Nationality:  "IE",
+				Addresses:    []kyc.Address{{Line1: "1 Main St", City: "Dublin", Country: "IE"}},
+				Documents:    []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "PA1", ExpiryDate: "2030-01-01"}},
+				PEP:          true,
+				ReviewStatus: kyc.ReviewPending,
+			}})
+			return err
+		},
+		"UpdateKYC": func() error {
+			_, err := sc.UpdateKYCExpecting(models.UserData{Record: kyc.Record{ClientID: "client1", Risk: 0.75}}, 3)
+			return err
+		},
+		"DeleteKYC":     func() error { _, err := sc.DeleteKYC("client1"); return err },
+		"SubmitKYC":     func() error { _, err := sc.SubmitKYC("client1"); return err },
+		"ApproveKYC":    func() error { _, err := sc.ApproveKYC("client1", "documents verified"); return err },
+		"RejectKYC":     func() error { _, err := sc.RejectKYC("client1", "document <forged>"); return err },
+		"GetKYCStatus":  func() error { _, err := sc.GetKYCStatus("client1"); return err },
+		"GetKYCHistory": func() error { _, err := sc.GetKYCHistory("client1"); return err },
+		"BatchKYCQuery": func() error { _, err := sc.ReadKYCBatch([]string{"client1", "client<2>&"}); return err },
+		"SearchKYC": func() error {
+			minRisk, maxRisk, createdAfter := 0.25, 0.75, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+			_, err := sc.SearchKYC(models.SearchCriteria{
+				MinRisk:        &minRisk,
+				MaxRisk:        &maxRisk,
+				ReviewStatuses: []kyc.ReviewStatus{kyc.ReviewApproved, kyc.ReviewRejected},
+				NameContains:   "O'Brien & <Sons>",
+				DocumentNumber: "PA1",
+				CreatedAfter:   &createdAfter,
+			}, 50, "token-1")
+			return err
+		},
+		"ScoreKYC": func() error {
+			_, err := sc.ScoreKYC(models.UserData{Record: kyc.Record{
+				ClientID:     "client1",
+				Nationality:  "IR",
+				CustomFields: []kyc.CustomField{{Key: "creditScore", Value: "420"}},
+			}})
+			return err
+		},
+	}
+
+	for _, op := range contract.Operations() {
+		t.Run(op.Name, func(t *testing.T) {
+			call, ok := calls[op.Name]
+			require.True(t, ok, "SOAPClient does not implement %s", op.Name)
+			require.NoError(t, call())
+
+			assert.Equal(t, op.SOAPAction, action)
+			root, violations, err := contract.KYCSchema().ValidateEnvelope(body)
+			require.NoError(t, err)
+			assert.Equal(t, op.Input, root.Local)
+			assert.Empty(t, violations)
+		})
+	}
+}
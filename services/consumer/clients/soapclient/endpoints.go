@@ -0,0 +1,254 @@
+package soapclient
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Strategy selects which endpoint serves the next SOAP call
+type Strategy string
+
+const (
+	StrategyRoundRobin Strategy = "round-robin" // Rotate over healthy endpoints
+	StrategyPriority   Strategy = "priority"    // Always use the first healthy endpoint, in the order given
+	StrategyLatency    Strategy = "latency"     // Use the healthy endpoint with the lowest observed latency
+)
+
+const (
+	defaultMaxEndpointFailures = 3
+	defaultProbeInterval       = 5 * time.Second
+	latencySmoothing           = 0.3 // Weight of the newest sample in the moving average
+)
+
+// endpointVars exposes the health of every endpoint under /debug/vars
+var endpointVars = expvar.NewMap("soapclient_endpoints")
+
+// EndpointStatus is a point-in-time snapshot of a single endpoint
+type EndpointStatus struct {
+	URL                 string        `json:"url"`
+	Healthy             bool          `json:"healthy"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	Latency             time.Duration `json:"latency"`
+}
+
+// endpoint tracks the passive health of a single SOAP service URL
+type endpoint struct {
+	url                 string
+	healthy             bool
+	consecutiveFailures int
+	latency             time.Duration // Exponentially weighted moving average
+	ejectedAt           time.Time
+}
+
+// endpointPool selects endpoints, ejects failing ones and re-admits them through active probing
+type endpointPool struct {
+	mu          sync.Mutex
+	endpoints   []*endpoint
+	strategy    Strategy
+	next        int
+	maxFailures int
+
+	probeInterval time.Duration
+	probeClient   *http.Client
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// newEndpointPool initializes a pool over urls, in priority order, publishing its status as name
+func newEndpointPool(name string, urls []string, strategy Strategy) *endpointPool {
+	p := &endpointPool{
+		strategy:      strategy,
+		maxFailures:   defaultMaxEndpointFailures,
+		probeInterval: defaultProbeInterval,
+		probeClient:   &http.Client{Timeout: 2 * time.Second},
+		stop:          make(chan struct{}),
+	}
+	for _, url := range urls {
+		p.endpoints = append(p.endpoints, &endpoint{url: url, healthy: true})
+	}
+	endpointVars.Set(name, expvar.Func(func() any { return p.Status() }))
+	return p
+}
+
+// pick returns the endpoint for the next attempt, skipping those already tried.
+// When every untried endpoint is ejected, the one ejected longest ago is used as a last resort.
+func (p *endpointPool) pick(tried map[*endpoint]bool) (*endpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var candidates []*endpoint
+	var fallback *endpoint
+	for _, e := range p.endpoints {
+		if tried[e] {
+			continue
+		}
+		if e.healthy {
+			candidates = append(candidates, e)
+		} else if fallback == nil || e.ejectedAt.Before(fallback.ejectedAt) {
+			fallback = e
+		}
+	}
+	if len(candidates) == 0 {
+		if fallback == nil {
+			return nil, fmt.Errorf("no SOAP endpoints left to try")
+		}
+		return fallback, nil
+	}
+
+	switch p.strategy {
+	case StrategyPriority:
+		return candidates[0], nil
+	case StrategyLatency:
+		// An endpoint without a sample yet ranks at the average of the measured ones rather than
+		// at zero, so it no longer beats every measured endpoint; ties go to the earlier endpoint
+		var measured, total time.Duration
+		for _, e := range candidates {
+			if e.latency > 0 {
+				measured++
+				total += e.latency
+			}
+		}
+		ranked := func(e *endpoint) time.Duration {
+			if e.latency == 0 && measured > 0 {
+				return total / measured
+			}
+			return e.latency
+		}
+		best := candidates[0]
+		for _, e := range candidates[1:] {
+			if ranked(e) < ranked(best) {
+				best = e
+			}
+		}
+		return best, nil
+	default:
+		e := candidates[p.next%len(candidates)]
+		p.next++
+		return e, nil
+	}
+}
+
+// reportSuccess records a successful call and its latency
+func (p *endpointPool) reportSuccess(e *endpoint, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.consecutiveFailures = 0
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(e.latency))
+	}
+	if !e.healthy {
+		e.healthy = true
+		log.Printf("Consumer Service: SOAP endpoint %s re-admitted after a successful call", e.url)
+	}
+}
+
+// reportFailure records a failed call and ejects the endpoint after too many consecutive failures
+func (p *endpointPool) reportFailure(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.consecutiveFailures++
+	if e.healthy && e.consecutiveFailures >= p.maxFailures {
+		e.healthy = false
+		e.ejectedAt = time.Now()
+		log.Printf("Consumer Service: SOAP endpoint %s ejected after %d consecutive failures", e.url, e.consecutiveFailures)
+	}
+}
+
+// Status returns a snapshot of every endpoint in priority order
+func (p *endpointPool) Status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		status = append(status, EndpointStatus{URL: e.url, Healthy: e.healthy, ConsecutiveFailures: e.consecutiveFailures, Latency: e.latency})
+	}
+	return status
+}
+
+// probeEjected runs the health check against every ejected endpoint and re-admits those that pass
+func (p *endpointPool) probeEjected() {
+	p.mu.Lock()
+	var ejected []*endpoint
+	for _, e := range p.endpoints {
+		if !e.healthy {
+			ejected = append(ejected, e)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, e := range ejected {
+		if err := ProbeEndpoint(p.probeClient, e.url); err != nil {
+			log.Printf("Consumer Service: SOAP endpoint %s still unhealthy: %v", e.url, err)
+			continue
+		}
+		p.mu.Lock()
+		e.healthy = true
+		e.consecutiveFailures = 0
+		p.mu.Unlock()
+		log.Printf("Consumer Service: SOAP endpoint %s re-admitted after a successful probe", e.url)
+	}
+}
+
+// run probes ejected endpoints until close is called
+func (p *endpointPool) run() {
+	ticker := time.NewTicker(p.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.probeEjected()
+		}
+	}
+}
+
+// close stops the active prober
+func (p *endpointPool) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// idempotentActions are the SOAP operations that may be sent again, e.g. to the next endpoint,
+// without risking a duplicate write. Others are only failed over when they were never sent.
+var idempotentActions = map[string]bool{
+	"http://example.com/kyc/KYCQuery":      true,
+	"http://example.com/kyc/GetKYCStatus":  true,
+	"http://example.com/kyc/GetKYCHistory": true,
+	"http://example.com/kyc/ScoreKYC":      true, // Computes a score without storing it
+	"http://example.com/kyc/BatchKYCQuery": true,
+	"http://example.com/kyc/SearchKYC":     true,
+}
+
+// notSent reports whether err shows that a request never reached the service, because no
+// connection could be made, so sending it elsewhere cannot apply it twice
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// ProbeEndpoint sends a HEAD request to url and reports whether the SOAP service is up.
+// A 405 counts as healthy because the service only accepts POST on its SOAP path.
+func ProbeEndpoint(client *http.Client, url string) error {
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request for SOAP service: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SOAP service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("SOAP service not ready (status: %d)", resp.StatusCode)
+	}
+	return nil
+}
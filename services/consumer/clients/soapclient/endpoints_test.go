@@ -0,0 +1,180 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer starts a SOAP stub that answers with status and counts its calls
+func newCountingServer(t *testing.T, status *atomic.Int32, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(int(status.Load()))
+			return
+		}
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		_, _ = io.WriteString(w, mockReadKYCResponseSuccess)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestEndpointPool_Pick(t *testing.T) {
+	urls := []string{"http://a", "http://b", "http://c"}
+
+	t.Run("Round robin rotates over healthy endpoints", func(t *testing.T) {
+		p := newEndpointPool(t.Name(), urls, StrategyRoundRobin)
+		var picked []string
+		for i := 0; i < 4; i++ {
+			e, err := p.pick(nil)
+			require.NoError(t, err)
+			picked = append(picked, e.url)
+		}
+		assert.Equal(t, []string{"http://a", "http://b", "http://c", "http://a"}, picked)
+	})
+
+	t.Run("Priority skips ejected endpoints", func(t *testing.T) {
+		p := newEndpointPool(t.Name(), urls, StrategyPriority)
+		p.maxFailures = 1
+		p.reportFailure(p.endpoints[0])
+		e, err := p.pick(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://b", e.url)
+	})
+
+	t.Run("Latency prefers the fastest endpoint", func(t *testing.T) {
+		p := newEndpointPool(t.Name(), urls, StrategyLatency)
+		p.reportSuccess(p.endpoints[0], 300*time.Millisecond)
+		p.reportSuccess(p.endpoints[1], 20*time.Millisecond)
+		p.reportSuccess(p.endpoints[2], 100*time.Millisecond)
+		e, err := p.pick(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://b", e.url)
+	})
+
+	t.Run("Latency ranks unmeasured endpoints at the average", func(t *testing.T) {
+		p := newEndpointPool(t.Name(), urls, StrategyLatency)
+		p.reportSuccess(p.endpoints[1], 100*time.Millisecond)
+		p.reportSuccess(p.endpoints[2], 300*time.Millisecond)
+		e, err := p.pick(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://b", e.url, "an unmeasured endpoint does not beat a faster measured one")
+
+		p = newEndpointPool(t.Name(), urls, StrategyLatency)
+		p.reportSuccess(p.endpoints[2], 300*time.Millisecond)
+		e, err = p.pick(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://a", e.url, "an unmeasured endpoint gets its turn when it ties")
+	})
+
+	t.Run("Falls back to an ejected endpoint when none are healthy", func(t *testing.T) {
+		p := newEndpointPool(t.Name(), urls[:1], StrategyRoundRobin)
+		p.maxFailures = 1
+		p.reportFailure(p.endpoints[0])
+		e, err := p.pick(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://a", e.url)
+
+		_, err = p.pick(map[*endpoint]bool{e: true})
+		assert.Error(t, err)
+	})
+}
+
+func TestSOAPClient_Failover(t *testing.T) {
+	var primaryStatus, secondaryStatus, primaryCalls, secondaryCalls atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	secondaryStatus.Store(http.StatusOK)
+	primary := newCountingServer(t, &primaryStatus, &primaryCalls)
+	secondary := newCountingServer(t, &secondaryStatus, &secondaryCalls)
+
+	sc := NewSOAPClient(primary.URL, WithEndpoints(secondary.URL), WithStrategy(StrategyPriority), WithPassiveHealthCheck(2, time.Hour))
+	defer sc.Close()
+
+	for i := 0; i < 3; i++ {
+		userData, err := sc.ReadKYC("client123")
+		require.NoError(t, err)
+		assert.Equal(t, "client123", userData.ClientID)
+	}
+	assert.Equal(t, int32(2), primaryCalls.Load(), "primary is ejected after two consecutive failures")
+	assert.Equal(t, int32(3), secondaryCalls.Load())
+
+	status := sc.Endpoints()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+
+	// Active probing re-admits the primary once it answers the HEAD check again
+	primaryStatus.Store(http.StatusOK)
+	sc.pool.probeEjected()
+	assert.True(t, sc.Endpoints()[0].Healthy)
+
+	_, err := sc.ReadKYC("client123")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), primaryCalls.Load())
+}
+
+func TestSOAPClient_FailoverDoesNotRetryClientErrors(t *testing.T) {
+	var primaryStatus, secondaryStatus, primaryCalls, secondaryCalls atomic.Int32
+	primaryStatus.Store(http.StatusNotFound)
+	secondaryStatus.Store(http.StatusOK)
+	primary := newCountingServer(t, &primaryStatus, &primaryCalls)
+	secondary := newCountingServer(t, &secondaryStatus, &secondaryCalls)
+
+	sc := NewSOAPClient(primary.URL, WithEndpoints(secondary.URL), WithStrategy(StrategyPriority))
+	defer sc.Close()
+
+	_, err := sc.ReadKYC("client123")
+	assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 404")
+	assert.Equal(t, int32(0), secondaryCalls.Load())
+}
+
+func TestSOAPClient_FailoverOfWrites(t *testing.T) {
+	var primaryStatus, secondaryStatus, primaryCalls, secondaryCalls atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	secondaryStatus.Store(http.StatusOK)
+	primary := newCountingServer(t, &primaryStatus, &primaryCalls)
+	secondary := newCountingServer(t, &secondaryStatus, &secondaryCalls)
+
+	t.Run("A write that reached an endpoint is not sent again", func(t *testing.T) {
+		sc := NewSOAPClient(primary.URL, WithEndpoints(secondary.URL), WithStrategy(StrategyPriority))
+		defer sc.Close()
+		_, err := sc.CreateKYC(models.UserData{Record: kyc.Record{ClientID: "client123"}})
+		assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 503")
+		assert.Equal(t, int32(1), primaryCalls.Load())
+		assert.Zero(t, secondaryCalls.Load())
+	})
+
+	t.Run("A write that could not connect fails over", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		sc := NewSOAPClient(closed.URL, WithEndpoints(secondary.URL), WithStrategy(StrategyPriority))
+		defer sc.Close()
+		_, err := sc.CreateKYC(models.UserData{Record: kyc.Record{ClientID: "client123"}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), secondaryCalls.Load())
+	})
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	var status, calls atomic.Int32
+	ts := newCountingServer(t, &status, &calls)
+
+	status.Store(http.StatusMethodNotAllowed)
+	assert.NoError(t, ProbeEndpoint(http.DefaultClient, ts.URL))
+
+	status.Store(http.StatusServiceUnavailable)
+	assert.Error(t, ProbeEndpoint(http.DefaultClient, ts.URL))
+
+	assert.Error(t, ProbeEndpoint(http.DefaultClient, "://invalid-url"))
+}
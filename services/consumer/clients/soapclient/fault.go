@@ -0,0 +1,54 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// FaultError is returned when the SOAP service answers with a soapenv:Fault
+type FaultError struct {
+	StatusCode int
+	Code       string // faultcode, e.g. soapenv:Client
+	String     string // faultstring
+	ErrorCode  string // ErrorCode of the KYCFault detail, empty for other faults
+}
+
+func (e *FaultError) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("SOAP fault %s (%s): %s", e.Code, e.ErrorCode, e.String)
+	}
+	return fmt.Sprintf("SOAP fault %s: %s", e.Code, e.String)
+}
+
+// IsServerFault reports whether the fault blames the service rather than the request
+func (e *FaultError) IsServerFault() bool {
+	_, local, found := strings.Cut(e.Code, ":")
+	if !found {
+		local = e.Code
+	}
+	return local == "Server"
+}
+
+// parseFault decodes a SOAP 1.1 Fault envelope, returning nil when body is not one
+func parseFault(body []byte) *FaultError {
+	var envelope struct {
+		Fault *struct {
+			Code   string `xml:"faultcode"`
+			String string `xml:"faultstring"`
+			Detail struct {
+				KYCFault struct {
+					ErrorCode string `xml:"ErrorCode"`
+				} `xml:"http://example.com/kyc KYCFault"`
+			} `xml:"detail"`
+		} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body>Fault"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Fault == nil {
+		return nil
+	}
+	return &FaultError{
+		Code:      strings.TrimSpace(envelope.Fault.Code),
+		String:    strings.TrimSpace(envelope.Fault.String),
+		ErrorCode: strings.TrimSpace(envelope.Fault.Detail.KYCFault.ErrorCode),
+	}
+}
@@ -0,0 +1,83 @@
+package soapclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultResponse renders a fault the way the KYC provider does in its "fault" error style
+func faultResponse(code, errorCode, message string) string {
+	return fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <soapenv:Fault>
+      <faultcode>%s</faultcode>
+      <faultstring>%s</faultstring>
+      <detail>
+        <KYCFault xmlns="http://example.com/kyc">
+          <ErrorCode>%s</ErrorCode>
+          <Operation>KYCQuery</Operation>
+        </KYCFault>
+      </detail>
+    </soapenv:Fault>
+  </soapenv:Body>
+</soapenv:Envelope>`, code, message, errorCode)
+}
+
+func TestParseFault(t *testing.T) {
+	fault := parseFault([]byte(faultResponse("soapenv:Client", "NOT_FOUND", "user with ClientID 'x' not found")))
+	require.NotNil(t, fault)
+	assert.Equal(t, &FaultError{Code: "soapenv:Client", String: "user with ClientID 'x' not found", ErrorCode: "NOT_FOUND"}, fault)
+	assert.False(t, fault.IsServerFault())
+	assert.Equal(t, "SOAP fault soapenv:Client (NOT_FOUND): user with ClientID 'x' not found", fault.Error())
+
+	assert.True(t, (&FaultError{Code: "Server"}).IsServerFault())
+	assert.Nil(t, parseFault([]byte(mockReadKYCResponseError)))
+	assert.Nil(t, parseFault([]byte("Internal Server Error")))
+}
+
+func TestReadKYC_Faults(t *testing.T) {
+	var calls atomic.Int32
+	var body atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, body.Load().(string))
+	}))
+	defer ts.Close()
+
+	cfg := DefaultBreakerConfig()
+	cfg.MinimumCalls = 2
+	cfg.WindowSize = 2
+	sc := NewSOAPClient(ts.URL, WithCircuitBreaker(cfg), WithCache(NewLRUCache(10), time.Minute, time.Minute))
+
+	t.Run("Client faults do not trip the breaker and NOT_FOUND is cached", func(t *testing.T) {
+		body.Store(faultResponse("soapenv:Client", "NOT_FOUND", "user not found"))
+		for i := 0; i < 3; i++ {
+			_, err := sc.ReadKYC("missing")
+			var fault *FaultError
+			require.True(t, errors.As(err, &fault))
+			assert.Equal(t, "NOT_FOUND", fault.ErrorCode)
+			assert.Equal(t, http.StatusInternalServerError, fault.StatusCode)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, StateClosed, sc.BreakerState())
+	})
+
+	t.Run("Server faults trip the breaker", func(t *testing.T) {
+		body.Store(faultResponse("soapenv:Server", "INTERNAL_ERROR", "boom"))
+		for i := 0; i < 2; i++ {
+			_, err := sc.ReadKYC(fmt.Sprintf("client%d", i))
+			assert.Error(t, err)
+		}
+		assert.Equal(t, StateOpen, sc.BreakerState())
+	})
+}
@@ -0,0 +1,9 @@
+// Package kycsoap contains the typed KYC SOAP client generated from the KYC provider's WSDL.
+//
+// Use it on top of the consumer's SOAP transport:
+//
+//	kyc := kycsoap.NewKYCPortTypeClient(soapclient.NewSOAPClient(url))
+//	resp, err := kyc.KYCQuery(&kycsoap.KYCQuery{ClientID: "clientA123"})
+package kycsoap
+
+//go:generate go run kafka-soap-e2e-test/tools/wsdlgen -wsdl ../../../../providers/kyc/contract/kyc.wsdl -package kycsoap -out kyc_gen.go
@@ -0,0 +1,565 @@
+// Code generated by wsdlgen from kyc.wsdl. DO NOT EDIT.
+
+package kycsoap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Namespace is the target namespace of the KYCService contract
+const Namespace = "http://example.com/kyc"
+
+// CountryCode is generated from the CountryCode simple type.
+// ISO 3166-1 alpha-2 country code.
+type CountryCode string
+
+// ReviewStatus is generated from the ReviewStatus simple type.
+// Where a record stands in the KYC review.
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "PENDING"
+	ReviewStatusInReview ReviewStatus = "IN_REVIEW"
+	ReviewStatusApproved ReviewStatus = "APPROVED"
+	ReviewStatusRejected ReviewStatus = "REJECTED"
+	ReviewStatusExpired  ReviewStatus = "EXPIRED"
+)
+
+// DocumentType is generated from the DocumentType simple type.
+// Kind of an identity document.
+type DocumentType string
+
+const (
+	DocumentTypePassport        DocumentType = "PASSPORT"
+	DocumentTypeNationalId      DocumentType = "NATIONAL_ID"
+	DocumentTypeDrivingLicence  DocumentType = "DRIVING_LICENCE"
+	DocumentTypeResidencePermit DocumentType = "RESIDENCE_PERMIT"
+)
+
+// AuditAction is generated from the AuditAction simple type.
+// Kind of change an audit event records.
+type AuditAction string
+
+const (
+	AuditActionCreate     AuditAction = "CREATE"
+	AuditActionUpdate     AuditAction = "UPDATE"
+	AuditActionDelete     AuditAction = "DELETE"
+	AuditActionTransition AuditAction = "TRANSITION"
+)
+
+// AuditSource is generated from the AuditSource simple type.
+// Interface a change came in through.
+type AuditSource string
+
+const (
+	AuditSourceSoap    AuditSource = "SOAP"
+	AuditSourceAdmin   AuditSource = "ADMIN"
+	AuditSourceFixture AuditSource = "FIXTURE"
+)
+
+// ErrorCode is generated from the ErrorCode simple type.
+// Machine-readable cause of a failed operation.
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrorCodeUnknownOperation   ErrorCode = "UNKNOWN_OPERATION"
+	ErrorCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrorCodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+	ErrorCodeInternalError      ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeTimeout            ErrorCode = "TIMEOUT"
+	ErrorCodeInvalidTransition  ErrorCode = "INVALID_TRANSITION"
+	ErrorCodeScoringUnavailable ErrorCode = "SCORING_UNAVAILABLE"
+	ErrorCodeVersionConflict    ErrorCode = "VERSION_CONFLICT"
+)
+
+// Address is generated from the Address complex type.
+// A postal address of the client.
+type Address struct {
+	Line1      string      `xml:"http://example.com/kyc Line1"`
+	Line2      string      `xml:"http://example.com/kyc Line2,omitempty"`
+	City       string      `xml:"http://example.com/kyc City"`
+	PostalCode string      `xml:"http://example.com/kyc PostalCode,omitempty"`
+	Country    CountryCode `xml:"http://example.com/kyc Country"`
+}
+
+// IdentityDocument is generated from the IdentityDocument complex type.
+// A document the client's identity was verified with.
+type IdentityDocument struct {
+	Type           DocumentType `xml:"http://example.com/kyc Type"`
+	Number         string       `xml:"http://example.com/kyc Number"`
+	IssuingCountry CountryCode  `xml:"http://example.com/kyc IssuingCountry,omitempty"`
+	ExpiryDate     string       `xml:"http://example.com/kyc ExpiryDate,omitempty"`
+}
+
+// CustomField is generated from the CustomField complex type.
+// A free-form attribute of the client that risk rules can refer to by key.
+type CustomField struct {
+	Key   string `xml:"http://example.com/kyc Key"`
+	Value string `xml:"http://example.com/kyc Value"`
+}
+
+// UserData is generated from the UserData complex type.
+// A KYC record for a single client. Timestamps and Version are maintained by the service.
+type UserData struct {
+	ClientID     string             `xml:"http://example.com/kyc ClientID"`
+	Risk         float64            `xml:"http://example.com/kyc Risk"`
+	LegalName    string             `xml:"http://example.com/kyc LegalName,omitempty"`
+	DateOfBirth  string             `xml:"http://example.com/kyc DateOfBirth,omitempty"`
+	Nationality  CountryCode        `xml:"http://example.com/kyc Nationality,omitempty"`
+	Address      []Address          `xml:"http://example.com/kyc Address,omitempty"`
+	Document     []IdentityDocument `xml:"http://example.com/kyc Document,omitempty"`
+	PEP          bool               `xml:"http://example.com/kyc PEP,omitempty"`
+	Sanctioned   bool               `xml:"http://example.com/kyc Sanctioned,omitempty"`
+	CustomField  []CustomField      `xml:"http://example.com/kyc CustomField,omitempty"`
+	ReviewStatus ReviewStatus       `xml:"http://example.com/kyc ReviewStatus,omitempty"`
+	ReviewNote   string             `xml:"http://example.com/kyc ReviewNote,omitempty"`
+	CreatedAt    *time.Time         `xml:"http://example.com/kyc CreatedAt,omitempty"`
+	UpdatedAt    *time.Time         `xml:"http://example.com/kyc UpdatedAt,omitempty"`
+	ReviewedAt   *time.Time         `xml:"http://example.com/kyc ReviewedAt,omitempty"`
+	Version      int64              `xml:"http://example.com/kyc Version,omitempty"`
+}
+
+// RiskContribution is generated from the RiskContribution complex type.
+// A scoring rule that matched and the weight it added to the score.
+type RiskContribution struct {
+	Rule   string  `xml:"http://example.com/kyc Rule"`
+	Weight float64 `xml:"http://example.com/kyc Weight"`
+	Detail string  `xml:"http://example.com/kyc Detail,omitempty"`
+}
+
+// RiskScore is generated from the RiskScore complex type.
+// A computed risk score and the breakdown of how it was reached.
+type RiskScore struct {
+	Score        float64            `xml:"http://example.com/kyc Score"`
+	Base         float64            `xml:"http://example.com/kyc Base"`
+	Contribution []RiskContribution `xml:"http://example.com/kyc Contribution,omitempty"`
+}
+
+// AuditEvent is generated from the AuditEvent complex type.
+// One entry of a record's history. Before is absent for a create and After for a delete.
+type AuditEvent struct {
+	Sequence      int64       `xml:"http://example.com/kyc Sequence"`
+	ClientID      string      `xml:"http://example.com/kyc ClientID"`
+	Action        AuditAction `xml:"http://example.com/kyc Action"`
+	Actor         string      `xml:"http://example.com/kyc Actor"`
+	Source        AuditSource `xml:"http://example.com/kyc Source"`
+	Operation     string      `xml:"http://example.com/kyc Operation,omitempty"`
+	CorrelationID string      `xml:"http://example.com/kyc CorrelationID,omitempty"`
+	Timestamp     time.Time   `xml:"http://example.com/kyc Timestamp"`
+	Before        *UserData   `xml:"http://example.com/kyc Before,omitempty"`
+	After         *UserData   `xml:"http://example.com/kyc After,omitempty"`
+}
+
+// BatchKYCItem is generated from the BatchKYCItem complex type.
+// The result of one ClientID of a BatchKYCQuery. ErrorCode is present when Status is Error, and FaultCode too in the fault error style: the faultcode the item's error would have had in a call of its own.
+type BatchKYCItem struct {
+	ClientID  string    `xml:"http://example.com/kyc ClientID"`
+	Status    string    `xml:"http://example.com/kyc Status"`
+	Message   string    `xml:"http://example.com/kyc Message"`
+	ErrorCode ErrorCode `xml:"http://example.com/kyc ErrorCode,omitempty"`
+	FaultCode string    `xml:"http://example.com/kyc FaultCode,omitempty"`
+	UserData  *UserData `xml:"http://example.com/kyc UserData,omitempty"`
+}
+
+// Violation is generated from the Violation complex type.
+// A single way in which a request does not conform to this schema.
+type Violation struct {
+	Kind     string `xml:"http://example.com/kyc Kind"`
+	Path     string `xml:"http://example.com/kyc Path"`
+	Message  string `xml:"http://example.com/kyc Message"`
+	Expected string `xml:"http://example.com/kyc Expected,omitempty"`
+}
+
+// KYCQuery is generated from the KYCQuery element.
+// Reads the KYC record of a client, as it stands now or, with AsOf, as it stood at that time.
+type KYCQuery struct {
+	XMLName  xml.Name   `xml:"http://example.com/kyc KYCQuery"`
+	ClientID string     `xml:"http://example.com/kyc ClientID"`
+	AsOf     *time.Time `xml:"http://example.com/kyc AsOf,omitempty"`
+}
+
+// CreateKYC is generated from the CreateKYC element.
+// Creates a new KYC record.
+type CreateKYC struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc CreateKYC"`
+	UserData UserData `xml:"http://example.com/kyc UserData"`
+}
+
+// UpdateKYC is generated from the UpdateKYC element.
+// Replaces an existing KYC record. With ExpectedVersion the update only succeeds if the stored record is at that version.
+type UpdateKYC struct {
+	XMLName         xml.Name `xml:"http://example.com/kyc UpdateKYC"`
+	UserData        UserData `xml:"http://example.com/kyc UserData"`
+	ExpectedVersion int64    `xml:"http://example.com/kyc ExpectedVersion,omitempty"`
+}
+
+// DeleteKYC is generated from the DeleteKYC element.
+// Deletes the KYC record of a client. With ExpectedVersion the delete only succeeds if the stored record is at that version.
+type DeleteKYC struct {
+	XMLName         xml.Name `xml:"http://example.com/kyc DeleteKYC"`
+	ClientID        string   `xml:"http://example.com/kyc ClientID"`
+	ExpectedVersion int64    `xml:"http://example.com/kyc ExpectedVersion,omitempty"`
+}
+
+// SubmitKYC is generated from the SubmitKYC element.
+// Submits a PENDING record for review, moving it to IN_REVIEW.
+type SubmitKYC struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc SubmitKYC"`
+	ClientID string   `xml:"http://example.com/kyc ClientID"`
+}
+
+// ApproveKYC is generated from the ApproveKYC element.
+// Approves a record that is IN_REVIEW.
+type ApproveKYC struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc ApproveKYC"`
+	ClientID string   `xml:"http://example.com/kyc ClientID"`
+	Comment  string   `xml:"http://example.com/kyc Comment,omitempty"`
+}
+
+// RejectKYC is generated from the RejectKYC element.
+// Rejects a record that is IN_REVIEW.
+type RejectKYC struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc RejectKYC"`
+	ClientID string   `xml:"http://example.com/kyc ClientID"`
+	Reason   string   `xml:"http://example.com/kyc Reason"`
+}
+
+// GetKYCStatus is generated from the GetKYCStatus element.
+// Reads where a record stands in the review lifecycle.
+type GetKYCStatus struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc GetKYCStatus"`
+	ClientID string   `xml:"http://example.com/kyc ClientID"`
+}
+
+// ScoreKYC is generated from the ScoreKYC element.
+// Scores a KYC record with the configured risk rules without storing it.
+type ScoreKYC struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc ScoreKYC"`
+	UserData UserData `xml:"http://example.com/kyc UserData"`
+}
+
+// GetKYCHistory is generated from the GetKYCHistory element.
+// Reads every recorded change of a client's KYC record, oldest first.
+type GetKYCHistory struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc GetKYCHistory"`
+	ClientID string   `xml:"http://example.com/kyc ClientID"`
+}
+
+// BatchKYCQuery is generated from the BatchKYCQuery element.
+// Reads the KYC records of several clients in one call. Every ClientID gets its own result, in request order.
+type BatchKYCQuery struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc BatchKYCQuery"`
+	ClientID []string `xml:"http://example.com/kyc ClientID,omitempty"`
+}
+
+// SearchKYC is generated from the SearchKYC element.
+// Finds the KYC records matching every given criterion, a page at a time in ClientID order. NameContains matches LegalName case-insensitively, DocumentNumber matches any document exactly, and PageToken is the NextPageToken of the previous page.
+type SearchKYC struct {
+	XMLName        xml.Name       `xml:"http://example.com/kyc SearchKYC"`
+	MinRisk        float64        `xml:"http://example.com/kyc MinRisk,omitempty"`
+	MaxRisk        float64        `xml:"http://example.com/kyc MaxRisk,omitempty"`
+	ReviewStatus   []ReviewStatus `xml:"http://example.com/kyc ReviewStatus,omitempty"`
+	NameContains   string         `xml:"http://example.com/kyc NameContains,omitempty"`
+	DocumentNumber string         `xml:"http://example.com/kyc DocumentNumber,omitempty"`
+	CreatedAfter   *time.Time     `xml:"http://example.com/kyc CreatedAfter,omitempty"`
+	PageSize       int            `xml:"http://example.com/kyc PageSize,omitempty"`
+	PageToken      string         `xml:"http://example.com/kyc PageToken,omitempty"`
+}
+
+// KYCResponse is generated from the KYCResponse element.
+// Result of KYCQuery, CreateKYC, UpdateKYC and the lifecycle operations. RiskScore is present when the service computed the record's risk.
+type KYCResponse struct {
+	XMLName   xml.Name   `xml:"http://example.com/kyc KYCResponse"`
+	Status    string     `xml:"http://example.com/kyc Status"`
+	Message   string     `xml:"http://example.com/kyc Message"`
+	UserData  *UserData  `xml:"http://example.com/kyc UserData,omitempty"`
+	RiskScore *RiskScore `xml:"http://example.com/kyc RiskScore,omitempty"`
+}
+
+// DeleteKYCResponse is generated from the DeleteKYCResponse element.
+// Result of DeleteKYC.
+type DeleteKYCResponse struct {
+	XMLName xml.Name `xml:"http://example.com/kyc DeleteKYCResponse"`
+	Status  string   `xml:"http://example.com/kyc Status"`
+	Message string   `xml:"http://example.com/kyc Message"`
+}
+
+// KYCStatusResponse is generated from the KYCStatusResponse element.
+// Result of GetKYCStatus.
+type KYCStatusResponse struct {
+	XMLName           xml.Name       `xml:"http://example.com/kyc KYCStatusResponse"`
+	Status            string         `xml:"http://example.com/kyc Status"`
+	Message           string         `xml:"http://example.com/kyc Message"`
+	ClientID          string         `xml:"http://example.com/kyc ClientID,omitempty"`
+	ReviewStatus      ReviewStatus   `xml:"http://example.com/kyc ReviewStatus,omitempty"`
+	ReviewNote        string         `xml:"http://example.com/kyc ReviewNote,omitempty"`
+	ReviewedAt        *time.Time     `xml:"http://example.com/kyc ReviewedAt,omitempty"`
+	AllowedTransition []ReviewStatus `xml:"http://example.com/kyc AllowedTransition,omitempty"`
+}
+
+// ScoreKYCResponse is generated from the ScoreKYCResponse element.
+// Result of ScoreKYC.
+type ScoreKYCResponse struct {
+	XMLName   xml.Name   `xml:"http://example.com/kyc ScoreKYCResponse"`
+	Status    string     `xml:"http://example.com/kyc Status"`
+	Message   string     `xml:"http://example.com/kyc Message"`
+	ClientID  string     `xml:"http://example.com/kyc ClientID,omitempty"`
+	RiskScore *RiskScore `xml:"http://example.com/kyc RiskScore,omitempty"`
+}
+
+// KYCHistoryResponse is generated from the KYCHistoryResponse element.
+// Result of GetKYCHistory.
+type KYCHistoryResponse struct {
+	XMLName  xml.Name     `xml:"http://example.com/kyc KYCHistoryResponse"`
+	Status   string       `xml:"http://example.com/kyc Status"`
+	Message  string       `xml:"http://example.com/kyc Message"`
+	ClientID string       `xml:"http://example.com/kyc ClientID,omitempty"`
+	Event    []AuditEvent `xml:"http://example.com/kyc Event,omitempty"`
+}
+
+// BatchKYCQueryResponse is generated from the BatchKYCQueryResponse element.
+// Result of BatchKYCQuery. Status is Success when the batch was read, even if some of its items failed.
+type BatchKYCQueryResponse struct {
+	XMLName xml.Name       `xml:"http://example.com/kyc BatchKYCQueryResponse"`
+	Status  string         `xml:"http://example.com/kyc Status"`
+	Message string         `xml:"http://example.com/kyc Message"`
+	Result  []BatchKYCItem `xml:"http://example.com/kyc Result,omitempty"`
+}
+
+// SearchKYCResponse is generated from the SearchKYCResponse element.
+// Result of SearchKYC: one page of matching records. Total counts the matches across all pages, and NextPageToken is absent on the last page.
+type SearchKYCResponse struct {
+	XMLName       xml.Name   `xml:"http://example.com/kyc SearchKYCResponse"`
+	Status        string     `xml:"http://example.com/kyc Status"`
+	Message       string     `xml:"http://example.com/kyc Message"`
+	Total         int        `xml:"http://example.com/kyc Total,omitempty"`
+	NextPageToken string     `xml:"http://example.com/kyc NextPageToken,omitempty"`
+	UserData      []UserData `xml:"http://example.com/kyc UserData,omitempty"`
+}
+
+// ValidationFault is generated from the ValidationFault element.
+// Fault detail listing every schema violation of a rejected request.
+type ValidationFault struct {
+	XMLName   xml.Name    `xml:"http://example.com/kyc ValidationFault"`
+	Violation []Violation `xml:"http://example.com/kyc Violation,omitempty"`
+}
+
+// KYCFault is generated from the KYCFault element.
+// Fault detail of a failed operation; faultstring carries the human-readable message.
+type KYCFault struct {
+	XMLName   xml.Name  `xml:"http://example.com/kyc KYCFault"`
+	ErrorCode ErrorCode `xml:"http://example.com/kyc ErrorCode"`
+	Operation string    `xml:"http://example.com/kyc Operation,omitempty"`
+}
+
+// Transport sends a SOAP envelope for soapAction and returns the raw response envelope.
+type Transport interface {
+	Call(soapAction string, envelope []byte) ([]byte, error)
+}
+
+// Fault is a SOAP 1.1 fault returned in place of an operation's response
+type Fault struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
+	Code    string   `xml:"faultcode"`
+	String  string   `xml:"faultstring"`
+	Actor   string   `xml:"faultactor,omitempty"`
+	Detail  struct {
+		Content []byte `xml:",innerxml"`
+	} `xml:"detail"`
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("SOAP fault %s: %s", f.Code, f.String)
+}
+
+type requestEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         struct {
+		XMLName xml.Name `xml:"soapenv:Body"`
+		Content any
+	}
+}
+
+const soapEnvNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+
+// call wraps req in a SOAP envelope, sends it and decodes the body into resp
+func call(t Transport, soapAction string, req, resp any) error {
+	env := requestEnvelope{XmlnsSoapenv: soapEnvNamespace}
+	env.Body.Content = req
+	body, err := xml.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SOAP request: %w", err)
+	}
+
+	raw, err := t.Call(soapAction, append([]byte(xml.Header), body...))
+	if err != nil {
+		return err
+	}
+
+	// Decode the first element inside soapenv:Body in place, so prefixes declared on the Envelope stay in scope
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	inBody := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to parse SOAP response: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !inBody {
+			inBody = start.Name.Space == soapEnvNamespace && start.Name.Local == "Body"
+			continue
+		}
+		if start.Name.Space == soapEnvNamespace && start.Name.Local == "Fault" {
+			var fault Fault
+			if err := dec.DecodeElement(&fault, &start); err != nil {
+				return fmt.Errorf("failed to parse SOAP fault: %w", err)
+			}
+			return &fault
+		}
+		if err := dec.DecodeElement(resp, &start); err != nil {
+			return fmt.Errorf("failed to parse SOAP response body: %w", err)
+		}
+		return nil
+	}
+}
+
+// KYCPortType is the client interface for the KYCPortType port type
+type KYCPortType interface {
+	KYCQuery(req *KYCQuery) (*KYCResponse, error)
+	CreateKYC(req *CreateKYC) (*KYCResponse, error)
+	UpdateKYC(req *UpdateKYC) (*KYCResponse, error)
+	DeleteKYC(req *DeleteKYC) (*DeleteKYCResponse, error)
+	SubmitKYC(req *SubmitKYC) (*KYCResponse, error)
+	ApproveKYC(req *ApproveKYC) (*KYCResponse, error)
+	RejectKYC(req *RejectKYC) (*KYCResponse, error)
+	GetKYCStatus(req *GetKYCStatus) (*KYCStatusResponse, error)
+	ScoreKYC(req *ScoreKYC) (*ScoreKYCResponse, error)
+	GetKYCHistory(req *GetKYCHistory) (*KYCHistoryResponse, error)
+	BatchKYCQuery(req *BatchKYCQuery) (*BatchKYCQueryResponse, error)
+	SearchKYC(req *SearchKYC) (*SearchKYCResponse, error)
+}
+
+// NewKYCPortTypeClient returns a KYCPortType that sends requests over t
+func NewKYCPortTypeClient(t Transport) KYCPortType {
+	return &kycPortTypeClient{transport: t}
+}
+
+type kycPortTypeClient struct {
+	transport Transport
+}
+
+// KYCQuery calls the KYCQuery operation (SOAPAction http://example.com/kyc/KYCQuery)
+func (c *kycPortTypeClient) KYCQuery(req *KYCQuery) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/KYCQuery", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateKYC calls the CreateKYC operation (SOAPAction http://example.com/kyc/CreateKYC)
+func (c *kycPortTypeClient) CreateKYC(req *CreateKYC) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/CreateKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateKYC calls the UpdateKYC operation (SOAPAction http://example.com/kyc/UpdateKYC)
+func (c *kycPortTypeClient) UpdateKYC(req *UpdateKYC) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/UpdateKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteKYC calls the DeleteKYC operation (SOAPAction http://example.com/kyc/DeleteKYC)
+func (c *kycPortTypeClient) DeleteKYC(req *DeleteKYC) (*DeleteKYCResponse, error) {
+	resp := new(DeleteKYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/DeleteKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SubmitKYC calls the SubmitKYC operation (SOAPAction http://example.com/kyc/SubmitKYC)
+func (c *kycPortTypeClient) SubmitKYC(req *SubmitKYC) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/SubmitKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ApproveKYC calls the ApproveKYC operation (SOAPAction http://example.com/kyc/ApproveKYC)
+func (c *kycPortTypeClient) ApproveKYC(req *ApproveKYC) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/ApproveKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RejectKYC calls the RejectKYC operation (SOAPAction http://example.com/kyc/RejectKYC)
+func (c *kycPortTypeClient) RejectKYC(req *RejectKYC) (*KYCResponse, error) {
+	resp := new(KYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/RejectKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetKYCStatus calls the GetKYCStatus operation (SOAPAction http://example.com/kyc/GetKYCStatus)
+func (c *kycPortTypeClient) GetKYCStatus(req *GetKYCStatus) (*KYCStatusResponse, error) {
+	resp := new(KYCStatusResponse)
+	if err := call(c.transport, "http://example.com/kyc/GetKYCStatus", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ScoreKYC calls the ScoreKYC operation (SOAPAction http://example.com/kyc/ScoreKYC)
+func (c *kycPortTypeClient) ScoreKYC(req *ScoreKYC) (*ScoreKYCResponse, error) {
+	resp := new(ScoreKYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/ScoreKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetKYCHistory calls the GetKYCHistory operation (SOAPAction http://example.com/kyc/GetKYCHistory)
+func (c *kycPortTypeClient) GetKYCHistory(req *GetKYCHistory) (*KYCHistoryResponse, error) {
+	resp := new(KYCHistoryResponse)
+	if err := call(c.transport, "http://example.com/kyc/GetKYCHistory", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BatchKYCQuery calls the BatchKYCQuery operation (SOAPAction http://example.com/kyc/BatchKYCQuery)
+func (c *kycPortTypeClient) BatchKYCQuery(req *BatchKYCQuery) (*BatchKYCQueryResponse, error) {
+	resp := new(BatchKYCQueryResponse)
+	if err := call(c.transport, "http://example.com/kyc/BatchKYCQuery", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SearchKYC calls the SearchKYC operation (SOAPAction http://example.com/kyc/SearchKYC)
+func (c *kycPortTypeClient) SearchKYC(req *SearchKYC) (*SearchKYCResponse, error) {
+	resp := new(SearchKYCResponse)
+	if err := call(c.transport, "http://example.com/kyc/SearchKYC", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
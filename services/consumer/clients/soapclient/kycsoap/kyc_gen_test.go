@@ -0,0 +1,71 @@
+package kycsoap
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kafka-soap-e2e-test/services/consumer/clients/soapclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transportFunc adapts a function to the Transport interface
+type transportFunc func(soapAction string, envelope []byte) ([]byte, error)
+
+func (f transportFunc) Call(soapAction string, envelope []byte) ([]byte, error) {
+	return f(soapAction, envelope)
+}
+
+func TestKYCPortTypeClient_OverSOAPClient(t *testing.T) {
+	var gotAction, gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotAction, gotBody = r.Header.Get("SOAPAction"), string(body)
+		_, _ = io.WriteString(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:kyc="http://example.com/kyc">
+   <soapenv:Body>
+      <kyc:KYCResponse>
+         <kyc:Status>Success</kyc:Status>
+         <kyc:Message>Found</kyc:Message>
+         <kyc:UserData>
+            <kyc:ClientID>client123</kyc:ClientID>
+            <kyc:Risk>0.4</kyc:Risk>
+         </kyc:UserData>
+      </kyc:KYCResponse>
+   </soapenv:Body>
+</soapenv:Envelope>`)
+	}))
+	defer ts.Close()
+
+	client := NewKYCPortTypeClient(soapclient.NewSOAPClient(ts.URL))
+	resp, err := client.KYCQuery(&KYCQuery{ClientID: "client123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://example.com/kyc/KYCQuery", gotAction)
+	assert.Contains(t, gotBody, `<KYCQuery xmlns="http://example.com/kyc"><ClientID xmlns="http://example.com/kyc">client123</ClientID></KYCQuery>`)
+	assert.Equal(t, "Success", resp.Status)
+	require.NotNil(t, resp.UserData)
+	assert.Equal(t, UserData{ClientID: "client123", Risk: 0.4}, *resp.UserData)
+}
+
+func TestKYCPortTypeClient_Fault(t *testing.T) {
+	client := NewKYCPortTypeClient(transportFunc(func(string, []byte) ([]byte, error) {
+		return []byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+   <soapenv:Body>
+      <soapenv:Fault>
+         <faultcode>soapenv:Client</faultcode>
+         <faultstring>ClientID is required</faultstring>
+      </soapenv:Fault>
+   </soapenv:Body>
+</soapenv:Envelope>`), nil
+	}))
+
+	_, err := client.DeleteKYC(&DeleteKYC{})
+	var fault *Fault
+	require.True(t, errors.As(err, &fault))
+	assert.Equal(t, "soapenv:Client", fault.Code)
+	assert.Equal(t, "SOAP fault soapenv:Client: ClientID is required", err.Error())
+}
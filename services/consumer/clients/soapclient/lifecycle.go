@@ -0,0 +1,108 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// transitionTemplate wraps a lifecycle operation or another operation that takes a ClientID; the
+// operation name is repeated for the closing tag
+const transitionTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
+  <soapenv:Header>
+    <KYCRequest xmlns="http://example.com/kyc"/>
+  </soapenv:Header>
+  <soapenv:Body>
+    <%[1]s xmlns="http://example.com/kyc">
+      <ClientID>%[2]s</ClientID>%[3]s
+    </%[1]s>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+// SubmitKYC performs a SubmitKYC operation, moving a PENDING record to IN_REVIEW
+func (sc *SOAPClient) SubmitKYC(clientID string) (models.UserData, error) {
+	return sc.transitionKYC("SubmitKYC", clientID, "")
+}
+
+// ApproveKYC performs an ApproveKYC operation, moving an IN_REVIEW record to APPROVED.
+// comment is optional.
+func (sc *SOAPClient) ApproveKYC(clientID, comment string) (models.UserData, error) {
+	var element string
+	if comment != "" {
+		element = fmt.Sprintf("\n      <Comment>%s</Comment>", xmlEscape(comment))
+	}
+	return sc.transitionKYC("ApproveKYC", clientID, element)
+}
+
+// RejectKYC performs a RejectKYC operation, moving an IN_REVIEW record to REJECTED.
+// The provider refuses a rejection without a reason.
+func (sc *SOAPClient) RejectKYC(clientID, reason string) (models.UserData, error) {
+	element := fmt.Sprintf("\n      <Reason>%s</Reason>", xmlEscape(reason))
+	return sc.transitionKYC("RejectKYC", clientID, element)
+}
+
+// transitionKYC sends one of the lifecycle operations; noteElement is the optional Comment or Reason element
+func (sc *SOAPClient) transitionKYC(operation, clientID, noteElement string) (models.UserData, error) {
+	requestBody := fmt.Sprintf(transitionTemplate, operation, xmlEscape(clientID), noteElement)
+	soapAction := "http://example.com/kyc/" + operation
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return models.UserData{}, err
+	}
+
+	var envelope models.StandardKYCResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC %s response: %v", operation, err)
+		return models.UserData{}, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+
+	if envelope.Body.KYCResult.UserData == nil {
+		return models.UserData{}, fmt.Errorf("no UserData found in KYC %s response", operation)
+	}
+
+	sc.invalidateCache(clientID)
+	userData := *envelope.Body.KYCResult.UserData
+	userData.Status = envelope.Body.KYCResult.Status
+	userData.Message = envelope.Body.KYCResult.Message
+	return userData, nil
+}
+
+// GetKYCStatus performs a GetKYCStatus operation. The returned UserData carries only the
+// review fields of the record plus the statuses it may move to next.
+func (sc *SOAPClient) GetKYCStatus(clientID string) (models.UserData, error) {
+	requestBody := fmt.Sprintf(transitionTemplate, "GetKYCStatus", xmlEscape(clientID), "")
+	soapAction := "http://example.com/kyc/GetKYCStatus"
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return models.UserData{}, err
+	}
+
+	var envelope models.KYCStatusResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC GetKYCStatus response: %v", err)
+		return models.UserData{}, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+
+	result := envelope.Body.KYCStatusResult
+	userData := models.UserData{
+		Record: kyc.Record{
+			ClientID:     result.ClientID,
+			ReviewStatus: result.ReviewStatus,
+			ReviewNote:   result.ReviewNote,
+			ReviewedAt:   result.ReviewedAt,
+		},
+		Status:             result.Status,
+		Message:            result.Message,
+		AllowedTransitions: result.AllowedTransitions,
+	}
+	if userData.ClientID == "" {
+		userData.ClientID = clientID
+	}
+	return userData, nil
+}
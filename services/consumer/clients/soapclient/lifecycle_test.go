@@ -0,0 +1,95 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mockRejectKYCResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <KYCResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>User rejected</Message>
+      <UserData>
+        <ClientID>client123</ClientID>
+        <Risk>0.5</Risk>
+        <ReviewStatus>REJECTED</ReviewStatus>
+        <ReviewNote>document forged</ReviewNote>
+      </UserData>
+    </KYCResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+	mockKYCStatusResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <KYCStatusResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>User status retrieved</Message>
+      <ClientID>client123</ClientID>
+      <ReviewStatus>IN_REVIEW</ReviewStatus>
+      <AllowedTransition>APPROVED</AllowedTransition>
+      <AllowedTransition>REJECTED</AllowedTransition>
+      <AllowedTransition>EXPIRED</AllowedTransition>
+    </KYCStatusResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`
+)
+
+func TestRejectKYC(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		_, _ = io.WriteString(w, mockRejectKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL)
+	userData, err := sc.RejectKYC("client123", "document <forged>")
+
+	require.NoError(t, err)
+	assert.Contains(t, body, "<Reason>document &lt;forged&gt;</Reason>")
+	assert.Equal(t, kyc.ReviewRejected, userData.ReviewStatus)
+	assert.Equal(t, "document forged", userData.ReviewNote)
+	assert.Equal(t, "Success", userData.Status)
+	assert.Equal(t, "User rejected", userData.Message)
+}
+
+func TestApproveKYC_RefusedTransition(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL)
+	_, err := sc.ApproveKYC("client123", "")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 409")
+}
+
+func TestGetKYCStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, mockKYCStatusResponseSuccess)
+	}))
+	defer ts.Close()
+
+	sc := NewSOAPClient(ts.URL)
+	userData, err := sc.GetKYCStatus("client123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "client123", userData.ClientID)
+	assert.Equal(t, kyc.ReviewInReview, userData.ReviewStatus)
+	assert.Equal(t, []kyc.ReviewStatus{kyc.ReviewApproved, kyc.ReviewRejected, kyc.ReviewExpired}, userData.AllowedTransitions)
+	assert.Equal(t, "Success", userData.Status)
+}
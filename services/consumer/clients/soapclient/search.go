@@ -0,0 +1,85 @@
+package soapclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+)
+
+const searchTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
+  <soapenv:Header>
+    <KYCRequest xmlns="http://example.com/kyc"/>
+  </soapenv:Header>
+  <soapenv:Body>
+    <SearchKYC xmlns="http://example.com/kyc">%s
+    </SearchKYC>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+// SearchKYC performs a SearchKYC, returning one page of the records matching criteria in ClientID
+// order. pageSize 0 uses the provider's default; pageToken is the NextPageToken of the previous
+// page, or empty for the first.
+func (sc *SOAPClient) SearchKYC(criteria models.SearchCriteria, pageSize int, pageToken string) (models.SearchPage, error) {
+	requestBody := fmt.Sprintf(searchTemplate, searchElements(criteria, pageSize, pageToken))
+	soapAction := "http://example.com/kyc/SearchKYC"
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	var envelope models.SearchKYCResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC SearchKYC response: %v", err)
+		return models.SearchPage{}, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+
+	result := envelope.Body.SearchKYCResult
+	log.Printf("Consumer Service: KYC search page of %d user(s): %s", len(result.Users), result.Message)
+	return models.SearchPage{
+		Status:        result.Status,
+		Message:       result.Message,
+		Total:         result.Total,
+		Users:         result.Users,
+		NextPageToken: result.NextPageToken,
+	}, nil
+}
+
+// searchElements renders the SearchKYC children in the order the schema requires
+func searchElements(criteria models.SearchCriteria, pageSize int, pageToken string) string {
+	var b strings.Builder
+	element := func(name, value string) {
+		fmt.Fprintf(&b, "\n      <%s>%s</%s>", name, xmlEscape(value), name)
+	}
+	if criteria.MinRisk != nil {
+		element("MinRisk", strconv.FormatFloat(*criteria.MinRisk, 'g', -1, 64))
+	}
+	if criteria.MaxRisk != nil {
+		element("MaxRisk", strconv.FormatFloat(*criteria.MaxRisk, 'g', -1, 64))
+	}
+	for _, status := range criteria.ReviewStatuses {
+		element("ReviewStatus", string(status))
+	}
+	if criteria.NameContains != "" {
+		element("NameContains", criteria.NameContains)
+	}
+	if criteria.DocumentNumber != "" {
+		element("DocumentNumber", criteria.DocumentNumber)
+	}
+	if criteria.CreatedAfter != nil {
+		element("CreatedAfter", criteria.CreatedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if pageSize > 0 {
+		element("PageSize", strconv.Itoa(pageSize))
+	}
+	if pageToken != "" {
+		element("PageToken", pageToken)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package soapclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mockSearchKYCResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <SearchKYCResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>2 of 3 matching user(s)</Message>
+      <Total>3</Total>
+      <NextPageToken>eyJzIjoiY2xpZW50SWQiLCJpZCI6ImNsaWVudDIifQ</NextPageToken>
+      <UserData>
+        <ClientID>client1</ClientID>
+        <Risk>0.6</Risk>
+        <LegalName>Ada Lovelace</LegalName>
+      </UserData>
+      <UserData>
+        <ClientID>client2</ClientID>
+        <Risk>0.7</Risk>
+      </UserData>
+    </SearchKYCResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+func TestSearchKYC(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "http://example.com/kyc/SearchKYC", r.Header.Get("SOAPAction"))
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		_, _ = io.WriteString(w, mockSearchKYCResponseSuccess)
+	}))
+	defer ts.Close()
+
+	minRisk := 0.5
+	createdAfter := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	page, err := NewSOAPClient(ts.URL).SearchKYC(models.SearchCriteria{
+		MinRisk:        &minRisk,
+		ReviewStatuses: []kyc.ReviewStatus{kyc.ReviewPending, kyc.ReviewInReview},
+		NameContains:   "O'Brien & <Sons>",
+		CreatedAfter:   &createdAfter,
+	}, 2, "token-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Success", page.Status)
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, "eyJzIjoiY2xpZW50SWQiLCJpZCI6ImNsaWVudDIifQ", page.NextPageToken)
+	require.Len(t, page.Users, 2)
+	assert.Equal(t, "Ada Lovelace", page.Users[0].LegalName)
+	assert.Equal(t, 0.7, page.Users[1].Risk)
+
+	assert.Contains(t, body, "<MinRisk>0.5</MinRisk>")
+	assert.NotContains(t, body, "<MaxRisk>", "unset criteria are left out")
+	assert.Contains(t, body, "<ReviewStatus>PENDING</ReviewStatus>\n      <ReviewStatus>IN_REVIEW</ReviewStatus>")
+	assert.Contains(t, body, "<NameContains>O&#39;Brien &amp; &lt;Sons&gt;</NameContains>")
+	assert.Contains(t, body, "<CreatedAfter>2024-03-01T11:00:00Z</CreatedAfter>")
+	assert.True(t, strings.Index(body, "<PageSize>2</PageSize>") < strings.Index(body, "<PageToken>token-1</PageToken>"))
+}
+
+func TestSearchKYC_InvalidRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, faultResponse("soapenv:Client", "INVALID_REQUEST", "Invalid SearchKYC request: invalid PageToken 'x'"))
+	}))
+	defer ts.Close()
+
+	_, err := NewSOAPClient(ts.URL).SearchKYC(models.SearchCriteria{}, 0, "x")
+	var fault *FaultError
+	require.ErrorAs(t, err, &fault)
+	assert.Equal(t, "INVALID_REQUEST", fault.ErrorCode)
+}
@@ -2,64 +2,354 @@ package soapclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"time"
 
 	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 // SOAPClient holds the HTTP client and SOAP service URL
 type SOAPClient struct {
-	client *http.Client
-	url    string
+	client  *http.Client
+	url     string          // Primary endpoint
+	breaker *CircuitBreaker // Optional, guards every SOAP call when set
+
+	extraURLs     []string // Additional endpoints, in priority order after url
+	strategy      Strategy
+	maxFailures   int
+	probeInterval time.Duration
+	pool          *endpointPool
+	cache         *readCache // Optional read-through cache for ReadKYC
+
+	actor         string // Sent as X-Actor so the provider's audit trail names this client
+	correlationID string // Sent as X-Correlation-ID, see WithCorrelationID
+	tenant        string // Sent as X-KYC-Tenant to select one of the provider's tenants
+
+	callbacks *callbacks // Optional, asks for responses as WS-Addressing callbacks, see WithCallbacks
+	accepted  func()     // Called when the provider acknowledges a call, see OnAccepted
+}
+
+// Option configures optional SOAPClient behaviour
+type Option func(*SOAPClient)
+
+// WithCircuitBreaker guards all SOAP calls with a circuit breaker and bulkhead
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(sc *SOAPClient) {
+		sc.breaker = NewCircuitBreaker("kyc-soap", cfg)
+	}
+}
+
+// WithEndpoints adds failover endpoints after the primary URL, in priority order
+func WithEndpoints(urls ...string) Option {
+	return func(sc *SOAPClient) {
+		sc.extraURLs = append(sc.extraURLs, urls...)
+	}
+}
+
+// WithStrategy sets how calls are spread over the endpoints (round-robin by default)
+func WithStrategy(strategy Strategy) Option {
+	return func(sc *SOAPClient) {
+		sc.strategy = strategy
+	}
+}
+
+// WithPassiveHealthCheck sets how many consecutive failures eject an endpoint
+// and how often ejected endpoints are probed for re-admission
+func WithPassiveHealthCheck(maxFailures int, probeInterval time.Duration) Option {
+	return func(sc *SOAPClient) {
+		sc.maxFailures = maxFailures
+		sc.probeInterval = probeInterval
+	}
+}
+
+// WithCache puts a read-through cache in front of ReadKYC. Found records are kept for ttl and
+// not-found results for negativeTTL (0 disables negative caching).
+func WithCache(cache Cache, ttl, negativeTTL time.Duration) Option {
+	return func(sc *SOAPClient) {
+		sc.cache = newReadCache("kyc-soap", cache, ttl, negativeTTL)
+	}
+}
+
+// WithTenant sends every call to the provider's tenant called tenant (as the X-KYC-Tenant header),
+// so that parallel test suites each see their own users
+func WithTenant(tenant string) Option {
+	return func(sc *SOAPClient) {
+		sc.tenant = tenant
+	}
+}
+
+// StatusError is returned when the SOAP service answers with a non-OK HTTP status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("SOAP service returned non-OK status: %d", e.StatusCode)
 }
 
 // NewSOAPClient initializes a new SOAPClient
-func NewSOAPClient(url string) *SOAPClient {
+func NewSOAPClient(url string, opts ...Option) *SOAPClient {
 	log.Printf("Consumer Service: Initializing SOAP client with URL: %s", url)
-	return &SOAPClient{
-		client: &http.Client{Timeout: 10 * time.Second},
-		url:    url,
+	sc := &SOAPClient{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		url:      url,
+		strategy: StrategyRoundRobin,
 	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+
+	sc.pool = newEndpointPool("kyc-soap", append([]string{url}, sc.extraURLs...), sc.strategy)
+	if sc.maxFailures > 0 {
+		sc.pool.maxFailures = sc.maxFailures
+	}
+	if sc.probeInterval > 0 {
+		sc.pool.probeInterval = sc.probeInterval
+	}
+	if len(sc.extraURLs) > 0 {
+		log.Printf("Consumer Service: SOAP client using %d endpoints with %s strategy", len(sc.extraURLs)+1, sc.strategy)
+		go sc.pool.run()
+	}
+	return sc
+}
+
+// Endpoints returns the health of every configured endpoint
+func (sc *SOAPClient) Endpoints() []EndpointStatus {
+	return sc.pool.Status()
 }
 
-// doSOAPRequest is a helper to send SOAP requests and return the raw response body
+// CacheMetrics returns the read-through cache counters, or zero values if no cache is configured
+func (sc *SOAPClient) CacheMetrics() CacheMetrics {
+	if sc.cache == nil {
+		return CacheMetrics{}
+	}
+	return sc.cache.metrics()
+}
+
+// invalidateCache drops clientID from the read-through cache after a successful mutation
+func (sc *SOAPClient) invalidateCache(clientID string) {
+	if sc.cache != nil {
+		sc.cache.invalidate(cacheKey(sc.tenant, clientID))
+	}
+}
+
+// Close stops background endpoint probing
+func (sc *SOAPClient) Close() {
+	sc.pool.close()
+}
+
+// BreakerState returns the state of the circuit breaker, or StateClosed if none is configured
+func (sc *SOAPClient) BreakerState() BreakerState {
+	if sc.breaker == nil {
+		return StateClosed
+	}
+	return sc.breaker.State()
+}
+
+// WaitUntilReady blocks while the circuit breaker is open
+func (sc *SOAPClient) WaitUntilReady(ctx context.Context) error {
+	if sc.breaker == nil {
+		return nil
+	}
+	return sc.breaker.WaitUntilReady(ctx)
+}
+
+// Call sends a raw SOAP envelope and returns the raw response body.
+// It lets generated clients (see package kycsoap) share the breaker, failover and transport of doSOAPRequest.
+func (sc *SOAPClient) Call(soapAction string, envelope []byte) ([]byte, error) {
+	return sc.doSOAPRequest(soapAction, envelope)
+}
+
+// doSOAPRequest is a helper to send SOAP requests and return the raw response body. Once the
+// provider has acknowledged a call its callback is awaited outside the breaker and failover: the
+// call has reached an endpoint that is up, and sending it again could apply it twice.
 func (sc *SOAPClient) doSOAPRequest(soapAction string, requestBody []byte) ([]byte, error) {
-	req, err := http.NewRequest("POST", sc.url, bytes.NewBuffer(requestBody))
+	var respBody []byte
+	var ack *acknowledgement
+	send := func() error {
+		var err error
+		respBody, ack, err = sc.sendWithFailover(soapAction, requestBody)
+		return err
+	}
+	var err error
+	if sc.breaker == nil {
+		err = send()
+	} else {
+		err = sc.breaker.Execute(send)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ack != nil {
+		return sc.awaitCallback(ack)
+	}
+	return respBody, nil
+}
+
+// sendWithFailover sends the request to the endpoint chosen by the strategy and moves on to
+// the next endpoint when the call fails with a transport error or a server-side status. Writes
+// move on only when they never reached the endpoint, as it may have applied them before failing.
+func (sc *SOAPClient) sendWithFailover(soapAction string, requestBody []byte) ([]byte, *acknowledgement, error) {
+	tried := make(map[*endpoint]bool)
+	var lastErr error
+	for {
+		e, err := sc.pool.pick(tried)
+		if err != nil {
+			if lastErr != nil {
+				return nil, nil, lastErr
+			}
+			return nil, nil, err
+		}
+		tried[e] = true
+
+		start := time.Now()
+		respBody, ack, err := sc.sendSOAPRequest(e.url, soapAction, requestBody)
+		if !isBreakerFailure(err) {
+			sc.pool.reportSuccess(e, time.Since(start))
+			return respBody, ack, err
+		}
+		sc.pool.reportFailure(e)
+		lastErr = err
+		if !idempotentActions[soapAction] && !notSent(err) {
+			return nil, nil, err
+		}
+		if len(tried) < len(sc.pool.endpoints) {
+			log.Printf("Consumer Service: SOAP call to %s failed: %v. Failing over to the next endpoint.", e.url, err)
+		}
+	}
+}
+
+// sendSOAPRequest performs the HTTP round trip for a single SOAP call. With callbacks configured the
+// provider acknowledges the call with 202 Accepted, and the returned acknowledgement is what the
+// response that arrives at the CallbackListener is awaited with.
+func (sc *SOAPClient) sendSOAPRequest(url, soapAction string, requestBody []byte) ([]byte, *acknowledgement, error) {
+	var ack *acknowledgement
+	accepted := false
+	if sc.callbacks != nil {
+		ack = &acknowledgement{messageID: newMessageID()}
+		requestBody = withAddressing(requestBody, ack.messageID, sc.callbacks.replyTo, soapAction, url)
+		ack.reply = sc.callbacks.listener.expect(ack.messageID)
+		defer func() {
+			if !accepted {
+				sc.callbacks.listener.forget(ack.messageID) // Answered or failed without a callback
+			}
+		}()
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SOAP request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create SOAP request: %w", err)
 	}
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", soapAction)
+	if sc.actor != "" {
+		req.Header.Set("X-Actor", sc.actor)
+	}
+	if sc.correlationID != "" {
+		req.Header.Set("X-Correlation-ID", sc.correlationID)
+	}
+	if sc.tenant != "" {
+		req.Header.Set("X-KYC-Tenant", sc.tenant)
+	}
 
-	log.Printf("Consumer Service: Sending HTTP request to SOAP service. URL: %s, SOAPAction: %s", sc.url, soapAction)
+	log.Printf("Consumer Service: Sending HTTP request to SOAP service. URL: %s, SOAPAction: %s", url, soapAction)
 	resp, err := sc.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call SOAP service: %w", err)
+		return nil, nil, fmt.Errorf("failed to call SOAP service: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		log.Printf("Consumer Service: SOAP service returned non-OK status: %d. Response: %s", resp.StatusCode, string(respBody))
-		return nil, fmt.Errorf("SOAP service returned non-OK status: %d", resp.StatusCode)
+	soapResponseBody, err := io.ReadAll(resp.Body)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to read SOAP response body: %w", err)
+	}
+	if resp.StatusCode == http.StatusAccepted && ack != nil {
+		accepted = true // awaitCallback forgets it
+		return nil, ack, nil
 	}
+	body, err := responseBody(resp.StatusCode, soapResponseBody)
+	return body, nil, err
+}
 
-	soapResponseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SOAP response body: %w", err)
+// responseBody returns the body of a SOAP response with the given HTTP status, or the fault or
+// status error it stands for
+func responseBody(status int, soapResponseBody []byte) ([]byte, error) {
+	if status != http.StatusOK {
+		log.Printf("Consumer Service: SOAP service returned non-OK status: %d. Response: %s", status, string(soapResponseBody))
+		if fault := parseFault(soapResponseBody); fault != nil {
+			fault.StatusCode = status
+			return nil, fault
+		}
+		return nil, &StatusError{StatusCode: status}
 	}
 	log.Printf("Consumer Service: Received SOAP response. Body length: %d", len(soapResponseBody))
 	return soapResponseBody, nil
 }
 
-// ReadKYC performs a KYCQuery operation
+// ReadKYC performs a KYCQuery operation, served from the cache when one is configured
 func (sc *SOAPClient) ReadKYC(clientID string) (models.UserData, error) {
+	if sc.cache == nil {
+		return sc.readKYC(clientID, nil)
+	}
+
+	key := cacheKey(sc.tenant, clientID)
+	if entry, ok := sc.cache.get(key); ok {
+		log.Printf("Consumer Service: KYC cache hit for ClientID: %s (not found: %t)", clientID, entry.NotFound)
+		return cachedRead(entry)
+	}
+
+	generation := sc.cache.beginFill(key)
+	userData, err := sc.readKYC(clientID, nil)
+	sc.cache.endFill(key, generation, readCacheEntry(userData, err))
+	return userData, err
+}
+
+// cachedRead returns what ReadKYC returned when it cached entry
+func cachedRead(entry CacheEntry) (models.UserData, error) {
+	if entry.Fault != nil {
+		return models.UserData{}, entry.Fault
+	}
+	if entry.NotFound && entry.UserData.Status == "" {
+		return models.UserData{}, &StatusError{StatusCode: http.StatusNotFound}
+	}
+	return entry.UserData, nil
+}
+
+// readCacheEntry returns the cache entry of a ReadKYC result, or nil when it is not cached
+func readCacheEntry(userData models.UserData, err error) *CacheEntry {
+	var statusErr *StatusError
+	var faultErr *FaultError
+	switch {
+	case errors.As(err, &faultErr) && faultErr.ErrorCode == "NOT_FOUND":
+		return &CacheEntry{NotFound: true, Fault: faultErr}
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return &CacheEntry{NotFound: true}
+	case err == nil && userData.Status == "Error":
+		return &CacheEntry{UserData: userData, NotFound: true}
+	case err == nil:
+		return &CacheEntry{UserData: userData}
+	}
+	return nil
+}
+
+// xmlEscape escapes s for use as element text in the request templates
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s)) // strings.Builder never fails
+	return b.String()
+}
+
+// readKYC sends the KYCQuery to the SOAP service, asking for the record as of asOf when it is set
+func (sc *SOAPClient) readKYC(clientID string, asOf *time.Time) (models.UserData, error) {
 	requestTemplate := `<?xml version="1.0" encoding="UTF-8"?>
 <soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
   <soapenv:Header>
@@ -67,11 +357,15 @@ func (sc *SOAPClient) ReadKYC(clientID string) (models.UserData, error) {
   </soapenv:Header>
   <soapenv:Body>
     <KYCQuery xmlns="http://example.com/kyc">
-      <ClientID>%s</ClientID>
+      <ClientID>%s</ClientID>%s
     </KYCQuery>
   </soapenv:Body>
 </soapenv:Envelope>`
-	requestBody := fmt.Sprintf(requestTemplate, clientID)
+	var asOfElement string
+	if asOf != nil {
+		asOfElement = fmt.Sprintf("\n      <AsOf>%s</AsOf>", asOf.UTC().Format(time.RFC3339Nano))
+	}
+	requestBody := fmt.Sprintf(requestTemplate, xmlEscape(clientID), asOfElement)
 	soapAction := "http://example.com/kyc/KYCQuery"
 
 	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
@@ -93,8 +387,7 @@ func (sc *SOAPClient) ReadKYC(clientID string) (models.UserData, error) {
 
 	// If UserData is present in the response, populate it
 	if envelope.Body.KYCResult.UserData != nil {
-		userData.ClientID = envelope.Body.KYCResult.UserData.ClientID
-		userData.Risk = envelope.Body.KYCResult.UserData.Risk
+		userData.Record = envelope.Body.KYCResult.UserData.Record
 	} else {
 		// If no UserData is returned, but the status is "Error", it's a valid scenario.
 		// We still need to populate the ClientID from the request if it's an error response
@@ -106,6 +399,20 @@ func (sc *SOAPClient) ReadKYC(clientID string) (models.UserData, error) {
 	return userData, nil
 }
 
+// userDataElement marshals record as the UserData element of a CreateKYC or UpdateKYC request.
+// Its children are unqualified, so they take the kyc namespace declared on the enclosing element.
+func userDataElement(record kyc.Record) (string, error) {
+	element := struct {
+		XMLName xml.Name `xml:"UserData"`
+		kyc.Record
+	}{Record: record}
+	data, err := xml.Marshal(element)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal UserData: %w", err)
+	}
+	return string(data), nil
+}
+
 // CreateKYC performs a CreateKYC operation
 func (sc *SOAPClient) CreateKYC(userData models.UserData) (models.UserData, error) {
 	requestTemplate := `<?xml version="1.0" encoding="UTF-8"?>
@@ -115,17 +422,15 @@ func (sc *SOAPClient) CreateKYC(userData models.UserData) (models.UserData, erro
   </soapenv:Header>
   <soapenv:Body>
     <CreateKYC xmlns="http://example.com/kyc">
-      <UserData>
-        <ClientID>%s</ClientID>
-        <Risk>%f</Risk>
-        <!-- Include other UserData fields as needed for creation -->
-      </UserData>
+      %s
     </CreateKYC>
   </soapenv:Body>
 </soapenv:Envelope>`
-	// Note: You might want to marshal userData directly to XML for more robust handling of all fields.
-	// For simplicity, using Sprintf for ClientID and Risk here.
-	requestBody := fmt.Sprintf(requestTemplate, userData.ClientID, userData.Risk)
+	userDataXML, err := userDataElement(userData.Record)
+	if err != nil {
+		return models.UserData{}, err
+	}
+	requestBody := fmt.Sprintf(requestTemplate, userDataXML)
 	soapAction := "http://example.com/kyc/CreateKYC"
 
 	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
@@ -143,14 +448,27 @@ func (sc *SOAPClient) CreateKYC(userData models.UserData) (models.UserData, erro
 		return models.UserData{}, fmt.Errorf("no UserData found in KYC Create response")
 	}
 
+	sc.invalidateCache(userData.ClientID)
 	createdUserData := *envelope.Body.KYCResult.UserData
 	createdUserData.Status = envelope.Body.KYCResult.Status
 	createdUserData.Message = envelope.Body.KYCResult.Message
+	createdUserData.RiskScore = envelope.Body.KYCResult.RiskScore
 	return createdUserData, nil
 }
 
 // UpdateKYC performs an UpdateKYC operation
 func (sc *SOAPClient) UpdateKYC(userData models.UserData) (models.UserData, error) {
+	return sc.updateKYC(userData, nil)
+}
+
+// UpdateKYCExpecting performs an UpdateKYC operation that only succeeds if the stored record is at
+// expectedVersion; otherwise the provider reports a VERSION_CONFLICT
+func (sc *SOAPClient) UpdateKYCExpecting(userData models.UserData, expectedVersion int64) (models.UserData, error) {
+	return sc.updateKYC(userData, &expectedVersion)
+}
+
+// updateKYC sends the UpdateKYC, with an ExpectedVersion element when expectedVersion is set
+func (sc *SOAPClient) updateKYC(userData models.UserData, expectedVersion *int64) (models.UserData, error) {
 	requestTemplate := `<?xml version="1.0" encoding="UTF-8"?>
 <soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
   <soapenv:Header>
@@ -158,15 +476,19 @@ func (sc *SOAPClient) UpdateKYC(userData models.UserData) (models.UserData, erro
   </soapenv:Header>
   <soapenv:Body>
     <UpdateKYC xmlns="http://example.com/kyc">
-      <UserData>
-        <ClientID>%s</ClientID>
-        <Risk>%f</Risk>
-        <!-- Include other UserData fields as needed for update -->
-      </UserData>
+      %s%s
     </UpdateKYC>
   </soapenv:Body>
 </soapenv:Envelope>`
-	requestBody := fmt.Sprintf(requestTemplate, userData.ClientID, userData.Risk)
+	userDataXML, err := userDataElement(userData.Record)
+	if err != nil {
+		return models.UserData{}, err
+	}
+	var expectedVersionElement string
+	if expectedVersion != nil {
+		expectedVersionElement = fmt.Sprintf("\n      <ExpectedVersion>%d</ExpectedVersion>", *expectedVersion)
+	}
+	requestBody := fmt.Sprintf(requestTemplate, userDataXML, expectedVersionElement)
 	soapAction := "http://example.com/kyc/UpdateKYC"
 
 	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
@@ -184,12 +506,60 @@ func (sc *SOAPClient) UpdateKYC(userData models.UserData) (models.UserData, erro
 		return models.UserData{}, fmt.Errorf("no UserData found in KYC Update response")
 	}
 
+	sc.invalidateCache(userData.ClientID)
 	updatedUserData := *envelope.Body.KYCResult.UserData
 	updatedUserData.Status = envelope.Body.KYCResult.Status
 	updatedUserData.Message = envelope.Body.KYCResult.Message
+	updatedUserData.RiskScore = envelope.Body.KYCResult.RiskScore
 	return updatedUserData, nil
 }
 
+// ScoreKYC performs a ScoreKYC operation. The provider scores the record without storing it;
+// the returned UserData is the submitted record with Risk set to the score.
+func (sc *SOAPClient) ScoreKYC(userData models.UserData) (models.UserData, error) {
+	requestTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://example.com/kyc">
+  <soapenv:Header>
+    <KYCRequest xmlns="http://example.com/kyc"/>
+  </soapenv:Header>
+  <soapenv:Body>
+    <ScoreKYC xmlns="http://example.com/kyc">
+      %s
+    </ScoreKYC>
+  </soapenv:Body>
+</soapenv:Envelope>`
+	userDataXML, err := userDataElement(userData.Record)
+	if err != nil {
+		return models.UserData{}, err
+	}
+	requestBody := fmt.Sprintf(requestTemplate, userDataXML)
+	soapAction := "http://example.com/kyc/ScoreKYC"
+
+	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
+	if err != nil {
+		return models.UserData{}, err
+	}
+
+	var envelope models.ScoreKYCResponseEnvelope
+	if err := xml.Unmarshal(respBody, &envelope); err != nil {
+		log.Printf("Consumer Service: Failed to unmarshal KYC Score response: %v", err)
+		return models.UserData{}, fmt.Errorf("failed to parse SOAP response: %w", err)
+	}
+
+	result := envelope.Body.ScoreKYCResult
+	if result.RiskScore == nil {
+		return models.UserData{}, fmt.Errorf("no RiskScore found in KYC Score response")
+	}
+	scored := models.UserData{
+		Record:    userData.Record,
+		Status:    result.Status,
+		Message:   result.Message,
+		RiskScore: result.RiskScore,
+	}
+	scored.Risk = result.RiskScore.Score
+	return scored, nil
+}
+
 // DeleteKYC performs a DeleteKYC operation
 func (sc *SOAPClient) DeleteKYC(clientID string) (string, error) {
 	requestTemplate := `<?xml version="1.0" encoding="UTF-8"?>
@@ -203,7 +573,7 @@ func (sc *SOAPClient) DeleteKYC(clientID string) (string, error) {
     </DeleteKYC>
   </soapenv:Body>
 </soapenv:Envelope>`
-	requestBody := fmt.Sprintf(requestTemplate, clientID)
+	requestBody := fmt.Sprintf(requestTemplate, xmlEscape(clientID))
 	soapAction := "http://example.com/kyc/DeleteKYC"
 
 	respBody, err := sc.doSOAPRequest(soapAction, []byte(requestBody))
@@ -217,6 +587,7 @@ func (sc *SOAPClient) DeleteKYC(clientID string) (string, error) {
 		return "", fmt.Errorf("failed to parse SOAP response: %w", err)
 	}
 
+	sc.invalidateCache(clientID)
 	return envelope.Body.DeleteKYCResult.Message, nil
 }
 
@@ -1,14 +1,17 @@
 package soapclient
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	"testing"
 	"time"
 
 	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -78,6 +81,26 @@ const (
   </soapenv:Body>
 </soapenv:Envelope>`
 
+	mockScoreKYCResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <ScoreKYCResponse xmlns="http://example.com/kyc">
+      <Status>Success</Status>
+      <Message>User scored</Message>
+      <ClientID>client123</ClientID>
+      <RiskScore>
+        <Score>0.5</Score>
+        <Base>0.1</Base>
+        <Contribution>
+          <Rule>high-risk-country</Rule>
+          <Weight>0.4</Weight>
+          <Detail>nationality IR</Detail>
+        </Contribution>
+      </RiskScore>
+    </ScoreKYCResponse>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
 	mockDeleteKYCResponseSuccess = `<?xml version="1.0" encoding="UTF-8"?>
 <soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
   <soapenv:Body>
@@ -220,7 +243,7 @@ func TestCreateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		newUserData := models.UserData{ClientID: "newclient", Risk: 0.1}
+		newUserData := models.UserData{Record: kyc.Record{ClientID: "newclient", Risk: 0.1}}
 		userData, err := sc.CreateKYC(newUserData)
 
 		require.NoError(t, err)
@@ -230,6 +253,36 @@ func TestCreateKYC(t *testing.T) {
 		assert.Equal(t, "User created", userData.Message)
 	})
 
+	t.Run("Carries The Full Record", func(t *testing.T) {
+		// Echo the request's UserData back, as the provider does for a successful create
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			start, end := strings.Index(string(body), "<UserData>"), strings.Index(string(body), "</UserData>")
+			require.True(t, start >= 0 && end > start)
+			_, _ = fmt.Fprintf(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body>`+
+				`<KYCResponse xmlns="http://example.com/kyc"><Status>Success</Status><Message>User created</Message>%s</KYCResponse>`+
+				`</soapenv:Body></soapenv:Envelope>`, body[start:end+len("</UserData>")])
+		}))
+		defer ts.Close()
+
+		record := kyc.Record{
+			ClientID:     "newclient",
+			Risk:         0.1,
+			LegalName:    "Jane Doe",
+			DateOfBirth:  "1985-04-12",
+			Nationality:  "DE",
+			Addresses:    []kyc.Address{{Line1: "Hauptstr. 1", City: "Berlin", Country: "DE"}},
+			Documents:    []kyc.IdentityDocument{{Type: kyc.DocumentNationalID, Number: "L01X00T47", ExpiryDate: "2030-06-30"}},
+			Sanctioned:   true,
+			ReviewStatus: kyc.ReviewInReview,
+		}
+		userData, err := NewSOAPClient(ts.URL).CreateKYC(models.UserData{Record: record})
+
+		require.NoError(t, err)
+		assert.Equal(t, record, userData.Record)
+		assert.Equal(t, "Success", userData.Status)
+	})
+
 	t.Run("HTTP Error During Create", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -237,7 +290,7 @@ func TestCreateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		newUserData := models.UserData{ClientID: "newclient", Risk: 0.1}
+		newUserData := models.UserData{Record: kyc.Record{ClientID: "newclient", Risk: 0.1}}
 		_, err := sc.CreateKYC(newUserData)
 
 		assert.Error(t, err)
@@ -251,7 +304,7 @@ func TestCreateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		newUserData := models.UserData{ClientID: "newclient", Risk: 0.1}
+		newUserData := models.UserData{Record: kyc.Record{ClientID: "newclient", Risk: 0.1}}
 		_, err := sc.CreateKYC(newUserData)
 
 		assert.Error(t, err)
@@ -267,7 +320,7 @@ func TestUpdateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		updateUserData := models.UserData{ClientID: "client123", Risk: 0.8}
+		updateUserData := models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}}
 		userData, err := sc.UpdateKYC(updateUserData)
 
 		require.NoError(t, err)
@@ -284,13 +337,32 @@ func TestUpdateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		updateUserData := models.UserData{ClientID: "client123", Risk: 0.8}
+		updateUserData := models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}}
 		_, err := sc.UpdateKYC(updateUserData)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 404")
 	})
 
+	t.Run("Version Conflict", func(t *testing.T) {
+		var body []byte
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, faultResponse("soapenv:Client", "VERSION_CONFLICT", "KYC record 'client123' is at version 4, not the expected version 3"))
+		}))
+		defer ts.Close()
+
+		sc := NewSOAPClient(ts.URL)
+		updateUserData := models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}}
+		_, err := sc.UpdateKYCExpecting(updateUserData, 3)
+
+		var fault *FaultError
+		require.ErrorAs(t, err, &fault)
+		assert.Equal(t, "VERSION_CONFLICT", fault.ErrorCode)
+		assert.Contains(t, string(body), "</UserData>\n      <ExpectedVersion>3</ExpectedVersion>")
+	})
+
 	t.Run("Unmarshal Error", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			_, _ = io.WriteString(w, "<invalid-xml>")
@@ -298,7 +370,7 @@ func TestUpdateKYC(t *testing.T) {
 		defer ts.Close()
 
 		sc := NewSOAPClient(ts.URL)
-		updateUserData := models.UserData{ClientID: "client123", Risk: 0.8}
+		updateUserData := models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.8}}
 		_, err := sc.UpdateKYC(updateUserData)
 
 		assert.Error(t, err)
@@ -306,6 +378,39 @@ func TestUpdateKYC(t *testing.T) {
 	})
 }
 
+func TestScoreKYC(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, mockScoreKYCResponseSuccess)
+		}))
+		defer ts.Close()
+
+		sc := NewSOAPClient(ts.URL)
+		userData, err := sc.ScoreKYC(models.UserData{Record: kyc.Record{ClientID: "client123", Risk: 0.1, Nationality: "IR"}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "client123", userData.ClientID)
+		assert.Equal(t, "IR", userData.Nationality)
+		assert.InDelta(t, 0.5, userData.Risk, 0.001)
+		assert.Equal(t, "Success", userData.Status)
+		require.NotNil(t, userData.RiskScore)
+		assert.Equal(t, []kyc.RiskContribution{{Rule: "high-risk-country", Weight: 0.4, Detail: "nationality IR"}}, userData.RiskScore.Contributions)
+	})
+
+	t.Run("Scoring Unavailable", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		sc := NewSOAPClient(ts.URL)
+		_, err := sc.ScoreKYC(models.UserData{Record: kyc.Record{ClientID: "client123"}})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "SOAP service returned non-OK status: 503")
+	})
+}
+
 func TestDeleteKYC(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
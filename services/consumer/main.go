@@ -3,16 +3,20 @@ package main
 import (
 	"context" // Added for context.WithTimeout
 	"encoding/json"
-	"fmt" // Added for fmt.Errorf
+	_ "expvar" // Registers /debug/vars for the metrics endpoint
+	"fmt"      // Added for fmt.Errorf
 	"log"
 	"net/http" // Added for health check
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	consumerPkg "kafka-soap-e2e-test/services/consumer/clients/consumer"
 	producerPkg "kafka-soap-e2e-test/services/consumer/clients/producer"
 	soapclientPkg "kafka-soap-e2e-test/services/consumer/clients/soapclient"
 	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
 
 	"github.com/segmentio/kafka-go"
 
@@ -34,6 +38,16 @@ func main() {
 	log.Printf("Consumer Service: KAFKA_BOOTSTRAP_SERVERS: %s", kafkaBootstrapServers)
 	log.Printf("Consumer Service: SOAP_SERVICE_URL: %s", soapServiceURL)
 
+	// SOAP_SERVICE_URL may list several comma-separated endpoints, the first being the primary
+	soapEndpoints := strings.Split(soapServiceURL, ",")
+	for i := range soapEndpoints {
+		soapEndpoints[i] = strings.TrimSpace(soapEndpoints[i])
+	}
+	soapStrategy := soapclientPkg.StrategyRoundRobin
+	if s := os.Getenv("SOAP_LB_STRATEGY"); s != "" {
+		soapStrategy = soapclientPkg.Strategy(s)
+	}
+
 	// Wait for Kafka connection to be established
 	err := waitForKafkaConnection(kafkaBootstrapServers, 60*time.Second) // Give Kafka up to 60 seconds
 	if err != nil {
@@ -59,11 +73,36 @@ func main() {
 	}
 	defer producer.Close()
 
+	// Metrics endpoint (circuit breaker state and counters under /debug/vars)
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			log.Printf("Consumer Service: Serving metrics on %s/debug/vars", addr)
+			if err := http.ListenAndServe(addr, nil); err != nil {
+				log.Printf("Consumer Service: Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
 	// SOAP Client setup
-	soapClient := soapclientPkg.NewSOAPClient(soapServiceURL)
+	soapOpts := []soapclientPkg.Option{
+		soapclientPkg.WithEndpoints(soapEndpoints[1:]...),
+		soapclientPkg.WithStrategy(soapStrategy),
+		soapclientPkg.WithCircuitBreaker(breakerConfigFromEnv()),
+		soapclientPkg.WithActor("consumer-service"),
+	}
+	if cacheOpt, ok := cacheOptionFromEnv(); ok {
+		soapOpts = append(soapOpts, cacheOpt)
+	}
+	if callbackOpt, ok := callbackOptionFromEnv(); ok {
+		soapOpts = append(soapOpts, callbackOpt)
+	}
+	soapClient := soapclientPkg.NewSOAPClient(soapEndpoints[0], soapOpts...)
+	defer soapClient.Close()
+	readCoalescer := readCoalescerFromEnv(soapClient) // nil unless READ batching is enabled
+	results := newResultPublisher(producer)
 
-	// Wait for SOAP service to be ready
-	err = waitForSoapService(soapServiceURL, 60*time.Second) // Give SOAP service up to 60 seconds
+	// Wait for any SOAP endpoint to be ready; the others are health-checked by the client
+	err = waitForSoapService(soapEndpoints, 60*time.Second) // Give SOAP service up to 60 seconds
 	if err != nil {
 		log.Println("Consumer Service: Shutting down due to SOAP service not being ready.")
 		log.Fatalf("Failed to wait for SOAP service: %v", err)
@@ -71,6 +110,15 @@ func main() {
 
 	log.Println("Consumer Service: Entering Kafka message processing loop.")
 	for {
+		// Pause consumption while the SOAP circuit breaker is open instead of failing every message
+		if soapClient.BreakerState() == soapclientPkg.StateOpen {
+			log.Println("Consumer Service: SOAP circuit breaker is open, pausing Kafka consumption.")
+			if err := soapClient.WaitUntilReady(context.Background()); err != nil {
+				log.Printf("Consumer Service: Error while waiting for SOAP circuit breaker: %v", err)
+			}
+			log.Println("Consumer Service: SOAP circuit breaker allows trial calls, resuming Kafka consumption.")
+		}
+
 		msg, err := consumer.ReadMessage(context.Background()) // Use background context for ReadMessage
 		if err == nil {
 			log.Printf("Received message from Kafka topic %s: %s\n", msg.Topic, string(msg.Value))
@@ -81,98 +129,385 @@ func main() {
 				continue
 			}
 
-			var processedEntity models.UserData
-			var err error
-			var deleteMessage string
-
-			switch kafkaMsg.Type {
-			case "READ":
-				log.Printf("Consumer Service: Performing KYC Read for ClientID: %s", kafkaMsg.ClientID)
-				processedEntity, err = soapClient.ReadKYC(kafkaMsg.ClientID)
-			case "CREATE":
-				log.Printf("Consumer Service: Performing KYC Create for ClientID: %s", kafkaMsg.UserData.ClientID)
-				// Ensure ClientID is correctly set from KafkaMessage if not already in UserData
-				if kafkaMsg.UserData.ClientID == "" {
-					kafkaMsg.UserData.ClientID = kafkaMsg.ClientID
-				}
-				processedEntity, err = soapClient.CreateKYC(kafkaMsg.UserData)
-			case "UPDATE":
-				log.Printf("Consumer Service: Performing KYC Update for ClientID: %s", kafkaMsg.UserData.ClientID)
-				// Ensure ClientID is correctly set from KafkaMessage if not already in UserData
-				if kafkaMsg.UserData.ClientID == "" {
-					kafkaMsg.UserData.ClientID = kafkaMsg.ClientID
-				}
-				processedEntity, err = soapClient.UpdateKYC(kafkaMsg.UserData)
-			case "DELETE":
-				log.Printf("Consumer Service: Performing KYC Delete for ClientID: %s", kafkaMsg.ClientID)
-				deleteMessage, err = soapClient.DeleteKYC(kafkaMsg.ClientID)
-				if err == nil {
-					// For delete, we construct a dummy UserData for the response topic
-					// to indicate success, as there's no UserData returned by DeleteKYC
-					processedEntity = models.UserData{
-						ClientID: kafkaMsg.ClientID,
-						Status:   "Success",
-						Message:  deleteMessage,
-					}
-				}
-			default:
-				log.Printf("Consumer Service: Unknown Kafka message type: %s", kafkaMsg.Type)
-				err = fmt.Errorf("unknown Kafka message type: %s", kafkaMsg.Type)
+			// Send queued reads before anything else so a write never overtakes an earlier read
+			if readCoalescer != nil && kafkaMsg.Type != "READ" {
+				readCoalescer.Flush()
 			}
 
-			if err != nil {
-				log.Printf("Failed to perform SOAP operation for type %s: %v", kafkaMsg.Type, err)
-				// Construct an error response to send back to Kafka
-				errorEntity := models.UserData{
-					ClientID: kafkaMsg.ClientID,
-					Status:   "Error",
-					Message:  fmt.Sprintf("Failed to perform %s operation: %v", kafkaMsg.Type, err),
-				}
-				entityBytes, marshalErr := json.Marshal(errorEntity)
-				if marshalErr != nil {
-					log.Printf("Failed to marshal error entity to JSON: %v", marshalErr)
-					continue
-				}
-				// Produce error message to Kafka
-				errorMsg := kafka.Message{
-					Topic:   producerPkg.KafkaResponseTopic,
-					Value:   entityBytes,
-					Headers: []kafka.Header{{Key: "correlationId", Value: []byte(kafkaMsg.CorrelationID)}},
-				}
-				err = producer.Produce(errorMsg)
-				if err != nil {
-					log.Printf("Failed to produce error message to Kafka: %v", err)
-				}
+			kycClient := soapClient.WithCorrelationID(kafkaMsg.CorrelationID) // Ties the provider's audit trail to this message
+			if readCoalescer != nil && kafkaMsg.Type == "READ" && kafkaMsg.AsOf == nil {
+				// Answered with the rest of its batch, under its own correlationId, possibly from the
+				// batch timer: results produces it after the results handed to it before
+				readMsg := kafkaMsg
+				readCoalescer.Read(kafkaMsg.ClientID, kafkaMsg.CorrelationID, func(userData models.UserData, err error) {
+					results.Result(readMsg, userData, err)
+				})
 				continue
 			}
 
-			// For successful operations, processedEntity holds the result
-			log.Printf("Consumer Service: Successfully performed %s operation. UserData ClientID: %s, Status: %s, Message: %s, Risk: %f",
-				kafkaMsg.Type, processedEntity.ClientID, processedEntity.Status, processedEntity.Message, processedEntity.Risk)
+			// The request runs on its own goroutine and the loop only waits until it is answered or, with
+			// callbacks, acknowledged: its callback then finishes it without holding up later messages
+			accepted := make(chan struct{}, 1)
+			done := make(chan struct{})
+			requestClient := kycClient.OnAccepted(func() {
+				select {
+				case accepted <- struct{}{}:
+				default:
+				}
+			})
+			go func() {
+				defer close(done)
+				processMessage(results, requestClient, kafkaMsg)
+			}()
+			select {
+			case <-done:
+			case <-accepted:
+				log.Printf("Consumer Service: %s for correlationId %s accepted, its callback will finish it", kafkaMsg.Type, kafkaMsg.CorrelationID)
+			}
+		} else if err != context.DeadlineExceeded && err != context.Canceled { // context.DeadlineExceeded for timeout from ReadMessage, context.Canceled if the context is cancelled
+			log.Printf("Consumer error: %v\n", err)
+		}
+	}
+}
+
+// processMessage performs the SOAP operation a Kafka request asks for and hands its outcome to results
+func processMessage(results *resultPublisher, kycClient *soapclientPkg.SOAPClient, kafkaMsg models.KafkaMessage) {
+	var processedEntity models.UserData
+	var err error
+	var deleteMessage string
 
-			// Publish to Response topic
-			entityBytes, err := json.Marshal(processedEntity)
-			if err != nil {
-				log.Printf("Failed to marshal processed entity to JSON: %v", err)
-				continue
+	switch kafkaMsg.Type {
+	case "READ":
+		log.Printf("Consumer Service: Performing KYC Read for ClientID: %s", kafkaMsg.ClientID)
+		if kafkaMsg.AsOf != nil {
+			processedEntity, err = kycClient.ReadKYCAsOf(kafkaMsg.ClientID, *kafkaMsg.AsOf)
+		} else {
+			processedEntity, err = kycClient.ReadKYC(kafkaMsg.ClientID)
+		}
+	case "CREATE":
+		log.Printf("Consumer Service: Performing KYC Create for ClientID: %s", kafkaMsg.UserData.ClientID)
+		// Ensure ClientID is correctly set from KafkaMessage if not already in UserData
+		if kafkaMsg.UserData.ClientID == "" {
+			kafkaMsg.UserData.ClientID = kafkaMsg.ClientID
+		}
+		processedEntity, err = kycClient.CreateKYC(kafkaMsg.UserData)
+	case "UPDATE":
+		log.Printf("Consumer Service: Performing KYC Update for ClientID: %s", kafkaMsg.UserData.ClientID)
+		// Ensure ClientID is correctly set from KafkaMessage if not already in UserData
+		if kafkaMsg.UserData.ClientID == "" {
+			kafkaMsg.UserData.ClientID = kafkaMsg.ClientID
+		}
+		if kafkaMsg.ExpectedVersion != nil {
+			processedEntity, err = kycClient.UpdateKYCExpecting(kafkaMsg.UserData, *kafkaMsg.ExpectedVersion)
+		} else {
+			processedEntity, err = kycClient.UpdateKYC(kafkaMsg.UserData)
+		}
+	case "SCORE":
+		log.Printf("Consumer Service: Performing KYC Score for ClientID: %s", kafkaMsg.UserData.ClientID)
+		if kafkaMsg.UserData.ClientID == "" {
+			kafkaMsg.UserData.ClientID = kafkaMsg.ClientID
+		}
+		processedEntity, err = kycClient.ScoreKYC(kafkaMsg.UserData)
+	case "DELETE":
+		log.Printf("Consumer Service: Performing KYC Delete for ClientID: %s", kafkaMsg.ClientID)
+		deleteMessage, err = kycClient.DeleteKYC(kafkaMsg.ClientID)
+		if err == nil {
+			// For delete, we construct a dummy UserData for the response topic
+			// to indicate success, as there's no UserData returned by DeleteKYC
+			processedEntity = models.UserData{
+				Record:  kyc.Record{ClientID: kafkaMsg.ClientID},
+				Status:  "Success",
+				Message: deleteMessage,
 			}
+		}
+	case "SUBMIT":
+		log.Printf("Consumer Service: Submitting KYC for review for ClientID: %s", kafkaMsg.ClientID)
+		processedEntity, err = kycClient.SubmitKYC(kafkaMsg.ClientID)
+	case "APPROVE":
+		log.Printf("Consumer Service: Approving KYC for ClientID: %s", kafkaMsg.ClientID)
+		processedEntity, err = kycClient.ApproveKYC(kafkaMsg.ClientID, kafkaMsg.Reason)
+	case "REJECT":
+		log.Printf("Consumer Service: Rejecting KYC for ClientID: %s", kafkaMsg.ClientID)
+		processedEntity, err = kycClient.RejectKYC(kafkaMsg.ClientID, kafkaMsg.Reason)
+	case "STATUS":
+		log.Printf("Consumer Service: Performing KYC Status for ClientID: %s", kafkaMsg.ClientID)
+		processedEntity, err = kycClient.GetKYCStatus(kafkaMsg.ClientID)
+	case "HISTORY":
+		log.Printf("Consumer Service: Performing KYC History for ClientID: %s", kafkaMsg.ClientID)
+		processedEntity, err = kycClient.GetKYCHistory(kafkaMsg.ClientID)
+	case "SEARCH":
+		log.Printf("Consumer Service: Performing KYC Search for correlationId: %s", kafkaMsg.CorrelationID)
+		publishSearch(results, kycClient, kafkaMsg) // Produces a message per result page
+		return
+	default:
+		log.Printf("Consumer Service: Unknown Kafka message type: %s", kafkaMsg.Type)
+		err = fmt.Errorf("unknown Kafka message type: %s", kafkaMsg.Type)
+	}
+
+	results.Result(kafkaMsg, processedEntity, err)
+}
+
+// resultPublisher produces to the response topic from a single goroutine, in the order results are
+// handed to it, so that requests finished by a callback or a READ batch never produce concurrently
+// with the loop
+type resultPublisher struct {
+	producer *producerPkg.Producer
+	queue    chan func()
+}
+
+func newResultPublisher(producer *producerPkg.Producer) *resultPublisher {
+	p := &resultPublisher{producer: producer, queue: make(chan func(), 100)}
+	go func() {
+		for publish := range p.queue {
+			publish()
+		}
+	}()
+	return p
+}
+
+// Result queues the outcome of a Kafka request, see publishResult
+func (p *resultPublisher) Result(kafkaMsg models.KafkaMessage, processedEntity models.UserData, err error) {
+	p.queue <- func() { publishResult(p.producer, kafkaMsg, processedEntity, err) }
+}
+
+// Produce queues message and returns once it has been produced
+func (p *resultPublisher) Produce(message kafka.Message) error {
+	produced := make(chan error, 1)
+	p.queue <- func() { produced <- p.producer.Produce(message) }
+	return <-produced
+}
+
+// publishResult produces the outcome of a Kafka request to the response topic, tagged with its correlationId
+func publishResult(producer *producerPkg.Producer, kafkaMsg models.KafkaMessage, processedEntity models.UserData, err error) {
+	if err != nil {
+		log.Printf("Failed to perform SOAP operation for type %s: %v", kafkaMsg.Type, err)
+		// Construct an error response to send back to Kafka
+		errorEntity := models.UserData{
+			Record:  kyc.Record{ClientID: kafkaMsg.ClientID},
+			Status:  "Error",
+			Message: fmt.Sprintf("Failed to perform %s operation: %v", kafkaMsg.Type, err),
+		}
+		entityBytes, marshalErr := json.Marshal(errorEntity)
+		if marshalErr != nil {
+			log.Printf("Failed to marshal error entity to JSON: %v", marshalErr)
+			return
+		}
+		// Produce error message to Kafka
+		errorMsg := kafka.Message{
+			Topic:   producerPkg.KafkaResponseTopic,
+			Value:   entityBytes,
+			Headers: []kafka.Header{{Key: "correlationId", Value: []byte(kafkaMsg.CorrelationID)}},
+		}
+		err = producer.Produce(errorMsg)
+		if err != nil {
+			log.Printf("Failed to produce error message to Kafka: %v", err)
+		}
+		return
+	}
+
+	// For successful operations, processedEntity holds the result
+	log.Printf("Consumer Service: Successfully performed %s operation. UserData ClientID: %s, Status: %s, Message: %s, Risk: %f",
+		kafkaMsg.Type, processedEntity.ClientID, processedEntity.Status, processedEntity.Message, processedEntity.Risk)
+
+	// Publish to Response topic
+	entityBytes, err := json.Marshal(processedEntity)
+	if err != nil {
+		log.Printf("Failed to marshal processed entity to JSON: %v", err)
+		return
+	}
+
+	log.Printf("Consumer Service: Producing message to Kafka topic: %s. Entity: %s", producerPkg.KafkaResponseTopic, string(entityBytes))
+	successMsg := kafka.Message{
+		Topic:   producerPkg.KafkaResponseTopic,
+		Value:   entityBytes,
+		Headers: []kafka.Header{{Key: "correlationId", Value: []byte(kafkaMsg.CorrelationID)}},
+	}
+	err = producer.Produce(successMsg)
+	if err != nil {
+		log.Printf("Failed to produce message to Kafka: %v", err)
+	} else {
+		log.Printf("Consumer Service: Successfully produced message to Kafka topic: %s", producerPkg.KafkaResponseTopic)
+	}
+}
 
-			log.Printf("Consumer Service: Producing message to Kafka topic: %s. Entity: %s", producerPkg.KafkaResponseTopic, string(entityBytes))
-			successMsg := kafka.Message{
-				Topic:   producerPkg.KafkaResponseTopic,
-				Value:   entityBytes,
-				Headers: []kafka.Header{{Key: "correlationId", Value: []byte(kafkaMsg.CorrelationID)}},
+// publishSearch runs a SEARCH page by page, producing each page to the response topic as it arrives.
+// Besides the correlationId, every message carries a "sequence" header numbering the pages from 1 and
+// a "last" header that is "true" on the final page, so callers can reassemble the result. A failed
+// page ends the stream with an error page.
+func publishSearch(producer *resultPublisher, kycClient *soapclientPkg.SOAPClient, kafkaMsg models.KafkaMessage) {
+	var criteria models.SearchCriteria
+	if kafkaMsg.Search != nil {
+		criteria = *kafkaMsg.Search
+	}
+	pageToken := ""
+	for sequence := 1; ; sequence++ {
+		page, err := kycClient.SearchKYC(criteria, kafkaMsg.PageSize, pageToken)
+		if err != nil {
+			log.Printf("Failed to perform SOAP operation for type %s: %v", kafkaMsg.Type, err)
+			page = models.SearchPage{Status: "Error", Message: fmt.Sprintf("Failed to perform %s operation: %v", kafkaMsg.Type, err)}
+		}
+		last := err != nil || page.NextPageToken == ""
+
+		entityBytes, err := json.Marshal(page)
+		if err != nil {
+			log.Printf("Failed to marshal search page to JSON: %v", err)
+			return
+		}
+		pageMsg := kafka.Message{
+			Topic: producerPkg.KafkaResponseTopic,
+			Value: entityBytes,
+			Headers: []kafka.Header{
+				{Key: "correlationId", Value: []byte(kafkaMsg.CorrelationID)},
+				{Key: "sequence", Value: []byte(strconv.Itoa(sequence))},
+				{Key: "last", Value: []byte(strconv.FormatBool(last))},
+			},
+		}
+		if err := producer.Produce(pageMsg); err != nil {
+			log.Printf("Failed to produce search page to Kafka: %v", err)
+			return
+		}
+		log.Printf("Consumer Service: Produced search page %d with %d of %d user(s) to Kafka topic: %s", sequence, len(page.Users), page.Total, producerPkg.KafkaResponseTopic)
+		if last {
+			return
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// breakerConfigFromEnv builds the SOAP circuit breaker configuration, applying any SOAP_BREAKER_* overrides
+func breakerConfigFromEnv() soapclientPkg.BreakerConfig {
+	cfg := soapclientPkg.DefaultBreakerConfig()
+	envFloat := func(key string, target *float64) {
+		if s := os.Getenv(key); s != "" {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				*target = v
+			} else {
+				log.Printf("Consumer Service: Ignoring invalid %s '%s': %v", key, s, err)
 			}
-			err = producer.Produce(successMsg)
-			if err != nil {
-				log.Printf("Failed to produce message to Kafka: %v", err)
+		}
+	}
+	envInt := func(key string, target *int) {
+		if s := os.Getenv(key); s != "" {
+			if v, err := strconv.Atoi(s); err == nil {
+				*target = v
 			} else {
-				log.Printf("Consumer Service: Successfully produced message to Kafka topic: %s", producerPkg.KafkaResponseTopic)
+				log.Printf("Consumer Service: Ignoring invalid %s '%s': %v", key, s, err)
 			}
-		} else if err != context.DeadlineExceeded && err != context.Canceled { // context.DeadlineExceeded for timeout from ReadMessage, context.Canceled if the context is cancelled
-			log.Printf("Consumer error: %v\n", err)
 		}
 	}
+	envDuration := func(key string, target *time.Duration) {
+		if s := os.Getenv(key); s != "" {
+			if v, err := time.ParseDuration(s); err == nil {
+				*target = v
+			} else {
+				log.Printf("Consumer Service: Ignoring invalid %s '%s': %v", key, s, err)
+			}
+		}
+	}
+
+	envInt("SOAP_BREAKER_WINDOW_SIZE", &cfg.WindowSize)
+	envInt("SOAP_BREAKER_MINIMUM_CALLS", &cfg.MinimumCalls)
+	envFloat("SOAP_BREAKER_FAILURE_RATE", &cfg.FailureRateThreshold)
+	envDuration("SOAP_BREAKER_SLOW_CALL_THRESHOLD", &cfg.SlowCallThreshold)
+	envFloat("SOAP_BREAKER_SLOW_CALL_RATE", &cfg.SlowCallRateThreshold)
+	envDuration("SOAP_BREAKER_OPEN_DURATION", &cfg.OpenDuration)
+	envInt("SOAP_BREAKER_HALF_OPEN_CALLS", &cfg.HalfOpenMaxCalls)
+	envInt("SOAP_BULKHEAD_MAX_CONCURRENT", &cfg.MaxConcurrent)
+	envDuration("SOAP_BULKHEAD_MAX_WAIT", &cfg.MaxWait)
+	return cfg
+}
+
+// cacheOptionFromEnv enables the KYC read-through cache when SOAP_CACHE_TTL is set
+func cacheOptionFromEnv() (soapclientPkg.Option, bool) {
+	s := os.Getenv("SOAP_CACHE_TTL")
+	if s == "" {
+		return nil, false
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		log.Printf("Consumer Service: Ignoring invalid SOAP_CACHE_TTL '%s', cache disabled", s)
+		return nil, false
+	}
+
+	maxEntries := 10000
+	if s := os.Getenv("SOAP_CACHE_MAX_ENTRIES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			maxEntries = v
+		} else {
+			log.Printf("Consumer Service: Ignoring invalid SOAP_CACHE_MAX_ENTRIES '%s': %v", s, err)
+		}
+	}
+	var negativeTTL time.Duration
+	if s := os.Getenv("SOAP_CACHE_NEGATIVE_TTL"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil {
+			negativeTTL = v
+		} else {
+			log.Printf("Consumer Service: Ignoring invalid SOAP_CACHE_NEGATIVE_TTL '%s': %v", s, err)
+		}
+	}
+
+	log.Printf("Consumer Service: KYC read cache enabled (TTL %s, negative TTL %s, max %d entries)", ttl, negativeTTL, maxEntries)
+	return soapclientPkg.WithCache(soapclientPkg.NewLRUCache(maxEntries), ttl, negativeTTL), true
+}
+
+// callbackOptionFromEnv enables asynchronous SOAP calls when SOAP_CALLBACK_ADDR is set: the callback
+// listener is served there and SOAP_CALLBACK_URL is the wsa:ReplyTo address the provider POSTs to
+func callbackOptionFromEnv() (soapclientPkg.Option, bool) {
+	addr := os.Getenv("SOAP_CALLBACK_ADDR")
+	if addr == "" {
+		return nil, false
+	}
+	replyTo := os.Getenv("SOAP_CALLBACK_URL")
+	if replyTo == "" {
+		log.Printf("Consumer Service: Ignoring SOAP_CALLBACK_ADDR '%s' without SOAP_CALLBACK_URL, callbacks disabled", addr)
+		return nil, false
+	}
+	timeout := 30 * time.Second
+	if s := os.Getenv("SOAP_CALLBACK_TIMEOUT"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v > 0 {
+			timeout = v
+		} else {
+			log.Printf("Consumer Service: Ignoring invalid SOAP_CALLBACK_TIMEOUT '%s'", s)
+		}
+	}
+
+	listener := soapclientPkg.NewCallbackListener()
+	go func() {
+		log.Printf("Consumer Service: Serving SOAP callbacks on %s", addr)
+		if err := http.ListenAndServe(addr, listener); err != nil {
+			log.Printf("Consumer Service: SOAP callback listener stopped: %v", err)
+		}
+	}()
+	log.Printf("Consumer Service: Asynchronous SOAP calls enabled (ReplyTo %s, timeout %s)", replyTo, timeout)
+	return soapclientPkg.WithCallbacks(replyTo, listener, timeout), true
+}
+
+// readCoalescerFromEnv batches READ messages arriving within SOAP_BATCH_WINDOW into BatchKYCQuery
+// calls of up to SOAP_BATCH_MAX_SIZE ClientIDs. It returns nil when SOAP_BATCH_WINDOW is unset.
+func readCoalescerFromEnv(soapClient *soapclientPkg.SOAPClient) *soapclientPkg.ReadCoalescer {
+	s := os.Getenv("SOAP_BATCH_WINDOW")
+	if s == "" {
+		return nil
+	}
+	window, err := time.ParseDuration(s)
+	if err != nil || window <= 0 {
+		log.Printf("Consumer Service: Ignoring invalid SOAP_BATCH_WINDOW '%s', READ batching disabled", s)
+		return nil
+	}
+	maxBatch := 100
+	if s := os.Getenv("SOAP_BATCH_MAX_SIZE"); s != "" {
+		if v, err := strconv.Atoi(s); err != nil || v <= 0 {
+			log.Printf("Consumer Service: Ignoring invalid SOAP_BATCH_MAX_SIZE '%s'", s)
+		} else if v > soapclientPkg.MaxBatchSize {
+			log.Printf("Consumer Service: Capping SOAP_BATCH_MAX_SIZE '%s' at %d, the most BatchKYCQuery accepts", s, soapclientPkg.MaxBatchSize)
+			maxBatch = soapclientPkg.MaxBatchSize
+		} else {
+			maxBatch = v
+		}
+	}
+
+	log.Printf("Consumer Service: READ batching enabled (window %s, max %d ClientIDs)", window, maxBatch)
+	return soapclientPkg.NewReadCoalescer(soapClient, window, maxBatch)
 }
 
 // waitForKafkaConnection polls Kafka until it's ready to serve requests or a timeout occurs.
@@ -212,30 +547,18 @@ func waitForKafkaConnection(bootstrapServers string, timeout time.Duration) erro
 	return fmt.Errorf("timed out waiting for Kafka to be ready")
 }
 
-// waitForSoapService polls the SOAP service URL until it's reachable or a timeout occurs.
-func waitForSoapService(url string, timeout time.Duration) error {
-	log.Printf("Consumer Service: Waiting for SOAP service at %s to be ready for %s", url, timeout)
+// waitForSoapService polls the SOAP service URLs until any of them is reachable or a timeout occurs.
+func waitForSoapService(urls []string, timeout time.Duration) error {
+	log.Printf("Consumer Service: Waiting for SOAP service at %s to be ready for %s", strings.Join(urls, ", "), timeout)
 	endTime := time.Now().Add(timeout)
 	for time.Now().Before(endTime) {
-		req, errReq := http.NewRequest(http.MethodHead, url, nil)
-		if errReq != nil {
-			log.Printf("Consumer Service: Failed to create HTTP request for SOAP service: %v. Retrying...", errReq)
-			time.Sleep(1 * time.Second)
-			continue
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err == nil { // Request was successful (no network error)
-			defer resp.Body.Close() // Close body immediately after checking status
-
-			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusMethodNotAllowed {
-				log.Printf("Consumer Service: SOAP service is ready (status: %d).", resp.StatusCode)
+		for _, url := range urls {
+			err := soapclientPkg.ProbeEndpoint(http.DefaultClient, url)
+			if err == nil {
+				log.Printf("Consumer Service: SOAP service at %s is ready.", url)
 				return nil
 			}
-			// Log non-200/405 status code
-			log.Printf("Consumer Service: SOAP service not yet ready (status: %d). Retrying...", resp.StatusCode)
-		} else { // Request failed (network error, timeout, etc.)
-			log.Printf("Consumer Service: Failed to reach SOAP service: %v. Retrying...", err)
+			log.Printf("Consumer Service: %v. Retrying...", err)
 		}
 		time.Sleep(1 * time.Second)
 	}
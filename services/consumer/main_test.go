@@ -1,6 +1,7 @@
 package main
 
 import (
+		"bytes"
 		"context"
 		"io"
 		"log"
@@ -9,6 +10,8 @@ import (
 		"testing"
 		"time"
 	
+		soapclientPkg "kafka-soap-e2e-test/services/consumer/clients/soapclient"
+
 		"github.com/segmentio/kafka-go"
 		"github.com/stretchr/testify/assert"
 		"github.com/stretchr/testify/require"
@@ -64,7 +67,7 @@ func TestWaitForSoapService(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		err := waitForSoapService(ts.URL, 5*time.Second)
+		err := waitForSoapService([]string{ts.URL}, 5*time.Second)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, callCount, 2) // Should have called at least twice to succeed
 	})
@@ -76,7 +79,7 @@ func TestWaitForSoapService(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		err := waitForSoapService(ts.URL, 1*time.Second)
+		err := waitForSoapService([]string{ts.URL}, 1*time.Second)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "timed out waiting for SOAP service to be ready")
 	})
@@ -87,7 +90,7 @@ func TestWaitForSoapService(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		err := waitForSoapService(ts.URL, 1*time.Second)
+		err := waitForSoapService([]string{ts.URL}, 1*time.Second)
 		require.NoError(t, err)
 	})
 
@@ -97,14 +100,28 @@ func TestWaitForSoapService(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		err := waitForSoapService(ts.URL, 1*time.Second)
+		err := waitForSoapService([]string{ts.URL}, 1*time.Second)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "timed out waiting for SOAP service to be ready")
 	})
 
+	t.Run("Any endpoint in the pool is enough", func(t *testing.T) {
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer down.Close()
+		up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+		defer up.Close()
+
+		err := waitForSoapService([]string{down.URL, up.URL}, 1*time.Second)
+		require.NoError(t, err)
+	})
+
 	t.Run("SOAP service URL is invalid", func(t *testing.T) {
 		// This causes http.NewRequest to return an error, or http.DefaultClient.Do to fail
-		err := waitForSoapService("://invalid-url", 1*time.Second)
+		err := waitForSoapService([]string{"://invalid-url"}, 1*time.Second)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "timed out waiting for SOAP service to be ready")
 	})
@@ -145,3 +162,76 @@ func (m *MockProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message)
 func (m *MockProducer) Close() error { return nil } // Close method for kafka.Writer returns error
 
 
+
+// TestBreakerConfigFromEnv tests that SOAP_BREAKER_* overrides are applied
+func TestBreakerConfigFromEnv(t *testing.T) {
+	originalLogOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(originalLogOutput)
+
+	t.Setenv("SOAP_BREAKER_FAILURE_RATE", "0.25")
+	t.Setenv("SOAP_BREAKER_OPEN_DURATION", "5s")
+	t.Setenv("SOAP_BULKHEAD_MAX_CONCURRENT", "3")
+	t.Setenv("SOAP_BREAKER_WINDOW_SIZE", "not-a-number")
+
+	cfg := breakerConfigFromEnv()
+	assert.Equal(t, 0.25, cfg.FailureRateThreshold)
+	assert.Equal(t, 5*time.Second, cfg.OpenDuration)
+	assert.Equal(t, 3, cfg.MaxConcurrent)
+	assert.Equal(t, soapclientPkg.DefaultBreakerConfig().WindowSize, cfg.WindowSize) // Invalid values keep the default
+}
+
+// TestCacheOptionFromEnv tests that the read cache is only enabled with a valid SOAP_CACHE_TTL
+func TestCacheOptionFromEnv(t *testing.T) {
+	originalLogOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(originalLogOutput)
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		_, ok := cacheOptionFromEnv()
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalid TTL disables the cache", func(t *testing.T) {
+		t.Setenv("SOAP_CACHE_TTL", "soon")
+		_, ok := cacheOptionFromEnv()
+		assert.False(t, ok)
+	})
+
+	t.Run("Enabled with a valid TTL", func(t *testing.T) {
+		t.Setenv("SOAP_CACHE_TTL", "30s")
+		t.Setenv("SOAP_CACHE_NEGATIVE_TTL", "5s")
+		opt, ok := cacheOptionFromEnv()
+		require.True(t, ok)
+		assert.NotNil(t, opt)
+	})
+}
+
+// TestReadCoalescerFromEnv tests that READ batching is only enabled with a valid SOAP_BATCH_WINDOW
+// and that SOAP_BATCH_MAX_SIZE is capped at what the provider accepts
+func TestReadCoalescerFromEnv(t *testing.T) {
+	originalLogOutput := log.Writer()
+	defer log.SetOutput(originalLogOutput)
+	soapClient := soapclientPkg.NewSOAPClient("http://localhost:1/soap")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		assert.Nil(t, readCoalescerFromEnv(soapClient))
+	})
+
+	t.Run("Invalid window disables batching", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		t.Setenv("SOAP_BATCH_WINDOW", "soon")
+		assert.Nil(t, readCoalescerFromEnv(soapClient))
+	})
+
+	t.Run("Max size is capped", func(t *testing.T) {
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		t.Setenv("SOAP_BATCH_WINDOW", "5ms")
+		t.Setenv("SOAP_BATCH_MAX_SIZE", "5000")
+		assert.NotNil(t, readCoalescerFromEnv(soapClient))
+		assert.Contains(t, logs.String(), "Capping SOAP_BATCH_MAX_SIZE '5000' at 1000")
+		assert.Contains(t, logs.String(), "max 1000 ClientIDs")
+	})
+}
@@ -1,25 +1,44 @@
 package models
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
 
 // Incoming Kafka message structure
 type KafkaMessage struct {
 	Type          string `json:"type"` // "Process" or "KYC" (e.g., "READ", "CREATE", "UPDATE", "DELETE")
 	CorrelationID string `json:"correlationId"`
 	ClientID      string `json:"clientId,omitempty"` // For KYC type
+	Reason        string `json:"reason,omitempty"`   // Comment for APPROVE, required reason for REJECT
+	// AsOf makes a READ return the record as it stood at that time instead of its current state
+	AsOf *time.Time `json:"asOf,omitempty"`
+	// ExpectedVersion makes an UPDATE fail with a version conflict unless the stored record is at this version
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"`
+	// Search selects the records of a SEARCH, whose result pages are produced one message each
+	Search *SearchCriteria `json:"search,omitempty"`
+	// PageSize bounds the records per SEARCH result page; zero uses the provider's default
+	PageSize int `json:"pageSize,omitempty"`
 	// Add other fields as needed for specific request types
 	UserData UserData `json:"userData,omitempty"` // For Create/Update operations
 }
 
-// UserData struct representing a comprehensive KYC response for Kafka and internal use
+// UserData is the KYC payload exchanged over Kafka: the shared KYC record plus the outcome of the
+// operation that produced it. In SOAP responses only the record is carried inside UserData;
+// Status and Message come from the enclosing KYCResponse.
 type UserData struct {
-	XMLName  xml.Name `xml:"UserData"` // Added for proper unmarshalling when embedded
-	ClientID string   `xml:"ClientID" json:"clientId"`
-	Risk     float64  `xml:"Risk" json:"risk"`
-	// Status and Message are part of the overall response, not typically within UserData itself
-	// in the KYC service's UserData struct, but included here if needed for client-side processing
-	Status  string `json:"status,omitempty"`
-	Message string `json:"message,omitempty"`
+	XMLName xml.Name `xml:"UserData" json:"-"` // Added for proper unmarshalling when embedded
+	kyc.Record
+	Status  string `xml:"-" json:"status,omitempty"`
+	Message string `xml:"-" json:"message,omitempty"`
+	// AllowedTransitions is only set for STATUS requests: the review statuses the record may move to next
+	AllowedTransitions []kyc.ReviewStatus `xml:"-" json:"allowedTransitions,omitempty"`
+	// RiskScore is set when the provider computed Risk: how the score was reached
+	RiskScore *kyc.RiskScore `xml:"-" json:"riskScore,omitempty"`
+	// History is only set for HISTORY requests: every recorded change of the record, oldest first
+	History []kyc.AuditEvent `xml:"-" json:"history,omitempty"`
 }
 
 // --- Structures for Standard KYC SOAP Responses (Read, Create, Update) ---
@@ -39,11 +58,12 @@ type StandardKYCResponseBody struct {
 
 // StandardKYCResult contains the status, message, and optional UserData for standard operations
 type StandardKYCResult struct {
-	XMLName  xml.Name  `xml:"http://example.com/kyc KYCResponse"`
-	XmlnsKyc string    `xml:"xmlns,attr"`
-	Status   string    `xml:"Status"`
-	Message  string    `xml:"Message"`
-	UserData *UserData `xml:"UserData,omitempty"`
+	XMLName   xml.Name       `xml:"http://example.com/kyc KYCResponse"`
+	XmlnsKyc  string         `xml:"xmlns,attr"`
+	Status    string         `xml:"Status"`
+	Message   string         `xml:"Message"`
+	UserData  *UserData      `xml:"UserData,omitempty"`
+	RiskScore *kyc.RiskScore `xml:"RiskScore,omitempty"`
 }
 
 // --- Structures for Delete KYC SOAP Responses ---
@@ -68,3 +88,151 @@ type DeleteKYCResult struct {
 	Status   string   `xml:"Status"`
 	Message  string   `xml:"Message"`
 }
+
+// --- Structures for KYC Status SOAP Responses ---
+
+// KYCStatusResponseEnvelope is the top-level SOAP envelope for GetKYCStatus responses
+type KYCStatusResponseEnvelope struct {
+	XMLName      xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         KYCStatusResponseBody
+}
+
+// KYCStatusResponseBody contains the KYCStatusResult
+type KYCStatusResponseBody struct {
+	XMLName         xml.Name        `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	KYCStatusResult KYCStatusResult `xml:"http://example.com/kyc KYCStatusResponse"`
+}
+
+// KYCStatusResult contains a record's review status and the statuses it may move to
+type KYCStatusResult struct {
+	XMLName            xml.Name           `xml:"http://example.com/kyc KYCStatusResponse"`
+	Status             string             `xml:"Status"`
+	Message            string             `xml:"Message"`
+	ClientID           string             `xml:"ClientID"`
+	ReviewStatus       kyc.ReviewStatus   `xml:"ReviewStatus"`
+	ReviewNote         string             `xml:"ReviewNote"`
+	ReviewedAt         *time.Time         `xml:"ReviewedAt"`
+	AllowedTransitions []kyc.ReviewStatus `xml:"AllowedTransition"`
+}
+
+// --- Structures for Score KYC SOAP Responses ---
+
+// ScoreKYCResponseEnvelope is the top-level SOAP envelope for ScoreKYC responses
+type ScoreKYCResponseEnvelope struct {
+	XMLName      xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         ScoreKYCResponseBody
+}
+
+// ScoreKYCResponseBody contains the ScoreKYCResult
+type ScoreKYCResponseBody struct {
+	XMLName        xml.Name       `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	ScoreKYCResult ScoreKYCResult `xml:"http://example.com/kyc ScoreKYCResponse"`
+}
+
+// ScoreKYCResult contains the computed score of a record that was not stored
+type ScoreKYCResult struct {
+	XMLName   xml.Name       `xml:"http://example.com/kyc ScoreKYCResponse"`
+	Status    string         `xml:"Status"`
+	Message   string         `xml:"Message"`
+	ClientID  string         `xml:"ClientID"`
+	RiskScore *kyc.RiskScore `xml:"RiskScore"`
+}
+
+// --- Structures for KYC History SOAP Responses ---
+
+// KYCHistoryResponseEnvelope is the top-level SOAP envelope for GetKYCHistory responses
+type KYCHistoryResponseEnvelope struct {
+	XMLName      xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         KYCHistoryResponseBody
+}
+
+// KYCHistoryResponseBody contains the KYCHistoryResult
+type KYCHistoryResponseBody struct {
+	XMLName          xml.Name         `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	KYCHistoryResult KYCHistoryResult `xml:"http://example.com/kyc KYCHistoryResponse"`
+}
+
+// KYCHistoryResult contains the audit trail of a record
+type KYCHistoryResult struct {
+	XMLName  xml.Name         `xml:"http://example.com/kyc KYCHistoryResponse"`
+	Status   string           `xml:"Status"`
+	Message  string           `xml:"Message"`
+	ClientID string           `xml:"ClientID"`
+	Events   []kyc.AuditEvent `xml:"Event"`
+}
+
+// BatchKYCQueryResponseEnvelope is the top-level SOAP envelope for BatchKYCQuery responses
+type BatchKYCQueryResponseEnvelope struct {
+	XMLName      xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         BatchKYCQueryResponseBody
+}
+
+// BatchKYCQueryResponseBody contains the BatchKYCQueryResult
+type BatchKYCQueryResponseBody struct {
+	XMLName             xml.Name            `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	BatchKYCQueryResult BatchKYCQueryResult `xml:"http://example.com/kyc BatchKYCQueryResponse"`
+}
+
+// BatchKYCQueryResult contains one result per requested ClientID, in request order
+type BatchKYCQueryResult struct {
+	XMLName xml.Name       `xml:"http://example.com/kyc BatchKYCQueryResponse"`
+	Status  string         `xml:"Status"`
+	Message string         `xml:"Message"`
+	Results []BatchKYCItem `xml:"Result"`
+}
+
+// BatchKYCItem is the result of one ClientID of a BatchKYCQuery
+type BatchKYCItem struct {
+	ClientID  string    `xml:"ClientID"`
+	Status    string    `xml:"Status"`
+	Message   string    `xml:"Message"`
+	ErrorCode string    `xml:"ErrorCode"`
+	FaultCode string    `xml:"FaultCode"` // Set in the provider's fault error style
+	UserData  *UserData `xml:"UserData,omitempty"`
+}
+
+// SearchCriteria selects the records of a SearchKYC; a record must match every criterion set
+type SearchCriteria struct {
+	MinRisk        *float64           `json:"minRisk,omitempty"`
+	MaxRisk        *float64           `json:"maxRisk,omitempty"`
+	ReviewStatuses []kyc.ReviewStatus `json:"reviewStatuses,omitempty"`
+	NameContains   string             `json:"nameContains,omitempty"`   // Case-insensitive substring of LegalName
+	DocumentNumber string             `json:"documentNumber,omitempty"` // Exact number of any identity document
+	CreatedAfter   *time.Time         `json:"createdAfter,omitempty"`
+}
+
+// SearchPage is one page of SearchKYC results, also the payload of each SEARCH response message
+type SearchPage struct {
+	Status        string       `json:"status"`
+	Message       string       `json:"message,omitempty"`
+	Total         int          `json:"total"` // Matching records across all pages
+	Users         []kyc.Record `json:"users"`
+	NextPageToken string       `json:"nextPageToken,omitempty"` // Absent on the last page
+}
+
+// SearchKYCResponseEnvelope is the top-level SOAP envelope for SearchKYC responses
+type SearchKYCResponseEnvelope struct {
+	XMLName      xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         SearchKYCResponseBody
+}
+
+// SearchKYCResponseBody contains the SearchKYCResult
+type SearchKYCResponseBody struct {
+	XMLName         xml.Name        `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	SearchKYCResult SearchKYCResult `xml:"http://example.com/kyc SearchKYCResponse"`
+}
+
+// SearchKYCResult contains one page of matching records
+type SearchKYCResult struct {
+	XMLName       xml.Name     `xml:"http://example.com/kyc SearchKYCResponse"`
+	Status        string       `xml:"Status"`
+	Message       string       `xml:"Message"`
+	Total         int          `xml:"Total"`
+	NextPageToken string       `xml:"NextPageToken"`
+	Users         []kyc.Record `xml:"UserData"`
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"kafka-soap-e2e-test/services/consumer/models" // For models.UserData
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 // writeJSONResponse is a helper function to send JSON responses
@@ -22,23 +25,60 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	}
 }
 
-// adminListUsers handles GET /admin/v1/users
-func adminListUsers(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+// etag returns the entity tag the admin API sends for a record at version
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion reads the If-Match header of r as the version the stored record must be at.
+// It returns nil if the header is absent or "*". A header that is not a single strong entity
+// tag returns ok false, since no record version can match it.
+func ifMatchVersion(r *http.Request) (expected *int64, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return nil, false
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &version, true
+}
+
+// writeRecordWriteError reports a failed update or delete: 412 when the If-Match precondition
+// failed, 404 otherwise
+func writeRecordWriteError(w http.ResponseWriter, err error) {
+	var conflictErr *kyc.VersionConflictError
+	if errors.As(err, &conflictErr) {
+		writeJSONResponse(w, http.StatusPreconditionFailed, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+}
+
+// adminListUsers handles GET /admin/v1/users. It returns one page of the records matching the
+// minRisk, maxRisk, status and prefix filters, ordered by ?sort=, together with the number of
+// matching records and the cursor of the next page.
+func adminListUsers(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	repo.mu.RLock()
-	defer repo.mu.RUnlock()
-	users := make([]models.UserData, 0, len(repo.users))
-	for _, user := range repo.users {
-		users = append(users, user)
+	query, err := parseUserQuery(r.URL.Query())
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
-	writeJSONResponse(w, http.StatusOK, users)
+	writeJSONResponse(w, http.StatusOK, repo.Page(query))
 }
 
-// adminGetUser handles GET /admin/v1/users/{clientID}
-func adminGetUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+// adminGetUser handles GET /admin/v1/users/{clientID}. With ?asOf=<RFC 3339 time> it returns the
+// record as it stood at that time.
+func adminGetUser(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -49,35 +89,53 @@ func adminGetUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := repo.Read(clientID)
+	var asOf *time.Time
+	if raw := r.URL.Query().Get("asOf"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid asOf '%s': want an RFC 3339 time", raw)})
+			return
+		}
+		asOf = &t
+	}
+
+	user, err := readRecord(repo, clientID, asOf)
 	if err != nil {
 		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
 		return
 	}
+	w.Header().Set("ETag", etag(user.Version))
 	writeJSONResponse(w, http.StatusOK, user)
 }
 
 // adminCreateUser handles POST /admin/v1/users
-func adminCreateUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+func adminCreateUser(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	var userData models.UserData
+	var userData kyc.Record
 	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
 		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
 
-	if err := repo.Create(userData); err != nil {
+	if err := userData.Validate(); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	created, err := createRecord(repo, adminAuditContext(r), userData, false)
+	if err != nil {
 		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		return
 	}
-	writeJSONResponse(w, http.StatusCreated, userData)
+	w.Header().Set("ETag", etag(created.Version))
+	writeJSONResponse(w, http.StatusCreated, created)
 }
 
-// adminUpdateUser handles PUT /admin/v1/users/{clientID}
-func adminUpdateUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+// adminUpdateUser handles PUT /admin/v1/users/{clientID}, honouring If-Match
+func adminUpdateUser(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -88,7 +146,7 @@ func adminUpdateUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var userData models.UserData
+	var userData kyc.Record
 	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
 		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
@@ -98,16 +156,27 @@ func adminUpdateUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request)
 		return
 	}
 	userData.ClientID = clientID // Ensure clientID from path is used
+	if err := userData.Validate(); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	expected, ok := ifMatchVersion(r)
+	if !ok {
+		writeJSONResponse(w, http.StatusPreconditionFailed, map[string]string{"error": "If-Match does not name a record version"})
+		return
+	}
 
-	if err := repo.Update(userData); err != nil {
-		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	updated, err := updateRecord(repo, adminAuditContext(r), userData, expected, false)
+	if err != nil {
+		writeRecordWriteError(w, err)
 		return
 	}
-	writeJSONResponse(w, http.StatusOK, userData)
+	w.Header().Set("ETag", etag(updated.Version))
+	writeJSONResponse(w, http.StatusOK, updated)
 }
 
-// adminDeleteUser handles DELETE /admin/v1/users/{clientID}
-func adminDeleteUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+// adminDeleteUser handles DELETE /admin/v1/users/{clientID}, honouring If-Match
+func adminDeleteUser(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -118,15 +187,21 @@ func adminDeleteUser(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := repo.Delete(clientID); err != nil {
-		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	expected, ok := ifMatchVersion(r)
+	if !ok {
+		writeJSONResponse(w, http.StatusPreconditionFailed, map[string]string{"error": "If-Match does not name a record version"})
+		return
+	}
+
+	if err := deleteRecord(repo, adminAuditContext(r), clientID, expected); err != nil {
+		writeRecordWriteError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("User '%s' deleted", clientID)})
 }
 
 // adminSetAction handles POST /admin/v1/actions/{clientID}
-func adminSetAction(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+func adminSetAction(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -148,7 +223,10 @@ func adminSetAction(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request)
 	action := Action(requestBody.Action)
 	switch action {
 	case ActionTimeout, ActionInternalError, ActionNotFound:
-		repo.SetAction(clientID, action)
+		if err := repo.SetAction(clientID, action); err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
 		writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Action '%s' set for ClientID '%s'", action, clientID)})
 	default:
 		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid action type"})
@@ -156,7 +234,7 @@ func adminSetAction(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request)
 }
 
 // adminClearAction handles DELETE /admin/v1/actions/{clientID}
-func adminClearAction(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+func adminClearAction(repo Repository, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -167,12 +245,38 @@ func adminClearAction(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	repo.ClearAction(clientID)
+	if err := repo.ClearAction(clientID); err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
 	writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Action cleared for ClientID '%s'", clientID)})
 }
 
+// adminSettings handles GET and PUT /admin/v1/settings. PUT only changes the fields present in the body.
+func adminSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSONResponse(w, http.StatusOK, settings.Get())
+	case http.MethodPut:
+		updated := settings.Get()
+		if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		if err := updated.validate(); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		settings.Set(updated)
+		log.Printf("KYC SOAP Server: Settings updated: %+v", updated)
+		writeJSONResponse(w, http.StatusOK, updated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // initAdminRoutes registers all admin API routes to the given ServeMux.
-func initAdminRoutes(mux *http.ServeMux, repo *InMemoryRepo) {
+func initAdminRoutes(mux *http.ServeMux, repo Repository) {
 	// Register Admin API handlers
 	mux.HandleFunc("/admin/v1/users/", func(w http.ResponseWriter, r *http.Request) { // Trailing slash to match {clientID}
 		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/v1/users/"), "/")
@@ -187,6 +291,10 @@ func initAdminRoutes(mux *http.ServeMux, repo *InMemoryRepo) {
 			default:
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			}
+		} else if len(parts) == 2 && parts[0] != "" && parts[1] == "transition" { // Matches /admin/v1/users/{clientID}/transition
+			adminTransitionUser(repo, parts[0], w, r)
+		} else if len(parts) == 2 && parts[0] != "" && parts[1] == "history" { // Matches /admin/v1/users/{clientID}/history
+			adminUserHistory(repo, parts[0], w, r)
 		} else { // This else branch is now specifically for the case where it's /admin/v1/users/ with no clientID (which is valid for POST to list users)
 			http.NotFound(w, r) // If there's nothing after the trailing slash, it's not a valid /users/{id} request
 		}
@@ -202,11 +310,23 @@ func initAdminRoutes(mux *http.ServeMux, repo *InMemoryRepo) {
 			default:
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			}
+		} else if len(parts) == 2 && parts[0] == "rules" && parts[1] != "" { // Matches /admin/v1/actions/rules/{id}
+			adminFaultRule(parts[1], w, r)
 		} else {
 			http.NotFound(w, r)
 		}
 	})
 
+	mux.HandleFunc("/admin/v1/settings", adminSettings)
+	initSnapshotRoutes(mux, repo)
+	initScoringRoutes(mux)
+	initBulkRoutes(mux, repo)
+	initFixtureRoutes(mux)
+	initFaultRoutes(mux)
+	initScenarioRoutes(mux)
+	initJournalRoutes(mux)
+	initCDCRoutes(mux)
+
 	// Handle /admin/v1/users (without trailing slash) for LIST (GET) and CREATE (POST)
 	mux.HandleFunc("/admin/v1/users", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// Request headers identifying who made a change and which flow it belongs to
+const (
+	headerActor         = "X-Actor"
+	headerCorrelationID = "X-Correlation-ID"
+)
+
+// anonymousActor is recorded for requests without an X-Actor header
+const anonymousActor = "anonymous"
+
+// auditContext describes where a mutation came from; it is stored with every audit event the
+// mutation produces
+type auditContext struct {
+	actor         string
+	source        kyc.AuditSource
+	operation     string // SOAP operation, admin route or fixture file
+	correlationID string
+	tenant        string // The tenant the request was routed to; empty for the default one
+}
+
+// requestAuditContext reads the actor and correlation ID of r
+func requestAuditContext(r *http.Request, source kyc.AuditSource, operation string) auditContext {
+	actor := r.Header.Get(headerActor)
+	if actor == "" {
+		actor = anonymousActor
+	}
+	return auditContext{actor: actor, source: source, operation: operation, correlationID: r.Header.Get(headerCorrelationID), tenant: currentTenant(r).name}
+}
+
+// adminAuditContext is requestAuditContext for the admin API, naming the route as the operation
+func adminAuditContext(r *http.Request) auditContext {
+	return requestAuditContext(r, kyc.SourceAdmin, r.Method+" "+r.URL.Path)
+}
+
+// fixtureAuditContext is used for records loaded from the fixture directory at startup
+func fixtureAuditContext(path string) auditContext {
+	return auditContext{actor: "system", source: kyc.SourceFixture, operation: path}
+}
+
+// event returns the audit event of a mutation made in this context. before is nil for a create
+// and after is nil for a delete.
+func (audit auditContext) event(action kyc.AuditAction, clientID string, before, after *kyc.Record) kyc.AuditEvent {
+	return kyc.AuditEvent{
+		ClientID:      clientID,
+		Action:        action,
+		Actor:         audit.actor,
+		Source:        audit.source,
+		Operation:     audit.operation,
+		CorrelationID: audit.correlationID,
+		Timestamp:     now().UTC(),
+		Before:        before,
+		After:         after,
+	}
+}
+
+// commitChanges applies the mutations described by events and records them in the audit log, and
+// in the change event outbox when changes are captured, in one atomic repository write. Nothing is
+// stored if it fails.
+func commitChanges(repo Repository, audit auditContext, events ...kyc.AuditEvent) error {
+	applied, err := repo.Apply(events...)
+	if err != nil {
+		return err
+	}
+	for _, event := range applied {
+		log.Printf("KYC SOAP Server: Audit event %d: %s of ClientID '%s' by %s via %s", event.Sequence, event.Action, event.ClientID, audit.actor, audit.source)
+	}
+	return nil
+}
+
+// readRecord returns the stored record of clientID, or with asOf set the record as it stood then
+func readRecord(repo Repository, clientID string, asOf *time.Time) (kyc.Record, error) {
+	if asOf == nil {
+		return repo.Read(clientID)
+	}
+	record, ok := kyc.StateAt(repo.History(clientID), *asOf)
+	if !ok {
+		return kyc.Record{}, fmt.Errorf("user with ClientID '%s' not found as of %s", clientID, asOf.Format(time.RFC3339))
+	}
+	return record, nil
+}
+
+// handleGetKYCHistory serves GetKYCHistory
+func handleGetKYCHistory(repo Repository, content []byte) (interface{}, *operationError) {
+	const operation = "GetKYCHistory"
+	var req kycModels.GetKYCHistoryRequest
+	if err := xml.Unmarshal(content, &req); err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	history := repo.History(req.ClientID)
+	if len(history) == 0 {
+		return nil, &operationError{operation: operation, code: ErrorCodeNotFound, message: fmt.Sprintf("no history for ClientID '%s'", req.ClientID), httpStatus: http.StatusNotFound}
+	}
+	return kycModels.KYCHistoryResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.KYCHistoryResponseBody{
+			KYCHistoryResult: kycModels.KYCHistoryResult{
+				Status:   "Success",
+				Message:  fmt.Sprintf("%d event(s) found", len(history)),
+				ClientID: req.ClientID,
+				Events:   history,
+			},
+		},
+	}, nil
+}
+
+// adminUserHistory handles GET /admin/v1/users/{clientID}/history
+func adminUserHistory(repo Repository, clientID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	history := repo.History(clientID)
+	if len(history) == 0 {
+		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("no history for ClientID '%s'", clientID)})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, history)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useClock makes now return the times in order, one per call, and restores it when the test ends
+func useClock(t *testing.T, times ...time.Time) {
+	t.Helper()
+	previous := now
+	t.Cleanup(func() { now = previous })
+	now = func() time.Time {
+		require.NotEmpty(t, times, "clock ran out of times")
+		next := times[0]
+		if len(times) > 1 {
+			times = times[1:]
+		}
+		return next
+	}
+}
+
+func TestSOAPHandler_AuditTrail(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, t0, t0, t0.Add(time.Hour), t0.Add(time.Hour), t0.Add(2*time.Hour))
+	repo := NewInMemoryRepo()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/soap", strings.NewReader(body))
+		req.Header.Set(headerActor, "analyst1")
+		req.Header.Set(headerCorrelationID, "corr-42")
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusOK, send(createSOAPRequest("CreateKYC", "client123", &kyc.Record{ClientID: "client123", Risk: 0.2})).Code)
+	require.Equal(t, http.StatusOK, send(createSOAPRequest("UpdateKYC", "client123", &kyc.Record{ClientID: "client123", Risk: 0.9})).Code)
+	require.Equal(t, http.StatusOK, send(createSOAPRequest("DeleteKYC", "client123", nil)).Code)
+
+	rec := send(createSOAPRequest("GetKYCHistory", "client123", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response kycModels.KYCHistoryResult
+	require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+	assert.Equal(t, "Success", response.Status)
+	require.Len(t, response.Events, 3)
+
+	created, updated, deleted := response.Events[0], response.Events[1], response.Events[2]
+	assert.Equal(t, []int64{1, 2, 3}, []int64{created.Sequence, updated.Sequence, deleted.Sequence})
+	assert.Equal(t, kyc.AuditCreate, created.Action)
+	assert.Nil(t, created.Before)
+	assert.Equal(t, 0.2, created.After.Risk)
+	assert.Equal(t, "analyst1", created.Actor)
+	assert.Equal(t, kyc.SourceSOAP, created.Source)
+	assert.Equal(t, "CreateKYC", created.Operation)
+	assert.Equal(t, "corr-42", created.CorrelationID)
+	assert.Equal(t, t0, created.Timestamp)
+
+	assert.Equal(t, kyc.AuditUpdate, updated.Action)
+	assert.Equal(t, 0.2, updated.Before.Risk)
+	assert.Equal(t, 0.9, updated.After.Risk)
+	assert.Equal(t, kyc.AuditDelete, deleted.Action)
+	assert.Equal(t, 0.9, deleted.Before.Risk)
+	assert.Nil(t, deleted.After)
+
+	// The deleted record can still be read as it stood before the delete
+	query := func(asOf time.Time) (int, kycModels.KYCResult) {
+		body := fmt.Sprintf(`%s%s<KYCQuery xmlns="%s"><ClientID>client123</ClientID><AsOf>%s</AsOf></KYCQuery>%s%s`,
+			soapEnvelopeStart, soapBodyStart, kycNamespaceAttr, asOf.Format(time.RFC3339), soapBodyEnd, soapEnvelopeEnd)
+		rec := send(body)
+		var result kycModels.KYCResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &result))
+		return rec.Code, result
+	}
+	code, result := query(t0.Add(30 * time.Minute))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0.2, result.UserData.Risk)
+	code, result = query(t0.Add(90 * time.Minute))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0.9, result.UserData.Risk)
+	code, _ = query(t0.Add(3 * time.Hour))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	rec = send(createSOAPRequest("GetKYCHistory", "nonexistent", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSOAPHandler_AuditTrailAnonymous(t *testing.T) {
+	repo := NewInMemoryRepo()
+	_, err := createRecord(repo, auditContext{}, kyc.Record{ClientID: "client123", Risk: 0.4}, true)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("SubmitKYC", "client123", nil))))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	history := repo.History("client123")
+	require.Len(t, history, 2)
+	assert.Equal(t, kyc.AuditTransition, history[1].Action)
+	assert.Equal(t, anonymousActor, history[1].Actor)
+	assert.Empty(t, history[1].CorrelationID)
+	assert.Equal(t, kyc.ReviewPending, history[1].Before.ReviewStatus)
+	assert.Equal(t, kyc.ReviewInReview, history[1].After.ReviewStatus)
+}
+
+func TestAdminAPI_History(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, t0, t0, t0.Add(time.Hour))
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(headerActor, "ops")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusCreated, serve("POST", "/admin/v1/users", `{"clientId":"client1","risk":0.3}`).Code)
+	require.Equal(t, http.StatusOK, serve("POST", "/admin/v1/users/client1/transition", `{"to":"APPROVED"}`).Code)
+
+	rec := serve("GET", "/admin/v1/users/client1/history", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var history []kyc.AuditEvent
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	require.Len(t, history, 2)
+	assert.Equal(t, kyc.SourceAdmin, history[0].Source)
+	assert.Equal(t, "ops", history[0].Actor)
+	assert.Equal(t, "POST /admin/v1/users", history[0].Operation)
+	assert.Equal(t, kyc.AuditTransition, history[1].Action)
+	assert.Equal(t, kyc.ReviewApproved, history[1].After.ReviewStatus)
+
+	rec = serve("GET", "/admin/v1/users/client1?asOf="+t0.Add(time.Minute).Format(time.RFC3339), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var record kyc.Record
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &record))
+	assert.Equal(t, kyc.ReviewPending, record.ReviewStatus)
+
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/admin/v1/users/client1?asOf="+t0.Add(-time.Minute).Format(time.RFC3339), "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/admin/v1/users/client1?asOf=yesterday", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/admin/v1/users/missing/history", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("POST", "/admin/v1/users/client1/history", "").Code)
+
+	// Restoring a snapshot rolls the history back with the records
+	snapshot := repo.Snapshot()
+	require.Equal(t, http.StatusOK, serve("DELETE", "/admin/v1/users/client1", "").Code)
+	assert.Len(t, repo.History("client1"), 3)
+	require.NoError(t, repo.Restore(snapshot))
+	assert.Len(t, repo.History("client1"), 2)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strings"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+)
+
+// maxBatchSize bounds the ClientIDs of one BatchKYCQuery
+const maxBatchSize = 1000
+
+// handleBatchKYCQuery serves BatchKYCQuery, reading every requested record with its own status.
+// A missing record fails its item only; the batch as a whole fails only when the request is invalid.
+// In the fault error style a failed item also names the faultcode a KYCQuery of it would have had,
+// so that clients can report it as they would that call's fault.
+func handleBatchKYCQuery(repo Repository, content []byte) (interface{}, *operationError) {
+	const operation = "BatchKYCQuery"
+	var req kycModels.BatchKYCQueryRequest
+	if err := xml.Unmarshal(content, &req); err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	switch {
+	case len(req.ClientIDs) == 0:
+		return nil, invalidRequestError(operation, fmt.Errorf("at least one ClientID is required"))
+	case len(req.ClientIDs) > maxBatchSize:
+		return nil, invalidRequestError(operation, fmt.Errorf("%d ClientIDs exceed the limit of %d", len(req.ClientIDs), maxBatchSize))
+	}
+
+	var faultCode string
+	if settings.Get().ErrorStyle == ErrorStyleFault {
+		faultCode = ErrorCodeNotFound.faultCode()
+	}
+	results := make([]kycModels.BatchKYCItem, 0, len(req.ClientIDs))
+	found := 0
+	for _, clientID := range req.ClientIDs {
+		clientID = strings.TrimSpace(clientID)
+		record, err := repo.Read(clientID)
+		if err != nil {
+			results = append(results, kycModels.BatchKYCItem{ClientID: clientID, Status: "Error", Message: err.Error(), ErrorCode: string(ErrorCodeNotFound), FaultCode: faultCode})
+			continue
+		}
+		found++
+		results = append(results, kycModels.BatchKYCItem{ClientID: clientID, Status: "Success", Message: "User data retrieved", UserData: &record})
+	}
+	log.Printf("KYC SOAP Server: %s found %d of %d user(s)", operation, found, len(results))
+	return kycModels.BatchKYCQueryResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.BatchKYCQueryResponseBody{
+			BatchKYCQueryResult: kycModels.BatchKYCQueryResult{
+				Status:  "Success",
+				Message: fmt.Sprintf("%d of %d user(s) found", found, len(results)),
+				Results: results,
+			},
+		},
+	}, nil
+}
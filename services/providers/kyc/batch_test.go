@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBatchRequest builds a BatchKYCQuery for clientIDs
+func createBatchRequest(clientIDs ...string) string {
+	var ids strings.Builder
+	for _, id := range clientIDs {
+		fmt.Fprintf(&ids, "<ClientID>%s</ClientID>", id)
+	}
+	return fmt.Sprintf(`%s%s<BatchKYCQuery xmlns="%s">%s</BatchKYCQuery>%s%s`, soapEnvelopeStart, soapBodyStart, kycNamespaceAttr, ids.String(), soapBodyEnd, soapEnvelopeEnd)
+}
+
+func TestSOAPHandler_BatchKYCQuery(t *testing.T) {
+	repo := NewInMemoryRepo()
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1}))
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client2", Risk: 0.2}))
+	send := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := send(createBatchRequest("client2", "nonexistent", "client1", "client2"))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response kycModels.BatchKYCQueryResult
+	require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+	assert.Equal(t, "Success", response.Status)
+	assert.Equal(t, "3 of 4 user(s) found", response.Message)
+	require.Len(t, response.Results, 4, "one result per ClientID, duplicates included")
+
+	for i, want := range []string{"client2", "nonexistent", "client1", "client2"} {
+		assert.Equal(t, want, response.Results[i].ClientID)
+	}
+	assert.Equal(t, "Success", response.Results[0].Status)
+	require.NotNil(t, response.Results[0].UserData)
+	assert.Equal(t, 0.2, response.Results[0].UserData.Risk)
+	assert.Equal(t, "Error", response.Results[1].Status)
+	assert.Equal(t, string(ErrorCodeNotFound), response.Results[1].ErrorCode)
+	assert.Empty(t, response.Results[1].FaultCode, "only set in the fault error style")
+	assert.Nil(t, response.Results[1].UserData)
+	assert.Equal(t, 0.1, response.Results[2].UserData.Risk)
+
+	t.Run("rejects empty and oversized batches", func(t *testing.T) {
+		rec := send(createBatchRequest())
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, "the schema requires a ClientID")
+		assert.Contains(t, rec.Body.String(), "ValidationFault")
+		ids := make([]string, maxBatchSize+1)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("client%d", i)
+		}
+		rec = send(createBatchRequest(ids...))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "exceed the limit of 1000")
+	})
+
+	t.Run("names the faultcode in the fault error style", func(t *testing.T) {
+		s := defaultSettings()
+		s.ErrorStyle = ErrorStyleFault
+		settings.Set(s)
+		t.Cleanup(func() { settings.Set(defaultSettings()) })
+		rec := send(createBatchRequest("client1", "nonexistent"))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response kycModels.BatchKYCQueryResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		assert.Empty(t, response.Results[0].FaultCode)
+		assert.Equal(t, faultCodeClient, response.Results[1].FaultCode)
+		assert.Equal(t, "user with ClientID 'nonexistent' not found", response.Results[1].Message, "the faultstring of a KYCQuery")
+	})
+
+	t.Run("conforms to the schema", func(t *testing.T) {
+		s := defaultSettings()
+		s.ValidationMode = ValidationStrict
+		settings.Set(s)
+		t.Cleanup(func() { settings.Set(defaultSettings()) })
+		rec := send(createBatchRequest("client1", "client2"))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+}
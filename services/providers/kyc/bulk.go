@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// BulkFormat is the encoding of a bulk import or export
+type BulkFormat string
+
+const (
+	FormatNDJSON BulkFormat = "ndjson" // One JSON record per line
+	FormatJSON   BulkFormat = "json"   // A JSON array of records
+	FormatCSV    BulkFormat = "csv"    // A header row naming the csvColumns, then one record per row
+)
+
+// contentType is the media type of data in format f
+func (f BulkFormat) contentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// parseBulkFormat accepts a format name
+func parseBulkFormat(name string) (BulkFormat, error) {
+	switch f := BulkFormat(strings.ToLower(name)); f {
+	case FormatNDJSON, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format '%s' (want %s, %s or %s)", name, FormatNDJSON, FormatJSON, FormatCSV)
+}
+
+// formatOfContentType maps a Content-Type or Accept value to a format; ok is false for other media types
+func formatOfContentType(value string) (format BulkFormat, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/json-seq":
+		return FormatNDJSON, true
+	case "application/json":
+		return FormatJSON, true
+	case "text/csv":
+		return FormatCSV, true
+	}
+	return "", false
+}
+
+// formatOfPath maps a file extension to a format; ok is false for other extensions
+func formatOfPath(path string) (format BulkFormat, ok bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, true
+	case ".json":
+		return FormatJSON, true
+	case ".csv":
+		return FormatCSV, true
+	}
+	return "", false
+}
+
+// ImportMode decides what an import does with records that already exist or are missing from it
+type ImportMode string
+
+const (
+	ImportUpsert     ImportMode = "upsert"      // Create new records, replace existing ones
+	ImportCreateOnly ImportMode = "create-only" // Create new records, report existing ones as row errors
+	ImportReplaceAll ImportMode = "replace-all" // Upsert, then delete every record not in the import; nothing is applied if any row is invalid
+)
+
+// parseImportMode accepts a mode name; empty selects ImportUpsert
+func parseImportMode(name string) (ImportMode, error) {
+	switch m := ImportMode(name); m {
+	case "":
+		return ImportUpsert, nil
+	case ImportUpsert, ImportCreateOnly, ImportReplaceAll:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown import mode '%s' (want %s, %s or %s)", name, ImportUpsert, ImportCreateOnly, ImportReplaceAll)
+}
+
+// importRow is one record read from an import, or the reason it could not be read
+type importRow struct {
+	row    int // Line for NDJSON and CSV, 1-based position for a JSON array
+	record kyc.Record
+	err    error
+}
+
+// rowError reports a record of an import that was not applied
+type rowError struct {
+	Row      int    `json:"row"`
+	ClientID string `json:"clientId,omitempty"`
+	Error    string `json:"error"`
+}
+
+// importReport is the outcome of an import
+type importReport struct {
+	Mode    ImportMode `json:"mode"`
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Deleted int        `json:"deleted"`
+	Failed  int        `json:"failed"`
+	Errors  []rowError `json:"errors,omitempty"`
+}
+
+// errImportAborted is returned by importRecords when a replace-all import had invalid rows
+var errImportAborted = errors.New("import aborted: replace-all applies nothing while any row is invalid")
+
+// readRecords decodes every record of r. A record that cannot be decoded becomes a row with an
+// error; the returned error means the input as a whole is unreadable.
+func readRecords(format BulkFormat, r io.Reader) ([]importRow, error) {
+	switch format {
+	case FormatJSON:
+		return readJSONRecords(r)
+	case FormatCSV:
+		return readCSVRecords(r)
+	default:
+		return readNDJSONRecords(r)
+	}
+}
+
+func readNDJSONRecords(r io.Reader) ([]importRow, error) {
+	var rows []importRow
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := importRow{row: line}
+		row.err = json.Unmarshal([]byte(text), &row.record)
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return rows, nil
+}
+
+func readJSONRecords(r io.Reader) ([]importRow, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("failed to read JSON: want an array of records")
+	}
+	var rows []importRow
+	for position := 1; decoder.More(); position++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("failed to read JSON record %d: %w", position, err)
+		}
+		row := importRow{row: position}
+		row.err = json.Unmarshal(raw, &row.record)
+		rows = append(rows, row)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+	return rows, nil
+}
+
+// csvColumns are the columns of a CSV export. Addresses, documents and custom fields are JSON
+// arrays within their cell. An import may leave out any column but clientId; createdAt,
+// updatedAt, reviewedAt and version are accepted but ignored, since the provider assigns them.
+var csvColumns = []string{
+	"clientId", "risk", "legalName", "dateOfBirth", "nationality", "pep", "sanctioned",
+	"reviewStatus", "reviewNote", "addresses", "documents", "customFields",
+	"createdAt", "updatedAt", "reviewedAt", "version",
+}
+
+func readCSVRecords(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for _, column := range header {
+		if !slices.Contains(csvColumns, column) {
+			return nil, fmt.Errorf("unknown CSV column '%s' (want some of %s)", column, strings.Join(csvColumns, ", "))
+		}
+	}
+	if !slices.Contains(header, "clientId") {
+		return nil, errors.New("CSV header lacks the clientId column")
+	}
+
+	var rows []importRow
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := importRow{row: line, err: err}
+		if err == nil {
+			row.record, row.err = recordFromCSV(header, cells)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// recordFromCSV decodes the cells of a CSV row named by header
+func recordFromCSV(header, cells []string) (kyc.Record, error) {
+	var record kyc.Record
+	for i, column := range header {
+		cell := cells[i]
+		var err error
+		switch column {
+		case "clientId":
+			record.ClientID = cell
+		case "risk":
+			if cell != "" {
+				record.Risk, err = strconv.ParseFloat(cell, 64)
+			}
+		case "legalName":
+			record.LegalName = cell
+		case "dateOfBirth":
+			record.DateOfBirth = kyc.Date(cell)
+		case "nationality":
+			record.Nationality = cell
+		case "pep":
+			record.PEP, err = parseCSVBool(cell)
+		case "sanctioned":
+			record.Sanctioned, err = parseCSVBool(cell)
+		case "reviewStatus":
+			record.ReviewStatus = kyc.ReviewStatus(cell)
+		case "reviewNote":
+			record.ReviewNote = cell
+		case "addresses":
+			err = unmarshalCSVCell(cell, &record.Addresses)
+		case "documents":
+			err = unmarshalCSVCell(cell, &record.Documents)
+		case "customFields":
+			err = unmarshalCSVCell(cell, &record.CustomFields)
+		}
+		if err != nil {
+			return kyc.Record{ClientID: record.ClientID}, fmt.Errorf("invalid %s '%s': %w", column, cell, err)
+		}
+	}
+	return record, nil
+}
+
+func parseCSVBool(cell string) (bool, error) {
+	if cell == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(cell)
+}
+
+func unmarshalCSVCell(cell string, v any) error {
+	if cell == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(cell), v)
+}
+
+// recordToCSV encodes record as a row of csvColumns
+func recordToCSV(record kyc.Record) ([]string, error) {
+	addresses, err1 := jsonCell(record.Addresses)
+	documents, err2 := jsonCell(record.Documents)
+	customFields, err3 := jsonCell(record.CustomFields)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, err
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return []string{
+		record.ClientID,
+		strconv.FormatFloat(record.Risk, 'f', -1, 64),
+		record.LegalName,
+		string(record.DateOfBirth),
+		record.Nationality,
+		strconv.FormatBool(record.PEP),
+		strconv.FormatBool(record.Sanctioned),
+		string(record.ReviewStatus),
+		record.ReviewNote,
+		addresses,
+		documents,
+		customFields,
+		formatTime(record.CreatedAt),
+		formatTime(record.UpdatedAt),
+		formatTime(record.ReviewedAt),
+		strconv.FormatInt(record.Version, 10),
+	}, nil
+}
+
+// jsonCell encodes items as a JSON array, or as an empty cell when there are none
+func jsonCell[T any](items []T) (string, error) {
+	if len(items) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(items)
+	return string(data), err
+}
+
+// writeRecords encodes records to w one at a time, so an export is streamed rather than built in
+// memory, and returns how many it wrote
+func writeRecords(format BulkFormat, w io.Writer, records iter.Seq[kyc.Record]) (int, error) {
+	written := 0
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvColumns); err != nil {
+			return written, err
+		}
+		for record := range records {
+			row, err := recordToCSV(record)
+			if err != nil {
+				return written, fmt.Errorf("failed to encode ClientID '%s': %w", record.ClientID, err)
+			}
+			if err := writer.Write(row); err != nil {
+				return written, err
+			}
+			written++
+		}
+		writer.Flush()
+		return written, writer.Error()
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return written, err
+		}
+		for record := range records {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return written, fmt.Errorf("failed to encode ClientID '%s': %w", record.ClientID, err)
+			}
+			separator := ",\n"
+			if written == 0 {
+				separator = "\n"
+			}
+			if _, err := io.WriteString(w, separator+string(data)); err != nil {
+				return written, err
+			}
+			written++
+		}
+		_, err := io.WriteString(w, "\n]\n")
+		return written, err
+	default:
+		encoder := json.NewEncoder(w)
+		for record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return written, fmt.Errorf("failed to encode ClientID '%s': %w", record.ClientID, err)
+			}
+			written++
+		}
+		return written, nil
+	}
+}
+
+// importRecords validates rows and applies them to repo according to mode. Rows that cannot be
+// applied are reported and skipped, except in ImportReplaceAll mode, which applies nothing and
+// returns errImportAborted if any row is invalid. The changes are worked out against the stored
+// records under writeMu and stored in a single repository write, so the import is applied whole
+// or, if that write fails, not at all.
+func importRecords(repo Repository, audit auditContext, mode ImportMode, rows []importRow) (importReport, error) {
+	report := importReport{Mode: mode, Rows: len(rows)}
+	fail := func(row importRow, err error) {
+		report.Failed++
+		report.Errors = append(report.Errors, rowError{Row: row.row, ClientID: row.record.ClientID, Error: err.Error()})
+	}
+
+	firstRow := make(map[string]int, len(rows))
+	valid := make([]importRow, 0, len(rows))
+	for _, row := range rows {
+		err := row.err
+		if err == nil {
+			err = row.record.Validate()
+		}
+		if err == nil {
+			if first, seen := firstRow[row.record.ClientID]; seen {
+				err = fmt.Errorf("duplicate of row %d", first)
+			} else {
+				firstRow[row.record.ClientID] = row.row
+			}
+		}
+		if err != nil {
+			fail(row, err)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	if mode == ImportReplaceAll && report.Failed > 0 {
+		return report, errImportAborted
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	events := make([]kyc.AuditEvent, 0, len(valid))
+	for _, row := range valid {
+		clientID := row.record.ClientID
+		previous, err := repo.Read(clientID)
+		switch {
+		case err != nil:
+			var created scoredRecord
+			if created, err = newRecord(row.record, false); err == nil {
+				events = append(events, audit.event(kyc.AuditCreate, clientID, nil, &created.Record))
+				report.Created++
+			}
+		case mode == ImportCreateOnly:
+			err = fmt.Errorf("user with ClientID '%s' already exists", clientID)
+		default:
+			var updated scoredRecord
+			if updated, err = replacementRecord(previous, row.record, false); err == nil {
+				events = append(events, audit.event(kyc.AuditUpdate, clientID, &previous, &updated.Record))
+				report.Updated++
+			}
+		}
+		if err != nil {
+			fail(row, err)
+		}
+	}
+
+	if mode == ImportReplaceAll {
+		for user := range repo.Records() {
+			if _, imported := firstRow[user.ClientID]; imported {
+				continue
+			}
+			events = append(events, audit.event(kyc.AuditDelete, user.ClientID, &user, nil))
+			report.Deleted++
+		}
+	}
+	if len(events) == 0 {
+		return report, nil
+	}
+	if err := commitChanges(repo, audit, events...); err != nil {
+		report.Created, report.Updated, report.Deleted = 0, 0, 0
+		return report, fmt.Errorf("failed to store the import: %w", err)
+	}
+	return report, nil
+}
+
+// requestFormat picks the format of an import or export from ?format=, falling back to the given
+// Content-Type or Accept header value. ok is false if neither names a format.
+func requestFormat(r *http.Request, header string) (format BulkFormat, ok bool, err error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, err := parseBulkFormat(name)
+		return format, err == nil, err
+	}
+	first, _, _ := strings.Cut(r.Header.Get(header), ",") // The preferred type of an Accept list
+	format, ok = formatOfContentType(strings.TrimSpace(first))
+	return format, ok, nil
+}
+
+// adminImport handles POST /admin/v1/import?mode=upsert|create-only|replace-all&format=ndjson|json|csv.
+// Without ?format= the Content-Type header selects the format.
+func adminImport(repo Repository, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mode, err := parseImportMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	format, ok, err := requestFormat(r, "Content-Type")
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		writeJSONResponse(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Set ?format= or a Content-Type of application/x-ndjson, application/json or text/csv"})
+		return
+	}
+
+	rows, err := readRecords(format, r.Body)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	report, err := importRecords(repo, adminAuditContext(r), mode, rows)
+	if errors.Is(err, errImportAborted) {
+		log.Printf("KYC SOAP Server: %v (%d of %d rows invalid)", err, report.Failed, report.Rows)
+		writeJSONResponse(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+	if err != nil {
+		log.Printf("KYC SOAP Server: Import of %d %s rows failed: %v", report.Rows, format, err)
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("KYC SOAP Server: Imported %d %s rows (%s): %d created, %d updated, %d deleted, %d failed",
+		report.Rows, format, mode, report.Created, report.Updated, report.Deleted, report.Failed)
+	writeJSONResponse(w, http.StatusOK, report)
+}
+
+// adminExport handles GET /admin/v1/export?format=ndjson|json|csv, streaming every record ordered
+// by ClientID. Without ?format= the Accept header selects the format, defaulting to NDJSON.
+func adminExport(repo Repository, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format, ok, err := requestFormat(r, "Accept")
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		format = FormatNDJSON
+	}
+
+	w.Header().Set("Content-Type", format.contentType())
+	w.WriteHeader(http.StatusOK)
+	written, err := writeRecords(format, w, repo.Records())
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to stream %s export after %d records: %v", format, written, err) // The status line has already been sent
+		return
+	}
+	log.Printf("KYC SOAP Server: Exported %d records as %s", written, format)
+}
+
+// initBulkRoutes registers the import and export admin routes
+func initBulkRoutes(mux *http.ServeMux, repo Repository) {
+	mux.HandleFunc("/admin/v1/import", func(w http.ResponseWriter, r *http.Request) {
+		adminImport(repo, w, r)
+	})
+	mux.HandleFunc("/admin/v1/export", func(w http.ResponseWriter, r *http.Request) {
+		adminExport(repo, w, r)
+	})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkFormats_RoundTrip(t *testing.T) {
+	repo := NewInMemoryRepo()
+	full := kyc.Record{
+		ClientID:     "client1",
+		Risk:         0.25,
+		LegalName:    "Jane \"JD\" Doe, Esq.",
+		DateOfBirth:  "1990-04-01",
+		Nationality:  "DE",
+		Addresses:    []kyc.Address{{Line1: "Hauptstr. 1", City: "Berlin", Country: "DE"}},
+		Documents:    []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "C01X00T47", ExpiryDate: "2030-01-01"}},
+		PEP:          true,
+		CustomFields: []kyc.CustomField{{Key: "creditScore", Value: "710"}},
+		ReviewStatus: kyc.ReviewInReview,
+		ReviewNote:   "line one\nline two",
+	}
+	_, err := createRecord(repo, auditContext{}, full, false)
+	require.NoError(t, err)
+	_, err = createRecord(repo, auditContext{}, kyc.Record{ClientID: "client0", Risk: 0.9}, false)
+	require.NoError(t, err)
+
+	for _, format := range []BulkFormat{FormatNDJSON, FormatJSON, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := writeRecords(format, &buf, repo.Records())
+			require.NoError(t, err)
+
+			rows, err := readRecords(format, &buf)
+			require.NoError(t, err)
+			require.Len(t, rows, 2)
+			for _, row := range rows {
+				require.NoError(t, row.err)
+			}
+			assert.Equal(t, "client0", rows[0].record.ClientID)
+			imported := rows[1].record
+			assert.Equal(t, full.LegalName, imported.LegalName)
+			assert.Equal(t, full.Addresses, imported.Addresses)
+			assert.Equal(t, full.Documents, imported.Documents)
+			assert.Equal(t, full.CustomFields, imported.CustomFields)
+			assert.Equal(t, full.ReviewNote, imported.ReviewNote)
+			assert.True(t, imported.PEP)
+		})
+	}
+}
+
+func TestImportRecords_RowErrors(t *testing.T) {
+	input := strings.Join([]string{
+		`clientId,risk,nationality,pep`,
+		`client1,0.1,DE,false`,
+		`client2,high,DE,false`,
+		`client3,0.3,Germany,false`,
+		`client1,0.4,FR,true`,
+		`client4,0.5`,
+		`client5,0.6,,yes`,
+	}, "\n")
+	rows, err := readRecords(FormatCSV, strings.NewReader(input))
+	require.NoError(t, err)
+
+	repo := NewInMemoryRepo()
+	report, err := importRecords(repo, auditContext{}, ImportUpsert, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 5, report.Failed)
+	require.Len(t, report.Errors, 5)
+	assert.Equal(t, []int{3, 4, 5, 6, 7}, []int{report.Errors[0].Row, report.Errors[1].Row, report.Errors[2].Row, report.Errors[3].Row, report.Errors[4].Row})
+	assert.Contains(t, report.Errors[0].Error, "invalid risk 'high'")
+	assert.Equal(t, "client3", report.Errors[1].ClientID)
+	assert.Equal(t, "duplicate of row 2", report.Errors[2].Error)
+	assert.Contains(t, report.Errors[3].Error, "wrong number of fields")
+	assert.Contains(t, report.Errors[4].Error, "invalid pep 'yes'")
+
+	_, err = readRecords(FormatCSV, strings.NewReader("clientId,colour\nclient1,red\n"))
+	assert.ErrorContains(t, err, "unknown CSV column 'colour'")
+	_, err = readRecords(FormatJSON, strings.NewReader(`{"clientId":"client1"}`))
+	assert.Error(t, err, "a JSON import must be an array")
+}
+
+func TestImportRecords_Modes(t *testing.T) {
+	seed := func(t *testing.T) *InMemoryRepo {
+		repo := NewInMemoryRepo()
+		for _, id := range []string{"client1", "client2"} {
+			_, err := createRecord(repo, auditContext{}, kyc.Record{ClientID: id, Risk: 0.1}, false)
+			require.NoError(t, err)
+		}
+		return repo
+	}
+	rows := func(t *testing.T, ndjson string) []importRow {
+		rows, err := readRecords(FormatNDJSON, strings.NewReader(ndjson))
+		require.NoError(t, err)
+		return rows
+	}
+	input := "{\"clientId\":\"client2\",\"risk\":0.8}\n\n{\"clientId\":\"client3\",\"risk\":0.3}\n"
+
+	t.Run("Upsert", func(t *testing.T) {
+		repo := seed(t)
+		report, err := importRecords(repo, auditContext{}, ImportUpsert, rows(t, input))
+		require.NoError(t, err)
+		assert.Equal(t, importReport{Mode: ImportUpsert, Rows: 2, Created: 1, Updated: 1}, report)
+		updated, _ := repo.Read("client2")
+		assert.Equal(t, 0.8, updated.Risk)
+		assert.Equal(t, int64(2), updated.Version)
+		assert.Len(t, repo.List(), 3)
+	})
+
+	t.Run("Create only", func(t *testing.T) {
+		repo := seed(t)
+		report, err := importRecords(repo, auditContext{}, ImportCreateOnly, rows(t, input))
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, rowError{Row: 1, ClientID: "client2", Error: "user with ClientID 'client2' already exists"}, report.Errors[0])
+		unchanged, _ := repo.Read("client2")
+		assert.Equal(t, 0.1, unchanged.Risk)
+	})
+
+	t.Run("Replace all", func(t *testing.T) {
+		repo := seed(t)
+		report, err := importRecords(repo, auditContext{}, ImportReplaceAll, rows(t, input))
+		require.NoError(t, err)
+		assert.Equal(t, importReport{Mode: ImportReplaceAll, Rows: 2, Created: 1, Updated: 1, Deleted: 1}, report)
+		_, err = repo.Read("client1")
+		assert.Error(t, err)
+		assert.Len(t, repo.History("client1"), 2, "the deletion is audited")
+	})
+
+	t.Run("Replace all aborts on invalid rows", func(t *testing.T) {
+		repo := seed(t)
+		report, err := importRecords(repo, auditContext{}, ImportReplaceAll, rows(t, input+"{\"clientId\":\"\"}\n"))
+		assert.ErrorIs(t, err, errImportAborted)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 4, report.Errors[0].Row)
+		assert.Len(t, repo.List(), 2, "nothing is applied")
+	})
+
+	t.Run("Stores the import in one write", func(t *testing.T) {
+		repo := &applyCounter{Repository: seed(t)}
+		_, err := importRecords(repo, auditContext{}, ImportReplaceAll, rows(t, input))
+		require.NoError(t, err)
+		assert.Equal(t, 1, repo.applies)
+	})
+
+	t.Run("A failed write stores nothing", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "store")
+		repo, err := OpenFileRepo(filepath.Join(dir, "kyc.json"))
+		require.NoError(t, err)
+		require.NoError(t, os.RemoveAll(dir))
+		report, err := importRecords(repo, auditContext{}, ImportUpsert, rows(t, input))
+		assert.ErrorContains(t, err, "failed to store the import")
+		assert.Zero(t, report.Created+report.Updated)
+		assert.Empty(t, repo.List())
+	})
+}
+
+// applyCounter counts the writes made through Repository.Apply
+type applyCounter struct {
+	Repository
+	applies int
+}
+
+func (r *applyCounter) Apply(events ...kyc.AuditEvent) ([]kyc.AuditEvent, error) {
+	r.applies++
+	return r.Repository.Apply(events...)
+}
+
+func TestAdminAPI_ImportExport(t *testing.T) {
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	serve := func(method, path, contentType, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("POST", "/admin/v1/import", "application/json", `[{"clientId":"client2","risk":0.2},{"clientId":"client1","risk":0.1}]`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report importReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, kyc.SourceAdmin, repo.History("client1")[0].Source)
+
+	rec = serve("POST", "/admin/v1/import?mode=create-only&format=csv", "", "clientId,risk\nclient1,0.5\n")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Failed)
+
+	rec = serve("POST", "/admin/v1/import?mode=replace-all", "application/x-ndjson", "{\"clientId\":\"client3\"}\n{\"clientId\":\"\"}\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, serve("POST", "/admin/v1/import", "text/plain", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/admin/v1/import?mode=merge", "application/json", "[]").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/admin/v1/import", "application/json", "{").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("GET", "/admin/v1/import", "", "").Code)
+
+	rec = serve("GET", "/admin/v1/export", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"clientId":"client1"`)
+
+	rec = serve("GET", "/admin/v1/export", "", "", "Accept", "text/csv, application/json;q=0.5")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "clientId,risk,"), rec.Body.String())
+
+	rec = serve("GET", "/admin/v1/export?format=json", "", "")
+	var exported []kyc.Record
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &exported))
+	assert.Len(t, exported, 2)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/admin/v1/export?format=xml", "", "").Code)
+}
+
+func TestRunCommand_ImportExport(t *testing.T) {
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	dir := t.TempDir()
+	store := filepath.Join(dir, "kyc.json")
+	input := filepath.Join(dir, "users.csv")
+	require.NoError(t, os.WriteFile(input, []byte("clientId,risk\nclient1,0.1\nclient2,0.2\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"import", "-store", store, "-actor", "ops", input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	var report importReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, 2, report.Created)
+
+	reopened, err := OpenFileRepo(store)
+	require.NoError(t, err)
+	assert.Len(t, reopened.List(), 2)
+	assert.Equal(t, "ops", reopened.History("client1")[0].Actor)
+
+	stdout.Reset()
+	code = runCommand([]string{"import", "-store", store, "-format", "ndjson", "-mode", "create-only"}, strings.NewReader(`{"clientId":"client1"}`), &stdout, &stderr)
+	assert.Equal(t, 1, code, "a rejected row fails the command")
+
+	stdout.Reset()
+	code = runCommand([]string{"export", "-store", store, "-format", "ndjson"}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+
+	assert.Equal(t, 1, runCommand([]string{"export", "-store", filepath.Join(dir, "missing.json")}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, runCommand([]string{"import", "-mode", "merge"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, runCommand([]string{"serve"}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+)
+
+// WS-Addressing 1.0 namespace and its special ReplyTo addresses
+const (
+	wsaNamespace = "http://www.w3.org/2005/08/addressing"
+	wsaAnonymous = wsaNamespace + "/anonymous" // Reply in the HTTP response, as without a ReplyTo
+	wsaNone      = wsaNamespace + "/none"      // Do not reply at all
+)
+
+// callbackStatusHeader carries the HTTP status the response would have had if it had been
+// returned synchronously, so the caller can treat both the same way
+const callbackStatusHeader = "X-KYC-Response-Status"
+
+// callbackClient delivers the responses of asynchronous requests. It does not follow redirects,
+// which could lead it to a host that is not allowed.
+var callbackClient = &http.Client{
+	Timeout:       10 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// callbackHosts are the hosts responses may be sent to, each "host" for any port or "host:port".
+// With none, as when KYC_CALLBACK_ALLOWED_HOSTS is unset, asynchronous requests are refused, so the
+// provider cannot be made to POST to arbitrary addresses.
+var callbackHosts []string
+
+// parseCallbackHosts splits a comma-separated KYC_CALLBACK_ALLOWED_HOSTS
+func parseCallbackHosts(s string) []string {
+	var hosts []string
+	for _, host := range strings.Split(s, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// callbackHostAllowed reports whether responses may be sent to u
+func callbackHostAllowed(u *url.URL) bool {
+	for _, allowed := range callbackHosts {
+		if strings.EqualFold(allowed, u.Host) || strings.EqualFold(allowed, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// A callback whose ReplyTo address fails is sent callbackAttempts times, callbackRetryDelay apart
+var (
+	callbackAttempts   = 3
+	callbackRetryDelay = time.Second
+)
+
+type callbackDispatchKey struct{}
+
+// isCallbackDispatch reports whether r is the deferred run of an asynchronous request
+func isCallbackDispatch(r *http.Request) bool {
+	dispatched, _ := r.Context().Value(callbackDispatchKey{}).(bool)
+	return dispatched
+}
+
+// asyncReplyTo returns the address a request wants its response sent to instead of returned, or ""
+func asyncReplyTo(header *kycModels.SOAPHeader) string {
+	if header == nil || header.ReplyTo == nil {
+		return ""
+	}
+	address := strings.TrimSpace(header.ReplyTo.Address)
+	if address == wsaAnonymous {
+		return ""
+	}
+	return address
+}
+
+// acceptAsync acknowledges a request with a ReplyTo address with 202 Accepted, then runs it through
+// soapHandler once the configured callback delay has passed and POSTs the response to replyTo.
+// Fault rules have already been applied to the acknowledgement and are not applied again.
+func acceptAsync(repo Repository, w http.ResponseWriter, r *http.Request, body []byte, messageID, replyTo, operation string) {
+	messageID = strings.TrimSpace(messageID)
+	if messageID == "" {
+		writeSOAPFault(w, kycModels.Fault{FaultCode: faultCodeClient, FaultString: "wsa:MessageID is required with a wsa:ReplyTo address"})
+		return
+	}
+	if replyTo != wsaNone {
+		u, err := url.Parse(replyTo)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeSOAPFault(w, kycModels.Fault{FaultCode: faultCodeClient, FaultString: fmt.Sprintf("wsa:ReplyTo address '%s' is not an http or https URL", replyTo)})
+			return
+		}
+		if !callbackHostAllowed(u) {
+			log.Printf("KYC SOAP Server: Refused %s %s: ReplyTo host %s is not in KYC_CALLBACK_ALLOWED_HOSTS", operation, messageID, u.Host)
+			writeSOAPFault(w, kycModels.Fault{FaultCode: faultCodeClient, FaultString: fmt.Sprintf("wsa:ReplyTo host '%s' is not allowed", u.Host)})
+			return
+		}
+	}
+
+	dispatch := r.Clone(context.WithValue(context.WithoutCancel(r.Context()), callbackDispatchKey{}, true))
+	dispatch.Body = io.NopCloser(bytes.NewReader(body))
+	delay := time.Duration(settings.Get().CallbackDelay)
+	go func() {
+		time.Sleep(delay)
+		rec := &callbackRecorder{header: make(http.Header)}
+		soapHandler(repo, rec, dispatch)
+		if replyTo == wsaNone {
+			log.Printf("KYC SOAP Server: Discarded the response to %s %s (status %d) as its ReplyTo is none", operation, messageID, rec.statusCode())
+			return
+		}
+		deliverCallback(replyTo, messageID, operation, dispatch.Header.Get(headerCorrelationID), rec)
+	}()
+
+	responseBytes, err := xml.MarshalIndent(kycModels.AcknowledgementEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Header:       kycModels.AddressingHeader{XmlnsWsa: wsaNamespace, MessageID: newMessageID(), RelatesTo: messageID},
+	}, "", "  ")
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to marshal acknowledgement: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to marshal acknowledgement: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write(responseBytes); err != nil {
+		log.Printf("KYC SOAP Server: Failed to write acknowledgement: %v", err)
+	}
+	log.Printf("KYC SOAP Server: Accepted %s %s, replying to %s in %s", operation, messageID, replyTo, delay)
+}
+
+// callbackRecorder captures the response soapHandler writes for an asynchronous request
+type callbackRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *callbackRecorder) Header() http.Header { return c.header }
+
+func (c *callbackRecorder) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *callbackRecorder) Write(p []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	return c.body.Write(p)
+}
+
+func (c *callbackRecorder) statusCode() int {
+	if c.status == 0 {
+		return http.StatusOK
+	}
+	return c.status
+}
+
+// callbackEnvelope returns the recorded response as a SOAP envelope addressed to replyTo and
+// related to the request's messageID. A plain-text error becomes a Server fault.
+func callbackEnvelope(rec *callbackRecorder, messageID, replyTo, operation string) ([]byte, error) {
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if !bytes.HasPrefix(body, []byte("<soapenv:Envelope")) {
+		var err error
+		body, err = xml.MarshalIndent(kycModels.FaultEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body:         kycModels.FaultBody{Fault: kycModels.Fault{FaultCode: faultCodeServer, FaultString: string(body)}},
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal callback fault: %w", err)
+		}
+	}
+
+	header, err := xml.MarshalIndent(kycModels.AddressingHeader{
+		XmlnsWsa:  wsaNamespace,
+		MessageID: newMessageID(),
+		RelatesTo: messageID,
+		Action:    kycNamespaceAttr + "/" + operation + "Response",
+		To:        replyTo,
+	}, "  ", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal callback header: %w", err)
+	}
+	end := bytes.IndexByte(body, '>') + 1 // The end of the Envelope start tag
+	envelope := make([]byte, 0, len(body)+len(header)+3)
+	envelope = append(envelope, body[:end]...)
+	envelope = append(envelope, "\n  "...)
+	envelope = append(envelope, header...)
+	return append(envelope, body[end:]...), nil
+}
+
+// deliverCallback POSTs the recorded response of an asynchronous request to replyTo, retrying a
+// failed delivery up to callbackAttempts times in all
+func deliverCallback(replyTo, messageID, operation, correlationID string, rec *callbackRecorder) {
+	envelope, err := callbackEnvelope(rec, messageID, replyTo, operation)
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to build the callback for %s %s: %v", operation, messageID, err)
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		err := postCallback(replyTo, operation, correlationID, rec.statusCode(), envelope)
+		if err == nil {
+			log.Printf("KYC SOAP Server: Delivered the response to %s %s (status %d) to %s", operation, messageID, rec.statusCode(), replyTo)
+			return
+		}
+		if attempt >= callbackAttempts {
+			log.Printf("KYC SOAP Server: Gave up delivering the response to %s %s to %s after %d attempts: %v", operation, messageID, replyTo, attempt, err)
+			return
+		}
+		log.Printf("KYC SOAP Server: Failed to deliver the response to %s %s to %s, retrying in %s: %v", operation, messageID, replyTo, callbackRetryDelay, err)
+		time.Sleep(callbackRetryDelay)
+	}
+}
+
+func postCallback(replyTo, operation, correlationID string, status int, envelope []byte) error {
+	req, err := http.NewRequest(http.MethodPost, replyTo, bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", kycNamespaceAttr+"/"+operation+"Response")
+	req.Header.Set(callbackStatusHeader, strconv.Itoa(status))
+	if correlationID != "" {
+		req.Header.Set(headerCorrelationID, correlationID)
+	}
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("ReplyTo answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newMessageID returns a random (version 4) urn:uuid message ID
+func newMessageID() string {
+	b := make([]byte, 16)
+	rand.Read(b) // Never fails, see crypto/rand.Read
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
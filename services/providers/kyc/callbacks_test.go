@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedCallback is a callback as the test's ReplyTo endpoint received it
+type receivedCallback struct {
+	header http.Header
+	body   string
+}
+
+// newReplyToServer returns a ReplyTo endpoint that hands every callback to the returned channel,
+// answering with the given statuses in turn and then with 202
+func newReplyToServer(t *testing.T, statuses ...int) (*httptest.Server, <-chan receivedCallback) {
+	t.Helper()
+	callbacks := make(chan receivedCallback, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- receivedCallback{header: r.Header.Clone(), body: string(body)}
+		status := http.StatusAccepted
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	allowCallbacks(t, server.Listener.Addr().String())
+	return server, callbacks
+}
+
+// allowCallbacks adds host to the hosts responses may be sent to for the rest of the test
+func allowCallbacks(t *testing.T, host string) {
+	t.Helper()
+	previous := callbackHosts
+	callbackHosts = append(slices.Clone(callbackHosts), host)
+	t.Cleanup(func() { callbackHosts = previous })
+}
+
+// withAddressing adds WS-Addressing headers to a request made by createSOAPRequest
+func withAddressing(request, messageID, replyTo string) string {
+	header := fmt.Sprintf(`<soapenv:Header xmlns:wsa="%s"><wsa:MessageID>%s</wsa:MessageID><wsa:ReplyTo><wsa:Address>%s</wsa:Address></wsa:ReplyTo></soapenv:Header>`, wsaNamespace, messageID, replyTo)
+	return strings.Replace(request, soapBodyStart, header+soapBodyStart, 1)
+}
+
+func awaitCallback(t *testing.T, callbacks <-chan receivedCallback) receivedCallback {
+	t.Helper()
+	select {
+	case callback := <-callbacks:
+		return callback
+	case <-time.After(2 * time.Second):
+		t.Fatal("no callback was delivered")
+		return receivedCallback{}
+	}
+}
+
+func TestAsyncRequests(t *testing.T) {
+	server, _ := newFaultServer(t)
+	replyTo, callbacks := newReplyToServer(t)
+	post := func(body string) (*http.Response, string) {
+		req, err := http.NewRequest("POST", server.URL+"/soap", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Correlation-ID", "corr-1")
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(respBody)
+	}
+
+	t.Run("acknowledges and calls back with the response", func(t *testing.T) {
+		resp, ack := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-1", replyTo.URL))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Contains(t, ack, "<wsa:RelatesTo>urn:uuid:msg-1</wsa:RelatesTo>")
+
+		callback := awaitCallback(t, callbacks)
+		assert.Equal(t, "200", callback.header.Get(callbackStatusHeader))
+		assert.Equal(t, "corr-1", callback.header.Get("X-Correlation-ID"))
+		assert.Equal(t, "http://example.com/kyc/KYCQueryResponse", callback.header.Get("SOAPAction"))
+		assert.Contains(t, callback.body, "<wsa:RelatesTo>urn:uuid:msg-1</wsa:RelatesTo>")
+		assert.Contains(t, callback.body, "<wsa:To>"+replyTo.URL+"</wsa:To>")
+		var result kycModels.KYCResult
+		require.NoError(t, unmarshalSOAPResponse([]byte(callback.body), &result), callback.body)
+		assert.Equal(t, "Success", result.Status)
+		require.NotNil(t, result.UserData)
+		assert.Equal(t, "client1", result.UserData.ClientID)
+	})
+
+	t.Run("calls back with errors and their status", func(t *testing.T) {
+		resp, _ := post(withAddressing(createSOAPRequest("KYCQuery", "nonexistent", nil), "urn:uuid:msg-2", replyTo.URL))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		callback := awaitCallback(t, callbacks)
+		assert.Equal(t, "404", callback.header.Get(callbackStatusHeader))
+		assert.Contains(t, callback.body, "<Status>Error</Status>")
+	})
+
+	t.Run("answers synchronously without a ReplyTo address", func(t *testing.T) {
+		resp, body := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-3", wsaAnonymous))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "<ClientID>client1</ClientID>")
+	})
+
+	t.Run("rejects a ReplyTo without a MessageID", func(t *testing.T) {
+		resp, body := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "", replyTo.URL))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Contains(t, body, "wsa:MessageID is required")
+		resp, body = post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-4", "mailto:someone"))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Contains(t, body, "is not an http or https URL")
+	})
+
+	t.Run("refuses a ReplyTo host that is not allowed", func(t *testing.T) {
+		resp, body := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-8", "http://169.254.169.254/latest/meta-data"))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Contains(t, body, "wsa:ReplyTo host &#39;169.254.169.254&#39; is not allowed")
+
+		allowCallbacks(t, "LOCALHOST")
+		u, err := url.Parse("http://localhost:9999/callback")
+		require.NoError(t, err)
+		assert.True(t, callbackHostAllowed(u), "a host without a port allows every port")
+		callbackHosts = []string{"localhost:8080"}
+		assert.False(t, callbackHostAllowed(u))
+		assert.Equal(t, []string{"a", "b:1"}, parseCallbackHosts(" a, ,b:1"))
+	})
+
+	t.Run("faults the acknowledgement only", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Operation: "KYCQuery", Fault: faults.TypeInternalError, MaxHits: 1})
+		resp, _ := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-5", replyTo.URL))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		faultRules.Clear()
+		resp, _ = post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-6", replyTo.URL))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		callback := awaitCallback(t, callbacks)
+		assert.Contains(t, callback.body, "urn:uuid:msg-6")
+		select {
+		case extra := <-callbacks:
+			t.Fatalf("unexpected callback: %s", extra.body)
+		default:
+		}
+	})
+
+	t.Run("waits for the callback delay", func(t *testing.T) {
+		s := defaultSettings()
+		s.CallbackDelay = faults.Duration(50 * time.Millisecond)
+		settings.Set(s)
+		t.Cleanup(func() { settings.Set(defaultSettings()) })
+		start := time.Now()
+		resp, _ := post(withAddressing(createSOAPRequest("KYCQuery", "client1", nil), "urn:uuid:msg-7", replyTo.URL))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Less(t, time.Since(start), 50*time.Millisecond, "the acknowledgement does not wait")
+		awaitCallback(t, callbacks)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestDeliverCallback_Retries(t *testing.T) {
+	attempts, retryDelay := callbackAttempts, callbackRetryDelay
+	callbackAttempts, callbackRetryDelay = 2, time.Millisecond
+	t.Cleanup(func() { callbackAttempts, callbackRetryDelay = attempts, retryDelay })
+	replyTo, callbacks := newReplyToServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	rec := &callbackRecorder{header: make(http.Header)}
+	http.Error(rec, "Simulated internal server error", http.StatusInternalServerError)
+	deliverCallback(replyTo.URL, "urn:uuid:msg-1", "KYCQuery", "", rec)
+
+	first, second := awaitCallback(t, callbacks), awaitCallback(t, callbacks)
+	assert.Equal(t, first.body, second.body)
+	assert.Equal(t, "500", first.header.Get(callbackStatusHeader))
+	assert.Contains(t, first.body, "<faultcode>soapenv:Server</faultcode>", "a plain-text error is sent as a fault")
+	assert.Contains(t, first.body, "<faultstring>Simulated internal server error</faultstring>")
+	assert.Empty(t, callbacks, "delivery stops after callbackAttempts")
+}
+
+func TestCallbackEnvelope(t *testing.T) {
+	rec := &callbackRecorder{header: make(http.Header)}
+	response, err := xml.MarshalIndent(kycModels.DeleteKYCResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body:         kycModels.DeleteKYCResponseBody{DeleteKYCResult: kycModels.DeleteKYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User deleted"}},
+	}, "", "  ")
+	require.NoError(t, err)
+	rec.Write(response)
+
+	envelope, err := callbackEnvelope(rec, "urn:uuid:msg-1", "http://consumer/callback", "DeleteKYC")
+	require.NoError(t, err)
+	var parsed kycModels.SOAPEnvelope
+	require.NoError(t, xml.Unmarshal(envelope, &parsed), string(envelope))
+	require.NotNil(t, parsed.Header)
+	assert.Equal(t, "http://example.com/kyc/DeleteKYCResponse", parsed.Header.Action)
+	assert.Equal(t, "http://consumer/callback", parsed.Header.To)
+	assert.True(t, strings.HasPrefix(parsed.Header.MessageID, "urn:uuid:"))
+	var result kycModels.DeleteKYCResult
+	require.NoError(t, unmarshalSOAPResponse(envelope, &result))
+	assert.Equal(t, "User deleted", result.Message)
+	assert.Contains(t, string(envelope), "<wsa:RelatesTo>urn:uuid:msg-1</wsa:RelatesTo>")
+}
+
+func TestAsyncRequests_Tenant(t *testing.T) {
+	server, _ := newTenantServer(t)
+	replyTo, callbacks := newReplyToServer(t)
+	code, _ := createTenant(t, server, `{"name":"suite-a","empty":true}`)
+	require.Equal(t, http.StatusCreated, code)
+	tenantA, _ := tenants.Get("suite-a")
+	require.NoError(t, tenantA.repo.Create(kyc.Record{ClientID: "client2", Risk: 0.2}))
+
+	resp, err := server.Client().Post(server.URL+"/t/suite-a/soap", "text/xml", strings.NewReader(withAddressing(createSOAPRequest("KYCQuery", "client2", nil), "urn:uuid:msg-1", replyTo.URL)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	callback := awaitCallback(t, callbacks)
+	assert.Equal(t, "200", callback.header.Get(callbackStatusHeader), "the deferred run uses the tenant's users")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/cdc"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// defaultChangeTopic is the topic change events go to when KYC_CDC_TOPIC is unset
+const defaultChangeTopic = "KYCChanges"
+
+// changeRelay publishes record changes to Kafka. It is nil while KYC_CDC_BROKERS is unset, in
+// which case changes are only kept in the audit log.
+var changeRelay atomic.Pointer[cdc.Relay]
+
+// openChangeRelay returns the relay configured by KYC_CDC_BROKERS (comma-separated) and
+// KYC_CDC_TOPIC, or nil when KYC_CDC_BROKERS is unset. Each repository keeps its unpublished events
+// in its own outbox, so they only survive a restart with the file store; with any other store it
+// refuses to start unless KYC_CDC_VOLATILE=true accepts losing them.
+func openChangeRelay(store string) (*cdc.Relay, error) {
+	brokers := os.Getenv("KYC_CDC_BROKERS")
+	if brokers == "" {
+		return nil, nil
+	}
+	if store != StoreFile && os.Getenv("KYC_CDC_VOLATILE") != "true" {
+		return nil, fmt.Errorf("the change event outbox is only durable with KYC_STORE=%s (set KYC_CDC_VOLATILE=true to keep it in memory)", StoreFile)
+	}
+	topic := os.Getenv("KYC_CDC_TOPIC")
+	if topic == "" {
+		topic = defaultChangeTopic
+	}
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(strings.Split(brokers, ",")...),
+		Balancer:               &kafka.Hash{}, // Keeps each ClientID on one partition
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		MaxAttempts:            1, // The relay retries with backoff
+		WriteTimeout:           10 * time.Second,
+	}
+	log.Printf("KYC SOAP Server: Publishing change events to topic %s on %s", topic, brokers)
+	return cdc.NewRelay(writer, topic), nil
+}
+
+// repoOutbox is the outbox a Repository keeps once it captures changes
+type repoOutbox struct {
+	repo Repository
+}
+
+func (o repoOutbox) Pending(n int) []cdc.Event { return o.repo.PendingChanges(n) }
+func (o repoOutbox) Remove(n int) error        { return o.repo.RemoveChanges(n) }
+
+// captureChanges makes repo write a change event for tenant with every change it stores, and
+// attaches its outbox to the relay. It returns the function that detaches it again, which does
+// nothing when change events are not configured.
+func captureChanges(tenant string, repo Repository) (detach func()) {
+	relay := changeRelay.Load()
+	if relay == nil {
+		return func() {}
+	}
+	repo.CaptureChanges(tenant, relay.Notify)
+	if pending := len(repo.PendingChanges(0)); pending > 0 {
+		log.Printf("KYC SOAP Server: %d change event(s) pending in the outbox of tenant '%s'", pending, tenant)
+	}
+	return relay.Attach(repoOutbox{repo})
+}
+
+// cdcStatus is the JSON body returned by the change event admin endpoints
+type cdcStatus struct {
+	Enabled bool `json:"enabled"`
+	*cdc.Stats
+	Outbox []cdc.Event `json:"outbox,omitempty"` // The unpublished events, oldest first
+}
+
+func currentCDCStatus(relay *cdc.Relay, pending []cdc.Event) cdcStatus {
+	if relay == nil {
+		return cdcStatus{}
+	}
+	stats := relay.Stats()
+	return cdcStatus{Enabled: true, Stats: &stats, Outbox: pending}
+}
+
+// adminGetCDC handles GET /admin/v1/cdc. With ?outbox=true the unpublished events are included.
+func adminGetCDC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	relay := changeRelay.Load()
+	var pending []cdc.Event
+	if relay != nil && r.URL.Query().Get("outbox") == "true" {
+		pending = relay.Pending()
+	}
+	writeJSONResponse(w, http.StatusOK, currentCDCStatus(relay, pending))
+}
+
+// adminFlushCDC handles POST /admin/v1/cdc/flush, publishing the outbox now instead of waiting
+// for the relay's backoff to run out
+func adminFlushCDC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	relay := changeRelay.Load()
+	if relay == nil {
+		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": "Change events are not configured (set KYC_CDC_BROKERS)"})
+		return
+	}
+	if err := relay.Flush(r.Context()); err != nil {
+		writeJSONResponse(w, http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to publish change events: %v", err)})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, currentCDCStatus(relay, nil))
+}
+
+// initCDCRoutes registers the change event admin routes
+func initCDCRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/cdc", adminGetCDC)
+	mux.HandleFunc("/admin/v1/cdc/flush", adminFlushCDC)
+}
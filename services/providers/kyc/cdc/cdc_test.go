@@ -0,0 +1,207 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWriter records the messages written to it and fails while err is set
+type fakeWriter struct {
+	mu       sync.Mutex
+	err      error
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+func (w *fakeWriter) written() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+// memOutbox is an Outbox kept in memory, whose Remove fails while removeErr is set
+type memOutbox struct {
+	mu        sync.Mutex
+	pending   []Event
+	removeErr error
+}
+
+func (o *memOutbox) add(events ...Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending = append(o.pending, events...)
+}
+
+func (o *memOutbox) Pending(n int) []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if n <= 0 {
+		n = len(o.pending)
+	}
+	return slices.Clone(o.pending[:min(n, len(o.pending))])
+}
+
+func (o *memOutbox) Remove(n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.removeErr != nil {
+		return o.removeErr
+	}
+	o.pending = slices.Delete(o.pending, 0, min(n, len(o.pending)))
+	return nil
+}
+
+func TestFromAudit(t *testing.T) {
+	before := kyc.Record{ClientID: "client1", Risk: 0.1}
+	after := kyc.Record{ClientID: "client1", Risk: 0.8}
+	at := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	event := FromAudit(kyc.AuditEvent{Sequence: 7, ClientID: "client1", Action: kyc.AuditUpdate, Actor: "tester", Source: kyc.SourceSOAP, Operation: "UpdateKYC", CorrelationID: "corr-1", Timestamp: at, Before: &before, After: &after}, "suite-a")
+
+	assert.Len(t, event.ID, 32)
+	assert.Equal(t, Event{ID: event.ID, Type: TypeUpdated, ClientID: "client1", Tenant: "suite-a", Sequence: 7, Actor: "tester", Source: kyc.SourceSOAP, Operation: "UpdateKYC", CorrelationID: "corr-1", Timestamp: at, Before: &before, After: &after}, event)
+	assert.NotEqual(t, event.ID, FromAudit(kyc.AuditEvent{}, "").ID)
+
+	assert.Equal(t, TypeCreated, FromAudit(kyc.AuditEvent{Action: kyc.AuditCreate}, "").Type)
+	assert.Equal(t, TypeDeleted, FromAudit(kyc.AuditEvent{Action: kyc.AuditDelete}, "").Type)
+	assert.Equal(t, TypeUpdated, FromAudit(kyc.AuditEvent{Action: kyc.AuditTransition}, "").Type)
+}
+
+func TestReset(t *testing.T) {
+	at := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	event := Reset("suite-a", 12, at)
+	assert.Len(t, event.ID, 32)
+	assert.Equal(t, Event{ID: event.ID, Type: TypeReset, Tenant: "suite-a", Sequence: 12, Timestamp: at}, event)
+}
+
+func TestRelay_Flush(t *testing.T) {
+	writer := &fakeWriter{}
+	relay := NewRelay(writer, "KYCChanges")
+	relay.BatchSize = 2
+	outbox, tenantOutbox := &memOutbox{}, &memOutbox{}
+	relay.Attach(outbox)
+	relay.Attach(tenantOutbox)
+	after := kyc.Record{ClientID: "client1", Risk: 0.1}
+	outbox.add(Event{ID: "1", Type: TypeCreated, ClientID: "client1", After: &after})
+	tenantOutbox.add(Event{ID: "2", Type: TypeUpdated, ClientID: "client1", Tenant: "suite-a", CorrelationID: "corr-1"})
+	outbox.add(Event{ID: "3", Type: TypeDeleted, ClientID: "client2"})
+
+	writer.fail(errors.New("broker unavailable"))
+	require.ErrorContains(t, relay.Flush(t.Context()), "broker unavailable")
+	stats := relay.Stats()
+	assert.Equal(t, 3, stats.Pending, "nothing leaves the outbox before Kafka accepts it")
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Equal(t, "broker unavailable", stats.LastError)
+
+	writer.fail(nil)
+	require.NoError(t, relay.Flush(t.Context()))
+	messages := writer.written()
+	require.Len(t, messages, 3)
+	var ids []string
+	for _, m := range messages {
+		var event Event
+		require.NoError(t, json.Unmarshal(m.Value, &event))
+		ids = append(ids, event.ID)
+		assert.Equal(t, "KYCChanges", m.Topic)
+		assert.Equal(t, event.ClientID, string(m.Key))
+	}
+	assert.Equal(t, []string{"1", "3", "2"}, ids, "oldest first within each outbox")
+	assert.Equal(t, []kafka.Header{{Key: "eventType", Value: []byte("KYCUpdated")}, {Key: "tenant", Value: []byte("suite-a")}, {Key: "correlationId", Value: []byte("corr-1")}}, messages[2].Headers)
+
+	stats = relay.Stats()
+	assert.Equal(t, 0, stats.Pending)
+	assert.Equal(t, int64(3), stats.Published)
+	assert.Empty(t, stats.LastError)
+}
+
+func TestRelay_FlushRemoveFails(t *testing.T) {
+	writer := &fakeWriter{}
+	relay := NewRelay(writer, "KYCChanges")
+	outbox := &memOutbox{removeErr: errors.New("no space left on device")}
+	relay.Attach(outbox)
+	outbox.add(Event{ID: "1", ClientID: "client1"})
+
+	require.ErrorContains(t, relay.Flush(t.Context()), "no space left on device")
+	assert.Len(t, writer.written(), 1, "the batch is written once, not in a loop")
+	stats := relay.Stats()
+	assert.Equal(t, 1, stats.Pending)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Equal(t, "no space left on device", stats.LastError)
+
+	outbox.mu.Lock()
+	outbox.removeErr = nil
+	outbox.mu.Unlock()
+	require.NoError(t, relay.Flush(t.Context()))
+	assert.Len(t, writer.written(), 2, "the next flush writes the batch again")
+	assert.Zero(t, relay.Stats().Pending)
+}
+
+func TestRelay_Detach(t *testing.T) {
+	writer := &fakeWriter{}
+	relay := NewRelay(writer, "KYCChanges")
+	kept, dropped := &memOutbox{}, &memOutbox{}
+	relay.Attach(kept)
+	detach := relay.Attach(dropped)
+	kept.add(Event{ID: "1", ClientID: "client1"})
+	dropped.add(Event{ID: "2", ClientID: "client1"})
+	assert.Len(t, relay.Pending(), 2)
+
+	detach()
+	assert.Len(t, relay.Pending(), 1)
+	require.NoError(t, relay.Flush(t.Context()))
+	assert.Len(t, writer.written(), 1)
+}
+
+func TestRelay_RunRetries(t *testing.T) {
+	writer := &fakeWriter{err: errors.New("broker unavailable")}
+	relay := NewRelay(writer, "KYCChanges")
+	outbox := &memOutbox{}
+	relay.Attach(outbox)
+	relay.MinBackoff, relay.MaxBackoff = time.Millisecond, 5*time.Millisecond
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	outbox.add(Event{ID: "1", ClientID: "client1"})
+	relay.Notify()
+	require.Eventually(t, func() bool { return relay.Stats().Failures >= 2 }, time.Second, time.Millisecond)
+	writer.fail(nil)
+	require.Eventually(t, func() bool { return len(writer.written()) == 1 }, time.Second, time.Millisecond)
+
+	outbox.add(Event{ID: "2", ClientID: "client1"})
+	relay.Notify()
+	require.Eventually(t, func() bool { return len(writer.written()) == 2 }, time.Second, time.Millisecond, "Notify wakes the relay")
+
+	cancel()
+	<-done
+}
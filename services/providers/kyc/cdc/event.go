@@ -0,0 +1,79 @@
+// Package cdc publishes the KYC provider's record changes to Kafka. Changes are first written to
+// an outbox, stored together with the change itself, and a relay publishes them from there in
+// order, keeping every change that Kafka has not acknowledged so that a broker outage delays
+// events rather than losing them.
+package cdc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// Type is the kind of change an Event announces
+type Type string
+
+const (
+	TypeCreated Type = "KYCCreated"
+	TypeUpdated Type = "KYCUpdated" // Also sent for review status transitions
+	TypeDeleted Type = "KYCDeleted"
+	TypeReset   Type = "KYCReset" // A tenant's records were replaced wholesale; consumers should read them again
+)
+
+// Event is the message published for one change of a KYC record. Before is nil for a create and
+// After is nil for a delete. A TypeReset event has no ClientID, records or actor.
+type Event struct {
+	ID            string          `json:"id"` // Unique per change, for consumers that must drop redeliveries
+	Type          Type            `json:"type"`
+	ClientID      string          `json:"clientId"`
+	Tenant        string          `json:"tenant,omitempty"`   // The provider tenant the record belongs to; empty for the default one
+	Sequence      int64           `json:"sequence,omitempty"` // The change's audit event, when it was recorded
+	Actor         string          `json:"actor"`
+	Source        kyc.AuditSource `json:"source"`
+	Operation     string          `json:"operation,omitempty"`
+	CorrelationID string          `json:"correlationId,omitempty"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Before        *kyc.Record     `json:"before,omitempty"`
+	After         *kyc.Record     `json:"after,omitempty"`
+}
+
+// FromAudit returns the event announcing the change that audit recorded in tenant
+func FromAudit(audit kyc.AuditEvent, tenant string) Event {
+	eventType := TypeUpdated
+	switch audit.Action {
+	case kyc.AuditCreate:
+		eventType = TypeCreated
+	case kyc.AuditDelete:
+		eventType = TypeDeleted
+	}
+	return Event{
+		ID:            newEventID(),
+		Type:          eventType,
+		ClientID:      audit.ClientID,
+		Tenant:        tenant,
+		Sequence:      audit.Sequence,
+		Actor:         audit.Actor,
+		Source:        audit.Source,
+		Operation:     audit.Operation,
+		CorrelationID: audit.CorrelationID,
+		Timestamp:     audit.Timestamp,
+		Before:        audit.Before,
+		After:         audit.After,
+	}
+}
+
+// Reset returns the event announcing that the records of tenant were replaced at the given time,
+// by a snapshot restore, a reset or a new tenant's seed. sequence is the last audit event of the
+// new state.
+func Reset(tenant string, sequence int64, at time.Time) Event {
+	return Event{ID: newEventID(), Type: TypeReset, Tenant: tenant, Sequence: sequence, Timestamp: at}
+}
+
+// newEventID returns 16 random bytes in hex
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b) // Never fails, see crypto/rand.Read
+	return hex.EncodeToString(b)
+}
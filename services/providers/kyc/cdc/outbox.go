@@ -0,0 +1,10 @@
+package cdc
+
+// Outbox holds the events that have not been published yet, oldest first. The provider keeps one
+// per repository, written in the same atomic write as the changes the events announce.
+type Outbox interface {
+	// Pending returns up to n of the oldest events, or all of them when n <= 0
+	Pending(n int) []Event
+	// Remove drops the n oldest events once they have been published
+	Remove(n int) error
+}
@@ -0,0 +1,223 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer is the part of kafka.Writer the relay uses, so tests can stand in for Kafka
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Relay publishes the events of its outboxes to a Kafka topic, keyed by ClientID so that the
+// changes of one record stay in order on one partition. Delivery is at least once: a batch that
+// fails is sent again whole, so consumers should drop events whose ID they have seen.
+type Relay struct {
+	writer Writer
+	topic  string
+	ready  chan struct{} // Signalled by Notify
+
+	BatchSize  int           // Events per Kafka write, 100 by default
+	MinBackoff time.Duration // Wait after the first failed write, doubled per failure up to MaxBackoff
+	MaxBackoff time.Duration
+
+	flushMu  sync.Mutex // Held while publishing, so a batch is never sent and removed twice
+	mu       sync.Mutex
+	stats    Stats
+	outboxes []attachedOutbox // In the order they were attached
+	nextID   int
+}
+
+// attachedOutbox is an outbox the relay publishes from
+type attachedOutbox struct {
+	id     int
+	outbox Outbox
+}
+
+// Stats describes a relay's progress
+type Stats struct {
+	Topic         string    `json:"topic"`
+	Pending       int       `json:"pending"`
+	Published     int64     `json:"published"`
+	Failures      int64     `json:"failures"`
+	LastError     string    `json:"lastError,omitempty"` // Cleared by the next successful write
+	LastPublished time.Time `json:"lastPublished,omitzero"`
+}
+
+// NewRelay returns a relay to topic through writer, with no outboxes attached yet
+func NewRelay(writer Writer, topic string) *Relay {
+	return &Relay{
+		writer:     writer,
+		topic:      topic,
+		ready:      make(chan struct{}, 1),
+		BatchSize:  100,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		stats:      Stats{Topic: topic},
+	}
+}
+
+// Attach makes the relay publish the events of outbox, and returns the function that stops it
+// again. Events still pending then are not published.
+func (r *Relay) Attach(outbox Outbox) (detach func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.nextID
+	r.nextID++
+	r.outboxes = append(r.outboxes, attachedOutbox{id: id, outbox: outbox})
+	r.Notify() // It may hold events from before a restart
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.outboxes = slices.DeleteFunc(r.outboxes, func(a attachedOutbox) bool { return a.id == id })
+	}
+}
+
+// attached returns the outboxes attached now
+func (r *Relay) attached() []Outbox {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	outboxes := make([]Outbox, len(r.outboxes))
+	for i, a := range r.outboxes {
+		outboxes[i] = a.outbox
+	}
+	return outboxes
+}
+
+// Notify wakes Run after events were added to an outbox
+func (r *Relay) Notify() {
+	select {
+	case r.ready <- struct{}{}:
+	default: // A signal is already waiting
+	}
+}
+
+// Pending returns the events that have not been published yet, oldest first within each outbox
+func (r *Relay) Pending() []Event {
+	var pending []Event
+	for _, outbox := range r.attached() {
+		pending = append(pending, outbox.Pending(0)...)
+	}
+	return pending
+}
+
+// Stats returns the relay's progress so far
+func (r *Relay) Stats() Stats {
+	r.mu.Lock()
+	stats := r.stats
+	r.mu.Unlock()
+	stats.Pending = len(r.Pending())
+	return stats
+}
+
+// Run publishes events as they are added until ctx is done, backing off while Kafka fails
+func (r *Relay) Run(ctx context.Context) {
+	backoff := r.MinBackoff
+	for {
+		err := r.Flush(ctx)
+		if err == nil {
+			backoff = r.MinBackoff
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.ready:
+			}
+			continue
+		}
+
+		log.Printf("KYC SOAP Server: Failed to publish change events, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, r.MaxBackoff)
+	}
+}
+
+// Flush publishes every pending event, oldest first within each outbox, and stops at the first
+// failed write
+func (r *Relay) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+	for _, outbox := range r.attached() {
+		if err := r.flush(ctx, outbox); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush publishes the pending events of outbox; callers must hold r.flushMu
+func (r *Relay) flush(ctx context.Context, outbox Outbox) error {
+	for {
+		batch := outbox.Pending(max(r.BatchSize, 1))
+		if len(batch) == 0 {
+			return nil
+		}
+		messages := make([]kafka.Message, 0, len(batch))
+		for _, event := range batch {
+			message, err := r.message(event)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, message)
+		}
+
+		if err := r.writer.WriteMessages(ctx, messages...); err != nil {
+			r.failed(err)
+			return fmt.Errorf("failed to write %d change events to topic %s: %w", len(messages), r.topic, err)
+		}
+		// Until they are removed the events stay pending and are written again by the next flush,
+		// after Run's backoff, which consumers drop as duplicates
+		if err := outbox.Remove(len(batch)); err != nil {
+			r.failed(err)
+			return fmt.Errorf("failed to remove %d published change events from the outbox: %w", len(batch), err)
+		}
+
+		r.mu.Lock()
+		r.stats.Published += int64(len(batch))
+		r.stats.LastError = ""
+		r.stats.LastPublished = time.Now().UTC()
+		r.mu.Unlock()
+		log.Printf("KYC SOAP Server: Published %d change events to topic %s", len(batch), r.topic)
+	}
+}
+
+// failed records a failed flush
+func (r *Relay) failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Failures++
+	r.stats.LastError = err.Error()
+}
+
+// Close closes the Kafka writer; events still in the outboxes stay there
+func (r *Relay) Close() error {
+	return r.writer.Close()
+}
+
+// message encodes event for Kafka, with its type, tenant and correlation ID also as headers
+func (r *Relay) message(event Event) (kafka.Message, error) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to encode change event %s: %w", event.ID, err)
+	}
+	headers := []kafka.Header{{Key: "eventType", Value: []byte(event.Type)}}
+	if event.Tenant != "" {
+		headers = append(headers, kafka.Header{Key: "tenant", Value: []byte(event.Tenant)})
+	}
+	if event.CorrelationID != "" {
+		headers = append(headers, kafka.Header{Key: "correlationId", Value: []byte(event.CorrelationID)})
+	}
+	return kafka.Message{Topic: r.topic, Key: []byte(event.ClientID), Value: value, Headers: headers}, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"kafka-soap-e2e-test/services/providers/kyc/cdc"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafka stands in for the change event relay's Kafka writer, failing while err is set
+type fakeKafka struct {
+	mu       sync.Mutex
+	err      error
+	messages []kafka.Message
+}
+
+func (k *fakeKafka) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.err != nil {
+		return k.err
+	}
+	k.messages = append(k.messages, msgs...)
+	return nil
+}
+
+func (k *fakeKafka) Close() error { return nil }
+
+// useChangeRelay enables change events for the test with a relay that only the test flushes
+func useChangeRelay(t *testing.T, writer cdc.Writer) *cdc.Relay {
+	t.Helper()
+	relay := cdc.NewRelay(writer, "KYCChanges")
+	changeRelay.Store(relay)
+	t.Cleanup(func() { changeRelay.Store(nil) })
+	return relay
+}
+
+func TestChangeEvents(t *testing.T) {
+	relay := useChangeRelay(t, &fakeKafka{})
+	repo := NewInMemoryRepo()
+	t.Cleanup(captureChanges("", repo))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) })
+	initAdminRoutes(mux, repo)
+	serve := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	create := httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("CreateKYC", "client1", &kyc.Record{ClientID: "client1", Risk: 0.1})))
+	create.Header.Set("X-Correlation-ID", "corr-1")
+	require.Equal(t, http.StatusOK, serve(create))
+	require.Equal(t, http.StatusOK, serve(httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("UpdateKYC", "client1", &kyc.Record{ClientID: "client1", Risk: 0.8})))))
+	require.Equal(t, http.StatusNotFound, serve(httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("UpdateKYC", "nonexistent", &kyc.Record{ClientID: "nonexistent", Risk: 0.8})))))
+	require.Equal(t, http.StatusOK, serve(httptest.NewRequest("DELETE", "/admin/v1/users/client1", nil)))
+
+	events := relay.Pending()
+	require.Len(t, events, 3, "failed mutations publish nothing")
+	assert.Equal(t, cdc.TypeCreated, events[0].Type)
+	assert.Equal(t, kyc.SourceSOAP, events[0].Source)
+	assert.Equal(t, "corr-1", events[0].CorrelationID)
+	assert.Nil(t, events[0].Before)
+	assert.Equal(t, 0.1, events[0].After.Risk)
+	assert.Equal(t, cdc.TypeUpdated, events[1].Type)
+	assert.Equal(t, 0.1, events[1].Before.Risk)
+	assert.Equal(t, 0.8, events[1].After.Risk)
+	assert.Equal(t, cdc.TypeDeleted, events[2].Type)
+	assert.Equal(t, kyc.SourceAdmin, events[2].Source)
+	assert.Equal(t, 0.8, events[2].Before.Risk)
+	assert.Nil(t, events[2].After)
+	assert.Equal(t, repo.History("client1")[2].Sequence, events[2].Sequence)
+}
+
+func TestChangeEvents_FixturesAndTenants(t *testing.T) {
+	relay := useChangeRelay(t, &fakeKafka{})
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client1.json"), []byte(`{"clientId":"client1","risk":0.1}`), 0o644))
+	fixtureRepo := NewInMemoryRepo()
+	detach := captureChanges("", fixtureRepo)
+	loadUserDataFromFiles(fixtureRepo, dir)
+	require.Len(t, fixtureRepo.List(), 1)
+	events := relay.Pending()
+	require.Len(t, events, 1, "records loaded from fixtures are published")
+	assert.Equal(t, cdc.TypeCreated, events[0].Type)
+	assert.Equal(t, kyc.SourceFixture, events[0].Source)
+	detach()
+
+	server, _ := newTenantServer(t)
+	code, _ := createTenant(t, server, `{"name":"suite-a","empty":true}`)
+	require.Equal(t, http.StatusCreated, code)
+	resp, err := server.Client().Post(server.URL+"/t/suite-a/admin/v1/users", "application/json", strings.NewReader(`{"ClientID":"client2","Risk":0.2}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, err = server.Client().Post(server.URL+"/t/suite-a/admin/v1/reset", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events = relay.Pending()
+	require.Len(t, events, 3)
+	assert.Equal(t, cdc.TypeReset, events[0].Type, "the seed of a new tenant is announced")
+	assert.Equal(t, "suite-a", events[0].Tenant)
+	assert.Equal(t, int64(0), events[0].Sequence)
+	assert.Equal(t, cdc.TypeCreated, events[1].Type)
+	assert.Equal(t, "suite-a", events[1].Tenant)
+	assert.Equal(t, "client2", events[1].ClientID)
+	assert.Equal(t, cdc.TypeReset, events[2].Type, "a reset is announced")
+	assert.Equal(t, "suite-a", events[2].Tenant)
+
+	require.True(t, tenants.Delete("suite-a"))
+	assert.Empty(t, relay.Pending(), "a deleted tenant's events are dropped")
+}
+
+func TestChangeEvents_StoredWithTheChange(t *testing.T) {
+	relay := useChangeRelay(t, &fakeKafka{err: errors.New("broker unavailable")})
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	captureChanges("", repo)
+	audit := auditContext{actor: "test", source: kyc.SourceAdmin}
+	require.NoError(t, commitChanges(repo, audit, audit.event(kyc.AuditCreate, "client1", nil, &kyc.Record{ClientID: "client1", Version: 1})))
+	require.NoError(t, repo.Restore(repo.Snapshot()))
+	require.Error(t, relay.Flush(context.Background()))
+	assert.Empty(t, repo.Snapshot().Outbox, "snapshots leave the outbox out")
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	events := reopened.PendingChanges(0)
+	require.Len(t, events, 2, "unpublished events survive a restart")
+	assert.Equal(t, cdc.TypeCreated, events[0].Type)
+	assert.Equal(t, cdc.TypeReset, events[1].Type)
+	assert.Equal(t, events[0].Sequence, events[1].Sequence, "a reset names the last audit event it keeps")
+
+	relay = useChangeRelay(t, &fakeKafka{})
+	captureChanges("", reopened)
+	require.NoError(t, relay.Flush(context.Background()))
+	reopened, err = OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Empty(t, reopened.PendingChanges(0), "published events are removed from the store")
+}
+
+func TestOpenChangeRelay(t *testing.T) {
+	t.Setenv("KYC_CDC_BROKERS", "localhost:9092")
+	_, err := openChangeRelay(StoreMemory)
+	assert.ErrorContains(t, err, "only durable with KYC_STORE=file")
+	relay, err := openChangeRelay(StoreFile)
+	require.NoError(t, err)
+	assert.NotNil(t, relay)
+
+	t.Setenv("KYC_CDC_VOLATILE", "true")
+	relay, err = openChangeRelay(StoreMemory)
+	require.NoError(t, err)
+	assert.NotNil(t, relay)
+}
+
+func TestAdminAPI_CDC(t *testing.T) {
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+	serve := func(method, target string) (*httptest.ResponseRecorder, cdcStatus) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		var status cdcStatus
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		}
+		return rec, status
+	}
+
+	rec, status := serve("GET", "/admin/v1/cdc")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, status.Enabled)
+	rec, _ = serve("POST", "/admin/v1/cdc/flush")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	writer := &fakeKafka{err: errors.New("broker unavailable")}
+	useChangeRelay(t, writer)
+	t.Cleanup(captureChanges("", repo))
+	audit := auditContext{actor: "test", source: kyc.SourceAdmin}
+	require.NoError(t, commitChanges(repo, audit, audit.event(kyc.AuditCreate, "client1", nil, &kyc.Record{ClientID: "client1", Version: 1})))
+	rec, _ = serve("POST", "/admin/v1/cdc/flush")
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	rec, status = serve("GET", "/admin/v1/cdc?outbox=true")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 1, status.Pending)
+	assert.Equal(t, "broker unavailable", status.LastError)
+	require.Len(t, status.Outbox, 1)
+	assert.Equal(t, "client1", status.Outbox[0].ClientID)
+
+	writer.mu.Lock()
+	writer.err = nil
+	writer.mu.Unlock()
+	rec, status = serve("POST", "/admin/v1/cdc/flush")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 0, status.Pending)
+	assert.Equal(t, int64(1), status.Published)
+	assert.Len(t, writer.messages, 1)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"kafka-soap-e2e-test/services/providers/kyc/scoring"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+const cliUsage = `Usage:
+  kyc-service                                    Run the SOAP and admin server
+  kyc-service import [flags] [file]              Import records into the file store (stdin without file)
+  kyc-service export [flags] [file]              Export records from the file store (stdout without file)
+
+Run 'kyc-service <command> -h' for the flags of a command.
+`
+
+// runCommand runs the subcommand named by args[0] offline against the file store and returns the
+// process exit code: 0 on success, 1 if the command failed or an import row was rejected, 2 for
+// bad usage
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "import":
+		return runImport(args[1:], stdin, stdout, stderr)
+	case "export":
+		return runExport(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n%s", args[0], cliUsage)
+		return 2
+	}
+}
+
+// storeFlags are the flags shared by the import and export commands
+type storeFlags struct {
+	store   string
+	format  string
+	verbose bool
+}
+
+func (f *storeFlags) register(flags *flag.FlagSet) {
+	defaultStore := os.Getenv("KYC_STORE_PATH")
+	if defaultStore == "" {
+		defaultStore = "data/kyc.json"
+	}
+	flags.StringVar(&f.store, "store", defaultStore, "file store to read and write (default $KYC_STORE_PATH)")
+	flags.StringVar(&f.format, "format", "", "ndjson, json or csv (default from the file extension, else ndjson)")
+	flags.BoolVar(&f.verbose, "v", false, "log every change to stderr")
+}
+
+// resolveFormat picks the format from -format, then the extension of path, then NDJSON
+func (f *storeFlags) resolveFormat(path string) (BulkFormat, error) {
+	if f.format != "" {
+		return parseBulkFormat(f.format)
+	}
+	if format, ok := formatOfPath(path); ok {
+		return format, nil
+	}
+	return FormatNDJSON, nil
+}
+
+// parseCommandFlags parses args into flags and returns the optional file argument
+func parseCommandFlags(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	switch flags.NArg() {
+	case 0:
+		return "-", nil
+	case 1:
+		return flags.Arg(0), nil
+	default:
+		return "", fmt.Errorf("want at most one file, got %d", flags.NArg())
+	}
+}
+
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var common storeFlags
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	common.register(flags)
+	mode := flags.String("mode", string(ImportUpsert), "upsert, create-only or replace-all")
+	actor := flags.String("actor", "cli", "actor recorded in the audit trail")
+	path, err := parseCommandFlags(flags, args)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	importMode, err := parseImportMode(*mode)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	format, err := common.resolveFormat(path)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	common.redirectLog(stderr)
+
+	input := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return commandError(stderr, err)
+		}
+		defer file.Close()
+		input = file
+	}
+	rows, err := readRecords(format, input)
+	if err != nil {
+		return commandError(stderr, err)
+	}
+	if err := loadScoringEngine(); err != nil {
+		return commandError(stderr, err)
+	}
+	store, err := OpenFileRepo(common.store)
+	if err != nil {
+		return commandError(stderr, err)
+	}
+	if os.Getenv("KYC_CDC_BROKERS") != "" {
+		store.CaptureChanges("", nil) // The server publishes the import's change events once it starts
+	}
+
+	audit := auditContext{actor: *actor, source: kyc.SourceAdmin, operation: "import " + path}
+	report, importErr := importRecords(store, audit, importMode, rows) // Writes the store once
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return commandError(stderr, err)
+	}
+	if importErr != nil {
+		return commandError(stderr, importErr)
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	var common storeFlags
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	common.register(flags)
+	path, err := parseCommandFlags(flags, args)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	format, err := common.resolveFormat(path)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	common.redirectLog(stderr)
+
+	if _, err := os.Stat(common.store); err != nil {
+		return commandError(stderr, err) // Opening would silently create an empty store
+	}
+	store, err := OpenFileRepo(common.store)
+	if err != nil {
+		return commandError(stderr, err)
+	}
+
+	output := stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return commandError(stderr, err)
+		}
+		defer file.Close()
+		output = file
+	}
+	if _, err := writeRecords(format, output, store.Records()); err != nil {
+		return commandError(stderr, err)
+	}
+	return 0
+}
+
+// redirectLog sends the provider's log to stderr with -v and discards it otherwise
+func (f *storeFlags) redirectLog(stderr io.Writer) {
+	if f.verbose {
+		log.SetOutput(stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+}
+
+// loadScoringEngine scores imported records like the server does when KYC_SCORING_RULES is set
+func loadScoringEngine() error {
+	rulesPath := os.Getenv("KYC_SCORING_RULES")
+	if rulesPath == "" {
+		return nil
+	}
+	engine, err := scoring.Open(rulesPath)
+	if err != nil {
+		return err
+	}
+	riskEngine.Store(engine)
+	return nil
+}
+
+// usageError reports bad flags or arguments; the flag package has already printed the usage for -h
+func usageError(stderr io.Writer, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintf(stderr, "kyc-service: %v\n", err)
+	return 2
+}
+
+func commandError(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "kyc-service: %v\n", err)
+	return 1
+}
@@ -0,0 +1,151 @@
+// Package contract embeds the WSDL 1.1 and XML Schema documents describing the KYC SOAP service.
+// The provider serves them at /soap?wsdl and /soap?xsd=N, tools/wsdlgen generates clients from them,
+// and Schema validates SOAP payloads against them.
+package contract
+
+import (
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Namespace is the target namespace of the KYC contract
+const Namespace = "http://example.com/kyc"
+
+//go:embed kyc.wsdl kyc.xsd
+var files embed.FS
+
+// schemaFiles are the schemas served as ?xsd=N, numbered from 1
+var schemaFiles = []string{"kyc.xsd"}
+
+var addressPattern = regexp.MustCompile(`(<soap:address\s+location=")[^"]*(")`)
+
+// WSDL returns the service description with its port address set to address
+// and every schemaLocation pointing at address?xsd=N.
+func WSDL(address string) []byte {
+	doc := string(mustRead("kyc.wsdl"))
+	for i, name := range schemaFiles {
+		doc = strings.ReplaceAll(doc, fmt.Sprintf(`schemaLocation="%s"`, name), fmt.Sprintf(`schemaLocation="%s?xsd=%d"`, address, i+1))
+	}
+	return []byte(addressPattern.ReplaceAllString(doc, "${1}"+address+"${2}"))
+}
+
+// XSD returns the n-th schema referenced by the WSDL, numbered from 1
+func XSD(n int) ([]byte, bool) {
+	if n < 1 || n > len(schemaFiles) {
+		return nil, false
+	}
+	return mustRead(schemaFiles[n-1]), true
+}
+
+func mustRead(name string) []byte {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("contract: embedded file %s is missing: %v", name, err))
+	}
+	return data
+}
+
+// Operation is a document/literal operation of the KYC binding
+type Operation struct {
+	Name       string
+	SOAPAction string
+	Input      string // Local name of the request element
+	Output     string // Local name of the response element
+}
+
+var (
+	operationsOnce sync.Once
+	operations     []Operation
+)
+
+// Operations returns the operations of the KYC binding in WSDL order
+func Operations() []Operation {
+	operationsOnce.Do(func() {
+		ops, err := parseOperations(mustRead("kyc.wsdl"))
+		if err != nil {
+			panic(fmt.Sprintf("contract: invalid embedded WSDL: %v", err))
+		}
+		operations = ops
+	})
+	return operations
+}
+
+// OperationForElement returns the operation whose request element is the given local name
+func OperationForElement(local string) (Operation, bool) {
+	for _, op := range Operations() {
+		if op.Input == local {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// parseOperations resolves each binding operation to its SOAPAction and message elements
+func parseOperations(wsdl []byte) ([]Operation, error) {
+	var defs struct {
+		Messages []struct {
+			Name  string `xml:"name,attr"`
+			Parts []struct {
+				Element string `xml:"element,attr"`
+			} `xml:"part"`
+		} `xml:"message"`
+		PortTypes []struct {
+			Operations []struct {
+				Name  string `xml:"name,attr"`
+				Input struct {
+					Message string `xml:"message,attr"`
+				} `xml:"input"`
+				Output struct {
+					Message string `xml:"message,attr"`
+				} `xml:"output"`
+			} `xml:"operation"`
+		} `xml:"portType"`
+		Bindings []struct {
+			Operations []struct {
+				Name string `xml:"name,attr"`
+				SOAP struct {
+					SOAPAction string `xml:"soapAction,attr"`
+				} `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+			} `xml:"operation"`
+		} `xml:"binding"`
+	}
+	if err := xml.Unmarshal(wsdl, &defs); err != nil {
+		return nil, err
+	}
+
+	local := func(qname string) string {
+		if _, name, ok := strings.Cut(qname, ":"); ok {
+			return name
+		}
+		return qname
+	}
+	elements := make(map[string]string)
+	for _, m := range defs.Messages {
+		if len(m.Parts) > 0 {
+			elements[m.Name] = local(m.Parts[0].Element)
+		}
+	}
+	actions := make(map[string]string)
+	for _, b := range defs.Bindings {
+		for _, op := range b.Operations {
+			actions[op.Name] = op.SOAP.SOAPAction
+		}
+	}
+
+	var ops []Operation
+	for _, pt := range defs.PortTypes {
+		for _, op := range pt.Operations {
+			ops = append(ops, Operation{
+				Name:       op.Name,
+				SOAPAction: actions[op.Name],
+				Input:      elements[local(op.Input.Message)],
+				Output:     elements[local(op.Output.Message)],
+			})
+		}
+	}
+	return ops, nil
+}
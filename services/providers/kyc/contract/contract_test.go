@@ -0,0 +1,163 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSDL_RewritesAddresses(t *testing.T) {
+	doc := string(WSDL("https://kyc.example.org/soap"))
+	assert.Contains(t, doc, `<soap:address location="https://kyc.example.org/soap"/>`)
+	assert.Contains(t, doc, `schemaLocation="https://kyc.example.org/soap?xsd=1"`)
+	assert.NotContains(t, doc, "localhost:8081")
+}
+
+func TestXSD(t *testing.T) {
+	doc, ok := XSD(1)
+	require.True(t, ok)
+	assert.Contains(t, string(doc), `targetNamespace="http://example.com/kyc"`)
+
+	_, ok = XSD(0)
+	assert.False(t, ok)
+	_, ok = XSD(2)
+	assert.False(t, ok)
+}
+
+func TestOperations(t *testing.T) {
+	assert.Equal(t, []Operation{
+		{Name: "KYCQuery", SOAPAction: "http://example.com/kyc/KYCQuery", Input: "KYCQuery", Output: "KYCResponse"},
+		{Name: "CreateKYC", SOAPAction: "http://example.com/kyc/CreateKYC", Input: "CreateKYC", Output: "KYCResponse"},
+		{Name: "UpdateKYC", SOAPAction: "http://example.com/kyc/UpdateKYC", Input: "UpdateKYC", Output: "KYCResponse"},
+		{Name: "DeleteKYC", SOAPAction: "http://example.com/kyc/DeleteKYC", Input: "DeleteKYC", Output: "DeleteKYCResponse"},
+		{Name: "SubmitKYC", SOAPAction: "http://example.com/kyc/SubmitKYC", Input: "SubmitKYC", Output: "KYCResponse"},
+		{Name: "ApproveKYC", SOAPAction: "http://example.com/kyc/ApproveKYC", Input: "ApproveKYC", Output: "KYCResponse"},
+		{Name: "RejectKYC", SOAPAction: "http://example.com/kyc/RejectKYC", Input: "RejectKYC", Output: "KYCResponse"},
+		{Name: "GetKYCStatus", SOAPAction: "http://example.com/kyc/GetKYCStatus", Input: "GetKYCStatus", Output: "KYCStatusResponse"},
+		{Name: "ScoreKYC", SOAPAction: "http://example.com/kyc/ScoreKYC", Input: "ScoreKYC", Output: "ScoreKYCResponse"},
+		{Name: "GetKYCHistory", SOAPAction: "http://example.com/kyc/GetKYCHistory", Input: "GetKYCHistory", Output: "KYCHistoryResponse"},
+		{Name: "BatchKYCQuery", SOAPAction: "http://example.com/kyc/BatchKYCQuery", Input: "BatchKYCQuery", Output: "BatchKYCQueryResponse"},
+		{Name: "SearchKYC", SOAPAction: "http://example.com/kyc/SearchKYC", Input: "SearchKYC", Output: "SearchKYCResponse"},
+	}, Operations())
+
+	op, ok := OperationForElement("DeleteKYC")
+	require.True(t, ok)
+	assert.Equal(t, "DeleteKYC", op.Name)
+	_, ok = OperationForElement("KYCResponse")
+	assert.False(t, ok)
+}
+
+func envelope(body string) []byte {
+	return []byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:kyc="http://example.com/kyc"><soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`)
+}
+
+func TestKYCSchema_ValidateEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		violations []string
+	}{
+		{
+			name: "Valid request with default namespace",
+			body: `<CreateKYC xmlns="http://example.com/kyc"><UserData><ClientID>c1</ClientID><Risk>0.5</Risk></UserData></CreateKYC>`,
+		},
+		{
+			name: "Valid request with prefix declared on the envelope",
+			body: `<kyc:KYCQuery><kyc:ClientID>c1</kyc:ClientID></kyc:KYCQuery>`,
+		},
+		{
+			name:       "Missing required element",
+			body:       `<kyc:CreateKYC><kyc:UserData><kyc:ClientID>c1</kyc:ClientID></kyc:UserData></kyc:CreateKYC>`,
+			violations: []string{"/CreateKYC/UserData/Risk: missing required element"},
+		},
+		{
+			name:       "Invalid double",
+			body:       `<kyc:CreateKYC><kyc:UserData><kyc:ClientID>c1</kyc:ClientID><kyc:Risk>high</kyc:Risk></kyc:UserData></kyc:CreateKYC>`,
+			violations: []string{`/CreateKYC/UserData/Risk: "high" is not a valid xs:double`},
+		},
+		{
+			name:       "Unexpected and unqualified elements",
+			body:       `<kyc:KYCQuery><ClientID>c1</ClientID><kyc:Extra/></kyc:KYCQuery>`,
+			violations: []string{`/KYCQuery/ClientID: element must be in namespace "http://example.com/kyc", got ""`, "/KYCQuery/Extra: unexpected element"},
+		},
+		{
+			name:       "Unknown operation",
+			body:       `<kyc:Bogus/>`,
+			violations: []string{"/Bogus: unknown element"},
+		},
+		{
+			name:       "Empty body",
+			body:       ``,
+			violations: []string{"/: SOAP Body is missing or empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, vs, err := KYCSchema().ValidateEnvelope(envelope(tt.body))
+			require.NoError(t, err)
+			var got []string
+			for _, v := range vs {
+				got = append(got, v.String())
+			}
+			assert.Equal(t, tt.violations, got)
+		})
+	}
+}
+
+func TestParseSchema_Facets(t *testing.T) {
+	s, err := ParseSchema([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:t" targetNamespace="urn:t" elementFormDefault="qualified">
+  <xs:simpleType name="Level"><xs:restriction base="xs:string"><xs:enumeration value="LOW"/><xs:enumeration value="HIGH"/></xs:restriction></xs:simpleType>
+  <xs:simpleType name="Score"><xs:restriction base="xs:double"><xs:minInclusive value="0"/><xs:maxInclusive value="1"/></xs:restriction></xs:simpleType>
+  <xs:simpleType name="Code"><xs:restriction base="xs:string"><xs:pattern value="[A-Z]{3}"/></xs:restriction></xs:simpleType>
+  <xs:element name="Item">
+    <xs:complexType>
+      <xs:all>
+        <xs:element name="Level" type="t:Level"/>
+        <xs:element name="Score" type="t:Score" minOccurs="0"/>
+        <xs:element name="Code" type="t:Code" minOccurs="0"/>
+      </xs:all>
+      <xs:attribute name="id" type="xs:int" use="required"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	require.NoError(t, err)
+
+	vs, err := s.ValidateElement([]byte(`<Item xmlns="urn:t" id="x"><Score>1.5</Score><Code>ab</Code><Level>MID</Level></Item>`))
+	require.NoError(t, err)
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.String())
+	}
+	assert.Equal(t, []string{
+		`/Item/@id: "x" is not a valid xs:int`,
+		"/Item/Score: 1.5 is greater than the maximum 1",
+		`/Item/Code: "ab" does not match pattern ^(?:[A-Z]{3})$`,
+		`/Item/Level: "MID" is not one of LOW, HIGH`,
+	}, got)
+
+	vs, err = s.ValidateElement([]byte(`<Item xmlns="urn:t" id="7"><Level>LOW</Level></Item>`))
+	require.NoError(t, err)
+	assert.Empty(t, vs)
+
+	_, err = ParseSchema([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:simpleType name="X"><xs:restriction base="xs:string"><xs:pattern value="("/></xs:restriction></xs:simpleType></xs:schema>`))
+	assert.Error(t, err)
+}
+
+func TestKYCSchema_ViolationDetails(t *testing.T) {
+	_, vs, err := KYCSchema().ValidateEnvelope(envelope(`<kyc:CreateKYC><kyc:UserData><kyc:Risk>high</kyc:Risk><Extra/></kyc:UserData></kyc:CreateKYC>`))
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Kind: ViolationMissing, Path: "/CreateKYC/UserData/ClientID", Message: "missing required element", Expected: "xs:string"},
+		{Kind: ViolationType, Path: "/CreateKYC/UserData/Risk", Message: `"high" is not a valid xs:double`, Expected: "xs:double"},
+		{Kind: ViolationUnexpected, Path: "/CreateKYC/UserData/Extra", Message: "unexpected element"},
+	}, vs)
+}
+
+func TestKYCSchema_ValidateEnvelopeRejectsNonEnvelopes(t *testing.T) {
+	for _, doc := range []string{`this is not xml`, `<KYCQuery xmlns="http://example.com/kyc"/>`, `<soapenv:Envelope`} {
+		_, _, err := KYCSchema().ValidateEnvelope([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+}
@@ -0,0 +1,665 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	xsdNamespace     = "http://www.w3.org/2001/XMLSchema"
+	soapEnvNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+)
+
+// ViolationKind classifies a Violation so callers can decide which ones to tolerate
+type ViolationKind string
+
+const (
+	ViolationMissing    ViolationKind = "missing"    // A required element or attribute is absent
+	ViolationType       ViolationKind = "type"       // A value does not match its simple type or facets
+	ViolationUnexpected ViolationKind = "unexpected" // An element or text the schema does not allow
+	ViolationOrder      ViolationKind = "order"      // A declared element is out of sequence or repeated
+	ViolationNamespace  ViolationKind = "namespace"  // An element is in the wrong namespace
+)
+
+// Violation is a single way in which a message does not conform to the schema
+type Violation struct {
+	Kind     ViolationKind `json:"kind"`
+	Path     string        `json:"path"` // Slash-separated element path, e.g. /CreateKYC/UserData/Risk
+	Message  string        `json:"message"`
+	Expected string        `json:"expected,omitempty"` // Expected type or namespace, when known
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Schema is a compiled XML Schema. It supports the subset used by document/literal contracts:
+// global elements, named and anonymous complex types with sequence or all content, attributes,
+// and simple types restricted by enumeration, pattern, length and inclusive bounds.
+type Schema struct {
+	targetNamespace string
+	qualified       bool
+	elements        map[string]*elementDecl
+	types           map[string]*typeDecl
+}
+
+type elementDecl struct {
+	name      string
+	typeName  qname
+	inline    *typeDecl
+	minOccurs int
+	maxOccurs int // -1 is unbounded
+}
+
+type attributeDecl struct {
+	name     string
+	typeName qname
+	required bool
+}
+
+type typeDecl struct {
+	name string
+
+	// Complex content
+	complex    bool
+	all        bool
+	children   []*elementDecl
+	attributes []attributeDecl
+
+	// Simple content
+	base         qname
+	enumerations []string
+	pattern      *regexp.Regexp
+	minLength    int
+	maxLength    int // -1 is unbounded
+	minInclusive *float64
+	maxInclusive *float64
+}
+
+type qname struct {
+	space string
+	local string
+}
+
+var (
+	kycSchemaOnce sync.Once
+	kycSchema     *Schema
+)
+
+// KYCSchema returns the compiled schema of the embedded KYC contract
+func KYCSchema() *Schema {
+	kycSchemaOnce.Do(func() {
+		s, err := ParseSchema(mustRead("kyc.xsd"))
+		if err != nil {
+			panic(fmt.Sprintf("contract: invalid embedded schema: %v", err))
+		}
+		kycSchema = s
+	})
+	return kycSchema
+}
+
+// --- Parsing ---
+
+type rawSchema struct {
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Attrs              []xml.Attr       `xml:",any,attr"`
+	Elements           []rawElement     `xml:"element"`
+	ComplexTypes       []rawComplexType `xml:"complexType"`
+	SimpleTypes        []rawSimpleType  `xml:"simpleType"`
+}
+
+type rawElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	MinOccurs   string          `xml:"minOccurs,attr"`
+	MaxOccurs   string          `xml:"maxOccurs,attr"`
+	ComplexType *rawComplexType `xml:"complexType"`
+	SimpleType  *rawSimpleType  `xml:"simpleType"`
+}
+
+type rawComplexType struct {
+	Name     string `xml:"name,attr"`
+	Sequence *struct {
+		Elements []rawElement `xml:"element"`
+	} `xml:"sequence"`
+	All *struct {
+		Elements []rawElement `xml:"element"`
+	} `xml:"all"`
+	Attributes []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+		Use  string `xml:"use,attr"`
+	} `xml:"attribute"`
+}
+
+type rawFacet struct {
+	Value string `xml:"value,attr"`
+}
+
+type rawSimpleType struct {
+	Name        string `xml:"name,attr"`
+	Restriction struct {
+		Base         string     `xml:"base,attr"`
+		Enumerations []rawFacet `xml:"enumeration"`
+		Pattern      *rawFacet  `xml:"pattern"`
+		MinLength    *rawFacet  `xml:"minLength"`
+		MaxLength    *rawFacet  `xml:"maxLength"`
+		MinInclusive *rawFacet  `xml:"minInclusive"`
+		MaxInclusive *rawFacet  `xml:"maxInclusive"`
+	} `xml:"restriction"`
+}
+
+// ParseSchema compiles a standalone XML Schema document
+func ParseSchema(data []byte) (*Schema, error) {
+	var raw rawSchema
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	ns := make(map[string]string)
+	for _, a := range raw.Attrs {
+		if a.Name.Space == "xmlns" {
+			ns[a.Name.Local] = a.Value
+		}
+	}
+	p := &schemaParser{ns: ns, target: raw.TargetNamespace}
+	s := &Schema{
+		targetNamespace: raw.TargetNamespace,
+		qualified:       raw.ElementFormDefault == "qualified",
+		elements:        make(map[string]*elementDecl),
+		types:           make(map[string]*typeDecl),
+	}
+
+	for _, st := range raw.SimpleTypes {
+		t, err := p.simpleType(st)
+		if err != nil {
+			return nil, err
+		}
+		s.types[st.Name] = t
+	}
+	for _, ct := range raw.ComplexTypes {
+		t, err := p.complexType(ct)
+		if err != nil {
+			return nil, err
+		}
+		s.types[ct.Name] = t
+	}
+	for _, el := range raw.Elements {
+		decl, err := p.element(el)
+		if err != nil {
+			return nil, err
+		}
+		s.elements[el.Name] = decl
+	}
+	return s, nil
+}
+
+type schemaParser struct {
+	ns     map[string]string
+	target string
+}
+
+func (p *schemaParser) resolve(value string) qname {
+	if prefix, local, ok := strings.Cut(value, ":"); ok {
+		return qname{space: p.ns[prefix], local: local}
+	}
+	return qname{space: p.target, local: value}
+}
+
+func (p *schemaParser) element(el rawElement) (*elementDecl, error) {
+	decl := &elementDecl{name: el.Name, minOccurs: 1, maxOccurs: 1}
+	if el.MinOccurs != "" {
+		n, err := strconv.Atoi(el.MinOccurs)
+		if err != nil {
+			return nil, fmt.Errorf("element %s: invalid minOccurs %q", el.Name, el.MinOccurs)
+		}
+		decl.minOccurs = n
+	}
+	switch el.MaxOccurs {
+	case "":
+	case "unbounded":
+		decl.maxOccurs = -1
+	default:
+		n, err := strconv.Atoi(el.MaxOccurs)
+		if err != nil {
+			return nil, fmt.Errorf("element %s: invalid maxOccurs %q", el.Name, el.MaxOccurs)
+		}
+		decl.maxOccurs = n
+	}
+
+	var err error
+	switch {
+	case el.ComplexType != nil:
+		decl.inline, err = p.complexType(*el.ComplexType)
+	case el.SimpleType != nil:
+		decl.inline, err = p.simpleType(*el.SimpleType)
+	case el.Type != "":
+		decl.typeName = p.resolve(el.Type)
+	default:
+		decl.typeName = qname{space: xsdNamespace, local: "string"}
+	}
+	return decl, err
+}
+
+func (p *schemaParser) complexType(ct rawComplexType) (*typeDecl, error) {
+	t := &typeDecl{name: ct.Name, complex: true}
+	var elements []rawElement
+	switch {
+	case ct.Sequence != nil:
+		elements = ct.Sequence.Elements
+	case ct.All != nil:
+		elements = ct.All.Elements
+		t.all = true
+	}
+	for _, el := range elements {
+		decl, err := p.element(el)
+		if err != nil {
+			return nil, err
+		}
+		t.children = append(t.children, decl)
+	}
+	for _, a := range ct.Attributes {
+		t.attributes = append(t.attributes, attributeDecl{name: a.Name, typeName: p.resolve(a.Type), required: a.Use == "required"})
+	}
+	return t, nil
+}
+
+func (p *schemaParser) simpleType(st rawSimpleType) (*typeDecl, error) {
+	r := st.Restriction
+	t := &typeDecl{name: st.Name, base: p.resolve(r.Base), maxLength: -1}
+	for _, e := range r.Enumerations {
+		t.enumerations = append(t.enumerations, e.Value)
+	}
+	if r.Pattern != nil {
+		re, err := regexp.Compile("^(?:" + r.Pattern.Value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("simple type %s: invalid pattern: %w", st.Name, err)
+		}
+		t.pattern = re
+	}
+	var err error
+	if r.MinLength != nil {
+		if t.minLength, err = strconv.Atoi(r.MinLength.Value); err != nil {
+			return nil, fmt.Errorf("simple type %s: invalid minLength", st.Name)
+		}
+	}
+	if r.MaxLength != nil {
+		if t.maxLength, err = strconv.Atoi(r.MaxLength.Value); err != nil {
+			return nil, fmt.Errorf("simple type %s: invalid maxLength", st.Name)
+		}
+	}
+	for _, bound := range []struct {
+		facet *rawFacet
+		dst   **float64
+	}{{r.MinInclusive, &t.minInclusive}, {r.MaxInclusive, &t.maxInclusive}} {
+		if bound.facet == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(bound.facet.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("simple type %s: invalid bound %q", st.Name, bound.facet.Value)
+		}
+		*bound.dst = &v
+	}
+	return t, nil
+}
+
+// --- Validation ---
+
+// node is a parsed XML element
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	text     strings.Builder
+}
+
+// ValidateEnvelope validates the first element of the SOAP Body against the matching global element.
+// It returns the name of that element; err is only set when the document is not a well-formed SOAP envelope.
+func (s *Schema) ValidateEnvelope(envelope []byte) (xml.Name, []Violation, error) {
+	dec := xml.NewDecoder(bytes.NewReader(envelope))
+	inEnvelope, inBody := false, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF && inEnvelope {
+			return xml.Name{}, []Violation{{Kind: ViolationMissing, Path: "/", Message: "SOAP Body is missing or empty"}}, nil
+		}
+		if err == io.EOF {
+			return xml.Name{}, nil, errors.New("document is not a SOAP envelope")
+		}
+		if err != nil {
+			return xml.Name{}, nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !inEnvelope {
+			if start.Name.Space != soapEnvNamespace || start.Name.Local != "Envelope" {
+				return xml.Name{}, nil, fmt.Errorf("document root is %s, not a SOAP envelope", start.Name.Local)
+			}
+			inEnvelope = true
+			continue
+		}
+		if !inBody {
+			inBody = start.Name.Space == soapEnvNamespace && start.Name.Local == "Body"
+			continue
+		}
+		root, err := readNode(dec, start)
+		if err != nil {
+			return start.Name, nil, err
+		}
+		return start.Name, s.validateRoot(root), nil
+	}
+}
+
+// ValidateElement validates a standalone XML document whose root is a global element of the schema
+func (s *Schema) ValidateElement(doc []byte) ([]Violation, error) {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root, err := readNode(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			return s.validateRoot(root), nil
+		}
+	}
+}
+
+func readNode(dec *xml.Decoder, start xml.StartElement) (*node, error) {
+	n := &node{name: start.Name, attrs: start.Attr}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readNode(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		case xml.CharData:
+			n.text.Write(t)
+		case xml.EndElement:
+			return n, nil
+		}
+	}
+}
+
+func (s *Schema) validateRoot(root *node) []Violation {
+	path := "/" + root.name.Local
+	decl, ok := s.elements[root.name.Local]
+	if !ok {
+		return []Violation{{Kind: ViolationUnexpected, Path: path, Message: "unknown element"}}
+	}
+	var vs []Violation
+	if root.name.Space != s.targetNamespace {
+		vs = append(vs, Violation{Kind: ViolationNamespace, Path: path, Message: fmt.Sprintf("element must be in namespace %q, got %q", s.targetNamespace, root.name.Space), Expected: s.targetNamespace})
+	}
+	return append(vs, s.validateNode(root, decl, path)...)
+}
+
+func (s *Schema) typeOf(decl *elementDecl) (*typeDecl, bool) {
+	if decl.inline != nil {
+		return decl.inline, true
+	}
+	return s.lookupType(decl.typeName)
+}
+
+func (s *Schema) lookupType(name qname) (*typeDecl, bool) {
+	if name.space == xsdNamespace {
+		return &typeDecl{base: name, maxLength: -1}, true
+	}
+	t, ok := s.types[name.local]
+	return t, ok
+}
+
+func (s *Schema) validateNode(n *node, decl *elementDecl, path string) []Violation {
+	t, ok := s.typeOf(decl)
+	if !ok {
+		return []Violation{{Kind: ViolationType, Path: path, Message: fmt.Sprintf("schema references unknown type %s", decl.typeName.local)}}
+	}
+	if !t.complex {
+		if len(n.children) > 0 {
+			return []Violation{{Kind: ViolationUnexpected, Path: path, Message: "element must not have child elements", Expected: typeLabel(decl)}}
+		}
+		return s.validateValue(n.text.String(), t, path)
+	}
+
+	var vs []Violation
+	for _, a := range t.attributes {
+		value, found := attr(n, a.name)
+		if !found {
+			if a.required {
+				vs = append(vs, Violation{Kind: ViolationMissing, Path: path + "/@" + a.name, Message: "missing required attribute", Expected: qnameLabel(a.typeName)})
+			}
+			continue
+		}
+		if at, ok := s.lookupType(a.typeName); ok {
+			vs = append(vs, s.validateValue(value, at, path+"/@"+a.name)...)
+		}
+	}
+	if strings.TrimSpace(n.text.String()) != "" {
+		vs = append(vs, Violation{Kind: ViolationUnexpected, Path: path, Message: "unexpected text content"})
+	}
+	if t.all {
+		return append(vs, s.validateAll(n, t, path)...)
+	}
+	return append(vs, s.validateSequence(n, t, path)...)
+}
+
+func attr(n *node, name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// validateChild checks a child's namespace and content against its declaration
+func (s *Schema) validateChild(child *node, decl *elementDecl, path string) []Violation {
+	var vs []Violation
+	want := ""
+	if s.qualified {
+		want = s.targetNamespace
+	}
+	if child.name.Space != want {
+		vs = append(vs, Violation{Kind: ViolationNamespace, Path: path, Message: fmt.Sprintf("element must be in namespace %q, got %q", want, child.name.Space), Expected: want})
+	}
+	return append(vs, s.validateNode(child, decl, path)...)
+}
+
+func (s *Schema) validateSequence(n *node, t *typeDecl, path string) []Violation {
+	var vs []Violation
+	i := 0
+	for _, decl := range t.children {
+		count := 0
+		for i < len(n.children) && n.children[i].name.Local == decl.name && (decl.maxOccurs < 0 || count < decl.maxOccurs) {
+			vs = append(vs, s.validateChild(n.children[i], decl, childPath(path, decl.name, count, decl.maxOccurs))...)
+			count++
+			i++
+		}
+		if count < decl.minOccurs {
+			vs = append(vs, Violation{Kind: ViolationMissing, Path: path + "/" + decl.name, Message: "missing required element", Expected: typeLabel(decl)})
+		}
+	}
+	for ; i < len(n.children); i++ {
+		name := n.children[i].name.Local
+		v := Violation{Kind: ViolationUnexpected, Path: path + "/" + name, Message: "unexpected element"}
+		for _, decl := range t.children {
+			if decl.name == name {
+				v.Kind, v.Message = ViolationOrder, "element is out of order or repeated too often"
+				break
+			}
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func (s *Schema) validateAll(n *node, t *typeDecl, path string) []Violation {
+	var vs []Violation
+	seen := make(map[string]bool)
+	for _, child := range n.children {
+		name := child.name.Local
+		var decl *elementDecl
+		for _, d := range t.children {
+			if d.name == name {
+				decl = d
+				break
+			}
+		}
+		switch {
+		case decl == nil:
+			vs = append(vs, Violation{Kind: ViolationUnexpected, Path: path + "/" + name, Message: "unexpected element"})
+		case seen[name]:
+			vs = append(vs, Violation{Kind: ViolationOrder, Path: path + "/" + name, Message: "element is repeated"})
+		default:
+			seen[name] = true
+			vs = append(vs, s.validateChild(child, decl, path+"/"+name)...)
+		}
+	}
+	for _, d := range t.children {
+		if d.minOccurs > 0 && !seen[d.name] {
+			vs = append(vs, Violation{Kind: ViolationMissing, Path: path + "/" + d.name, Message: "missing required element", Expected: typeLabel(d)})
+		}
+	}
+	return vs
+}
+
+func childPath(parent, name string, index, maxOccurs int) string {
+	if maxOccurs == 1 {
+		return parent + "/" + name
+	}
+	return fmt.Sprintf("%s/%s[%d]", parent, name, index+1)
+}
+
+// validateValue checks text content against a simple type and its facets
+func (s *Schema) validateValue(raw string, t *typeDecl, path string) []Violation {
+	builtin := t.base
+	var restrictions []*typeDecl
+	for builtin.space != xsdNamespace {
+		base, ok := s.types[builtin.local]
+		if !ok || base.complex {
+			return []Violation{{Kind: ViolationType, Path: path, Message: fmt.Sprintf("schema references unknown simple type %s", builtin.local)}}
+		}
+		restrictions = append(restrictions, base)
+		builtin = base.base
+	}
+
+	value := raw
+	if builtin.local != "string" {
+		value = strings.TrimSpace(raw)
+	}
+	expected := "xs:" + builtin.local
+	if t.name != "" {
+		expected = t.name
+	}
+	if msg := checkBuiltin(builtin.local, value); msg != "" {
+		return []Violation{{Kind: ViolationType, Path: path, Message: msg, Expected: expected}}
+	}
+
+	var vs []Violation
+	for _, r := range append([]*typeDecl{t}, restrictions...) {
+		if msg := r.checkFacets(value); msg != "" {
+			vs = append(vs, Violation{Kind: ViolationType, Path: path, Message: msg, Expected: expected})
+		}
+	}
+	return vs
+}
+
+// typeLabel names the declared type of an element for Violation.Expected
+func typeLabel(decl *elementDecl) string {
+	if decl.inline != nil {
+		if decl.inline.complex {
+			return "complex content"
+		}
+		return qnameLabel(decl.inline.base)
+	}
+	return qnameLabel(decl.typeName)
+}
+
+func qnameLabel(name qname) string {
+	if name.space == xsdNamespace {
+		return "xs:" + name.local
+	}
+	return name.local
+}
+
+func (t *typeDecl) checkFacets(value string) string {
+	if len(t.enumerations) > 0 {
+		found := false
+		for _, e := range t.enumerations {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(t.enumerations, ", "))
+		}
+	}
+	if t.pattern != nil && !t.pattern.MatchString(value) {
+		return fmt.Sprintf("%q does not match pattern %s", value, t.pattern.String())
+	}
+	if n := len([]rune(value)); n < t.minLength || (t.maxLength >= 0 && n > t.maxLength) {
+		return fmt.Sprintf("length %d is outside the allowed range", n)
+	}
+	if t.minInclusive != nil || t.maxInclusive != nil {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%q is not numeric", value)
+		}
+		if t.minInclusive != nil && v < *t.minInclusive {
+			return fmt.Sprintf("%s is less than the minimum %s", value, strconv.FormatFloat(*t.minInclusive, 'g', -1, 64))
+		}
+		if t.maxInclusive != nil && v > *t.maxInclusive {
+			return fmt.Sprintf("%s is greater than the maximum %s", value, strconv.FormatFloat(*t.maxInclusive, 'g', -1, 64))
+		}
+	}
+	return ""
+}
+
+// checkBuiltin validates the lexical form of a built-in XML Schema type.
+// Types it does not know are accepted as strings.
+func checkBuiltin(builtin, value string) string {
+	var err error
+	switch builtin {
+	case "double", "float", "decimal":
+		_, err = strconv.ParseFloat(value, 64)
+	case "int":
+		_, err = strconv.ParseInt(value, 10, 32)
+	case "long", "integer":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "boolean":
+		if value != "true" && value != "false" && value != "1" && value != "0" {
+			err = strconv.ErrSyntax
+		}
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "dateTime":
+		if _, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			_, err = time.Parse("2006-01-02T15:04:05.999999999", value)
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("%q is not a valid xs:%s", value, builtin)
+	}
+	return ""
+}
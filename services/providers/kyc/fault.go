@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+)
+
+// Standard SOAP 1.1 fault codes
+const (
+	faultCodeClient = "soapenv:Client"
+	faultCodeServer = "soapenv:Server"
+)
+
+// ErrorStyle selects how failed operations are reported to SOAP clients
+type ErrorStyle string
+
+const (
+	ErrorStyleLegacy ErrorStyle = "legacy" // KYCResponse with Status "Error" and a 4xx status, or plain text for simulated server errors
+	ErrorStyleFault  ErrorStyle = "fault"  // soapenv:Fault with HTTP 500 and a KYCFault detail
+)
+
+// ErrorCode is the machine-readable cause carried in a KYCFault detail
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrorCodeUnknownOperation   ErrorCode = "UNKNOWN_OPERATION"
+	ErrorCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrorCodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+	ErrorCodeInternalError      ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeTimeout            ErrorCode = "TIMEOUT"
+	ErrorCodeInvalidTransition  ErrorCode = "INVALID_TRANSITION"
+	ErrorCodeScoringUnavailable ErrorCode = "SCORING_UNAVAILABLE"
+	ErrorCodeVersionConflict    ErrorCode = "VERSION_CONFLICT"
+)
+
+// faultCode maps an error code to the SOAP fault code blaming the client or the server
+func (c ErrorCode) faultCode() string {
+	switch c {
+	case ErrorCodeInternalError, ErrorCodeTimeout, ErrorCodeScoringUnavailable:
+		return faultCodeServer
+	}
+	return faultCodeClient
+}
+
+// operationError is a failed SOAP operation, reported according to the configured ErrorStyle
+type operationError struct {
+	operation  string // Root element of the request
+	code       ErrorCode
+	message    string
+	httpStatus int  // HTTP status of the legacy response
+	plainText  bool // The legacy response is a plain-text http.Error rather than a SOAP payload
+}
+
+// invalidRequestError reports a request body that could not be decoded
+func invalidRequestError(operation string, err error) *operationError {
+	return &operationError{
+		operation:  operation,
+		code:       ErrorCodeInvalidRequest,
+		message:    fmt.Sprintf("Invalid %s request: %v", operation, err),
+		httpStatus: http.StatusBadRequest,
+	}
+}
+
+// writeOperationError sends e in the given style
+func writeOperationError(w http.ResponseWriter, style ErrorStyle, e *operationError) {
+	if style == ErrorStyleFault {
+		writeSOAPFault(w, kycModels.Fault{
+			FaultCode:   e.code.faultCode(),
+			FaultString: e.message,
+			Detail: &kycModels.FaultDetail{
+				KYCFault: &kycModels.KYCFault{ErrorCode: string(e.code), Operation: e.operation},
+			},
+		})
+		return
+	}
+
+	if e.plainText {
+		http.Error(w, e.message, e.httpStatus)
+		log.Printf("KYC SOAP Server: Sent plain-text error (Status: %d): %s", e.httpStatus, e.message)
+		return
+	}
+
+	var responseEnvelope interface{}
+	switch e.operation {
+	case "DeleteKYC":
+		responseEnvelope = kycModels.DeleteKYCResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.DeleteKYCResponseBody{
+				DeleteKYCResult: kycModels.DeleteKYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: e.message},
+			},
+		}
+	case "GetKYCStatus":
+		responseEnvelope = kycModels.KYCStatusResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.KYCStatusResponseBody{
+				KYCStatusResult: kycModels.KYCStatusResult{Status: "Error", Message: e.message},
+			},
+		}
+	case "ScoreKYC":
+		responseEnvelope = kycModels.ScoreKYCResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.ScoreKYCResponseBody{
+				ScoreKYCResult: kycModels.ScoreKYCResult{Status: "Error", Message: e.message},
+			},
+		}
+	case "GetKYCHistory":
+		responseEnvelope = kycModels.KYCHistoryResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.KYCHistoryResponseBody{
+				KYCHistoryResult: kycModels.KYCHistoryResult{Status: "Error", Message: e.message},
+			},
+		}
+	case "BatchKYCQuery":
+		responseEnvelope = kycModels.BatchKYCQueryResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.BatchKYCQueryResponseBody{
+				BatchKYCQueryResult: kycModels.BatchKYCQueryResult{Status: "Error", Message: e.message},
+			},
+		}
+	case "SearchKYC":
+		responseEnvelope = kycModels.SearchKYCResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.SearchKYCResponseBody{
+				SearchKYCResult: kycModels.SearchKYCResult{Status: "Error", Message: e.message},
+			},
+		}
+	default:
+		responseEnvelope = kycModels.KYCResponseEnvelope{
+			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+			Body: kycModels.KYCResponseBody{
+				KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: e.message},
+			},
+		}
+	}
+
+	responseBytes, err := xml.MarshalIndent(responseEnvelope, "", "  ")
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to marshal response envelope: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(e.httpStatus)
+	if _, err := w.Write(responseBytes); err != nil {
+		log.Printf("KYC SOAP Server: Failed to write response: %v", err)
+	}
+	log.Printf("KYC SOAP Server: Sent SOAP response (Status: %d):\n%s", e.httpStatus, string(responseBytes))
+}
+
+// writeSOAPFault sends fault with HTTP 500, as SOAP 1.1 requires for every fault
+func writeSOAPFault(w http.ResponseWriter, fault kycModels.Fault) {
+	envelope := kycModels.FaultEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body:         kycModels.FaultBody{Fault: fault},
+	}
+	responseBytes, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to marshal SOAP fault: %v", err)
+		http.Error(w, "Failed to marshal SOAP fault", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	if _, err := w.Write(responseBytes); err != nil {
+		log.Printf("KYC SOAP Server: Failed to write SOAP fault: %v", err)
+	}
+	log.Printf("KYC SOAP Server: Sent SOAP fault %s: %s", fault.FaultCode, fault.FaultString)
+}
+
+// validationFault builds the Client fault that lists every schema violation of a rejected request
+func validationFault(violations []contract.Violation) kycModels.Fault {
+	detail := &kycModels.ValidationFault{}
+	for _, v := range violations {
+		detail.Violations = append(detail.Violations, kycModels.Violation{
+			Kind:     string(v.Kind),
+			Path:     v.Path,
+			Message:  v.Message,
+			Expected: v.Expected,
+		})
+	}
+	return kycModels.Fault{
+		FaultCode:   faultCodeClient,
+		FaultString: "Request does not conform to the KYC schema",
+		Detail:      &kycModels.FaultDetail{ValidationFault: detail},
+	}
+}
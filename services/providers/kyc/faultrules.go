@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+)
+
+// faultRules are the default tenant's fault injection rules managed through /admin/v1/actions and
+// scenarios managed through /admin/v1/scenarios. They are checked before the per-ClientID actions
+// stored in the repository.
+var faultRules = faults.NewEngine()
+
+// requestClientID returns the first ClientID in a SOAP body, or "" when the operation has none
+func requestClientID(content []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "ClientID" {
+			var clientID string
+			if err := decoder.DecodeElement(&clientID, &start); err != nil {
+				return ""
+			}
+			return strings.TrimSpace(clientID)
+		}
+	}
+}
+
+// matchFault returns the fault rule or scenario step that applies to a request and the latency
+// to add. A per-ClientID action set through POST /admin/v1/actions/{clientID} applies to
+// KYCQuery only, as it always has.
+func matchFault(engine *faults.Engine, repo Repository, operation, clientID string) (faults.Rule, time.Duration, bool) {
+	if rule, delay, ok := engine.Match(operation, clientID); ok {
+		return rule, delay, true
+	}
+	if operation != "KYCQuery" || clientID == "" {
+		return faults.Rule{}, 0, false
+	}
+	action, exists := repo.GetAction(clientID)
+	if !exists {
+		return faults.Rule{}, 0, false
+	}
+	rule := faults.Rule{ID: "action-" + clientID, Operation: operation, ClientID: clientID, Fault: faults.Type(action)}
+	return rule, engine.Delay(rule), true
+}
+
+// injectFault waits out the latency of rule and applies its fault. It reports whether the
+// response was already sent; otherwise the handler carries on, writing through the returned
+// faultWriter when the fault mangles the real response.
+func injectFault(w http.ResponseWriter, r *http.Request, operation, clientID string, rule faults.Rule, delay time.Duration) (*faultWriter, bool) {
+	log.Printf("KYC SOAP Server: Applying fault rule '%s' (%s after %v) to %s for ClientID '%s'", rule.ID, rule.Fault, delay, operation, clientID)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			log.Printf("KYC SOAP Server: Client went away during the injected latency of rule '%s'", rule.ID)
+			return nil, true
+		}
+	}
+
+	switch rule.Fault {
+	case faults.TypeDelay:
+		return nil, false
+	case faults.TypeTimeout:
+		writeOperationError(w, settings.Get().ErrorStyle, &operationError{operation: operation, code: ErrorCodeTimeout, message: "Timeout", httpStatus: http.StatusRequestTimeout, plainText: true})
+	case faults.TypeInternalError:
+		writeOperationError(w, settings.Get().ErrorStyle, &operationError{operation: operation, code: ErrorCodeInternalError, message: "Internal Server Error (simulated)", httpStatus: http.StatusInternalServerError, plainText: true})
+	case faults.TypeNotFound:
+		writeOperationError(w, settings.Get().ErrorStyle, &operationError{operation: operation, code: ErrorCodeNotFound, message: fmt.Sprintf("User with ClientID '%s' not found (simulated)", clientID), httpStatus: http.StatusNotFound})
+	case faults.TypeServiceUnavailable:
+		retryAfter := int(math.Ceil(rule.RetryAfterOrDefault().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "Service Unavailable (simulated)", http.StatusServiceUnavailable)
+	case faults.TypeConnectionReset:
+		resetConnection(w)
+	case faults.TypeEmptyBody:
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+	default: // Faults that mangle the real response
+		return &faultWriter{ResponseWriter: w, request: r, rule: rule, header: make(http.Header), status: http.StatusOK}, false
+	}
+	return nil, true
+}
+
+// resetConnection drops the client's connection without a response, with an RST rather than a
+// FIN where the connection is TCP
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		log.Printf("KYC SOAP Server: Cannot take over the connection to reset it, aborting instead: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// faultWriter holds back the real response so that flush can send it mangled by the rule's fault
+type faultWriter struct {
+	http.ResponseWriter
+	request *http.Request
+	rule    faults.Rule
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+}
+
+func (f *faultWriter) Header() http.Header         { return f.header }
+func (f *faultWriter) WriteHeader(status int)      { f.status = status }
+func (f *faultWriter) Write(p []byte) (int, error) { return f.body.Write(p) }
+
+// flush sends the held-back response with the fault applied
+func (f *faultWriter) flush() {
+	body := f.body.Bytes()
+	for key, values := range f.header {
+		f.ResponseWriter.Header()[key] = values
+	}
+	header := f.ResponseWriter.Header()
+	header.Del("Content-Length")
+
+	switch f.rule.Fault {
+	case faults.TypeWrongContentType:
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	case faults.TypeTruncatedXML:
+		body = body[:len(body)/2]
+	case faults.TypeMalformedXML:
+		body = malformXML(body)
+	case faults.TypeSlowDrip:
+		f.drip(body)
+		return
+	}
+	f.ResponseWriter.WriteHeader(f.status)
+	if _, err := f.ResponseWriter.Write(body); err != nil {
+		log.Printf("KYC SOAP Server: Failed to write response: %v", err)
+	}
+}
+
+// drip writes body a chunk at a time, flushing each chunk and pausing in between
+func (f *faultWriter) drip(body []byte) {
+	chunkSize, interval := f.rule.DripOrDefault()
+	controller := http.NewResponseController(f.ResponseWriter)
+	f.ResponseWriter.WriteHeader(f.status)
+	for len(body) > 0 {
+		chunk := body[:min(chunkSize, len(body))]
+		body = body[len(chunk):]
+		if _, err := f.ResponseWriter.Write(chunk); err != nil {
+			log.Printf("KYC SOAP Server: Failed to write response: %v", err)
+			return
+		}
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("KYC SOAP Server: Failed to flush response: %v", err)
+			return
+		}
+		if len(body) == 0 {
+			return
+		}
+		select {
+		case <-time.After(interval):
+		case <-f.request.Context().Done():
+			return
+		}
+	}
+}
+
+// malformXML renames the last end tag of body so it no longer matches its start tag
+func malformXML(body []byte) []byte {
+	i := bytes.LastIndex(body, []byte("</"))
+	if i < 0 {
+		return append(body, "</Malformed>"...)
+	}
+	return append(body[:i:i], "</Malformed>"...)
+}
+
+// adminListFaultRules handles GET /admin/v1/actions
+func adminListFaultRules(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, currentTenant(r).faults.Rules())
+}
+
+// adminAddFaultRule handles POST /admin/v1/actions. A rule with the ID of an active rule replaces it.
+func adminAddFaultRule(w http.ResponseWriter, r *http.Request) {
+	var rule faults.Rule
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	added, err := currentTenant(r).faults.Add(rule)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("KYC SOAP Server: Added fault rule '%s': %s for operation '%s' and ClientID '%s'", added.ID, added.Fault, added.Operation, added.ClientID)
+	writeJSONResponse(w, http.StatusCreated, added)
+}
+
+// adminClearFaultRules handles DELETE /admin/v1/actions. Per-ClientID actions are left alone.
+func adminClearFaultRules(w http.ResponseWriter, r *http.Request) {
+	currentTenant(r).faults.Clear()
+	log.Printf("KYC SOAP Server: Cleared all fault rules")
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All fault rules cleared"})
+}
+
+// adminFaultRule handles GET and DELETE /admin/v1/actions/rules/{id}
+func adminFaultRule(id string, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rule, ok := currentTenant(r).faults.Get(id)
+		if !ok {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Fault rule '%s' not found", id)})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, rule)
+	case http.MethodDelete:
+		if !currentTenant(r).faults.Remove(id) {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Fault rule '%s' not found", id)})
+			return
+		}
+		log.Printf("KYC SOAP Server: Removed fault rule '%s'", id)
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Fault rule '%s' removed", id)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// initFaultRoutes registers the fault rule admin routes. The per-ClientID actions and
+// /admin/v1/actions/rules/{id} share /admin/v1/actions/ and are routed in initAdminRoutes.
+func initFaultRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/actions", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			adminListFaultRules(w, r)
+		case http.MethodPost:
+			adminAddFaultRule(w, r)
+		case http.MethodDelete:
+			adminClearFaultRules(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
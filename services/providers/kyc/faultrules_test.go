@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFaultServer serves the SOAP and admin routes over a real connection, which the connection
+// reset and slow drip faults need
+func newFaultServer(t *testing.T) (*httptest.Server, Repository) {
+	t.Helper()
+	repo := NewInMemoryRepo()
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) })
+	initAdminRoutes(mux, repo)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Cleanup(faultRules.Clear)
+	return server, repo
+}
+
+func addFaultRule(t *testing.T, rule faults.Rule) {
+	t.Helper()
+	_, err := faultRules.Add(rule)
+	require.NoError(t, err)
+}
+
+func postSOAP(t *testing.T, server *httptest.Server, operation, clientID string) (*http.Response, []byte, error) {
+	t.Helper()
+	resp, err := server.Client().Post(server.URL+"/soap", "text/xml", strings.NewReader(createSOAPRequest(operation, clientID, nil)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+func TestRequestClientID(t *testing.T) {
+	assert.Equal(t, "client1", requestClientID([]byte(`<KYCQuery xmlns="http://example.com/kyc"><ClientID> client1 </ClientID></KYCQuery>`)))
+	assert.Equal(t, "client2", requestClientID([]byte(`<CreateKYC><UserData><ClientID>client2</ClientID><Risk>0.1</Risk></UserData></CreateKYC>`)))
+	assert.Equal(t, "", requestClientID([]byte(`<Unknown/>`)))
+}
+
+func TestSOAPHandler_FaultRules(t *testing.T) {
+	server, _ := newFaultServer(t)
+	wellFormed := func(body []byte) error {
+		var envelope struct{}
+		return xml.Unmarshal(body, &envelope)
+	}
+
+	t.Run("Any operation and ClientID pattern", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Operation: "GetKYC*", ClientID: "client*", Fault: faults.TypeNotFound, MaxHits: 1})
+		resp, body, err := postSOAP(t, server, "GetKYCStatus", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Contains(t, string(body), "not found (simulated)")
+
+		resp, _, err = postSOAP(t, server, "GetKYCStatus", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "the rule expired after one hit")
+	})
+
+	t.Run("Service unavailable", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeServiceUnavailable, RetryAfter: faults.Duration(1500 * time.Millisecond), MaxHits: 1})
+		resp, _, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeEmptyBody, MaxHits: 1})
+		resp, body, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, body)
+	})
+
+	t.Run("Wrong content type", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeWrongContentType, MaxHits: 1})
+		resp, body, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.NoError(t, wellFormed(body))
+	})
+
+	t.Run("Truncated and malformed XML", func(t *testing.T) {
+		for _, fault := range []faults.Type{faults.TypeTruncatedXML, faults.TypeMalformedXML} {
+			addFaultRule(t, faults.Rule{Fault: fault, MaxHits: 1})
+			resp, body, err := postSOAP(t, server, "KYCQuery", "client1")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Error(t, wellFormed(body), "%s response must not parse", fault)
+		}
+	})
+
+	t.Run("Slow drip", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeSlowDrip, ChunkSize: 64, Interval: faults.Duration(10 * time.Millisecond), MaxHits: 1})
+		start := time.Now()
+		resp, body, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, wellFormed(body))
+		assert.GreaterOrEqual(t, time.Since(start), time.Duration(len(body)/64)*10*time.Millisecond)
+	})
+
+	t.Run("Connection reset", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeConnectionReset, MaxHits: 1})
+		_, _, err := postSOAP(t, server, "KYCQuery", "client1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Latency before the real response", func(t *testing.T) {
+		addFaultRule(t, faults.Rule{Fault: faults.TypeDelay, Latency: &faults.Latency{Distribution: faults.DistributionFixed, Value: faults.Duration(50 * time.Millisecond)}, MaxHits: 1})
+		start := time.Now()
+		resp, _, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestSOAPHandler_FaultRulesBeforeActions(t *testing.T) {
+	server, repo := newFaultServer(t)
+	require.NoError(t, repo.SetAction("client1", ActionNotFound))
+	addFaultRule(t, faults.Rule{ClientID: "client1", Fault: faults.TypeInternalError, MaxHits: 1})
+
+	resp, _, err := postSOAP(t, server, "KYCQuery", "client1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp, _, err = postSOAP(t, server, "KYCQuery", "client1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "the action applies once the rule is used up")
+
+	resp, _, err = postSOAP(t, server, "GetKYCStatus", "client1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "actions only apply to KYCQuery")
+}
+
+func TestAdminAPI_FaultRules(t *testing.T) {
+	server, repo := newFaultServer(t)
+	snapshots.SetBaseline(repo.Snapshot())
+	do := func(method, path, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+
+	resp, body := do("POST", "/admin/v1/actions", `{"operation":"KYCQuery","clientId":"client*","fault":"Delay","latency":{"distribution":"uniform","min":"10ms","max":"20ms"},"probability":0.5,"ttl":"1m"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var added faults.Rule
+	require.NoError(t, json.Unmarshal(body, &added))
+	assert.NotEmpty(t, added.ID)
+	assert.NotNil(t, added.ExpiresAt)
+
+	resp, _ = do("POST", "/admin/v1/actions", `{"fault":"Explode"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do("POST", "/admin/v1/actions", `{"fault":"Timeout","retries":3}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "unknown fields are refused")
+	resp, _ = do("POST", "/admin/v1/actions/client1", `{"action":"Timeout"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "per-ClientID actions still work")
+
+	resp, body = do("GET", "/admin/v1/actions", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rules []faults.Rule
+	require.NoError(t, json.Unmarshal(body, &rules))
+	require.Len(t, rules, 1)
+	assert.Equal(t, added.ID, rules[0].ID)
+
+	resp, _ = do("GET", "/admin/v1/actions/rules/"+added.ID, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do("DELETE", "/admin/v1/actions/rules/"+added.ID, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do("GET", "/admin/v1/actions/rules/"+added.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	addFaultRule(t, faults.Rule{Fault: faults.TypeEmptyBody})
+	resp, _ = do("DELETE", "/admin/v1/actions", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, faultRules.Rules())
+	_, ok := repo.GetAction("client1")
+	assert.True(t, ok, "clearing the rules leaves per-ClientID actions alone")
+
+	addFaultRule(t, faults.Rule{Fault: faults.TypeEmptyBody})
+	resp, _ = do("POST", "/admin/v1/reset", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, faultRules.Rules(), "a reset removes every fault rule")
+}
+
+func TestInjectFault_ClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	req := httptest.NewRequest("POST", "/soap", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	rule := faults.Rule{ID: "slow", Fault: faults.TypeInternalError}
+
+	writer, sent := injectFault(rec, req, "KYCQuery", "client1", rule, time.Hour)
+	assert.Nil(t, writer)
+	assert.True(t, sent)
+	assert.Zero(t, rec.Body.Len(), "nothing is written once the client has gone")
+}
+
+func TestSOAPHandler_Scenario(t *testing.T) {
+	server, repo := newFaultServer(t)
+	t.Cleanup(faultRules.ClearScenarios)
+	require.NoError(t, repo.SetAction("client1", ActionNotFound))
+	_, err := faultRules.AddScenario(faults.Scenario{Name: "retry", Operation: "KYCQuery", ClientID: "client1", Steps: []faults.Step{
+		{Fault: faults.TypeServiceUnavailable, Times: 2},
+		{},
+	}})
+	require.NoError(t, err)
+
+	var statuses []int
+	for range 4 {
+		resp, _, err := postSOAP(t, server, "KYCQuery", "client1")
+		require.NoError(t, err)
+		statuses = append(statuses, resp.StatusCode)
+	}
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK, http.StatusNotFound}, statuses,
+		"a succeeding step serves the real response rather than the action, which applies once the scenario is exhausted")
+}
+
+func TestAdminAPI_Scenarios(t *testing.T) {
+	server, _ := newFaultServer(t)
+	t.Cleanup(faultRules.ClearScenarios)
+	do := func(method, path, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+
+	resp, body := do("POST", "/admin/v1/scenarios", `{"name":"flaky","clientId":"client1","cycle":true,"steps":[{"fault":"InternalError"},{"times":2}]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	resp, _ = do("POST", "/admin/v1/scenarios", `{"name":"nothing","steps":[]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, _, err := postSOAP(t, server, "KYCQuery", "client1")
+	require.NoError(t, err)
+	resp, body = do("GET", "/admin/v1/scenarios/flaky", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var scenario faults.Scenario
+	require.NoError(t, json.Unmarshal(body, &scenario))
+	assert.Equal(t, 1, scenario.Calls)
+	assert.Equal(t, 1, scenario.Step)
+
+	resp, body = do("POST", "/admin/v1/scenarios/flaky/rewind", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &scenario))
+	assert.Equal(t, 0, scenario.Calls)
+
+	resp, body = do("GET", "/admin/v1/scenarios", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var scenarios []faults.Scenario
+	require.NoError(t, json.Unmarshal(body, &scenarios))
+	assert.Len(t, scenarios, 1)
+
+	resp, _ = do("DELETE", "/admin/v1/scenarios/flaky", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do("GET", "/admin/v1/scenarios/flaky", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = do("GET", "/admin/v1/scenarios/flaky/rewind", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,218 @@
+package faults
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Engine holds the active rules and scenarios in the order they were added. It is safe for
+// concurrent use.
+type Engine struct {
+	mu        sync.Mutex
+	rules     []Rule
+	scenarios []Scenario
+	nextID    int
+	rng       *rand.Rand
+	now       func() time.Time
+}
+
+// NewEngine returns an Engine without rules or scenarios
+func NewEngine() *Engine {
+	return &Engine{rng: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), now: time.Now}
+}
+
+// Add validates rule and activates it, replacing an active rule with the same ID. A rule without
+// an ID is given one. The hit count starts at zero and a TTL counts from now.
+func (e *Engine) Add(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if rule.ID == "" {
+		e.nextID++
+		rule.ID = fmt.Sprintf("rule-%d", e.nextID)
+	}
+	rule.Hits, rule.CreatedAt, rule.ExpiresAt = 0, e.now().UTC(), nil
+	if rule.TTL > 0 {
+		expiresAt := rule.CreatedAt.Add(time.Duration(rule.TTL))
+		rule.ExpiresAt = &expiresAt
+	}
+	if i := e.indexOf(rule.ID); i >= 0 {
+		e.rules[i] = rule
+	} else {
+		e.rules = append(e.rules, rule)
+	}
+	return rule, nil
+}
+
+// Rules returns the active rules in the order they are evaluated
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	return append(make([]Rule, 0, len(e.rules)), e.rules...)
+}
+
+// Get returns the active rule with id
+func (e *Engine) Get(id string) (Rule, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	if i := e.indexOf(id); i >= 0 {
+		return e.rules[i], true
+	}
+	return Rule{}, false
+}
+
+// Remove deactivates the rule with id and reports whether it was active
+func (e *Engine) Remove(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.indexOf(id)
+	if i < 0 {
+		return false
+	}
+	e.rules = slices.Delete(e.rules, i, i+1)
+	return true
+}
+
+// Clear deactivates every rule, leaving the scenarios alone
+func (e *Engine) Clear() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = nil
+}
+
+// Match finds the first rule that matches the request and wins its probability roll, counts the
+// hit and returns the rule with the delay to apply. A rule that used up its MaxHits is removed.
+// Without such a rule the first matching scenario that is not exhausted answers with its next
+// step; a step that serves the real response comes back as a Delay rule.
+func (e *Engine) Match(operation, clientID string) (rule Rule, delay time.Duration, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	for i := range e.rules {
+		candidate := &e.rules[i]
+		if !candidate.Matches(operation, clientID) {
+			continue
+		}
+		if candidate.Probability != nil && e.rng.Float64() >= *candidate.Probability {
+			continue
+		}
+		candidate.Hits++
+		rule = *candidate
+		if rule.MaxHits > 0 && rule.Hits >= rule.MaxHits {
+			e.rules = slices.Delete(e.rules, i, i+1)
+		}
+		return rule, rule.Delay(e.rng), true
+	}
+	for i := range e.scenarios {
+		scenario := &e.scenarios[i]
+		if scenario.Exhausted || !scenario.Matches(operation, clientID) {
+			continue
+		}
+		step := scenario.Step
+		rule = scenario.Steps[step].rule(fmt.Sprintf("%s#%d", scenario.Name, step+1))
+		scenario.Calls++
+		scenario.position()
+		return rule, rule.Delay(e.rng), true
+	}
+	return Rule{}, 0, false
+}
+
+// Delay draws a delay for rule from the engine's random source, for rules that are not managed by it
+func (e *Engine) Delay(rule Rule) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return rule.Delay(e.rng)
+}
+
+// AddScenario validates scenario and activates it from its first step, replacing an active
+// scenario with the same name
+func (e *Engine) AddScenario(scenario Scenario) (Scenario, error) {
+	if err := scenario.Validate(); err != nil {
+		return Scenario{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	scenario.Steps = slices.Clone(scenario.Steps)
+	scenario.Calls = 0
+	scenario.position()
+	if i := e.scenarioIndex(scenario.Name); i >= 0 {
+		e.scenarios[i] = scenario
+	} else {
+		e.scenarios = append(e.scenarios, scenario)
+	}
+	return scenario, nil
+}
+
+// Scenarios returns the active scenarios with their positions, in the order they are evaluated
+func (e *Engine) Scenarios() []Scenario {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append(make([]Scenario, 0, len(e.scenarios)), e.scenarios...)
+}
+
+// Scenario returns the active scenario called name
+func (e *Engine) Scenario(name string) (Scenario, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i := e.scenarioIndex(name); i >= 0 {
+		return e.scenarios[i], true
+	}
+	return Scenario{}, false
+}
+
+// RewindScenario sends the scenario called name back to its first step
+func (e *Engine) RewindScenario(name string) (Scenario, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.scenarioIndex(name)
+	if i < 0 {
+		return Scenario{}, false
+	}
+	e.scenarios[i].Calls = 0
+	e.scenarios[i].position()
+	return e.scenarios[i], true
+}
+
+// RemoveScenario deactivates the scenario called name and reports whether it was active
+func (e *Engine) RemoveScenario(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.scenarioIndex(name)
+	if i < 0 {
+		return false
+	}
+	e.scenarios = slices.Delete(e.scenarios, i, i+1)
+	return true
+}
+
+// ClearScenarios deactivates every scenario
+func (e *Engine) ClearScenarios() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenarios = nil
+}
+
+// expire removes rules whose TTL has run out; the caller holds mu
+func (e *Engine) expire() {
+	now := e.now()
+	e.rules = slices.DeleteFunc(e.rules, func(r Rule) bool {
+		return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+	})
+}
+
+// indexOf returns the position of the rule with id, or -1; the caller holds mu
+func (e *Engine) indexOf(id string) int {
+	return slices.IndexFunc(e.rules, func(r Rule) bool { return r.ID == id })
+}
+
+// scenarioIndex returns the position of the scenario called name, or -1; the caller holds mu
+func (e *Engine) scenarioIndex(name string) int {
+	return slices.IndexFunc(e.scenarios, func(s Scenario) bool { return s.Name == name })
+}
@@ -0,0 +1,233 @@
+package faults
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_Validate(t *testing.T) {
+	half := 0.5
+	tooLikely := 1.5
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{name: "Legacy action", rule: Rule{ClientID: "client1", Fault: TypeTimeout}},
+		{name: "Wildcards and limits", rule: Rule{Operation: "*KYC", ClientID: "client-*", Fault: TypeServiceUnavailable, Probability: &half, MaxHits: 3, TTL: Duration(time.Minute)}},
+		{name: "Unknown fault", rule: Rule{Fault: "Explode"}, wantErr: "unknown fault"},
+		{name: "Bad pattern", rule: Rule{ClientID: "[", Fault: TypeNotFound}, wantErr: "invalid clientId pattern"},
+		{name: "Delay without latency", rule: Rule{Fault: TypeDelay}, wantErr: "needs a latency"},
+		{name: "Bad latency", rule: Rule{Fault: TypeDelay, Latency: &Latency{Distribution: DistributionUniform, Min: 10, Max: 5}}, wantErr: "uniform latency"},
+		{name: "Probability above 1", rule: Rule{Fault: TypeEmptyBody, Probability: &tooLikely}, wantErr: "probability"},
+		{name: "Negative hits", rule: Rule{Fault: TypeEmptyBody, MaxHits: -1}, wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRule_JSON(t *testing.T) {
+	var rule Rule
+	require.NoError(t, json.Unmarshal([]byte(`{"fault":"Delay","latency":{"distribution":"fixed","value":"250ms"},"ttl":"1m"}`), &rule))
+	assert.Equal(t, Duration(250*time.Millisecond), rule.Latency.Value)
+	assert.Equal(t, Duration(time.Minute), rule.TTL)
+	assert.Error(t, json.Unmarshal([]byte(`{"fault":"Delay","ttl":60}`), &rule))
+}
+
+func TestLatency_Sample(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	samples := func(l Latency) []time.Duration {
+		out := make([]time.Duration, 10000)
+		for i := range out {
+			out[i] = l.Sample(rng)
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	fixed := samples(Latency{Distribution: DistributionFixed, Value: Duration(time.Second)})
+	assert.Equal(t, time.Second, fixed[0])
+	assert.Equal(t, time.Second, fixed[len(fixed)-1])
+
+	uniform := samples(Latency{Distribution: DistributionUniform, Min: Duration(100 * time.Millisecond), Max: Duration(200 * time.Millisecond)})
+	assert.GreaterOrEqual(t, uniform[0], 100*time.Millisecond)
+	assert.Less(t, uniform[len(uniform)-1], 200*time.Millisecond)
+
+	normal := samples(Latency{Distribution: DistributionNormal, Mean: Duration(100 * time.Millisecond), StdDev: Duration(80 * time.Millisecond), Max: Duration(250 * time.Millisecond)})
+	assert.Equal(t, time.Duration(0), normal[0], "negative samples are clamped")
+	assert.Equal(t, 250*time.Millisecond, normal[len(normal)-1], "Max caps the samples")
+	assert.InDelta(t, float64(100*time.Millisecond), float64(normal[len(normal)/2]), float64(5*time.Millisecond))
+
+	longTail := samples(Latency{Distribution: DistributionLongTail, Median: Duration(50 * time.Millisecond), P99: Duration(time.Second)})
+	assert.InDelta(t, float64(50*time.Millisecond), float64(longTail[len(longTail)/2]), float64(5*time.Millisecond))
+	assert.InDelta(t, float64(time.Second), float64(longTail[len(longTail)*99/100]), float64(150*time.Millisecond))
+}
+
+func TestEngine_Match(t *testing.T) {
+	engine := NewEngine()
+	query, err := engine.Add(Rule{Operation: "KYCQuery", ClientID: "vip-*", Fault: TypeNotFound, MaxHits: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "rule-1", query.ID)
+	_, err = engine.Add(Rule{ID: "everything", Fault: TypeInternalError})
+	require.NoError(t, err)
+
+	rule, delay, ok := engine.Match("KYCQuery", "vip-1")
+	require.True(t, ok)
+	assert.Equal(t, "rule-1", rule.ID)
+	assert.Equal(t, 1, rule.Hits)
+	assert.Zero(t, delay)
+
+	rule, _, _ = engine.Match("KYCQuery", "vip-2")
+	assert.Equal(t, "rule-1", rule.ID)
+	rule, _, _ = engine.Match("KYCQuery", "vip-3")
+	assert.Equal(t, "everything", rule.ID, "rule-1 is removed after MaxHits")
+	_, ok = engine.Get("rule-1")
+	assert.False(t, ok)
+
+	rule, _, _ = engine.Match("DeleteKYC", "")
+	assert.Equal(t, "everything", rule.ID, "an empty pattern matches any operation and ClientID")
+	assert.Equal(t, 2, rule.Hits)
+
+	assert.True(t, engine.Remove("everything"))
+	assert.False(t, engine.Remove("everything"))
+	_, _, ok = engine.Match("KYCQuery", "vip-1")
+	assert.False(t, ok)
+	assert.Empty(t, engine.Rules())
+}
+
+func TestEngine_AddReplacesRule(t *testing.T) {
+	engine := NewEngine()
+	_, err := engine.Add(Rule{ID: "flaky", Fault: TypeEmptyBody})
+	require.NoError(t, err)
+	engine.Match("KYCQuery", "client1")
+	replaced, err := engine.Add(Rule{ID: "flaky", Fault: TypeTruncatedXML})
+	require.NoError(t, err)
+	assert.Zero(t, replaced.Hits)
+	assert.Equal(t, []Rule{replaced}, engine.Rules())
+
+	_, err = engine.Add(Rule{Fault: "Explode"})
+	assert.Error(t, err)
+	assert.Len(t, engine.Rules(), 1)
+}
+
+func TestEngine_TTL(t *testing.T) {
+	clock := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	engine := NewEngine()
+	engine.now = func() time.Time { return clock }
+	rule, err := engine.Add(Rule{Fault: TypeTimeout, TTL: Duration(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(time.Minute), *rule.ExpiresAt)
+
+	_, delay, ok := engine.Match("KYCQuery", "client1")
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, delay, "a Timeout without latency keeps the legacy 5 seconds")
+
+	clock = clock.Add(time.Minute)
+	_, _, ok = engine.Match("KYCQuery", "client1")
+	assert.False(t, ok)
+	assert.Empty(t, engine.Rules())
+}
+
+func TestEngine_Probability(t *testing.T) {
+	engine := NewEngine()
+	engine.rng = rand.New(rand.NewPCG(3, 4))
+	quarter, never := 0.25, 0.0
+	_, err := engine.Add(Rule{ID: "never", Fault: TypeInternalError, Probability: &never})
+	require.NoError(t, err)
+	_, err = engine.Add(Rule{ID: "sometimes", Fault: TypeServiceUnavailable, Probability: &quarter})
+	require.NoError(t, err)
+
+	hits := 0
+	for range 4000 {
+		if rule, _, ok := engine.Match("KYCQuery", "client1"); ok {
+			assert.Equal(t, "sometimes", rule.ID, "a rule that loses its roll lets the next one try")
+			hits++
+		}
+	}
+	assert.InDelta(t, 1000, hits, 100)
+}
+
+func TestScenario_Validate(t *testing.T) {
+	valid := Scenario{Name: "retry", ClientID: "client1", Steps: []Step{{Fault: TypeServiceUnavailable, Times: 2}, {}}}
+	assert.NoError(t, valid.Validate())
+
+	unnamed := valid
+	unnamed.Name = ""
+	assert.ErrorContains(t, unnamed.Validate(), "needs a name")
+	assert.ErrorContains(t, Scenario{Name: "empty"}.Validate(), "at least one step")
+	assert.ErrorContains(t, Scenario{Name: "bad", Steps: []Step{{}, {Fault: "Explode"}}}.Validate(), "step 2: unknown fault")
+	assert.ErrorContains(t, Scenario{Name: "bad", Steps: []Step{{Times: -1}}}.Validate(), "step 1: times")
+}
+
+func TestEngine_Scenario(t *testing.T) {
+	engine := NewEngine()
+	added, err := engine.AddScenario(Scenario{Name: "retry", Operation: "KYCQuery", ClientID: "client1", Steps: []Step{
+		{Fault: TypeServiceUnavailable, Times: 2},
+		{},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, added.Step)
+
+	answers := func(n int) []Type {
+		var out []Type
+		for range n {
+			rule, _, ok := engine.Match("KYCQuery", "client1")
+			if !ok {
+				out = append(out, "")
+				continue
+			}
+			out = append(out, rule.Fault)
+		}
+		return out
+	}
+	assert.Equal(t, []Type{TypeServiceUnavailable, TypeServiceUnavailable, TypeDelay, ""}, answers(4))
+	scenario, _ := engine.Scenario("retry")
+	assert.Equal(t, 3, scenario.Calls)
+	assert.True(t, scenario.Exhausted)
+
+	_, _, ok := engine.Match("KYCQuery", "client2")
+	assert.False(t, ok, "other ClientIDs are not part of the scenario")
+
+	scenario, ok = engine.RewindScenario("retry")
+	require.True(t, ok)
+	assert.False(t, scenario.Exhausted)
+	assert.Equal(t, []Type{TypeServiceUnavailable}, answers(1))
+	scenario, _ = engine.Scenario("retry")
+	assert.Equal(t, 0, scenario.Step, "the first step answers two calls")
+
+	assert.True(t, engine.RemoveScenario("retry"))
+	assert.Empty(t, engine.Scenarios())
+}
+
+func TestEngine_ScenarioCycles(t *testing.T) {
+	engine := NewEngine()
+	_, err := engine.AddScenario(Scenario{Name: "flaky", Cycle: true, Steps: []Step{{}, {Fault: TypeInternalError}}})
+	require.NoError(t, err)
+	_, err = engine.Add(Rule{ClientID: "client9", Fault: TypeNotFound, MaxHits: 1})
+	require.NoError(t, err)
+
+	rule, _, _ := engine.Match("DeleteKYC", "client9")
+	assert.Equal(t, TypeNotFound, rule.Fault, "rules are checked before scenarios")
+	var answers []Type
+	for range 5 {
+		rule, _, _ := engine.Match("DeleteKYC", "client9")
+		answers = append(answers, rule.Fault)
+	}
+	assert.Equal(t, []Type{TypeDelay, TypeInternalError, TypeDelay, TypeInternalError, TypeDelay}, answers)
+	rule, _, _ = engine.Match("KYCQuery", "")
+	assert.Equal(t, "flaky#2", rule.ID)
+}
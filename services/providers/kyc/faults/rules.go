@@ -0,0 +1,225 @@
+// Package faults decides which injected fault, if any, the KYC provider answers a request with.
+// Rules match an operation and a ClientID, may fire only with some probability, add latency
+// drawn from a distribution and expire after a number of hits or a time to live.
+package faults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"time"
+)
+
+// Type is the fault a rule injects
+type Type string
+
+const (
+	TypeDelay              Type = "Delay"              // Only the latency; the request is then served normally
+	TypeTimeout            Type = "Timeout"            // 408 after the latency, 5 seconds by default
+	TypeInternalError      Type = "InternalError"      // Plain-text 500
+	TypeNotFound           Type = "NotFound"           // NOT_FOUND operation error
+	TypeServiceUnavailable Type = "ServiceUnavailable" // Plain-text 503 with a Retry-After header
+	TypeConnectionReset    Type = "ConnectionReset"    // The TCP connection is reset without a response
+	TypeEmptyBody          Type = "EmptyBody"          // 200 with an XML content type and no body
+	TypeWrongContentType   Type = "WrongContentType"   // The real response labelled text/html
+	TypeTruncatedXML       Type = "TruncatedXML"       // The first half of the real response
+	TypeMalformedXML       Type = "MalformedXML"       // The real response with a mismatched end tag
+	TypeSlowDrip           Type = "SlowDrip"           // The real response written a chunk at a time
+)
+
+// Types lists every valid Type
+var Types = []Type{
+	TypeDelay, TypeTimeout, TypeInternalError, TypeNotFound, TypeServiceUnavailable, TypeConnectionReset,
+	TypeEmptyBody, TypeWrongContentType, TypeTruncatedXML, TypeMalformedXML, TypeSlowDrip,
+}
+
+// Duration is a time.Duration written in JSON as a Go duration string such as "250ms"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Distribution is the shape of a Latency
+type Distribution string
+
+const (
+	DistributionFixed    Distribution = "fixed"    // Always Value
+	DistributionUniform  Distribution = "uniform"  // Evenly spread between Min and Max
+	DistributionNormal   Distribution = "normal"   // Around Mean with StdDev, never negative
+	DistributionLongTail Distribution = "longtail" // Log-normal: half the samples below Median, 1% above P99
+)
+
+// Latency is the delay a rule adds before its fault. Which fields apply depends on Distribution;
+// Max also caps the normal and long-tail distributions when set.
+type Latency struct {
+	Distribution Distribution `json:"distribution"`
+	Value        Duration     `json:"value,omitempty"`
+	Min          Duration     `json:"min,omitempty"`
+	Max          Duration     `json:"max,omitempty"`
+	Mean         Duration     `json:"mean,omitempty"`
+	StdDev       Duration     `json:"stddev,omitempty"`
+	Median       Duration     `json:"median,omitempty"`
+	P99          Duration     `json:"p99,omitempty"`
+}
+
+// z99 is the standard normal quantile of the 99th percentile
+const z99 = 2.3263478740408408
+
+func (l Latency) validate() error {
+	switch l.Distribution {
+	case DistributionFixed:
+		if l.Value <= 0 {
+			return errors.New("fixed latency needs a positive value")
+		}
+	case DistributionUniform:
+		if l.Min < 0 || l.Max <= l.Min {
+			return errors.New("uniform latency needs 0 <= min < max")
+		}
+	case DistributionNormal:
+		if l.Mean <= 0 || l.StdDev < 0 {
+			return errors.New("normal latency needs a positive mean and a non-negative stddev")
+		}
+	case DistributionLongTail:
+		if l.Median <= 0 || l.P99 <= l.Median {
+			return errors.New("long-tail latency needs 0 < median < p99")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q (want fixed, uniform, normal or longtail)", l.Distribution)
+	}
+	return nil
+}
+
+// Sample draws a delay from the distribution
+func (l Latency) Sample(rng *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case DistributionFixed:
+		return time.Duration(l.Value)
+	case DistributionUniform:
+		return time.Duration(l.Min) + time.Duration(rng.Int64N(int64(l.Max-l.Min)))
+	case DistributionNormal:
+		d = float64(l.Mean) + rng.NormFloat64()*float64(l.StdDev)
+	case DistributionLongTail:
+		mu := math.Log(float64(l.Median))
+		sigma := (math.Log(float64(l.P99)) - mu) / z99
+		d = math.Exp(mu + sigma*rng.NormFloat64())
+	}
+	if d < 0 {
+		d = 0
+	}
+	if l.Max > 0 && d > float64(l.Max) {
+		d = float64(l.Max)
+	}
+	return time.Duration(d)
+}
+
+// Rule injects Fault into the requests whose operation and ClientID match its patterns
+type Rule struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation,omitempty"` // SOAP operation or path.Match pattern; empty or "*" matches every operation
+	ClientID  string `json:"clientId,omitempty"`  // ClientID or path.Match pattern; empty or "*" matches every request
+	Fault     Type   `json:"fault"`
+
+	Latency     *Latency `json:"latency,omitempty"`
+	Probability *float64 `json:"probability,omitempty"` // Chance from 0 to 1 that a matching request is hit; always when absent
+	MaxHits     int      `json:"maxHits,omitempty"`     // The rule is removed after this many hits; 0 for no limit
+	TTL         Duration `json:"ttl,omitempty"`         // The rule is removed this long after it was added; 0 for no limit
+
+	RetryAfter Duration `json:"retryAfter,omitempty"` // ServiceUnavailable: Retry-After header, 1s by default
+	ChunkSize  int      `json:"chunkSize,omitempty"`  // SlowDrip: bytes per write, 16 by default
+	Interval   Duration `json:"interval,omitempty"`   // SlowDrip: pause between writes, 100ms by default
+
+	// Set by the Engine
+	Hits      int        `json:"hits"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Validate checks the rule for unknown faults, bad patterns and impossible limits
+func (r Rule) Validate() error {
+	if !slices.Contains(Types, r.Fault) {
+		return fmt.Errorf("unknown fault %q (want one of %v)", r.Fault, Types)
+	}
+	if _, err := path.Match(r.Operation, ""); err != nil {
+		return fmt.Errorf("invalid operation pattern %q: %w", r.Operation, err)
+	}
+	if _, err := path.Match(r.ClientID, ""); err != nil {
+		return fmt.Errorf("invalid clientId pattern %q: %w", r.ClientID, err)
+	}
+	if r.Latency != nil {
+		if err := r.Latency.validate(); err != nil {
+			return err
+		}
+	} else if r.Fault == TypeDelay {
+		return errors.New("a Delay fault needs a latency")
+	}
+	if r.Probability != nil && (*r.Probability < 0 || *r.Probability > 1) {
+		return fmt.Errorf("probability %v is not between 0 and 1", *r.Probability)
+	}
+	if r.MaxHits < 0 || r.TTL < 0 || r.RetryAfter < 0 || r.ChunkSize < 0 || r.Interval < 0 {
+		return errors.New("maxHits, ttl, retryAfter, chunkSize and interval must not be negative")
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a request for operation about clientID
+func (r Rule) Matches(operation, clientID string) bool {
+	return matchPattern(r.Operation, operation) && matchPattern(r.ClientID, clientID)
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value) // Patterns are validated when the rule is added
+	return matched
+}
+
+// Delay draws the latency of one hit; 5 seconds for a Timeout without a latency
+func (r Rule) Delay(rng *rand.Rand) time.Duration {
+	if r.Latency == nil {
+		if r.Fault == TypeTimeout {
+			return 5 * time.Second
+		}
+		return 0
+	}
+	return r.Latency.Sample(rng)
+}
+
+// RetryAfterOrDefault is the Retry-After of a ServiceUnavailable fault
+func (r Rule) RetryAfterOrDefault() time.Duration {
+	if r.RetryAfter > 0 {
+		return time.Duration(r.RetryAfter)
+	}
+	return time.Second
+}
+
+// DripOrDefault is the chunk size and pause of a SlowDrip fault
+func (r Rule) DripOrDefault() (chunkSize int, interval time.Duration) {
+	chunkSize, interval = r.ChunkSize, time.Duration(r.Interval)
+	if chunkSize == 0 {
+		chunkSize = 16
+	}
+	if interval == 0 {
+		interval = 100 * time.Millisecond
+	}
+	return chunkSize, interval
+}
@@ -0,0 +1,115 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Step is one response of a Scenario, given Times calls in a row. A step without a Fault serves
+// the real response, after its latency if it has one.
+type Step struct {
+	Fault   Type     `json:"fault,omitempty"`
+	Latency *Latency `json:"latency,omitempty"`
+	Times   int      `json:"times,omitempty"` // Calls this step answers; 1 when absent
+
+	RetryAfter Duration `json:"retryAfter,omitempty"`
+	ChunkSize  int      `json:"chunkSize,omitempty"`
+	Interval   Duration `json:"interval,omitempty"`
+}
+
+// times is the number of calls the step answers
+func (s Step) times() int {
+	return max(s.Times, 1)
+}
+
+// rule is the rule that answers one call with the step
+func (s Step) rule(id string) Rule {
+	fault := s.Fault
+	if fault == "" {
+		fault = TypeDelay // Only the latency, possibly none, then the real response
+	}
+	return Rule{ID: id, Fault: fault, Latency: s.Latency, RetryAfter: s.RetryAfter, ChunkSize: s.ChunkSize, Interval: s.Interval}
+}
+
+func (s Step) validate() error {
+	if s.Times < 0 {
+		return errors.New("times must not be negative")
+	}
+	if s.Fault == "" {
+		if s.Latency != nil {
+			return s.Latency.validate()
+		}
+		return nil
+	}
+	return s.rule("").Validate()
+}
+
+// Scenario answers the calls it matches with its steps in order, one call at a time, so that for
+// example the first two queries for a ClientID fail and the third succeeds. Once every step was
+// used the scenario starts over if Cycle is set and stops matching otherwise.
+type Scenario struct {
+	Name      string `json:"name"`
+	Operation string `json:"operation,omitempty"` // SOAP operation or path.Match pattern; empty or "*" matches every operation
+	ClientID  string `json:"clientId,omitempty"`  // ClientID or path.Match pattern; empty or "*" matches every request
+	Steps     []Step `json:"steps"`
+	Cycle     bool   `json:"cycle,omitempty"`
+
+	// Set by the Engine
+	Calls     int  `json:"calls"`     // Calls answered so far
+	Step      int  `json:"step"`      // Index of the step that answers the next call
+	Exhausted bool `json:"exhausted"` // Every step was used and the scenario does not cycle
+}
+
+// Validate checks the scenario for a missing name, bad patterns and invalid steps
+func (s Scenario) Validate() error {
+	if s.Name == "" {
+		return errors.New("a scenario needs a name")
+	}
+	if _, err := path.Match(s.Operation, ""); err != nil {
+		return fmt.Errorf("invalid operation pattern %q: %w", s.Operation, err)
+	}
+	if _, err := path.Match(s.ClientID, ""); err != nil {
+		return fmt.Errorf("invalid clientId pattern %q: %w", s.ClientID, err)
+	}
+	if len(s.Steps) == 0 {
+		return errors.New("a scenario needs at least one step")
+	}
+	for i, step := range s.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the scenario applies to a request for operation about clientID
+func (s Scenario) Matches(operation, clientID string) bool {
+	return matchPattern(s.Operation, operation) && matchPattern(s.ClientID, clientID)
+}
+
+// length is the number of calls one pass through the steps answers
+func (s Scenario) length() int {
+	n := 0
+	for _, step := range s.Steps {
+		n += step.times()
+	}
+	return n
+}
+
+// position sets Step and Exhausted from Calls
+func (s *Scenario) position() {
+	call := s.Calls
+	if s.Cycle {
+		call %= s.length()
+	}
+	s.Exhausted = call >= s.length()
+	s.Step = len(s.Steps)
+	for i, step := range s.Steps {
+		if call < step.times() {
+			s.Step = i
+			break
+		}
+		call -= step.times()
+	}
+}
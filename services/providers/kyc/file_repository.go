@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"kafka-soap-e2e-test/services/providers/kyc/cdc"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// compactAfter is the number of log entries past which a FileRepo folds its log into a new
+// snapshot; it grows with the stored state so that rewriting the snapshot stays rare
+var compactAfter = 1000
+
+// FileRepo is a Repository persisted to a JSON snapshot file and an append-only log next to it.
+// Every write appends one fsynced line holding only what it changed, so its cost does not grow
+// with the number of records or the length of the audit log. Once the log holds more entries than
+// the state holds records and events, it is folded into a new snapshot written atomically (temp
+// file, fsync, rename). Log entries already in the snapshot are skipped when the log is replayed,
+// so a crash at any point leaves either the previous or the new state, never a partial one.
+type FileRepo struct {
+	mu      sync.RWMutex
+	path    string
+	users   map[string]kyc.Record
+	actions map[string]Action
+	events  []kyc.AuditEvent
+	outbox  []cdc.Event // Unpublished change events, written with the changes they announce
+	capture *changeCapture
+	writes  int64 // Number of the last write stored
+	logged  int   // Entries in the log
+	index   recordIndex
+}
+
+// fileSnapshot is the content of a FileRepo's snapshot file
+type fileSnapshot struct {
+	Snapshot
+	Writes int64 `json:"writes,omitempty"` // Number of the last write folded into it
+}
+
+// fileChange is a line of a FileRepo's log: what a single write changed
+type fileChange struct {
+	Write   int64                  `json:"write"`
+	Users   map[string]*kyc.Record `json:"users,omitempty"`   // A nil record is deleted
+	Actions map[string]*Action     `json:"actions,omitempty"` // A nil action is cleared
+	Events  []kyc.AuditEvent       `json:"events,omitempty"`  // Appended to the audit log
+	Outbox  []cdc.Event            `json:"outbox,omitempty"`  // Appended to the outbox
+	Removed int                    `json:"removed,omitempty"` // Number of the oldest outbox events dropped
+}
+
+// OpenFileRepo loads the repository stored at path and replays its log, starting empty if
+// neither exists yet
+func OpenFileRepo(path string) (*FileRepo, error) {
+	repo := &FileRepo{
+		path:    path,
+		users:   make(map[string]kyc.Record),
+		actions: make(map[string]Action),
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		var snapshot fileSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		repo.load(snapshot.Snapshot)
+		repo.writes = snapshot.Writes
+	}
+	if err := repo.replay(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// logPath is where the log of the repository at r.path is kept
+func (r *FileRepo) logPath() string {
+	return r.path + ".log"
+}
+
+// load replaces the in-memory state with a copy of snapshot
+func (r *FileRepo) load(snapshot Snapshot) {
+	snapshot = snapshot.clone()
+	r.users, r.actions, r.events, r.outbox = snapshot.Users, snapshot.Actions, snapshot.Events, snapshot.Outbox
+	if r.users == nil {
+		r.users = make(map[string]kyc.Record)
+	}
+	r.index.changed()
+}
+
+// replay applies the log entries written after the snapshot and folds them into a new one.
+// An incomplete last line is a write cut short by a crash, which was never acknowledged.
+func (r *FileRepo) replay() error {
+	data, err := os.ReadFile(r.logPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", r.logPath(), err)
+	}
+	for line := range bytes.Lines(data) {
+		var change fileChange
+		if err := json.Unmarshal(line, &change); err != nil {
+			if !bytes.HasSuffix(line, []byte("\n")) {
+				log.Printf("KYC SOAP Server: Ignoring the incomplete last entry of %s", r.logPath())
+				break
+			}
+			return fmt.Errorf("failed to parse %s: %w", r.logPath(), err)
+		}
+		if change.Write > r.writes {
+			r.apply(change)
+		}
+	}
+	return r.compact()
+}
+
+// apply stores change in the in-memory state; callers must hold r.mu
+func (r *FileRepo) apply(change fileChange) {
+	storeChanges(r.users, change.Users)
+	if len(change.Users) > 0 {
+		r.index.changed()
+	}
+	for clientID, action := range change.Actions {
+		if action == nil {
+			delete(r.actions, clientID)
+		} else {
+			r.actions[clientID] = *action
+		}
+	}
+	r.events = append(r.events, change.Events...)
+	r.outbox = append(slices.Delete(r.outbox, 0, min(change.Removed, len(r.outbox))), change.Outbox...)
+	r.writes = change.Write
+}
+
+// write appends change to the log and only then applies it, folding the log into a new snapshot
+// once it has grown past compactAfter; callers must hold r.mu
+func (r *FileRepo) write(change fileChange) error {
+	change.Write = r.writes + 1
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to encode repository change: %w", err)
+	}
+
+	file, err := os.OpenFile(r.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.logPath(), err)
+	}
+	info, err := file.Stat()
+	if err == nil {
+		if _, err = file.Write(append(data, '\n')); err == nil {
+			err = file.Sync()
+		} else {
+			file.Truncate(info.Size()) // Drop a partial line so later entries stay readable
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", r.logPath(), err)
+	}
+	if r.logged == 0 {
+		syncDir(filepath.Dir(r.path)) // The log may have just been created
+	}
+
+	r.apply(change)
+	r.logged++
+	if r.logged > max(compactAfter, len(r.users)+len(r.events)) {
+		if err := r.compact(); err != nil {
+			log.Printf("KYC SOAP Server: Failed to compact %s, its log keeps growing: %v", r.path, err)
+		}
+	}
+	return nil
+}
+
+// compact writes the current state to a new snapshot and removes the log; callers must hold r.mu
+func (r *FileRepo) compact() error {
+	if err := r.persist(fileSnapshot{Snapshot: r.state(), Writes: r.writes}); err != nil {
+		return err
+	}
+	if err := os.Remove(r.logPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", r.logPath(), err)
+	}
+	r.logged = 0
+	return nil
+}
+
+// state returns the current state, outbox included, without copying it; callers must hold r.mu
+func (r *FileRepo) state() Snapshot {
+	return Snapshot{Users: r.users, Actions: r.actions, Events: r.events, Outbox: r.outbox}
+}
+
+// persist writes snapshot to a temporary file next to r.path and renames it into place
+func (r *FileRepo) persist(snapshot fileSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode repository: %w", err)
+	}
+
+	dir := filepath.Dir(r.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", r.path, err)
+	}
+	syncDir(dir) // So the rename itself survives a crash
+	return nil
+}
+
+// syncDir flushes the entries of dir, best effort
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Create adds new UserData to the repository
+func (r *FileRepo) Create(userData kyc.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.users[userData.ClientID]; exists {
+		return fmt.Errorf("user with ClientID '%s' already exists", userData.ClientID)
+	}
+	return r.write(fileChange{Users: map[string]*kyc.Record{userData.ClientID: &userData}})
+}
+
+// Read retrieves UserData by ClientID
+func (r *FileRepo) Read(clientID string) (kyc.Record, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if user, exists := r.users[clientID]; exists {
+		return user, nil
+	}
+	return kyc.Record{}, fmt.Errorf("user with ClientID '%s' not found", clientID)
+}
+
+// Update modifies existing UserData in the repository
+func (r *FileRepo) Update(userData kyc.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.users[userData.ClientID]; !exists {
+		return fmt.Errorf("user with ClientID '%s' not found", userData.ClientID)
+	}
+	return r.write(fileChange{Users: map[string]*kyc.Record{userData.ClientID: &userData}})
+}
+
+// Delete removes UserData by ClientID
+func (r *FileRepo) Delete(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.users[clientID]; !exists {
+		return fmt.Errorf("user with ClientID '%s' not found", clientID)
+	}
+	return r.write(fileChange{Users: map[string]*kyc.Record{clientID: nil}})
+}
+
+// List returns every stored UserData in no particular order
+func (r *FileRepo) List() []kyc.Record {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	users := make([]kyc.Record, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// Records yields every stored UserData ordered by ClientID
+func (r *FileRepo) Records() iter.Seq[kyc.Record] {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return readEach(slices.Sorted(maps.Keys(r.users)), r.Read)
+}
+
+// Page returns the page of UserData q selects
+func (r *FileRepo) Page(q userQuery) userPage {
+	return r.index.page(q, r.List)
+}
+
+// SetAction sets a simulated action for a given ClientID
+func (r *FileRepo) SetAction(clientID string, action Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.write(fileChange{Actions: map[string]*Action{clientID: &action}})
+}
+
+// GetAction retrieves the simulated action for a given ClientID
+func (r *FileRepo) GetAction(clientID string) (Action, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	action, exists := r.actions[clientID]
+	return action, exists
+}
+
+// ClearAction removes the simulated action for a given ClientID
+func (r *FileRepo) ClearAction(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.write(fileChange{Actions: map[string]*Action{clientID: nil}})
+}
+
+// Apply stores the changes of events and appends them to the audit log in a single log entry,
+// or applies nothing if any does not match
+func (r *FileRepo) Apply(events ...kyc.AuditEvent) ([]kyc.AuditEvent, error) {
+	r.mu.Lock()
+	changed, err := checkChanges(r.users, events)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	applied := sequenceEvents(r.events, events)
+	err = r.write(fileChange{Users: changed, Events: applied, Outbox: r.capture.changes(applied)})
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	r.notify()
+	return applied, nil
+}
+
+// notify reports a write that added change events
+func (r *FileRepo) notify() {
+	r.mu.RLock()
+	capture := r.capture
+	r.mu.RUnlock()
+	capture.notify()
+}
+
+// History returns the audit events of clientID, oldest first
+func (r *FileRepo) History(clientID string) []kyc.AuditEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return historyOf(r.events, clientID)
+}
+
+// Snapshot returns a copy of the current users, actions and audit log
+func (r *FileRepo) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	snapshot := r.state().clone()
+	snapshot.Outbox = nil
+	return snapshot
+}
+
+// Restore replaces all users, actions and the audit log with those of snapshot by writing them
+// as a new snapshot, in a single atomic write
+func (r *FileRepo) Restore(snapshot Snapshot) error {
+	r.mu.Lock()
+	next := fileSnapshot{Snapshot: snapshot.clone(), Writes: r.writes + 1}
+	next.Outbox = append(slices.Clone(r.outbox), r.capture.reset(next.Events)...)
+	if err := r.persist(next); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	r.load(next.Snapshot)
+	r.writes = next.Writes
+	if err := os.Remove(r.logPath()); err != nil && !os.IsNotExist(err) {
+		log.Printf("KYC SOAP Server: Failed to remove %s, its entries are in the snapshot: %v", r.logPath(), err)
+	} else {
+		r.logged = 0
+	}
+	r.mu.Unlock()
+	r.notify()
+	return nil
+}
+
+// CaptureChanges adds the change events of every later Apply and Restore to the outbox
+func (r *FileRepo) CaptureChanges(tenant string, added func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.capture = &changeCapture{tenant: tenant, added: added}
+}
+
+// PendingChanges returns up to n of the oldest change events in the outbox
+func (r *FileRepo) PendingChanges(n int) []cdc.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return oldestChanges(r.outbox, n)
+}
+
+// RemoveChanges drops the n oldest change events from the outbox
+func (r *FileRepo) RemoveChanges(n int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n = min(n, len(r.outbox)); n <= 0 {
+		return nil
+	}
+	return r.write(fileChange{Removed: n})
+}
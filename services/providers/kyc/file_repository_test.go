@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+func TestFileRepo_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store", "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Empty(t, repo.List())
+
+	user := kyc.Record{ClientID: "client1", Risk: 0.5, ReviewStatus: kyc.ReviewApproved}
+	require.NoError(t, repo.Create(user))
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client2", Risk: 0.1}))
+	require.NoError(t, repo.Delete("client2"))
+	user.ReviewStatus = kyc.ReviewRejected
+	require.NoError(t, repo.Update(user))
+	require.NoError(t, repo.SetAction("client1", ActionTimeout))
+	require.NoError(t, repo.SetAction("client3", ActionNotFound))
+	require.NoError(t, repo.ClearAction("client3"))
+	previous := user
+	user.Risk = 0.6
+	applied, err := repo.Apply(kyc.AuditEvent{ClientID: "client1", Action: kyc.AuditUpdate, Before: &previous, After: &user})
+	require.NoError(t, err)
+	event := applied[0]
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, []kyc.Record{user}, reopened.List())
+	action, ok := reopened.GetAction("client1")
+	assert.True(t, ok)
+	assert.Equal(t, ActionTimeout, action)
+	_, ok = reopened.GetAction("client3")
+	assert.False(t, ok)
+	assert.Equal(t, []kyc.AuditEvent{event}, reopened.History("client1"))
+
+	// Sequence numbers continue after a reopen
+	next, err := reopened.Apply(kyc.AuditEvent{ClientID: "client1", Action: kyc.AuditDelete, Before: &user})
+	require.NoError(t, err)
+	assert.Equal(t, event.Sequence+1, next[0].Sequence)
+	assert.Empty(t, reopened.List())
+
+	// Only the snapshot and its log, no temporary files, are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"kyc.json", "kyc.json.log"}, names)
+}
+
+func TestFileRepo_AppendsWritesToLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.5}))
+	require.NoError(t, repo.Restore(repo.Snapshot())) // Writes a snapshot
+	snapshot, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Writes leave the snapshot alone and add one line each to the log
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client2", Risk: 0.7}))
+	require.NoError(t, repo.SetAction("client2", ActionTimeout))
+	require.NoError(t, repo.Delete("client1"))
+	unchanged, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, unchanged)
+	logged, err := os.ReadFile(path + ".log")
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(logged, []byte("\n")))
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Snapshot(), reopened.Snapshot())
+	assert.NoFileExists(t, path+".log", "the log is folded into the snapshot on open")
+}
+
+func TestFileRepo_CompactsLog(t *testing.T) {
+	defer func(previous int) { compactAfter = previous }(compactAfter)
+	compactAfter = 3
+
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	for _, clientID := range []string{"client1", "client2", "client3"} {
+		require.NoError(t, repo.Create(kyc.Record{ClientID: clientID}))
+	}
+	assert.FileExists(t, path+".log")
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, repo.SetAction("client1", ActionTimeout))
+	assert.NoFileExists(t, path+".log")
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Snapshot(), reopened.Snapshot())
+}
+
+func TestFileRepo_ReplaySkipsCompactedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	user := kyc.Record{ClientID: "client1", Risk: 0.5}
+	_, err = repo.Apply(kyc.AuditEvent{ClientID: "client1", Action: kyc.AuditCreate, After: &user})
+	require.NoError(t, err)
+	logged, err := os.ReadFile(path + ".log")
+	require.NoError(t, err)
+
+	// A crash between writing the snapshot and removing the log leaves entries already in the snapshot
+	_, err = OpenFileRepo(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".log", logged, 0o644))
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Snapshot(), reopened.Snapshot())
+	assert.Len(t, reopened.History("client1"), 1)
+}
+
+func TestFileRepo_IgnoresIncompleteLastEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1"}))
+
+	// A crash in the middle of an append leaves a partial line
+	file, err := os.OpenFile(path+".log", os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"write":2,"users":{"cli`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, repo.List(), reopened.List())
+
+	// A damaged entry followed by others is corruption, not a torn write
+	require.NoError(t, os.WriteFile(path+".log", []byte("{not json\n{}\n"), 0o644))
+	_, err = OpenFileRepo(path)
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestFileRepo_Errors(t *testing.T) {
+	repo, err := OpenFileRepo(filepath.Join(t.TempDir(), "kyc.json"))
+	require.NoError(t, err)
+	user := kyc.Record{ClientID: "client1", Risk: 0.5}
+	require.NoError(t, repo.Create(user))
+
+	err = repo.Create(user)
+	assert.ErrorContains(t, err, "already exists")
+	_, err = repo.Read("missing")
+	assert.ErrorContains(t, err, "not found")
+	assert.ErrorContains(t, repo.Update(kyc.Record{ClientID: "missing"}), "not found")
+	assert.ErrorContains(t, repo.Delete("missing"), "not found")
+}
+
+func TestFileRepo_FailedWriteKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := OpenFileRepo(filepath.Join(dir, "kyc.json"))
+	require.NoError(t, err)
+	user := kyc.Record{ClientID: "client1", Risk: 0.5}
+	require.NoError(t, repo.Create(user))
+
+	// Point the store at a directory that does not exist so the log cannot be written
+	repo.path = filepath.Join(dir, "gone", "kyc.json")
+	assert.Error(t, repo.Create(kyc.Record{ClientID: "client2"}))
+	assert.Error(t, repo.Delete("client1"))
+
+	assert.Equal(t, []kyc.Record{user}, repo.List())
+}
+
+func TestFileRepo_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o644))
+	_, err := OpenFileRepo(path)
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestNewRepository(t *testing.T) {
+	repo, err := newRepository("", "")
+	require.NoError(t, err)
+	assert.IsType(t, &InMemoryRepo{}, repo)
+
+	repo, err = newRepository(StoreFile, filepath.Join(t.TempDir(), "kyc.json"))
+	require.NoError(t, err)
+	assert.IsType(t, &FileRepo{}, repo)
+
+	_, err = newRepository("bolt", "")
+	assert.ErrorContains(t, err, "unknown repository backend")
+}
+
+func TestFileRepo_Restore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kyc.json")
+	repo, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.5}))
+	snapshot := repo.Snapshot()
+
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client2", Risk: 0.7}))
+	require.NoError(t, repo.SetAction("client2", ActionInternalError))
+	require.NoError(t, repo.Restore(snapshot))
+
+	reopened, err := OpenFileRepo(path)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, reopened.Snapshot())
+}
+
+func TestFileRepo_FailedWriteStoresNothing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	repo, err := OpenFileRepo(filepath.Join(dir, "kyc.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(dir))
+
+	_, err = createRecord(repo, fixtureAuditContext("test"), kyc.Record{ClientID: "client1", Risk: 0.5}, false)
+	assert.ErrorContains(t, err, "failed to open")
+	assert.Empty(t, repo.List())
+	assert.Empty(t, repo.History("client1"), "the audit event is written with the record or not at all")
+}
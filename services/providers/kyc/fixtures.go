@@ -0,0 +1,370 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// fixtureFile is what a fixture file held when it was last applied
+type fixtureFile struct {
+	clientID string
+	digest   [sha256.Size]byte
+}
+
+// fixtureIssue names a fixture file or record that a sync did not apply, and why
+type fixtureIssue struct {
+	File     string `json:"file"`
+	ClientID string `json:"clientId,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// fixtureReport is the outcome of a fixture sync
+type fixtureReport struct {
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Deleted int            `json:"deleted"`
+	Skipped []fixtureIssue `json:"skipped,omitempty"` // Records owned by runtime changes
+	Errors  []fixtureIssue `json:"errors,omitempty"`  // Unreadable or invalid files
+}
+
+// fixtureLoader keeps the repository in step with the JSON fixtures in a directory, one record
+// per file. A record is owned by the fixtures while the last change in its audit trail came from
+// a fixture file; records created or changed at runtime are left alone unless a sync overwrites.
+type fixtureLoader struct {
+	repo Repository
+	dir  string
+
+	mu      sync.Mutex // Serializes syncs
+	files   map[string]fixtureFile
+	synced  bool // Whether the initial sync has run
+	watcher *fsnotify.Watcher
+}
+
+// fixtures is the loader of the provider's fixture directory, nil until main sets it up
+var fixtures atomic.Pointer[fixtureLoader]
+
+func newFixtureLoader(repo Repository, dir string) *fixtureLoader {
+	return &fixtureLoader{repo: repo, dir: dir, files: make(map[string]fixtureFile)}
+}
+
+// fixtureVersion returns the version of the record of clientID as a fixture file last left it, and
+// whether the fixtures still own the record. Passed as the expected version of a write, it makes
+// the write fail if the record is changed at runtime after the ownership check.
+func fixtureVersion(repo Repository, clientID string) (int64, bool) {
+	history := repo.History(clientID)
+	if len(history) == 0 {
+		return 0, false
+	}
+	last := history[len(history)-1]
+	if last.Source != kyc.SourceFixture || last.After == nil {
+		return 0, false
+	}
+	return last.After.Version, true
+}
+
+// ownedVersion is the expected version of a fixture write: nil with overwrite, otherwise the version
+// the fixtures left the record at. ok is false if the fixtures do not own the record.
+func ownedVersion(repo Repository, clientID string, overwrite bool) (expected *int64, ok bool) {
+	if overwrite {
+		return nil, true
+	}
+	version, owned := fixtureVersion(repo, clientID)
+	return &version, owned
+}
+
+// Sync applies the fixture files that were added, changed or removed since the last sync. A
+// fixture record replaces or deletes a stored one only if the fixtures own it, so data created
+// or changed at runtime survives; with overwrite every file is applied again and wins regardless.
+// The initial sync only adds records the repository does not hold yet, so a persistent store
+// keeps what it had when the provider was restarted.
+func (l *fixtureLoader) Sync(overwrite bool) fixtureReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	initial := !l.synced
+	l.synced = true
+
+	var report fixtureReport
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		log.Printf("KYC SOAP Server: Could not read fixture directory %s: %v", l.dir, err)
+		report.Errors = append(report.Errors, fixtureIssue{File: l.dir, Reason: err.Error()})
+		return report
+	}
+
+	present := make(map[string]bool, len(entries))
+	claimed := make(map[string]string) // ClientID to the file that holds it in this sync
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue // Skip directories and non-json files
+		}
+		path := filepath.Join(l.dir, entry.Name())
+		present[path] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			l.reportError(&report, path, err)
+			continue
+		}
+		digest := sha256.Sum256(data)
+		previous, known := l.files[path]
+		if known && previous.digest == digest && !overwrite {
+			claimed[previous.clientID] = path
+			continue
+		}
+
+		var record kyc.Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			l.reportError(&report, path, fmt.Errorf("failed to unmarshal JSON: %w", err))
+			continue
+		}
+		if err := record.Validate(); err != nil {
+			l.reportError(&report, path, fmt.Errorf("invalid UserData: %w", err))
+			continue
+		}
+		if other, taken := claimed[record.ClientID]; taken {
+			l.reportError(&report, path, fmt.Errorf("ClientID '%s' is already defined by %s", record.ClientID, other))
+			continue
+		}
+		claimed[record.ClientID] = path
+
+		if known && previous.clientID != record.ClientID {
+			l.remove(&report, path, previous.clientID, overwrite)
+		}
+		l.apply(&report, path, record, initial, overwrite)
+		l.files[path] = fixtureFile{clientID: record.ClientID, digest: digest}
+	}
+
+	for path, previous := range l.files {
+		if present[path] {
+			continue
+		}
+		delete(l.files, path)
+		if claimed[previous.clientID] == "" {
+			l.remove(&report, path, previous.clientID, overwrite)
+		}
+	}
+
+	log.Printf("KYC SOAP Server: Synced fixtures from %s: %d created, %d updated, %d deleted, %d skipped, %d errors",
+		l.dir, report.Created, report.Updated, report.Deleted, len(report.Skipped), len(report.Errors))
+	return report
+}
+
+// apply creates or replaces the record of a fixture file
+func (l *fixtureLoader) apply(report *fixtureReport, path string, record kyc.Record, initial, overwrite bool) {
+	audit := fixtureAuditContext(path)
+	if _, err := l.repo.Read(record.ClientID); err != nil {
+		if _, err := createRecord(l.repo, audit, record, false); err != nil {
+			l.reportError(report, path, fmt.Errorf("failed to add ClientID '%s': %w", record.ClientID, err))
+			return
+		}
+		log.Printf("KYC SOAP Server: Loaded UserData for ClientID '%s' from %s", record.ClientID, path)
+		report.Created++
+		return
+	}
+
+	if initial && !overwrite {
+		log.Printf("KYC SOAP Server: Keeping stored UserData for ClientID '%s' over %s", record.ClientID, path)
+		return
+	}
+	expectedVersion, owned := ownedVersion(l.repo, record.ClientID, overwrite)
+	if !owned {
+		l.skip(report, path, record.ClientID, "record was created or changed at runtime")
+		return
+	}
+	_, err := updateRecord(l.repo, audit, record, expectedVersion, false)
+	var conflict *kyc.VersionConflictError
+	if errors.As(err, &conflict) {
+		l.skip(report, path, record.ClientID, "record was changed at runtime during the sync")
+		return
+	}
+	if err != nil {
+		l.reportError(report, path, fmt.Errorf("failed to update ClientID '%s': %w", record.ClientID, err))
+		return
+	}
+	log.Printf("KYC SOAP Server: Reloaded UserData for ClientID '%s' from %s", record.ClientID, path)
+	report.Updated++
+}
+
+// remove deletes the record of a fixture file that was removed or now holds another ClientID
+func (l *fixtureLoader) remove(report *fixtureReport, path, clientID string, overwrite bool) {
+	if _, err := l.repo.Read(clientID); err != nil {
+		return // Already deleted at runtime
+	}
+	expectedVersion, owned := ownedVersion(l.repo, clientID, overwrite)
+	if !owned {
+		l.skip(report, path, clientID, "record was changed at runtime; keeping it although its fixture is gone")
+		return
+	}
+	err := deleteRecord(l.repo, fixtureAuditContext(path), clientID, expectedVersion)
+	var conflict *kyc.VersionConflictError
+	if errors.As(err, &conflict) {
+		l.skip(report, path, clientID, "record was changed at runtime during the sync; keeping it although its fixture is gone")
+		return
+	}
+	if err != nil {
+		l.reportError(report, path, fmt.Errorf("failed to delete ClientID '%s': %w", clientID, err))
+		return
+	}
+	log.Printf("KYC SOAP Server: Removed UserData for ClientID '%s' with its fixture %s", clientID, path)
+	report.Deleted++
+}
+
+func (l *fixtureLoader) skip(report *fixtureReport, path, clientID, reason string) {
+	log.Printf("KYC SOAP Server: Not applying %s to ClientID '%s': %s", path, clientID, reason)
+	report.Skipped = append(report.Skipped, fixtureIssue{File: path, ClientID: clientID, Reason: reason})
+}
+
+func (l *fixtureLoader) reportError(report *fixtureReport, path string, err error) {
+	log.Printf("KYC SOAP Server: Skipping fixture %s: %v", path, err)
+	report.Errors = append(report.Errors, fixtureIssue{File: path, Reason: err.Error()})
+}
+
+// fixtureSyncDelay lets a burst of file events (an editor's truncate, write and rename) settle
+// before syncing, so a half-written file is not what gets loaded
+const fixtureSyncDelay = 200 * time.Millisecond
+
+// Watch syncs the fixtures whenever a file in the directory is created, written, renamed or
+// removed, until Close is called
+func (l *fixtureLoader) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch fixtures: %w", err)
+	}
+	if err := watcher.Add(l.dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch fixture directory %s: %w", l.dir, err)
+	}
+	l.mu.Lock()
+	l.watcher = watcher
+	l.mu.Unlock()
+
+	go func() {
+		var pending *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				if pending != nil {
+					pending.Stop()
+				}
+				pending = time.AfterFunc(fixtureSyncDelay, func() { l.Sync(false) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("KYC SOAP Server: Error watching fixture directory %s: %v", l.dir, err)
+			}
+		}
+	}()
+	log.Printf("KYC SOAP Server: Watching fixture directory %s", l.dir)
+	return nil
+}
+
+// Close stops watching the fixture directory
+func (l *fixtureLoader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.watcher == nil {
+		return nil
+	}
+	err := l.watcher.Close()
+	l.watcher = nil
+	return err
+}
+
+// fixtureLoaderFor returns the loader of the tenant a request was routed to. Only the default
+// tenant loads fixtures; other tenants get a copy of its records when they are created.
+func fixtureLoaderFor(r *http.Request) *fixtureLoader {
+	if currentTenant(r) != defaultTenant {
+		return nil
+	}
+	return fixtures.Load()
+}
+
+// fixtureRecord describes a record loaded from a fixture file for GET /admin/v1/fixtures
+type fixtureRecord struct {
+	File     string `json:"file"`
+	ClientID string `json:"clientId"`
+	State    string `json:"state"` // "fixture", "changed" (at runtime) or "deleted" (at runtime)
+}
+
+// fixtureStatus is the JSON body of GET /admin/v1/fixtures
+type fixtureStatus struct {
+	Dir      string          `json:"dir"`
+	Watching bool            `json:"watching"`
+	Records  []fixtureRecord `json:"records"`
+}
+
+// Status lists the fixture files and whether their records are still as the fixtures left them
+func (l *fixtureLoader) Status() fixtureStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := fixtureStatus{Dir: l.dir, Watching: l.watcher != nil, Records: make([]fixtureRecord, 0, len(l.files))}
+	for path, file := range l.files {
+		state := "fixture"
+		if _, err := l.repo.Read(file.clientID); err != nil {
+			state = "deleted"
+		} else if _, owned := fixtureVersion(l.repo, file.clientID); !owned {
+			state = "changed"
+		}
+		status.Records = append(status.Records, fixtureRecord{File: path, ClientID: file.clientID, State: state})
+	}
+	slices.SortFunc(status.Records, func(a, b fixtureRecord) int { return strings.Compare(a.File, b.File) })
+	return status
+}
+
+// adminGetFixtures handles GET /admin/v1/fixtures
+func adminGetFixtures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	loader := fixtureLoaderFor(r)
+	if loader == nil {
+		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": "No fixture directory is configured"})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, loader.Status())
+}
+
+// adminReloadFixtures handles POST /admin/v1/fixtures/reload. With ?overwrite=true every fixture
+// is applied again, replacing records created or changed at runtime.
+func adminReloadFixtures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	loader := fixtureLoaderFor(r)
+	if loader == nil {
+		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": "No fixture directory is configured"})
+		return
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+	writeJSONResponse(w, http.StatusOK, loader.Sync(overwrite))
+}
+
+// initFixtureRoutes registers the fixture admin routes
+func initFixtureRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/fixtures", adminGetFixtures)
+	mux.HandleFunc("/admin/v1/fixtures/reload", adminReloadFixtures)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFixture writes record as the fixture file name in dir
+func writeFixture(t *testing.T, dir, name string, record kyc.Record) {
+	t.Helper()
+	data, err := json.Marshal(record)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+}
+
+func TestFixtureLoader_Sync(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.1})
+	writeFixture(t, dir, "user2.json", kyc.Record{ClientID: "client2", Risk: 0.2})
+	writeFixture(t, dir, "user3.json", kyc.Record{ClientID: "client3", Risk: 0.3})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644))
+	repo := NewInMemoryRepo()
+
+	loader := newFixtureLoader(repo, dir)
+	report := loader.Sync(false)
+	assert.Equal(t, 3, report.Created)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, filepath.Join(dir, "broken.json"), report.Errors[0].File)
+
+	_, err := updateRecord(repo, auditContext{source: kyc.SourceAdmin}, kyc.Record{ClientID: "client2", Risk: 0.9}, nil, false)
+	require.NoError(t, err)
+	_, err = createRecord(repo, auditContext{source: kyc.SourceSOAP}, kyc.Record{ClientID: "client4", Risk: 0.4}, false)
+	require.NoError(t, err)
+	require.NoError(t, deleteRecord(repo, auditContext{source: kyc.SourceAdmin}, "client3", nil))
+
+	// Unchanged files leave runtime changes alone, including the delete
+	report = loader.Sync(false)
+	report.Errors = nil // broken.json is reported by every sync
+	assert.Equal(t, fixtureReport{}, report)
+	_, err = repo.Read("client3")
+	assert.Error(t, err)
+
+	// Changed files apply only to records the fixtures still own
+	writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.15})
+	writeFixture(t, dir, "user2.json", kyc.Record{ClientID: "client2", Risk: 0.25})
+	writeFixture(t, dir, "user4.json", kyc.Record{ClientID: "client4", Risk: 0.45})
+	report = loader.Sync(false)
+	assert.Equal(t, 1, report.Updated)
+	require.Len(t, report.Skipped, 2)
+	assert.ElementsMatch(t, []string{"client2", "client4"}, []string{report.Skipped[0].ClientID, report.Skipped[1].ClientID})
+	client1, _ := repo.Read("client1")
+	assert.Equal(t, 0.15, client1.Risk)
+	assert.Equal(t, kyc.SourceFixture, repo.History("client1")[1].Source)
+	client2, _ := repo.Read("client2")
+	assert.Equal(t, 0.9, client2.Risk)
+
+	// Removed files delete the records the fixtures own
+	require.NoError(t, os.Remove(filepath.Join(dir, "user1.json")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "user2.json")))
+	report = loader.Sync(false)
+	assert.Equal(t, 1, report.Deleted)
+	_, err = repo.Read("client1")
+	assert.Error(t, err)
+	_, err = repo.Read("client2")
+	assert.NoError(t, err, "a record changed at runtime outlives its fixture")
+
+	// Overwrite applies every fixture, recreating what was deleted and replacing runtime changes
+	report = loader.Sync(true)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	client4, _ := repo.Read("client4")
+	assert.Equal(t, 0.45, client4.Risk)
+	_, err = repo.Read("client3")
+	assert.NoError(t, err)
+}
+
+func TestFixtureLoader_InitialSyncKeepsStoredRecords(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.1})
+	repo := NewInMemoryRepo()
+	_, err := createRecord(repo, fixtureAuditContext(filepath.Join(dir, "user1.json")), kyc.Record{ClientID: "client1", Risk: 0.5}, false)
+	require.NoError(t, err)
+
+	report := newFixtureLoader(repo, dir).Sync(false)
+	assert.Equal(t, fixtureReport{}, report)
+	stored, _ := repo.Read("client1")
+	assert.Equal(t, 0.5, stored.Risk)
+}
+
+// racingRepo runs race once, right after the first History call: between a sync's ownership
+// check and its write
+type racingRepo struct {
+	Repository
+	race func()
+}
+
+func (r *racingRepo) History(clientID string) []kyc.AuditEvent {
+	history := r.Repository.History(clientID)
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+	return history
+}
+
+func TestFixtureLoader_RuntimeChangeDuringSync(t *testing.T) {
+	for name, change := range map[string]func(t *testing.T, dir string){
+		"update": func(t *testing.T, dir string) {
+			writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.2})
+		},
+		"delete": func(t *testing.T, dir string) {
+			require.NoError(t, os.Remove(filepath.Join(dir, "user1.json")))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.1})
+			repo := &racingRepo{Repository: NewInMemoryRepo()}
+			loader := newFixtureLoader(repo, dir)
+			require.Equal(t, 1, loader.Sync(false).Created)
+
+			change(t, dir)
+			repo.race = func() {
+				_, err := updateRecord(repo.Repository, auditContext{source: kyc.SourceAdmin}, kyc.Record{ClientID: "client1", Risk: 0.9}, nil, false)
+				require.NoError(t, err)
+			}
+			report := loader.Sync(false)
+			require.Len(t, report.Skipped, 1)
+			assert.Contains(t, report.Skipped[0].Reason, "changed at runtime during the sync")
+			client1, err := repo.Read("client1")
+			require.NoError(t, err)
+			assert.Equal(t, 0.9, client1.Risk, "the runtime change is kept")
+		})
+	}
+}
+
+func TestFixtureLoader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewInMemoryRepo()
+	loader := newFixtureLoader(repo, dir)
+	loader.Sync(false)
+	require.NoError(t, loader.Watch())
+	defer loader.Close()
+
+	writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.1})
+	assert.Eventually(t, func() bool {
+		_, err := repo.Read("client1")
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond, "a new fixture file is loaded")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "user1.json")))
+	assert.Eventually(t, func() bool {
+		_, err := repo.Read("client1")
+		return err != nil
+	}, 5*time.Second, 20*time.Millisecond, "a removed fixture file is unloaded")
+}
+
+func TestAdminAPI_Fixtures(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "user1.json", kyc.Record{ClientID: "client1", Risk: 0.1})
+	writeFixture(t, dir, "user2.json", kyc.Record{ClientID: "client2", Risk: 0.2})
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusConflict, serve("GET", "/admin/v1/fixtures").Code)
+	fixtures.Store(loadUserDataFromFiles(repo, dir))
+	t.Cleanup(func() { fixtures.Store(nil) })
+
+	_, err := updateRecord(repo, auditContext{source: kyc.SourceAdmin}, kyc.Record{ClientID: "client2", Risk: 0.9}, nil, false)
+	require.NoError(t, err)
+
+	rec := serve("GET", "/admin/v1/fixtures")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status fixtureStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, dir, status.Dir)
+	assert.False(t, status.Watching)
+	assert.Equal(t, []fixtureRecord{
+		{File: filepath.Join(dir, "user1.json"), ClientID: "client1", State: "fixture"},
+		{File: filepath.Join(dir, "user2.json"), ClientID: "client2", State: "changed"},
+	}, status.Records)
+
+	rec = serve("POST", "/admin/v1/fixtures/reload?overwrite=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var report fixtureReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Updated)
+	client2, _ := repo.Read("client2")
+	assert.Equal(t, 0.2, client2.Risk)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("GET", "/admin/v1/fixtures/reload").Code)
+}
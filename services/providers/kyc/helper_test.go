@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+func TestLoadUserDataFromFiles_Fixtures(t *testing.T) {
+	repo := NewInMemoryRepo()
+	loadUserDataFromFiles(repo, "../../../tests/usecases/kyc")
+
+	assert.Len(t, repo.List(), 5, "every fixture should be valid")
+	user, err := repo.Read("clientB456")
+	require.NoError(t, err)
+	assert.True(t, user.Sanctioned)
+	assert.Equal(t, kyc.ReviewRejected, user.ReviewStatus)
+	assert.NotNil(t, user.CreatedAt)
+	assert.NotNil(t, user.ReviewedAt)
+}
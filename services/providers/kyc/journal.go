@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// journalEntry is one SOAP call as the provider received and answered it
+type journalEntry struct {
+	Seq       int64       `json:"seq"`
+	Time      time.Time   `json:"time"`
+	Operation string      `json:"operation,omitempty"`
+	ClientID  string      `json:"clientId,omitempty"`
+	Headers   http.Header `json:"headers"`
+	Body      string      `json:"body"`
+	Status    int         `json:"status"` // 0 when the connection was dropped without a response
+	LatencyMs float64     `json:"latencyMs"`
+}
+
+// requestJournal keeps the most recent SOAP calls, dropping the oldest once it holds capacity
+type requestJournal struct {
+	mu       sync.Mutex
+	capacity int
+	entries  []journalEntry // Oldest first
+	seq      int64
+}
+
+// defaultJournalSize is how many calls the journal keeps unless KYC_JOURNAL_SIZE says otherwise
+const defaultJournalSize = 1000
+
+// journal records every SOAP call to the default tenant for GET /admin/v1/requests
+var journal = newRequestJournal(defaultJournalSize)
+
+func newRequestJournal(capacity int) *requestJournal {
+	return &requestJournal{capacity: capacity}
+}
+
+// Capacity is how many calls the journal keeps
+func (j *requestJournal) Capacity() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.capacity
+}
+
+// Resize changes how many calls the journal keeps, dropping the oldest that no longer fit
+func (j *requestJournal) Resize(capacity int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.capacity = capacity
+	j.trim()
+}
+
+func (j *requestJournal) add(entry journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	entry.Seq = j.seq
+	j.entries = append(j.entries, entry)
+	j.trim()
+}
+
+// trim drops the oldest entries beyond capacity; the caller holds mu
+func (j *requestJournal) trim() {
+	if excess := len(j.entries) - j.capacity; excess > 0 {
+		j.entries = append([]journalEntry(nil), j.entries[excess:]...)
+	}
+}
+
+// Entries returns the recorded calls that pass filter, oldest first
+func (j *requestJournal) Entries(filter journalFilter) []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	matched := make([]journalEntry, 0)
+	for _, entry := range j.entries {
+		if filter.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	if filter.limit > 0 && len(matched) > filter.limit {
+		matched = matched[len(matched)-filter.limit:] // The most recent ones
+	}
+	return matched
+}
+
+// Clear forgets every recorded call; sequence numbers keep counting up
+func (j *requestJournal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+}
+
+// journalFilter selects journal entries. Zero fields select everything.
+type journalFilter struct {
+	operation string
+	clientID  string
+	status    int
+	since     time.Time
+	afterSeq  int64
+	contains  string
+	limit     int
+}
+
+// parseJournalFilter reads the query of GET /admin/v1/requests: operation, clientId, status,
+// since (RFC 3339), after (a sequence number), contains (a body substring) and limit
+func parseJournalFilter(query url.Values) (journalFilter, error) {
+	filter := journalFilter{
+		operation: query.Get("operation"),
+		clientID:  query.Get("clientId"),
+		contains:  query.Get("contains"),
+	}
+	var err error
+	if s := query.Get("status"); s != "" {
+		if filter.status, err = strconv.Atoi(s); err != nil {
+			return journalFilter{}, fmt.Errorf("invalid status %q", s)
+		}
+	}
+	if s := query.Get("since"); s != "" {
+		if filter.since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return journalFilter{}, fmt.Errorf("invalid since %q, want an RFC 3339 time", s)
+		}
+	}
+	if s := query.Get("after"); s != "" {
+		if filter.afterSeq, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return journalFilter{}, fmt.Errorf("invalid after %q, want a sequence number", s)
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		if filter.limit, err = strconv.Atoi(s); err != nil || filter.limit < 1 {
+			return journalFilter{}, fmt.Errorf("invalid limit %q", s)
+		}
+	}
+	return filter, nil
+}
+
+func (f journalFilter) matches(entry journalEntry) bool {
+	return (f.operation == "" || entry.Operation == f.operation) &&
+		(f.clientID == "" || entry.ClientID == f.clientID) &&
+		(f.status == 0 || entry.Status == f.status) &&
+		!entry.Time.Before(f.since) &&
+		entry.Seq > f.afterSeq &&
+		strings.Contains(entry.Body, f.contains)
+}
+
+// requestOperation returns the name of the first element in the SOAP Body of envelope
+func requestOperation(envelope []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(envelope))
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if inBody {
+				return start.Name.Local
+			}
+			inBody = start.Name.Local == "Body"
+		}
+	}
+}
+
+// journalWriter notes the status a handler answered with
+type journalWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *journalWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *journalWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the connection, which injected faults flush and hijack
+func (w *journalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Record wraps a SOAP handler so that every POST it serves is added to the journal, including
+// calls that a fault drops without a response
+func (j *requestJournal) Record(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("KYC SOAP Server: Failed to read request body for the journal: %v", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		received, start := now().UTC(), time.Now()
+		recorder := &journalWriter{ResponseWriter: w}
+		defer func() {
+			j.add(journalEntry{
+				Time:      received,
+				Operation: requestOperation(body),
+				ClientID:  requestClientID(body),
+				Headers:   r.Header.Clone(),
+				Body:      string(body),
+				Status:    recorder.status,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			})
+		}()
+		next(recorder, r)
+	}
+}
+
+// adminRequests handles GET /admin/v1/requests, the journal filtered by the query, and DELETE
+// /admin/v1/requests, which clears it
+func adminRequests(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		filter, err := parseJournalFilter(r.URL.Query())
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, currentTenant(r).journal.Entries(filter))
+	case http.MethodDelete:
+		currentTenant(r).journal.Clear()
+		log.Printf("KYC SOAP Server: Cleared the request journal")
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Request journal cleared"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// initJournalRoutes registers the request journal admin routes
+func initJournalRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/requests", adminRequests)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestOperation(t *testing.T) {
+	assert.Equal(t, "KYCQuery", requestOperation([]byte(createSOAPRequest("KYCQuery", "client1", nil))))
+	assert.Equal(t, "", requestOperation([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body/></soapenv:Envelope>`)))
+	assert.Equal(t, "", requestOperation([]byte(`not xml`)))
+}
+
+func TestRequestJournal_Record(t *testing.T) {
+	useClock(t, time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC))
+	repo := NewInMemoryRepo()
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1}))
+	j := newRequestJournal(2)
+	handler := j.Record(func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) })
+	post := func(operation, clientID string) {
+		req := httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest(operation, clientID, nil)))
+		req.Header.Set("X-Correlation-ID", "corr-"+clientID)
+		handler(httptest.NewRecorder(), req)
+	}
+
+	post("KYCQuery", "client1")
+	post("KYCQuery", "nonexistent")
+	post("UpdateKYC", "client1")
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/soap?wsdl", nil))
+
+	entries := j.Entries(journalFilter{})
+	require.Len(t, entries, 2, "the oldest call is dropped beyond capacity and GETs are not recorded")
+	assert.Equal(t, int64(2), entries[0].Seq)
+	assert.Equal(t, "KYCQuery", entries[0].Operation)
+	assert.Equal(t, "nonexistent", entries[0].ClientID)
+	assert.Equal(t, http.StatusNotFound, entries[0].Status)
+	assert.Equal(t, "corr-nonexistent", entries[0].Headers.Get("X-Correlation-ID"))
+	assert.Equal(t, time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC), entries[0].Time)
+	assert.Equal(t, "UpdateKYC", entries[1].Operation)
+	assert.Equal(t, http.StatusOK, entries[1].Status)
+	assert.Contains(t, entries[1].Body, "<ClientID>client1</ClientID>")
+
+	j.Clear()
+	post("KYCQuery", "client1")
+	entries = j.Entries(journalFilter{})
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(4), entries[0].Seq, "sequence numbers survive a clear")
+}
+
+func TestRequestJournal_DroppedConnection(t *testing.T) {
+	repo := NewInMemoryRepo()
+	j := newRequestJournal(10)
+	server := httptest.NewServer(j.Record(func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) }))
+	defer server.Close()
+	addFaultRule(t, faults.Rule{Fault: faults.TypeConnectionReset, MaxHits: 1})
+	t.Cleanup(faultRules.Clear)
+
+	_, err := server.Client().Post(server.URL, "text/xml", strings.NewReader(createSOAPRequest("KYCQuery", "client1", nil)))
+	require.Error(t, err)
+	require.Eventually(t, func() bool { return len(j.Entries(journalFilter{})) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, j.Entries(journalFilter{})[0].Status)
+}
+
+func TestParseJournalFilter(t *testing.T) {
+	filter, err := parseJournalFilter(url.Values{"operation": {"UpdateKYC"}, "clientId": {"client1"}, "status": {"200"}, "since": {"2026-01-15T12:00:00Z"}, "after": {"3"}, "contains": {"<Risk>0.8</Risk>"}, "limit": {"5"}})
+	require.NoError(t, err)
+	assert.Equal(t, journalFilter{operation: "UpdateKYC", clientID: "client1", status: 200, since: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC), afterSeq: 3, contains: "<Risk>0.8</Risk>", limit: 5}, filter)
+
+	for _, bad := range []url.Values{{"status": {"ok"}}, {"since": {"yesterday"}}, {"after": {"x"}}, {"limit": {"0"}}} {
+		_, err := parseJournalFilter(bad)
+		assert.Error(t, err, "%v", bad)
+	}
+}
+
+func TestAdminAPI_Requests(t *testing.T) {
+	repo := NewInMemoryRepo()
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/soap", journal.Record(func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) }))
+	initAdminRoutes(mux, repo)
+	journal.Clear()
+	t.Cleanup(journal.Clear)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	serve("POST", "/soap", createSOAPRequest("KYCQuery", "client1", nil))
+	serve("POST", "/soap", createSOAPRequest("UpdateKYC", "client1", &kyc.Record{ClientID: "client1", Risk: 0.8}))
+	serve("POST", "/soap", createSOAPRequest("UpdateKYC", "client2", &kyc.Record{ClientID: "client2", Risk: 0.8}))
+
+	rec := serve("GET", "/admin/v1/requests?operation=UpdateKYC&clientId=client1&contains="+url.QueryEscape("<Risk>0.8</Risk>"), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries []journalEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+
+	rec = serve("GET", "/admin/v1/requests?status=404", "")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "client2", entries[0].ClientID)
+
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/admin/v1/requests?limit=-1", "").Code)
+	assert.Equal(t, http.StatusOK, serve("DELETE", "/admin/v1/requests", "").Code)
+	assert.Equal(t, "[]\n", serve("GET", "/admin/v1/requests", "").Body.String())
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// transitionRecord moves the stored record of clientID to status to and records the move in the
+// audit log. Unless force is set the lifecycle must allow the move; a refused move returns a
+// *kyc.TransitionError.
+func transitionRecord(repo Repository, audit auditContext, clientID string, to kyc.ReviewStatus, note string, force bool) (kyc.Record, error) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	record, err := repo.Read(clientID)
+	if err != nil {
+		return kyc.Record{}, err
+	}
+	previous := record
+	if force {
+		record.ReviewStatus, record.ReviewNote = to, note
+	} else if err := record.Transition(to, note); err != nil {
+		return kyc.Record{}, err
+	}
+	record.Touch(&previous, now())
+	after := record
+	if err := commitChanges(repo, audit, audit.event(kyc.AuditTransition, clientID, &previous, &after)); err != nil {
+		return kyc.Record{}, err
+	}
+	log.Printf("KYC SOAP Server: ClientID '%s' moved from %s to %s", clientID, previous.CurrentStatus(), to)
+	return record, nil
+}
+
+// recordError maps a failed update, delete or transition to the operation error reported to SOAP clients
+func recordError(operation string, err error) *operationError {
+	var transitionErr *kyc.TransitionError
+	if errors.As(err, &transitionErr) {
+		return &operationError{operation: operation, code: ErrorCodeInvalidTransition, message: err.Error(), httpStatus: http.StatusConflict}
+	}
+	var conflictErr *kyc.VersionConflictError
+	if errors.As(err, &conflictErr) {
+		return &operationError{operation: operation, code: ErrorCodeVersionConflict, message: err.Error(), httpStatus: http.StatusConflict}
+	}
+	return &operationError{operation: operation, code: ErrorCodeNotFound, message: err.Error(), httpStatus: http.StatusNotFound}
+}
+
+// handleTransitionOperation serves SubmitKYC, ApproveKYC and RejectKYC
+func handleTransitionOperation(repo Repository, audit auditContext, operation string, content []byte) (interface{}, *operationError) {
+	var clientID, note, message string
+	var to kyc.ReviewStatus
+	var err error
+
+	switch operation {
+	case "SubmitKYC":
+		var req kycModels.SubmitKYCRequest
+		err = xml.Unmarshal(content, &req)
+		clientID, to, message = req.ClientID, kyc.ReviewInReview, "User submitted for review"
+	case "ApproveKYC":
+		var req kycModels.ApproveKYCRequest
+		err = xml.Unmarshal(content, &req)
+		clientID, to, note, message = req.ClientID, kyc.ReviewApproved, req.Comment, "User approved"
+	case "RejectKYC":
+		var req kycModels.RejectKYCRequest
+		err = xml.Unmarshal(content, &req)
+		clientID, to, note, message = req.ClientID, kyc.ReviewRejected, req.Reason, "User rejected"
+		if err == nil && strings.TrimSpace(req.Reason) == "" {
+			err = errors.New("a reason is required")
+		}
+	}
+	if err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+
+	record, err := transitionRecord(repo, audit, clientID, to, note, false)
+	if err != nil {
+		log.Printf("KYC SOAP Server: Error in %s for user %s: %v", operation, clientID, err)
+		return nil, recordError(operation, err)
+	}
+	return kycModels.KYCResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.KYCResponseBody{
+			KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: message, UserData: &record},
+		},
+	}, nil
+}
+
+// handleGetKYCStatus serves GetKYCStatus
+func handleGetKYCStatus(repo Repository, content []byte) (interface{}, *operationError) {
+	const operation = "GetKYCStatus"
+	var req kycModels.GetKYCStatusRequest
+	if err := xml.Unmarshal(content, &req); err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	record, err := repo.Read(req.ClientID)
+	if err != nil {
+		log.Printf("KYC SOAP Server: Error reading status of user %s: %v", req.ClientID, err)
+		return nil, &operationError{operation: operation, code: ErrorCodeNotFound, message: err.Error(), httpStatus: http.StatusNotFound}
+	}
+	status := record.CurrentStatus()
+	return kycModels.KYCStatusResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.KYCStatusResponseBody{
+			KYCStatusResult: kycModels.KYCStatusResult{
+				Status:             "Success",
+				Message:            "User status retrieved",
+				ClientID:           record.ClientID,
+				ReviewStatus:       status,
+				ReviewNote:         record.ReviewNote,
+				ReviewedAt:         record.ReviewedAt,
+				AllowedTransitions: status.Next(),
+			},
+		},
+	}, nil
+}
+
+// adminTransitionUser handles POST /admin/v1/users/{clientID}/transition, forcing a review status
+// regardless of the lifecycle so tests can set up any state
+func adminTransitionUser(repo Repository, clientID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var requestBody struct {
+		To     kyc.ReviewStatus `json:"to"`
+		Reason string           `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if !slices.Contains(kyc.ReviewStatuses, requestBody.To) {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid review status '%s'", requestBody.To)})
+		return
+	}
+
+	record, err := transitionRecord(repo, adminAuditContext(r), clientID, requestBody.To, requestBody.Reason, true)
+	if err != nil {
+		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("ETag", etag(record.Version))
+	writeJSONResponse(w, http.StatusOK, record)
+}
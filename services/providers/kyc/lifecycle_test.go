@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSOAPHandler_Lifecycle(t *testing.T) {
+	repo := NewInMemoryRepo()
+	_, err := createRecord(repo, auditContext{}, kyc.Record{ClientID: "client123", Risk: 0.4}, true)
+	require.NoError(t, err)
+
+	transition := func(operation string) (int, kycModels.KYCResult) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest(operation, "client123", nil))))
+		var response kycModels.KYCResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		return rec.Code, response
+	}
+	status := func() kycModels.KYCStatusResult {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("GetKYCStatus", "client123", nil))))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response kycModels.KYCStatusResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		return response
+	}
+
+	current := status()
+	assert.Equal(t, kyc.ReviewPending, current.ReviewStatus)
+	assert.Equal(t, []kyc.ReviewStatus{kyc.ReviewInReview, kyc.ReviewExpired}, current.AllowedTransitions)
+
+	code, response := transition("ApproveKYC")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, response.Message, "cannot move from PENDING to APPROVED")
+
+	code, response = transition("SubmitKYC")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, kyc.ReviewInReview, response.UserData.ReviewStatus)
+	assert.Nil(t, response.UserData.ReviewedAt)
+
+	code, response = transition("RejectKYC")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, kyc.ReviewRejected, response.UserData.ReviewStatus)
+	assert.Equal(t, "document forged", response.UserData.ReviewNote)
+
+	current = status()
+	assert.Equal(t, kyc.ReviewRejected, current.ReviewStatus)
+	assert.Equal(t, "document forged", current.ReviewNote)
+	assert.NotNil(t, current.ReviewedAt)
+	assert.Equal(t, []kyc.ReviewStatus{kyc.ReviewPending}, current.AllowedTransitions)
+
+	t.Run("Reject requires a reason", func(t *testing.T) {
+		body := strings.Replace(createSOAPRequest("RejectKYC", "client123", nil), "document forged", " ", 1)
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "a reason is required")
+	})
+
+	t.Run("Update without a status keeps the stored one", func(t *testing.T) {
+		updated, err := updateRecord(repo, auditContext{}, kyc.Record{ClientID: "client123", Risk: 0.8}, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, kyc.ReviewRejected, updated.ReviewStatus)
+		assert.Equal(t, "document forged", updated.ReviewNote)
+	})
+}
+
+func TestAdminTransitionUser(t *testing.T) {
+	repo := NewInMemoryRepo()
+	assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.4, ReviewStatus: kyc.ReviewPending}))
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// Admin transitions bypass the lifecycle
+	rec := serve("POST", "/admin/v1/users/client123/transition", `{"to":"APPROVED","reason":"seeded"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	record, err := repo.Read("client123")
+	require.NoError(t, err)
+	assert.Equal(t, kyc.ReviewApproved, record.ReviewStatus)
+	assert.Equal(t, "seeded", record.ReviewNote)
+	assert.NotNil(t, record.ReviewedAt)
+
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/admin/v1/users/client123/transition", `{"to":"DONE"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve("POST", "/admin/v1/users/missing/transition", `{"to":"PENDING"}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("GET", "/admin/v1/users/client123/transition", "").Code)
+}
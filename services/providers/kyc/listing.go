@@ -0,0 +1,295 @@
+package main
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	maxIndexTotals  = 100 // Filter sets whose match count a recordIndex keeps
+)
+
+// sortFields maps the field names accepted by ?sort= to the comparison they sort by
+var sortFields = map[string]func(a, b kyc.Record) int{
+	"clientId": func(a, b kyc.Record) int { return 0 }, // The ClientID tie-break does the work
+	"risk":     func(a, b kyc.Record) int { return cmp.Compare(a.Risk, b.Risk) },
+	"createdAt": func(a, b kyc.Record) int {
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	},
+	"updatedAt": func(a, b kyc.Record) int {
+		return compareTimes(a.UpdatedAt, b.UpdatedAt)
+	},
+}
+
+// compareTimes orders unset times before every set time
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
+
+// userQuery is a parsed GET /admin/v1/users or SearchKYC request: which records to return, in which order, and where the page starts
+type userQuery struct {
+	limit          int
+	minRisk        *float64
+	maxRisk        *float64
+	statuses       []kyc.ReviewStatus
+	prefix         string
+	nameContains   string // Lower-cased; matched case-insensitively against LegalName
+	documentNumber string
+	createdAfter   *time.Time
+	sortField      string
+	descending     bool
+	after          *pageCursor // Last record of the previous page
+}
+
+// pageCursor identifies the last record of a page by its sort key. Keying on the record rather
+// than an offset keeps paging stable while records are created or deleted between requests.
+type pageCursor struct {
+	Sort      string     `json:"s"`
+	ClientID  string     `json:"id"`
+	Risk      float64    `json:"r,omitempty"`
+	CreatedAt *time.Time `json:"c,omitempty"`
+	UpdatedAt *time.Time `json:"u,omitempty"`
+}
+
+// userPage is the JSON body returned by GET /admin/v1/users
+type userPage struct {
+	Users      []kyc.Record `json:"users"`
+	Total      int          `json:"total"`                // Records matching the filters across all pages
+	NextCursor string       `json:"nextCursor,omitempty"` // Absent on the last page
+}
+
+// parseUserQuery reads the limit, cursor, minRisk, maxRisk, status, prefix and sort parameters
+func parseUserQuery(values url.Values) (userQuery, error) {
+	query := userQuery{limit: defaultPageSize, sortField: "clientId", prefix: values.Get("prefix")}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return userQuery{}, fmt.Errorf("invalid limit '%s': want a number from 1 to %d", raw, maxPageSize)
+		}
+		query.limit = limit
+	}
+
+	var err error
+	if query.minRisk, err = parseRiskBound(values, "minRisk"); err != nil {
+		return userQuery{}, err
+	}
+	if query.maxRisk, err = parseRiskBound(values, "maxRisk"); err != nil {
+		return userQuery{}, err
+	}
+	if query.minRisk != nil && query.maxRisk != nil && *query.minRisk > *query.maxRisk {
+		return userQuery{}, fmt.Errorf("minRisk %v is greater than maxRisk %v", *query.minRisk, *query.maxRisk)
+	}
+
+	for _, raw := range values["status"] { // Repeated or comma-separated
+		for _, s := range strings.Split(raw, ",") {
+			status := kyc.ReviewStatus(strings.TrimSpace(s))
+			if !slices.Contains(kyc.ReviewStatuses, status) {
+				return userQuery{}, fmt.Errorf("invalid status '%s': want one of %v", status, kyc.ReviewStatuses)
+			}
+			query.statuses = append(query.statuses, status)
+		}
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		field, descending := strings.CutPrefix(raw, "-")
+		if _, ok := sortFields[field]; !ok {
+			return userQuery{}, fmt.Errorf("invalid sort '%s': want clientId, risk, createdAt or updatedAt, prefixed with '-' for descending order", raw)
+		}
+		query.sortField, query.descending = field, descending
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			return userQuery{}, fmt.Errorf("invalid cursor: %w", err)
+		}
+		if cursor.Sort != query.sortString() {
+			return userQuery{}, fmt.Errorf("cursor was issued for sort '%s', not '%s'", cursor.Sort, query.sortString())
+		}
+		query.after = &cursor
+	}
+	return query, nil
+}
+
+// parseRiskBound reads the optional risk parameter name
+func parseRiskBound(values url.Values, name string) (*float64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	risk, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s': want a number", name, raw)
+	}
+	return &risk, nil
+}
+
+// sortString is the sort parameter the query was parsed from
+func (q userQuery) sortString() string {
+	if q.descending {
+		return "-" + q.sortField
+	}
+	return q.sortField
+}
+
+// matches reports whether user passes every filter of q
+func (q userQuery) matches(user kyc.Record) bool {
+	if q.minRisk != nil && user.Risk < *q.minRisk {
+		return false
+	}
+	if q.maxRisk != nil && user.Risk > *q.maxRisk {
+		return false
+	}
+	if len(q.statuses) > 0 && !slices.Contains(q.statuses, user.CurrentStatus()) {
+		return false
+	}
+	if q.nameContains != "" && !strings.Contains(strings.ToLower(user.LegalName), q.nameContains) {
+		return false
+	}
+	if q.documentNumber != "" && !slices.ContainsFunc(user.Documents, func(d kyc.IdentityDocument) bool { return d.Number == q.documentNumber }) {
+		return false
+	}
+	if q.createdAfter != nil && (user.CreatedAt == nil || !user.CreatedAt.After(*q.createdAfter)) {
+		return false
+	}
+	return strings.HasPrefix(user.ClientID, q.prefix)
+}
+
+// sortedBy returns the ascending comparison of field, breaking ties by ClientID so the order is total
+func sortedBy(field string) func(a, b kyc.Record) int {
+	return func(a, b kyc.Record) int {
+		if c := sortFields[field](a, b); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ClientID, b.ClientID)
+	}
+}
+
+// filterKey identifies the filters of q, regardless of its order and page
+func (q userQuery) filterKey() string {
+	key, _ := json.Marshal([]any{q.minRisk, q.maxRisk, q.statuses, q.prefix, q.nameContains, q.documentNumber, q.createdAfter}) // Cannot fail for these types
+	return string(key)
+}
+
+// recordIndex keeps the records of a repository sorted by each listing order, and how many match
+// each set of filters, until the next write. A page then starts at its cursor by binary search, so
+// paging through the store neither copies nor sorts it for every page. Writes only call changed,
+// which never waits for a query.
+type recordIndex struct {
+	generation atomic.Uint64 // Bumped by every write
+
+	mu     sync.Mutex
+	built  uint64                  // Generation of orders and totals
+	orders map[string][]kyc.Record // Ascending, by sort field
+	totals map[string]int          // Matching records, by filterKey
+}
+
+// changed drops the index after a write to the repository
+func (x *recordIndex) changed() {
+	x.generation.Add(1)
+}
+
+// page returns the page q selects from the records list returns, calling list only when the
+// order q needs was not built since the last write
+func (x *recordIndex) page(q userQuery, list func() []kyc.Record) userPage {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if generation := x.generation.Load(); x.orders == nil || x.built != generation {
+		x.built, x.orders, x.totals = generation, make(map[string][]kyc.Record), make(map[string]int)
+	}
+	compare := sortedBy(q.sortField)
+	sorted, ok := x.orders[q.sortField]
+	if !ok {
+		sorted = list()
+		slices.SortFunc(sorted, compare)
+		x.orders[q.sortField] = sorted
+	}
+
+	total, ok := x.totals[q.filterKey()]
+	if !ok {
+		for _, user := range sorted {
+			if q.matches(user) {
+				total++
+			}
+		}
+		if len(x.totals) >= maxIndexTotals {
+			clear(x.totals)
+		}
+		x.totals[q.filterKey()] = total
+	}
+
+	// Walk sorted from the record after the cursor, backwards for a descending order
+	i, step := 0, 1
+	if q.descending {
+		i, step = len(sorted)-1, -1
+	}
+	if q.after != nil {
+		at, found := slices.BinarySearchFunc(sorted, q.after.record(), compare)
+		switch {
+		case q.descending:
+			i = at - 1
+		case found:
+			i = at + 1
+		default:
+			i = at
+		}
+	}
+	page := userPage{Users: make([]kyc.Record, 0, min(q.limit, total)), Total: total}
+	for ; i >= 0 && i < len(sorted); i += step {
+		if !q.matches(sorted[i]) {
+			continue
+		}
+		if len(page.Users) == q.limit {
+			page.NextCursor = q.cursorAfter(page.Users[len(page.Users)-1])
+			break
+		}
+		page.Users = append(page.Users, sorted[i])
+	}
+	return page
+}
+
+// cursorAfter encodes the cursor of the page following user
+func (q userQuery) cursorAfter(user kyc.Record) string {
+	cursor := pageCursor{Sort: q.sortString(), ClientID: user.ClientID, Risk: user.Risk, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt}
+	data, _ := json.Marshal(cursor) // Cannot fail for these field types
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// record returns a record carrying just the sort keys of the cursor, for comparison with stored records
+func (c pageCursor) record() kyc.Record {
+	return kyc.Record{ClientID: c.ClientID, Risk: c.Risk, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+func decodeCursor(raw string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return pageCursor{}, err
+	}
+	return cursor, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAPI_ListUsers(t *testing.T) {
+	repo := NewInMemoryRepo()
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 25 {
+		created := t0.Add(time.Duration(24-i) * time.Minute) // Created in reverse ClientID order
+		status := kyc.ReviewPending
+		if i%5 == 0 {
+			status = kyc.ReviewApproved
+		}
+		require.NoError(t, repo.Create(kyc.Record{
+			ClientID:     fmt.Sprintf("client%02d", i),
+			Risk:         float64(i%10) / 10,
+			ReviewStatus: status,
+			CreatedAt:    &created,
+		}))
+	}
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "other", Risk: 0.5}))
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	list := func(query string) (int, userPage) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/v1/users?"+query, nil))
+		var page userPage
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		}
+		return rec.Code, page
+	}
+	ids := func(page userPage) []string {
+		var ids []string
+		for _, user := range page.Users {
+			ids = append(ids, user.ClientID)
+		}
+		return ids
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		code, page := list("")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 26, page.Total)
+		assert.Len(t, page.Users, 26)
+		assert.Equal(t, "client00", page.Users[0].ClientID)
+		assert.Equal(t, "other", page.Users[25].ClientID)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Cursor walks every page once", func(t *testing.T) {
+		var seen []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 10, "paging did not terminate")
+			code, page := list("prefix=client&sort=-createdAt&limit=10&cursor=" + cursor)
+			require.Equal(t, http.StatusOK, code)
+			assert.Equal(t, 25, page.Total)
+			seen = append(seen, ids(page)...)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		require.Len(t, seen, 25)
+		assert.Equal(t, "client00", seen[0])
+		assert.Equal(t, "client24", seen[24])
+	})
+
+	t.Run("Cursor survives a delete", func(t *testing.T) {
+		_, first := list("prefix=client&limit=3")
+		require.Equal(t, []string{"client00", "client01", "client02"}, ids(first))
+		require.NoError(t, repo.Delete("client02"))
+		t.Cleanup(func() { _ = repo.Create(kyc.Record{ClientID: "client02", Risk: 0.2}) })
+
+		_, second := list("prefix=client&limit=3&cursor=" + first.NextCursor)
+		assert.Equal(t, []string{"client03", "client04", "client05"}, ids(second))
+	})
+
+	t.Run("Pages follow writes", func(t *testing.T) {
+		_, before := list("minRisk=0.9&sort=-risk")
+		require.Equal(t, []string{"client19", "client09"}, ids(before))
+
+		original, err := repo.Read("client09")
+		require.NoError(t, err)
+		updated := original
+		updated.Risk = 0.95
+		require.NoError(t, repo.Update(updated))
+		require.NoError(t, repo.Create(kyc.Record{ClientID: "client99", Risk: 0.99}))
+		t.Cleanup(func() {
+			_ = repo.Delete("client99")
+			_ = repo.Update(original)
+		})
+
+		_, after := list("minRisk=0.9&sort=-risk")
+		assert.Equal(t, 3, after.Total)
+		assert.Equal(t, []string{"client99", "client09", "client19"}, ids(after))
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		_, page := list("minRisk=0.8&maxRisk=0.9&status=PENDING")
+		assert.Equal(t, []string{"client08", "client09", "client18", "client19"}, ids(page))
+
+		_, page = list("status=APPROVED,IN_REVIEW&sort=-risk")
+		assert.Equal(t, []string{"client15", "client05", "client20", "client10", "client00"}, ids(page))
+
+		_, page = list("status=PENDING&prefix=oth")
+		assert.Equal(t, []string{"other"}, ids(page), "a record without a status counts as PENDING")
+	})
+
+	t.Run("Bad parameters", func(t *testing.T) {
+		_, page := list("sort=risk&limit=2")
+		for _, query := range []string{
+			"limit=0",
+			"limit=1001",
+			"limit=ten",
+			"minRisk=low",
+			"minRisk=0.9&maxRisk=0.1",
+			"status=DONE",
+			"sort=name",
+			"cursor=!!!",
+			"cursor=" + page.NextCursor, // Issued for sort=risk
+		} {
+			code, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+}
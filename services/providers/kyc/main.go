@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 
 	kycModels "kafka-soap-e2e-test/services/providers/kyc/models" // Import the new models package
+	"kafka-soap-e2e-test/services/providers/kyc/scoring"
+	"kafka-soap-e2e-test/services/shared/kyc"
 	"log"
 	"net/http"
 	"os"
-
-	"time" // Added for time.Sleep for ActionTimeout
+	"strconv"
+	"strings"
 )
 
 const (
@@ -27,9 +31,13 @@ const (
 // --- SOAP Server Handler ---
 
 // soapHandler handles all incoming SOAP requests for CRUD operations
-func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
+func soapHandler(repo Repository, w http.ResponseWriter, r *http.Request) {
 	log.Printf("KYC SOAP Server: Received request for %s %s", r.Method, r.URL.Path)
 
+	if serveContract(w, r) {
+		return
+	}
+
 	// Assume POST for all SOAP operations, as is standard for SOAP
 	if r.Method != "POST" {
 		http.Error(w, "SOAP requests must use POST method", http.StatusMethodNotAllowed)
@@ -45,6 +53,12 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("KYC SOAP Server: Raw request body: %s", string(bodyBytes))
 
+	if violations := validateRequest(settings.Get().ValidationMode, bodyBytes); len(violations) > 0 {
+		log.Printf("KYC SOAP Server: Rejecting request with %d schema violation(s)", len(violations))
+		writeSOAPFault(w, validationFault(violations))
+		return
+	}
+
 	var envelope kycModels.SOAPEnvelope
 	if err := xml.Unmarshal(bodyBytes, &envelope); err != nil {
 		log.Printf("KYC SOAP Server: Failed to unmarshal SOAP envelope: %v. Raw body: %s", err, string(bodyBytes)) // Add raw body to log
@@ -53,7 +67,7 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 	}
 
 	var responseEnvelope interface{}
-	var statusCode int = http.StatusOK
+	var opErr *operationError
 
 	// Determine operation based on the root element in the SOAP body
 	root := ""
@@ -71,60 +85,49 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("KYC SOAP Server: Detected SOAP operation: %s", root)
 
+	audit := requestAuditContext(r, kyc.SourceSOAP, root)
+
+	// Apply a matching fault rule or per-ClientID action before the operation runs. The deferred run
+	// of an asynchronous request was faulted when it was accepted.
+	dispatched := isCallbackDispatch(r)
+	clientID := requestClientID(envelope.Body.Content)
+	if !dispatched {
+		if rule, delay, ok := matchFault(currentTenant(r).faults, repo, root, clientID); ok {
+			faulty, sent := injectFault(w, r, root, clientID, rule, delay)
+			if sent {
+				return
+			}
+			if faulty != nil {
+				defer faulty.flush()
+				w = faulty
+			}
+		}
+	}
+
+	// A request with a WS-Addressing ReplyTo is acknowledged now and answered at that address later
+	if replyTo := asyncReplyTo(envelope.Header); replyTo != "" && !dispatched {
+		acceptAsync(repo, w, r, bodyBytes, envelope.Header.MessageID, replyTo, root)
+		return
+	}
+
 	// Handle different CRUD operations
 	switch root {
 	case "KYCQuery": // Read operation
 		var req kycModels.KYCQuery
 		if err := xml.Unmarshal(envelope.Body.Content, &req); err != nil {
 			log.Printf("KYC SOAP Server: Failed to unmarshal KYCQuery request: %v", err)
-			statusCode = http.StatusBadRequest
-			responseEnvelope = kycModels.KYCResponseEnvelope{
-				XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-				Body: kycModels.KYCResponseBody{
-					KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("Invalid KYCQuery request: %v", err)},
-				},
-			}
+			opErr = invalidRequestError(root, err)
 		} else {
-			// Check for action override
-			if action, exists := repo.GetAction(req.ClientID); exists {
-				log.Printf("KYC SOAP Server: Applying action override '%s' for ClientID '%s'", action, req.ClientID)
-				switch action {
-				case ActionTimeout:
-					time.Sleep(5 * time.Second) // Simulate a timeout
-					http.Error(w, "Timeout", http.StatusRequestTimeout)
-					return
-				case ActionInternalError:
-					http.Error(w, "Internal Server Error (simulated)", http.StatusInternalServerError)
-					return
-				case ActionNotFound:
-					statusCode = http.StatusNotFound
-					responseEnvelope = kycModels.KYCResponseEnvelope{
-						XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-						Body: kycModels.KYCResponseBody{
-							KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("User with ClientID '%s' not found (simulated)", req.ClientID)},
-						},
-					}
-				}
-			}
-
-			if responseEnvelope == nil { // Only proceed if no action override has generated a response yet
-				userData, err := repo.Read(req.ClientID)
-				if err != nil {
-					log.Printf("KYC SOAP Server: Error reading user %s: %v", req.ClientID, err)
-					statusCode = http.StatusNotFound
-					responseEnvelope = kycModels.KYCResponseEnvelope{
-						XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-						Body: kycModels.KYCResponseBody{
-							KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: err.Error()},
-						},
-					}
-				} else {
-					responseEnvelope = kycModels.KYCResponseEnvelope{
-						XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-						Body: kycModels.KYCResponseBody{
-							KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User data retrieved", UserData: &userData},
-						},
-					}
+			userData, err := readRecord(repo, req.ClientID, req.AsOf)
+			if err != nil {
+				log.Printf("KYC SOAP Server: Error reading user %s: %v", req.ClientID, err)
+				opErr = &operationError{operation: root, code: ErrorCodeNotFound, message: err.Error(), httpStatus: http.StatusNotFound}
+			} else {
+				responseEnvelope = kycModels.KYCResponseEnvelope{
+					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+					Body: kycModels.KYCResponseBody{
+						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User data retrieved", UserData: &userData},
+					},
 				}
 			}
 		}
@@ -133,28 +136,24 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 		var req kycModels.CreateKYCRequest
 		if err := xml.Unmarshal(envelope.Body.Content, &req); err != nil {
 			log.Printf("KYC SOAP Server: Failed to unmarshal CreateKYC request: %v", err)
-			statusCode = http.StatusBadRequest
-			responseEnvelope = kycModels.KYCResponseEnvelope{
-				XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-				Body: kycModels.KYCResponseBody{
-					KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("Invalid CreateKYC request: %v", err)},
-				},
-			}
+			opErr = invalidRequestError(root, err)
+		} else if err := req.UserData.Validate(); err != nil {
+			log.Printf("KYC SOAP Server: Invalid UserData in CreateKYC request: %v", err)
+			opErr = invalidRequestError(root, err)
 		} else {
-			if err := repo.Create(req.UserData); err != nil {
+			created, err := createRecord(repo, audit, req.UserData, true)
+			var transitionErr *kyc.TransitionError
+			if errors.As(err, &transitionErr) {
+				log.Printf("KYC SOAP Server: Refused initial status for user %s: %v", req.UserData.ClientID, err)
+				opErr = recordError(root, err)
+			} else if err != nil {
 				log.Printf("KYC SOAP Server: Error creating user %s: %v", req.UserData.ClientID, err)
-				statusCode = http.StatusConflict // 409 for resource conflict
-				responseEnvelope = kycModels.KYCResponseEnvelope{
-					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-					Body: kycModels.KYCResponseBody{
-						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: err.Error()},
-					},
-				}
+				opErr = &operationError{operation: root, code: ErrorCodeAlreadyExists, message: err.Error(), httpStatus: http.StatusConflict} // 409 for resource conflict
 			} else {
 				responseEnvelope = kycModels.KYCResponseEnvelope{
 					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
 					Body: kycModels.KYCResponseBody{
-						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User created", UserData: &req.UserData},
+						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User created", UserData: &created.Record, RiskScore: created.RiskScore},
 					},
 				}
 			}
@@ -164,28 +163,20 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 		var req kycModels.UpdateKYCRequest
 		if err := xml.Unmarshal(envelope.Body.Content, &req); err != nil {
 			log.Printf("KYC SOAP Server: Failed to unmarshal UpdateKYC request: %v", err)
-			statusCode = http.StatusBadRequest
-			responseEnvelope = kycModels.KYCResponseEnvelope{
-				XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-				Body: kycModels.KYCResponseBody{
-					KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("Invalid UpdateKYC request: %v", err)},
-				},
-			}
+			opErr = invalidRequestError(root, err)
+		} else if err := req.UserData.Validate(); err != nil {
+			log.Printf("KYC SOAP Server: Invalid UserData in UpdateKYC request: %v", err)
+			opErr = invalidRequestError(root, err)
 		} else {
-			if err := repo.Update(req.UserData); err != nil {
+			updated, err := updateRecord(repo, audit, req.UserData, req.ExpectedVersion, true)
+			if err != nil {
 				log.Printf("KYC SOAP Server: Error updating user %s: %v", req.UserData.ClientID, err)
-				statusCode = http.StatusNotFound
-				responseEnvelope = kycModels.KYCResponseEnvelope{
-					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-					Body: kycModels.KYCResponseBody{
-						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: err.Error()},
-					},
-				}
+				opErr = recordError(root, err) // NOT_FOUND unless the status change or version was refused
 			} else {
 				responseEnvelope = kycModels.KYCResponseEnvelope{
 					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
 					Body: kycModels.KYCResponseBody{
-						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User updated", UserData: &req.UserData},
+						KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Success", Message: "User updated", UserData: &updated.Record, RiskScore: updated.RiskScore},
 					},
 				}
 			}
@@ -195,23 +186,11 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 		var req kycModels.DeleteKYCRequest
 		if err := xml.Unmarshal(envelope.Body.Content, &req); err != nil {
 			log.Printf("KYC SOAP Server: Failed to unmarshal DeleteKYC request: %v", err)
-			statusCode = http.StatusBadRequest
-			responseEnvelope = kycModels.DeleteKYCResponseEnvelope{
-				XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-				Body: kycModels.DeleteKYCResponseBody{
-					DeleteKYCResult: kycModels.DeleteKYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("Invalid DeleteKYC request: %v", err)},
-				},
-			}
+			opErr = invalidRequestError(root, err)
 		} else {
-			if err := repo.Delete(req.ClientID); err != nil {
+			if err := deleteRecord(repo, audit, req.ClientID, req.ExpectedVersion); err != nil {
 				log.Printf("KYC SOAP Server: Error deleting user %s: %v", req.ClientID, err)
-				statusCode = http.StatusNotFound
-				responseEnvelope = kycModels.DeleteKYCResponseEnvelope{
-					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-					Body: kycModels.DeleteKYCResponseBody{
-						DeleteKYCResult: kycModels.DeleteKYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: err.Error()},
-					},
-				}
+				opErr = recordError(root, err)
 			} else {
 				responseEnvelope = kycModels.DeleteKYCResponseEnvelope{
 					XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
@@ -222,17 +201,35 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+	case "SubmitKYC", "ApproveKYC", "RejectKYC":
+		responseEnvelope, opErr = handleTransitionOperation(repo, audit, root, envelope.Body.Content)
+
+	case "GetKYCStatus":
+		responseEnvelope, opErr = handleGetKYCStatus(repo, envelope.Body.Content)
+
+	case "ScoreKYC":
+		responseEnvelope, opErr = handleScoreKYC(envelope.Body.Content)
+
+	case "GetKYCHistory":
+		responseEnvelope, opErr = handleGetKYCHistory(repo, envelope.Body.Content)
+
+	case "BatchKYCQuery":
+		responseEnvelope, opErr = handleBatchKYCQuery(repo, envelope.Body.Content)
+
+	case "SearchKYC":
+		responseEnvelope, opErr = handleSearchKYC(repo, envelope.Body.Content)
+
 	default:
-		statusCode = http.StatusBadRequest
-		responseEnvelope = kycModels.KYCResponseEnvelope{
-			XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
-			Body: kycModels.KYCResponseBody{
-				KYCResult: kycModels.KYCResult{XmlnsKyc: kycNamespaceAttr, Status: "Error", Message: fmt.Sprintf("Unknown SOAP operation: %s", root)}},
-		}
+		opErr = &operationError{operation: root, code: ErrorCodeUnknownOperation, message: fmt.Sprintf("Unknown SOAP operation: %s", root), httpStatus: http.StatusBadRequest}
+	}
+
+	if opErr != nil {
+		writeOperationError(w, settings.Get().ErrorStyle, opErr)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
-	w.WriteHeader(statusCode)
+	w.WriteHeader(http.StatusOK)
 
 	// Marshal the specific response envelope into XML
 	responseBytes, err := xml.MarshalIndent(responseEnvelope, "", "  ")
@@ -246,26 +243,99 @@ func soapHandler(repo *InMemoryRepo, w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("KYC SOAP Server: Failed to write response: %v", err)
 	}
-	log.Printf("KYC SOAP Server: Sent SOAP response (Status: %d):\n%s", statusCode, string(responseBytes))
+	log.Printf("KYC SOAP Server: Sent SOAP response (Status: %d):\n%s", http.StatusOK, string(responseBytes))
 }
 
 // main function to start the SOAP server
 func main() {
-	repo := NewInMemoryRepo() // Use NewInMemoryRepo directly
-	log.Println("KYC SOAP Server: Initializing with in-memory repository.")
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
 
-	dataFolder := "tests/usecases/kyc"      // Define the path
-	loadUserDataFromFiles(repo, dataFolder) // Call the new function
+	s, err := settingsFromEnv()
+	if err != nil {
+		log.Fatalf("KYC SOAP Server: %v", err)
+	}
+	settings.Set(s)
+	log.Printf("KYC SOAP Server: Request validation mode: %s, error style: %s", s.ValidationMode, s.ErrorStyle)
+	callbackHosts = parseCallbackHosts(os.Getenv("KYC_CALLBACK_ALLOWED_HOSTS"))
+	if len(callbackHosts) > 0 {
+		log.Printf("KYC SOAP Server: Asynchronous requests may call back to %s", strings.Join(callbackHosts, ", "))
+	}
+
+	store := os.Getenv("KYC_STORE")
+	storePath := os.Getenv("KYC_STORE_PATH")
+	if storePath == "" {
+		storePath = "data/kyc.json"
+	}
+	relay, err := openChangeRelay(store)
+	if err != nil {
+		log.Fatalf("KYC SOAP Server: Failed to configure change events: %v", err)
+	}
+	if relay != nil {
+		defer relay.Close()
+		go relay.Run(context.Background())
+		changeRelay.Store(relay)
+	}
+
+	repo, err := newRepository(store, storePath)
+	if err != nil {
+		log.Fatalf("KYC SOAP Server: Failed to open repository: %v", err)
+	}
+	if store == StoreFile {
+		log.Printf("KYC SOAP Server: Initializing with file repository at %s.", storePath)
+	} else {
+		log.Println("KYC SOAP Server: Initializing with in-memory repository.")
+	}
+	captureChanges("", repo) // Before the fixtures load, so they are published too
+	if err := tenants.UseStore(store, tenantStoreDir(storePath)); err != nil {
+		log.Fatalf("KYC SOAP Server: Failed to open tenants: %v", err)
+	}
+
+	if rulesPath := os.Getenv("KYC_SCORING_RULES"); rulesPath != "" {
+		engine, err := scoring.Open(rulesPath)
+		if err != nil {
+			log.Fatalf("KYC SOAP Server: %v", err)
+		}
+		if err := engine.Watch(); err != nil {
+			log.Printf("KYC SOAP Server: Scoring rules will only reload through the admin API: %v", err)
+		}
+		defer engine.Close()
+		riskEngine.Store(engine)
+	}
+
+	dataFolder := os.Getenv("KYC_FIXTURES_DIR")
+	if dataFolder == "" {
+		dataFolder = "tests/usecases/kyc"
+	}
+	loader := loadUserDataFromFiles(repo, dataFolder)
+	snapshots.SetBaseline(repo.Snapshot()) // POST /admin/v1/reset returns here
+	if os.Getenv("KYC_FIXTURES_WATCH") != "false" {
+		if err := loader.Watch(); err != nil {
+			log.Printf("KYC SOAP Server: Fixtures will only reload through the admin API: %v", err)
+		}
+		defer loader.Close()
+	}
+	fixtures.Store(loader)
 
 	// Create a new ServeMux for routing
 	mux := http.NewServeMux()
 
-	// Register SOAP handler
-	mux.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) {
+	if size := os.Getenv("KYC_JOURNAL_SIZE"); size != "" {
+		capacity, err := strconv.Atoi(size)
+		if err != nil || capacity < 1 {
+			log.Fatalf("KYC SOAP Server: Invalid KYC_JOURNAL_SIZE %q", size)
+		}
+		journal.Resize(capacity)
+	}
+
+	// Register SOAP handler, recording every call in the request journal
+	mux.HandleFunc("/soap", journal.Record(func(w http.ResponseWriter, r *http.Request) {
 		soapHandler(repo, w, r)
-	})
+	}))
 
 	initAdminRoutes(mux, repo)
+	initTenantRoutes(mux)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -274,5 +344,5 @@ func main() {
 	listenAddr := fmt.Sprintf(":%s", port)
 
 	log.Printf("KYC SOAP Server: Listening on %s", listenAddr)
-	log.Fatal(http.ListenAndServe(listenAddr, mux))
+	log.Fatal(http.ListenAndServe(listenAddr, tenants.Route(mux)))
 }
@@ -9,26 +9,35 @@ import (
 	"strings"
 	"testing"
 
-	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
 	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
 )
 
 // Helper function to create a SOAP request XML
-func createSOAPRequest(operation string, clientID string, userData *models.UserData) string {
+func createSOAPRequest(operation string, clientID string, userData *kyc.Record) string {
 	var bodyContent string
 	switch operation {
 	case "KYCQuery":
 		bodyContent = fmt.Sprintf(`<%s xmlns="%s"><ClientID>%s</ClientID></%s>`, operation, kycNamespaceAttr, clientID, operation)
 	case "CreateKYC", "UpdateKYC":
 		if userData == nil {
-			userData = &models.UserData{ClientID: clientID, Risk: 0.0, Status: "Pending", Message: "Default message"}
+			userData = &kyc.Record{ClientID: clientID, Risk: 0.0, ReviewStatus: kyc.ReviewPending}
 		}
-		userDataXML, _ := xml.Marshal(userData)
+		userDataXML, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"UserData"`
+			kyc.Record
+		}{Record: *userData})
 		bodyContent = fmt.Sprintf(`<%s xmlns="%s">%s</%s>`, operation, kycNamespaceAttr, string(userDataXML), operation)
-	case "DeleteKYC":
+	case "DeleteKYC", "SubmitKYC", "GetKYCStatus", "GetKYCHistory":
 		bodyContent = fmt.Sprintf(`<%s xmlns="%s"><ClientID>%s</ClientID></%s>`, operation, kycNamespaceAttr, clientID, operation)
+	case "ApproveKYC":
+		bodyContent = fmt.Sprintf(`<%s xmlns="%s"><ClientID>%s</ClientID><Comment>documents verified</Comment></%s>`, operation, kycNamespaceAttr, clientID, operation)
+	case "RejectKYC":
+		bodyContent = fmt.Sprintf(`<%s xmlns="%s"><ClientID>%s</ClientID><Reason>document forged</Reason></%s>`, operation, kycNamespaceAttr, clientID, operation)
 	default:
 		bodyContent = fmt.Sprintf(`<%s xmlns="%s"></%s>`, operation, kycNamespaceAttr, operation) // Unknown operation
 	}
@@ -61,7 +70,7 @@ func unmarshalSOAPResponse(responseBody []byte, target interface{}) error {
 
 func TestSOAPHandler_KYCQuery(t *testing.T) {
 	repo := NewInMemoryRepo()
-	assert.NoError(t, repo.Create(models.UserData{ClientID: "client123", Risk: 0.7, Status: "Active", Message: "OK"}))
+	assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7, ReviewStatus: kyc.ReviewInReview}))
 
 	tests := []struct {
 		name             string
@@ -69,7 +78,7 @@ func TestSOAPHandler_KYCQuery(t *testing.T) {
 		expectedCode     int
 		expectedStatus   string
 		expectedMessage  string
-		expectedUserData *models.UserData
+		expectedUserData *kyc.Record
 	}{
 		{
 			name:             "Successful KYCQuery",
@@ -77,7 +86,7 @@ func TestSOAPHandler_KYCQuery(t *testing.T) {
 			expectedCode:     http.StatusOK,
 			expectedStatus:   "Success",
 			expectedMessage:  "User data retrieved",
-			expectedUserData: &models.UserData{XMLName: xml.Name{Space: "http://example.com/kyc", Local: "UserData"}, ClientID: "client123", Risk: 0.7, Status: "Active", Message: "OK"},
+			expectedUserData: &kyc.Record{ClientID: "client123", Risk: 0.7, ReviewStatus: kyc.ReviewInReview},
 		},
 		{
 			name:             "KYCQuery Client Not Found",
@@ -118,29 +127,36 @@ func TestSOAPHandler_CreateKYC(t *testing.T) {
 
 	tests := []struct {
 		name            string
-		userData        models.UserData
+		userData        kyc.Record
 		expectedCode    int
 		expectedStatus  string
 		expectedMessage string
 	}{
 		{
 			name:            "Successful CreateKYC",
-			userData:        models.UserData{ClientID: "newclient", Risk: 0.1, Status: "Pending", Message: "New user"},
+			userData:        kyc.Record{ClientID: "newclient", Risk: 0.1, ReviewStatus: kyc.ReviewPending},
 			expectedCode:    http.StatusOK,
 			expectedStatus:  "Success",
 			expectedMessage: "User created",
 		},
 		{
 			name:            "CreateKYC Already Exists",
-			userData:        models.UserData{ClientID: "client123", Risk: 0.2, Status: "Active", Message: "Existing user"},
+			userData:        kyc.Record{ClientID: "client123", Risk: 0.2, ReviewStatus: kyc.ReviewPending},
 			expectedCode:    http.StatusConflict,
 			expectedStatus:  "Error",
 			expectedMessage: "user with ClientID 'client123' already exists",
 		},
+		{
+			name:            "CreateKYC Already Decided",
+			userData:        kyc.Record{ClientID: "decided", Risk: 0.2, ReviewStatus: kyc.ReviewApproved},
+			expectedCode:    http.StatusConflict,
+			expectedStatus:  "Error",
+			expectedMessage: "cannot be created as APPROVED",
+		},
 	}
 
 	// Create a client123 before running tests to test conflict
-	assert.NoError(t, repo.Create(models.UserData{ClientID: "client123", Risk: 0.7, Status: "Active", Message: "OK"}))
+	assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7, ReviewStatus: kyc.ReviewInReview}))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -161,8 +177,7 @@ func TestSOAPHandler_CreateKYC(t *testing.T) {
 				// Risk might be slightly different due to float precision, compare ClientID
 				assert.NotNil(t, response.UserData)
 				assert.Equal(t, tt.userData.ClientID, response.UserData.ClientID)
-				assert.Equal(t, tt.userData.Status, response.UserData.Status)
-				assert.Equal(t, tt.userData.Message, response.UserData.Message)
+				assert.Equal(t, tt.userData.ReviewStatus, response.UserData.ReviewStatus)
 				// Risk is assigned by server, so just check it's a float
 				assert.NotZero(t, response.UserData.Risk) // Ensure it's not default 0
 			} else {
@@ -174,29 +189,36 @@ func TestSOAPHandler_CreateKYC(t *testing.T) {
 
 func TestSOAPHandler_UpdateKYC(t *testing.T) {
 	repo := NewInMemoryRepo()
-	assert.NoError(t, repo.Create(models.UserData{ClientID: "client123", Risk: 0.7, Status: "Active", Message: "OK"}))
+	assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7, ReviewStatus: kyc.ReviewInReview}))
 
 	tests := []struct {
 		name            string
-		userData        models.UserData
+		userData        kyc.Record
 		expectedCode    int
 		expectedStatus  string
 		expectedMessage string
 	}{
 		{
 			name:            "Successful UpdateKYC",
-			userData:        models.UserData{ClientID: "client123", Risk: 0.9, Status: "Suspended", Message: "Updated message"},
+			userData:        kyc.Record{ClientID: "client123", Risk: 0.9, ReviewStatus: kyc.ReviewRejected},
 			expectedCode:    http.StatusOK,
 			expectedStatus:  "Success",
 			expectedMessage: "User updated",
 		},
 		{
 			name:            "UpdateKYC Client Not Found",
-			userData:        models.UserData{ClientID: "nonexistent", Risk: 0.1},
+			userData:        kyc.Record{ClientID: "nonexistent", Risk: 0.1},
 			expectedCode:    http.StatusNotFound,
 			expectedStatus:  "Error",
 			expectedMessage: "user with ClientID 'nonexistent' not found",
 		},
+		{
+			name:            "UpdateKYC Refused Transition",
+			userData:        kyc.Record{ClientID: "client123", Risk: 0.9, ReviewStatus: kyc.ReviewApproved},
+			expectedCode:    http.StatusConflict,
+			expectedStatus:  "Error",
+			expectedMessage: "cannot move from REJECTED to APPROVED",
+		},
 	}
 
 	for _, tt := range tests {
@@ -218,8 +240,7 @@ func TestSOAPHandler_UpdateKYC(t *testing.T) {
 				assert.NotNil(t, response.UserData)
 				assert.Equal(t, tt.userData.ClientID, response.UserData.ClientID)
 				assert.Equal(t, tt.userData.Risk, response.UserData.Risk)
-				assert.Equal(t, tt.userData.Status, response.UserData.Status)
-				assert.Equal(t, tt.userData.Message, response.UserData.Message)
+				assert.Equal(t, tt.userData.ReviewStatus, response.UserData.ReviewStatus)
 			} else {
 				assert.Nil(t, response.UserData)
 			}
@@ -229,7 +250,7 @@ func TestSOAPHandler_UpdateKYC(t *testing.T) {
 
 func TestSOAPHandler_DeleteKYC(t *testing.T) {
 	repo := NewInMemoryRepo()
-	assert.NoError(t, repo.Create(models.UserData{ClientID: "client123", Risk: 0.7, Status: "Active", Message: "OK"}))
+	assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7, ReviewStatus: kyc.ReviewInReview}))
 
 	tests := []struct {
 		name            string
@@ -311,3 +332,315 @@ func TestSOAPHandler_UnknownOperation(t *testing.T) {
 	assert.Equal(t, "Error", response.Status)
 	assert.Contains(t, response.Message, "Unknown SOAP operation: UnknownOperation")
 }
+
+func TestSOAPHandler_Contract(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	t.Run("WSDL uses the request host as service address", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/soap?wsdl", nil)
+		req.Host = "kyc-provider-service:8081"
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `<soap:address location="http://kyc-provider-service:8081/soap"/>`)
+		assert.Contains(t, rec.Body.String(), `schemaLocation="http://kyc-provider-service:8081/soap?xsd=1"`)
+	})
+
+	t.Run("WSDL honours forwarded headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/soap?wsdl", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "kyc.example.org")
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, req)
+
+		assert.Contains(t, rec.Body.String(), `<soap:address location="https://kyc.example.org/soap"/>`)
+	})
+
+	t.Run("WSDL ignores forwarded headers that are not a host", func(t *testing.T) {
+		for _, fwd := range []string{`evil.example"/><x y="`, "evil.example/path", "evil example", "$1.example"} {
+			req := httptest.NewRequest("GET", "/soap?wsdl", nil)
+			req.Host = "kyc-provider-service:8081"
+			req.Header.Set("X-Forwarded-Proto", "javascript")
+			req.Header.Set("X-Forwarded-Host", fwd)
+			rec := httptest.NewRecorder()
+			soapHandler(repo, rec, req)
+
+			assert.Contains(t, rec.Body.String(), `<soap:address location="http://kyc-provider-service:8081/soap"/>`, fwd)
+			assert.NotContains(t, rec.Body.String(), "evil", fwd)
+		}
+	})
+
+	t.Run("WSDL escapes the service address", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/soap?wsdl", nil)
+		req.Host = `kyc"provider`
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, req)
+
+		assert.Contains(t, rec.Body.String(), `<soap:address location="http://localhost/soap"/>`, "an invalid Host falls back to localhost")
+		assert.Equal(t, "http://example.com/soap&amp;x", serviceAddress(httptest.NewRequest("GET", "/soap&x?wsdl", nil)))
+	})
+
+	t.Run("XSD by index", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("GET", "/soap?xsd=1", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<xs:complexType name="UserData">`)
+
+		rec = httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("GET", "/soap?xsd=2", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Plain GET is still rejected", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("GET", "/soap", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+// TestSOAPHandler_ResponsesMatchContract checks every response body against the published schema
+func TestSOAPHandler_ResponsesMatchContract(t *testing.T) {
+	repo := NewInMemoryRepo()
+	requests := []string{
+		createSOAPRequest("CreateKYC", "client1", &kyc.Record{ClientID: "client1", Risk: 0.3}),
+		createSOAPRequest("KYCQuery", "client1", nil),
+		createSOAPRequest("KYCQuery", "missing", nil),
+		createSOAPRequest("UpdateKYC", "client1", &kyc.Record{ClientID: "client1", Risk: 0.6, ReviewStatus: kyc.ReviewInReview}),
+		createSOAPRequest("ApproveKYC", "client1", nil),
+		createSOAPRequest("SubmitKYC", "client1", nil),
+		createSOAPRequest("GetKYCStatus", "client1", nil),
+		createSOAPRequest("GetKYCStatus", "missing", nil),
+		createSOAPRequest("DeleteKYC", "client1", nil),
+		createSOAPRequest("DeleteKYC", "client1", nil),
+	}
+	for _, body := range requests {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(body)))
+
+		root, violations, err := contract.KYCSchema().ValidateEnvelope(rec.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Empty(t, violations, "response %s violates the contract", root.Local)
+	}
+}
+
+func TestSOAPHandler_Validation(t *testing.T) {
+	defer settings.Set(defaultSettings())
+
+	missingClientID := fmt.Sprintf(`%s%s<CreateKYC xmlns="%s"><UserData><Risk>abc</Risk></UserData></CreateKYC>%s%s`,
+		soapEnvelopeStart, soapBodyStart, kycNamespaceAttr, soapBodyEnd, soapEnvelopeEnd)
+	extraElement := fmt.Sprintf(`%s%s<KYCQuery xmlns="%s"><ClientID>client123</ClientID><Channel>web</Channel></KYCQuery>%s%s`,
+		soapEnvelopeStart, soapBodyStart, kycNamespaceAttr, soapBodyEnd, soapEnvelopeEnd)
+
+	tests := []struct {
+		name           string
+		mode           ValidationMode
+		body           string
+		expectedCode   int
+		expectedFault  bool
+		expectedStatus string
+	}{
+		{name: "Strict rejects invalid values", mode: ValidationStrict, body: missingClientID, expectedCode: http.StatusInternalServerError, expectedFault: true},
+		{name: "Lenient rejects invalid values", mode: ValidationLenient, body: missingClientID, expectedCode: http.StatusInternalServerError, expectedFault: true},
+		{name: "Off lets invalid values through", mode: ValidationOff, body: missingClientID, expectedCode: http.StatusBadRequest, expectedStatus: "Error"},
+		{name: "Strict rejects unknown elements", mode: ValidationStrict, body: extraElement, expectedCode: http.StatusInternalServerError, expectedFault: true},
+		{name: "Lenient tolerates unknown elements", mode: ValidationLenient, body: extraElement, expectedCode: http.StatusOK, expectedStatus: "Success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewInMemoryRepo()
+			assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7}))
+			settings.Set(Settings{ValidationMode: tt.mode})
+
+			rec := httptest.NewRecorder()
+			soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			if !tt.expectedFault {
+				var response kycModels.KYCResult
+				assert.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedStatus, response.Status)
+				return
+			}
+			fault := unmarshalSOAPFault(t, rec.Body.Bytes())
+			assert.Equal(t, "soapenv:Client", fault.FaultCode)
+			if assert.NotNil(t, fault.Detail.ValidationFault) {
+				assert.NotEmpty(t, fault.Detail.ValidationFault.Violations)
+			}
+		})
+	}
+
+	t.Run("Fault lists every violation", func(t *testing.T) {
+		settings.Set(Settings{ValidationMode: ValidationStrict})
+		rec := httptest.NewRecorder()
+		soapHandler(NewInMemoryRepo(), rec, httptest.NewRequest("POST", "/soap", strings.NewReader(missingClientID)))
+
+		fault := unmarshalSOAPFault(t, rec.Body.Bytes())
+		require.NotNil(t, fault.Detail.ValidationFault)
+		assert.Equal(t, []kycModels.Violation{
+			{Kind: "missing", Path: "/CreateKYC/UserData/ClientID", Message: "missing required element", Expected: "xs:string"},
+			{Kind: "type", Path: "/CreateKYC/UserData/Risk", Message: `"abc" is not a valid xs:double`, Expected: "xs:double"},
+		}, fault.Detail.ValidationFault.Violations)
+
+		violations, err := contract.KYCSchema().ValidateElement(fault.Detail.Content)
+		assert.NoError(t, err)
+		assert.Empty(t, violations, "fault detail must match the ValidationFault element of the contract")
+	})
+}
+
+// testFault decodes the SOAP Fault written by writeSOAPFault
+type testFault struct {
+	FaultCode   string `xml:"faultcode"`
+	FaultString string `xml:"faultstring"`
+	Detail      struct {
+		Content         []byte                     `xml:",innerxml"`
+		ValidationFault *kycModels.ValidationFault `xml:"http://example.com/kyc ValidationFault"`
+		KYCFault        *kycModels.KYCFault        `xml:"http://example.com/kyc KYCFault"`
+	} `xml:"detail"`
+}
+
+// Helper to unmarshal a SOAP fault response
+func unmarshalSOAPFault(t *testing.T, responseBody []byte) testFault {
+	t.Helper()
+	var envelope struct {
+		Fault testFault `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body>Fault"`
+	}
+	require.NoError(t, xml.Unmarshal(responseBody, &envelope))
+	return envelope.Fault
+}
+
+func TestAdminSettings(t *testing.T) {
+	defer settings.Set(defaultSettings())
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, NewInMemoryRepo())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/admin/v1/settings", strings.NewReader(`{"validationMode":"strict"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ValidationStrict, settings.Get().ValidationMode)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/admin/v1/settings", strings.NewReader(`{"validationMode":"picky"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, ValidationStrict, settings.Get().ValidationMode)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/v1/settings", nil))
+	assert.JSONEq(t, `{"validationMode":"strict","errorStyle":"legacy"}`, rec.Body.String())
+}
+
+func TestAdminSnapshots(t *testing.T) {
+	defer func() { snapshots = newSnapshotStore() }()
+	repo := NewInMemoryRepo()
+	repo.Create(kyc.Record{ClientID: "fixture1", Risk: 0.1})
+	snapshots.SetBaseline(repo.Snapshot())
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// Take a snapshot after a test-specific change
+	repo.Create(kyc.Record{ClientID: "client1", Risk: 0.5})
+	repo.SetAction("client1", ActionTimeout)
+	rec := serve("POST", "/admin/v1/snapshots", `{"name":"before"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"name":"before","users":2,"actions":1}`, rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/admin/v1/snapshots", `{"name":""}`).Code)
+
+	// Mutate, then restore
+	repo.Delete("client1")
+	repo.ClearAction("client1")
+	repo.Create(kyc.Record{ClientID: "client2", Risk: 0.9})
+	rec = serve("POST", "/admin/v1/snapshots/before/restore", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, err := repo.Read("client1")
+	assert.NoError(t, err)
+	_, err = repo.Read("client2")
+	assert.Error(t, err)
+	action, ok := repo.GetAction("client1")
+	assert.True(t, ok)
+	assert.Equal(t, ActionTimeout, action)
+
+	// A restored snapshot stays reusable after further changes
+	repo.Delete("client1")
+	assert.Equal(t, http.StatusOK, serve("POST", "/admin/v1/snapshots/before/restore", "").Code)
+	_, err = repo.Read("client1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, serve("POST", "/admin/v1/snapshots/missing/restore", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("GET", "/admin/v1/snapshots/before/restore", "").Code)
+
+	rec = serve("GET", "/admin/v1/snapshots", "")
+	assert.JSONEq(t, `[{"name":"before","users":2,"actions":1}]`, rec.Body.String())
+
+	// Reset returns to the fixture baseline
+	rec = serve("POST", "/admin/v1/reset", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []kyc.Record{{ClientID: "fixture1", Risk: 0.1}}, repo.List())
+	_, ok = repo.GetAction("client1")
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, serve("DELETE", "/admin/v1/snapshots/before", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/admin/v1/snapshots/before", "").Code)
+}
+
+func TestSOAPHandler_ErrorStyles(t *testing.T) {
+	defer settings.Set(defaultSettings())
+
+	tests := []struct {
+		name              string
+		body              string
+		action            Action
+		legacyCode        int
+		legacyBody        string
+		expectedFaultCode string
+		expectedErrorCode ErrorCode
+	}{
+		{name: "Read of unknown user", body: createSOAPRequest("KYCQuery", "missing", nil), legacyCode: http.StatusNotFound, legacyBody: "<Status>Error</Status>", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeNotFound},
+		{name: "Create of existing user", body: createSOAPRequest("CreateKYC", "client123", &kyc.Record{ClientID: "client123", Risk: 0.1}), legacyCode: http.StatusConflict, legacyBody: "already exists", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeAlreadyExists},
+		{name: "Delete of unknown user", body: createSOAPRequest("DeleteKYC", "missing", nil), legacyCode: http.StatusNotFound, legacyBody: "<DeleteKYCResponse", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeNotFound},
+		{name: "Refused transition", body: createSOAPRequest("ApproveKYC", "client123", nil), legacyCode: http.StatusConflict, legacyBody: "cannot move from PENDING to APPROVED", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeInvalidTransition},
+		{name: "Stale delete", body: soapEnvelopeStart + soapBodyStart + `<DeleteKYC xmlns="http://example.com/kyc"><ClientID>client123</ClientID><ExpectedVersion>5</ExpectedVersion></DeleteKYC>` + soapBodyEnd + soapEnvelopeEnd, legacyCode: http.StatusConflict, legacyBody: "not the expected version 5", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeVersionConflict},
+		{name: "Status of unknown user", body: createSOAPRequest("GetKYCStatus", "missing", nil), legacyCode: http.StatusNotFound, legacyBody: "<KYCStatusResponse", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeNotFound},
+		{name: "Simulated internal error", body: createSOAPRequest("KYCQuery", "client123", nil), action: ActionInternalError, legacyCode: http.StatusInternalServerError, legacyBody: "Internal Server Error (simulated)", expectedFaultCode: "soapenv:Server", expectedErrorCode: ErrorCodeInternalError},
+		{name: "Unknown operation", body: createSOAPRequest("UnknownOperation", "client123", nil), legacyCode: http.StatusBadRequest, legacyBody: "Unknown SOAP operation", expectedFaultCode: "soapenv:Client", expectedErrorCode: ErrorCodeUnknownOperation},
+	}
+
+	for _, tt := range tests {
+		for _, style := range []ErrorStyle{ErrorStyleLegacy, ErrorStyleFault} {
+			t.Run(fmt.Sprintf("%s (%s)", tt.name, style), func(t *testing.T) {
+				repo := NewInMemoryRepo()
+				assert.NoError(t, repo.Create(kyc.Record{ClientID: "client123", Risk: 0.7}))
+				if tt.action != "" {
+					repo.SetAction("client123", tt.action)
+				}
+				settings.Set(Settings{ValidationMode: ValidationLenient, ErrorStyle: style})
+
+				rec := httptest.NewRecorder()
+				soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(tt.body)))
+
+				if style == ErrorStyleLegacy {
+					assert.Equal(t, tt.legacyCode, rec.Code)
+					assert.Contains(t, rec.Body.String(), tt.legacyBody)
+					return
+				}
+				assert.Equal(t, http.StatusInternalServerError, rec.Code)
+				fault := unmarshalSOAPFault(t, rec.Body.Bytes())
+				assert.Equal(t, tt.expectedFaultCode, fault.FaultCode)
+				assert.NotEmpty(t, fault.FaultString)
+				require.NotNil(t, fault.Detail.KYCFault)
+				assert.Equal(t, string(tt.expectedErrorCode), fault.Detail.KYCFault.ErrorCode)
+
+				violations, err := contract.KYCSchema().ValidateElement(fault.Detail.Content)
+				assert.NoError(t, err)
+				assert.Empty(t, violations, "fault detail must match the KYCFault element of the contract")
+			})
+		}
+	}
+}
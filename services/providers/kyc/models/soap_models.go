@@ -2,7 +2,9 @@ package models
 
 import (
 	"encoding/xml"
-	consumerModels "kafka-soap-e2e-test/services/consumer/models" // Alias to avoid conflict with this package's name
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 // --- SOAP Request/Response Models for this Service ---
@@ -10,9 +12,24 @@ import (
 // Generic SOAP Envelope for parsing incoming requests
 type SOAPEnvelope struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	Header  *SOAPHeader
 	Body    SOAPBody
 }
 
+// SOAPHeader holds the WS-Addressing headers of a request; other header blocks are ignored
+type SOAPHeader struct {
+	XMLName   xml.Name           `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	MessageID string             `xml:"http://www.w3.org/2005/08/addressing MessageID"`
+	ReplyTo   *EndpointReference `xml:"http://www.w3.org/2005/08/addressing ReplyTo"`
+	Action    string             `xml:"http://www.w3.org/2005/08/addressing Action"`
+	To        string             `xml:"http://www.w3.org/2005/08/addressing To"`
+}
+
+// EndpointReference is a WS-Addressing endpoint such as wsa:ReplyTo
+type EndpointReference struct {
+	Address string `xml:"http://www.w3.org/2005/08/addressing Address"`
+}
+
 type SOAPBody struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
 	Content []byte   `xml:",innerxml"` // To unmarshal the actual payload of the operation
@@ -20,28 +37,88 @@ type SOAPBody struct {
 
 // KYCQuery - For Read operation
 type KYCQuery struct {
-	XMLName  xml.Name `xml:"http://example.com/kyc KYCQuery"`
-	ClientID string   `xml:"ClientID"`
+	XMLName  xml.Name   `xml:"http://example.com/kyc KYCQuery"`
+	ClientID string     `xml:"ClientID"`
+	AsOf     *time.Time `xml:"AsOf,omitempty"` // Read the record as it stood at this time
 }
 
 // CreateKYCRequest - For Create operation, expects full UserData
 type CreateKYCRequest struct {
-	XMLName  xml.Name                `xml:"http://example.com/kyc CreateKYC"`
-	UserData consumerModels.UserData `xml:"UserData"`
+	XMLName  xml.Name   `xml:"http://example.com/kyc CreateKYC"`
+	UserData kyc.Record `xml:"UserData"`
 }
 
 // UpdateKYCRequest - For Update operation, expects full UserData
 type UpdateKYCRequest struct {
-	XMLName  xml.Name                `xml:"http://example.com/kyc UpdateKYC"`
-	UserData consumerModels.UserData `xml:"UserData"`
+	XMLName         xml.Name   `xml:"http://example.com/kyc UpdateKYC"`
+	UserData        kyc.Record `xml:"UserData"`
+	ExpectedVersion *int64     `xml:"ExpectedVersion,omitempty"` // Refuse the update unless the stored record is at this version
 }
 
 // DeleteKYCRequest - For Delete operation, expects ClientID
 type DeleteKYCRequest struct {
-	XMLName  xml.Name `xml:"http://example.com/kyc DeleteKYC"`
+	XMLName         xml.Name `xml:"http://example.com/kyc DeleteKYC"`
+	ClientID        string   `xml:"ClientID"`
+	ExpectedVersion *int64   `xml:"ExpectedVersion,omitempty"` // Refuse the delete unless the stored record is at this version
+}
+
+// SubmitKYCRequest - For the PENDING -> IN_REVIEW transition
+type SubmitKYCRequest struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc SubmitKYC"`
+	ClientID string   `xml:"ClientID"`
+}
+
+// ApproveKYCRequest - For the IN_REVIEW -> APPROVED transition
+type ApproveKYCRequest struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc ApproveKYC"`
+	ClientID string   `xml:"ClientID"`
+	Comment  string   `xml:"Comment"`
+}
+
+// RejectKYCRequest - For the IN_REVIEW -> REJECTED transition
+type RejectKYCRequest struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc RejectKYC"`
+	ClientID string   `xml:"ClientID"`
+	Reason   string   `xml:"Reason"`
+}
+
+// GetKYCStatusRequest - For reading a record's lifecycle status
+type GetKYCStatusRequest struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc GetKYCStatus"`
+	ClientID string   `xml:"ClientID"`
+}
+
+// ScoreKYCRequest - For scoring a record without storing it
+type ScoreKYCRequest struct {
+	XMLName  xml.Name   `xml:"http://example.com/kyc ScoreKYC"`
+	UserData kyc.Record `xml:"UserData"`
+}
+
+// GetKYCHistoryRequest - For reading a record's audit trail
+type GetKYCHistoryRequest struct {
+	XMLName  xml.Name `xml:"http://example.com/kyc GetKYCHistory"`
 	ClientID string   `xml:"ClientID"`
 }
 
+// BatchKYCQueryRequest - For reading several records in one call
+type BatchKYCQueryRequest struct {
+	XMLName   xml.Name `xml:"http://example.com/kyc BatchKYCQuery"`
+	ClientIDs []string `xml:"ClientID"`
+}
+
+// SearchKYCRequest - For finding records by criteria, a page at a time
+type SearchKYCRequest struct {
+	XMLName        xml.Name           `xml:"http://example.com/kyc SearchKYC"`
+	MinRisk        *float64           `xml:"MinRisk,omitempty"`
+	MaxRisk        *float64           `xml:"MaxRisk,omitempty"`
+	ReviewStatuses []kyc.ReviewStatus `xml:"ReviewStatus,omitempty"`
+	NameContains   string             `xml:"NameContains,omitempty"`
+	DocumentNumber string             `xml:"DocumentNumber,omitempty"`
+	CreatedAfter   *time.Time         `xml:"CreatedAfter,omitempty"`
+	PageSize       int                `xml:"PageSize,omitempty"`
+	PageToken      string             `xml:"PageToken,omitempty"`
+}
+
 // KYCResponseEnvelope for Read, Update, Create operations
 type KYCResponseEnvelope struct {
 	XMLName      xml.Name `xml:"soapenv:Envelope"`
@@ -55,11 +132,12 @@ type KYCResponseBody struct {
 }
 
 type KYCResult struct {
-	XMLName  xml.Name                 `xml:"http://example.com/kyc KYCResponse"` // Use full namespace
-	XmlnsKyc string                   `xml:"xmlns"`
-	Status   string                   `xml:"Status"`
-	Message  string                   `xml:"Message"`
-	UserData *consumerModels.UserData `xml:"UserData,omitempty"` // Pointer to optionally include UserData
+	XMLName   xml.Name       `xml:"http://example.com/kyc KYCResponse"` // Use full namespace
+	XmlnsKyc  string         `xml:"-"`                                  // Namespace is declared by XMLName
+	Status    string         `xml:"Status"`
+	Message   string         `xml:"Message"`
+	UserData  *kyc.Record    `xml:"UserData,omitempty"`  // Pointer to optionally include UserData
+	RiskScore *kyc.RiskScore `xml:"RiskScore,omitempty"` // Breakdown of a risk computed by the scoring engine
 }
 
 // DeleteKYCResponse - specific for delete
@@ -76,7 +154,188 @@ type DeleteKYCResponseBody struct {
 
 type DeleteKYCResult struct {
 	XMLName  xml.Name `xml:"http://example.com/kyc DeleteKYCResponse"` // Use full namespace
-	XmlnsKyc string   `xml:"xmlns"`
+	XmlnsKyc string   `xml:"-"`                                        // Namespace is declared by XMLName
 	Status   string   `xml:"Status"`
 	Message  string   `xml:"Message"`
 }
+
+// KYCStatusResponseEnvelope - specific for GetKYCStatus
+type KYCStatusResponseEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         KYCStatusResponseBody
+}
+
+type KYCStatusResponseBody struct {
+	XMLName         xml.Name        `xml:"soapenv:Body"`
+	KYCStatusResult KYCStatusResult `xml:"http://example.com/kyc KYCStatusResponse"`
+}
+
+type KYCStatusResult struct {
+	XMLName            xml.Name           `xml:"http://example.com/kyc KYCStatusResponse"`
+	Status             string             `xml:"Status"`
+	Message            string             `xml:"Message"`
+	ClientID           string             `xml:"ClientID,omitempty"`
+	ReviewStatus       kyc.ReviewStatus   `xml:"ReviewStatus,omitempty"`
+	ReviewNote         string             `xml:"ReviewNote,omitempty"`
+	ReviewedAt         *time.Time         `xml:"ReviewedAt,omitempty"`
+	AllowedTransitions []kyc.ReviewStatus `xml:"AllowedTransition,omitempty"`
+}
+
+// ScoreKYCResponseEnvelope - specific for ScoreKYC
+type ScoreKYCResponseEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         ScoreKYCResponseBody
+}
+
+type ScoreKYCResponseBody struct {
+	XMLName        xml.Name       `xml:"soapenv:Body"`
+	ScoreKYCResult ScoreKYCResult `xml:"http://example.com/kyc ScoreKYCResponse"`
+}
+
+type ScoreKYCResult struct {
+	XMLName   xml.Name       `xml:"http://example.com/kyc ScoreKYCResponse"`
+	Status    string         `xml:"Status"`
+	Message   string         `xml:"Message"`
+	ClientID  string         `xml:"ClientID,omitempty"`
+	RiskScore *kyc.RiskScore `xml:"RiskScore,omitempty"`
+}
+
+// KYCHistoryResponseEnvelope - specific for GetKYCHistory
+type KYCHistoryResponseEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         KYCHistoryResponseBody
+}
+
+type KYCHistoryResponseBody struct {
+	XMLName          xml.Name         `xml:"soapenv:Body"`
+	KYCHistoryResult KYCHistoryResult `xml:"http://example.com/kyc KYCHistoryResponse"`
+}
+
+type KYCHistoryResult struct {
+	XMLName  xml.Name         `xml:"http://example.com/kyc KYCHistoryResponse"`
+	Status   string           `xml:"Status"`
+	Message  string           `xml:"Message"`
+	ClientID string           `xml:"ClientID,omitempty"`
+	Events   []kyc.AuditEvent `xml:"Event,omitempty"`
+}
+
+// BatchKYCQueryResponseEnvelope - specific for BatchKYCQuery
+type BatchKYCQueryResponseEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         BatchKYCQueryResponseBody
+}
+
+type BatchKYCQueryResponseBody struct {
+	XMLName             xml.Name            `xml:"soapenv:Body"`
+	BatchKYCQueryResult BatchKYCQueryResult `xml:"http://example.com/kyc BatchKYCQueryResponse"`
+}
+
+type BatchKYCQueryResult struct {
+	XMLName xml.Name       `xml:"http://example.com/kyc BatchKYCQueryResponse"`
+	Status  string         `xml:"Status"`
+	Message string         `xml:"Message"`
+	Results []BatchKYCItem `xml:"Result,omitempty"`
+}
+
+// BatchKYCItem is the result of one ClientID of a BatchKYCQuery
+type BatchKYCItem struct {
+	ClientID  string      `xml:"ClientID"`
+	Status    string      `xml:"Status"`
+	Message   string      `xml:"Message"`
+	ErrorCode string      `xml:"ErrorCode,omitempty"`
+	FaultCode string      `xml:"FaultCode,omitempty"` // Set in the fault error style
+	UserData  *kyc.Record `xml:"UserData,omitempty"`
+}
+
+// SearchKYCResponseEnvelope - specific for SearchKYC
+type SearchKYCResponseEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         SearchKYCResponseBody
+}
+
+type SearchKYCResponseBody struct {
+	XMLName         xml.Name        `xml:"soapenv:Body"`
+	SearchKYCResult SearchKYCResult `xml:"http://example.com/kyc SearchKYCResponse"`
+}
+
+type SearchKYCResult struct {
+	XMLName       xml.Name     `xml:"http://example.com/kyc SearchKYCResponse"`
+	Status        string       `xml:"Status"`
+	Message       string       `xml:"Message"`
+	Total         int          `xml:"Total,omitempty"`
+	NextPageToken string       `xml:"NextPageToken,omitempty"`
+	Users         []kyc.Record `xml:"UserData,omitempty"`
+}
+
+// --- SOAP Faults ---
+
+// AddressingHeader is the WS-Addressing soapenv:Header of an acknowledgement or a callback. It is
+// written inside an envelope that declares the soapenv prefix.
+type AddressingHeader struct {
+	XMLName   xml.Name `xml:"soapenv:Header"`
+	XmlnsWsa  string   `xml:"xmlns:wsa,attr"`
+	MessageID string   `xml:"wsa:MessageID,omitempty"`
+	RelatesTo string   `xml:"wsa:RelatesTo"`
+	Action    string   `xml:"wsa:Action,omitempty"`
+	To        string   `xml:"wsa:To,omitempty"`
+}
+
+// AcknowledgementEnvelope accepts an asynchronous request; its response follows to the ReplyTo address
+type AcknowledgementEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Header       AddressingHeader
+	Body         struct {
+		XMLName xml.Name `xml:"soapenv:Body"`
+	}
+}
+
+// FaultEnvelope carries a SOAP 1.1 Fault in place of an operation's response
+type FaultEnvelope struct {
+	XMLName      xml.Name `xml:"soapenv:Envelope"`
+	XmlnsSoapenv string   `xml:"xmlns:soapenv,attr"`
+	Body         FaultBody
+}
+
+type FaultBody struct {
+	XMLName xml.Name `xml:"soapenv:Body"`
+	Fault   Fault
+}
+
+type Fault struct {
+	XMLName     xml.Name     `xml:"soapenv:Fault"`
+	FaultCode   string       `xml:"faultcode"`
+	FaultString string       `xml:"faultstring"`
+	Detail      *FaultDetail `xml:"detail,omitempty"`
+}
+
+// FaultDetail holds the typed detail elements declared in the service's XSD
+type FaultDetail struct {
+	ValidationFault *ValidationFault `xml:"http://example.com/kyc ValidationFault,omitempty"`
+	KYCFault        *KYCFault        `xml:"http://example.com/kyc KYCFault,omitempty"`
+}
+
+// KYCFault is the detail of a failed operation
+type KYCFault struct {
+	XMLName   xml.Name `xml:"http://example.com/kyc KYCFault"`
+	ErrorCode string   `xml:"ErrorCode"`
+	Operation string   `xml:"Operation,omitempty"`
+}
+
+// ValidationFault lists the schema violations of a rejected request
+type ValidationFault struct {
+	XMLName    xml.Name    `xml:"http://example.com/kyc ValidationFault"`
+	Violations []Violation `xml:"Violation"`
+}
+
+type Violation struct {
+	Kind     string `xml:"Kind"`
+	Path     string `xml:"Path"`
+	Message  string `xml:"Message"`
+	Expected string `xml:"Expected,omitempty"`
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// now is the clock used for record timestamps
+var now = time.Now
+
+// writeMu serializes the read-modify-write of updates, deletes and transitions, so that writes
+// without an expected version do not fail Repository.Apply's version check against each other
+var writeMu sync.Mutex
+
+// createRecord scores and stamps record as new, stores it and records the change in the audit log.
+// The caller validates it first. Records without a review status start as PENDING; with
+// enforceLifecycle set they must.
+func createRecord(repo Repository, audit auditContext, record kyc.Record, enforceLifecycle bool) (scoredRecord, error) {
+	created, err := newRecord(record, enforceLifecycle)
+	if err != nil {
+		return scoredRecord{}, err
+	}
+	after := created.Record
+	if err := commitChanges(repo, audit, audit.event(kyc.AuditCreate, record.ClientID, nil, &after)); err != nil {
+		return scoredRecord{}, err
+	}
+	return created, nil
+}
+
+// newRecord scores and stamps record as new, as createRecord stores it
+func newRecord(record kyc.Record, enforceLifecycle bool) (scoredRecord, error) {
+	if record.ReviewStatus == "" {
+		record.ReviewStatus = kyc.ReviewPending
+	}
+	if enforceLifecycle && record.ReviewStatus != kyc.ReviewPending {
+		return scoredRecord{}, &kyc.TransitionError{ClientID: record.ClientID, To: record.ReviewStatus}
+	}
+	score := scoreRecord(&record)
+	record.Touch(nil, now())
+	return scoredRecord{Record: record, RiskScore: score}, nil
+}
+
+// updateRecord scores and stamps record as a replacement of the stored one, stores it and records
+// the change in the audit log. The caller validates it first. With expectedVersion set the stored
+// record must be at that version. Without a review status the stored status and note are kept;
+// with enforceLifecycle set a changed status must be a transition the lifecycle allows.
+func updateRecord(repo Repository, audit auditContext, record kyc.Record, expectedVersion *int64, enforceLifecycle bool) (scoredRecord, error) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	previous, err := repo.Read(record.ClientID)
+	if err != nil {
+		return scoredRecord{}, err
+	}
+	if err := previous.CheckVersion(expectedVersion); err != nil {
+		return scoredRecord{}, err
+	}
+	updated, err := replacementRecord(previous, record, enforceLifecycle)
+	if err != nil {
+		return scoredRecord{}, err
+	}
+	after := updated.Record
+	if err := commitChanges(repo, audit, audit.event(kyc.AuditUpdate, record.ClientID, &previous, &after)); err != nil {
+		return scoredRecord{}, err
+	}
+	return updated, nil
+}
+
+// replacementRecord scores and stamps record as a replacement of previous, as updateRecord stores it
+func replacementRecord(previous, record kyc.Record, enforceLifecycle bool) (scoredRecord, error) {
+	switch to := record.ReviewStatus; {
+	case to == "":
+		record.ReviewStatus, record.ReviewNote = previous.ReviewStatus, previous.ReviewNote
+	case enforceLifecycle && to != previous.CurrentStatus():
+		candidate := previous
+		if err := candidate.Transition(to, record.ReviewNote); err != nil {
+			return scoredRecord{}, err
+		}
+	}
+	score := scoreRecord(&record)
+	record.Touch(&previous, now())
+	return scoredRecord{Record: record, RiskScore: score}, nil
+}
+
+// deleteRecord removes the record of clientID and records the deletion in the audit log. With
+// expectedVersion set the stored record must be at that version.
+func deleteRecord(repo Repository, audit auditContext, clientID string, expectedVersion *int64) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	previous, err := repo.Read(clientID)
+	if err != nil {
+		return err
+	}
+	if err := previous.CheckVersion(expectedVersion); err != nil {
+		return err
+	}
+	return commitChanges(repo, audit, audit.event(kyc.AuditDelete, clientID, &previous, nil))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedRequest wraps an UpdateKYC or DeleteKYC body element carrying an ExpectedVersion
+func versionedRequest(operation, payload string, expectedVersion int64) string {
+	return fmt.Sprintf(`%s%s<%s xmlns="%s">%s<ExpectedVersion>%d</ExpectedVersion></%s>%s%s`,
+		soapEnvelopeStart, soapBodyStart, operation, kycNamespaceAttr, payload, expectedVersion, operation, soapBodyEnd, soapEnvelopeEnd)
+}
+
+func TestSOAPHandler_ExpectedVersion(t *testing.T) {
+	repo := NewInMemoryRepo()
+	created, err := createRecord(repo, auditContext{}, kyc.Record{ClientID: "client123", Risk: 0.2}, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), created.Version)
+
+	send := func(body string) (int, kycModels.KYCResult) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(body)))
+		var response kycModels.KYCResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		return rec.Code, response
+	}
+	userData := `<UserData><ClientID>client123</ClientID><Risk>0.5</Risk></UserData>`
+
+	// Two writers based on version 1: the first wins, the second is told about the conflict
+	code, response := send(versionedRequest("UpdateKYC", userData, 1))
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), response.UserData.Version)
+
+	code, response = send(versionedRequest("UpdateKYC", userData, 1))
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "KYC record 'client123' is at version 2, not the expected version 1", response.Message)
+
+	// Without an expected version the update is unconditional
+	code, response = send(createSOAPRequest("UpdateKYC", "client123", &kyc.Record{ClientID: "client123", Risk: 0.6}))
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), response.UserData.Version)
+
+	rec := httptest.NewRecorder()
+	soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(versionedRequest("DeleteKYC", "<ClientID>client123</ClientID>", 2))))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	_, err = repo.Read("client123")
+	assert.NoError(t, err, "a refused delete leaves the record in place")
+
+	rec = httptest.NewRecorder()
+	soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(versionedRequest("DeleteKYC", "<ClientID>client123</ClientID>", 3))))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminAPI_ETags(t *testing.T) {
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+
+	serve := func(method, path, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("POST", "/admin/v1/users", `{"clientId":"client1","risk":0.3}`, "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = serve("GET", "/admin/v1/users/client1", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = serve("PUT", "/admin/v1/users/client1", `{"risk":0.4}`, `"1"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	var updated kyc.Record
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, int64(2), updated.Version)
+
+	assert.Equal(t, http.StatusPreconditionFailed, serve("PUT", "/admin/v1/users/client1", `{"risk":0.5}`, `"1"`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, serve("PUT", "/admin/v1/users/client1", `{"risk":0.5}`, `W/"2"`).Code)
+	assert.Equal(t, http.StatusOK, serve("PUT", "/admin/v1/users/client1", `{"risk":0.5}`, "*").Code)
+	assert.Equal(t, http.StatusNotFound, serve("PUT", "/admin/v1/users/missing", `{"risk":0.5}`, `"1"`).Code)
+
+	assert.Equal(t, http.StatusPreconditionFailed, serve("DELETE", "/admin/v1/users/client1", "", `"2"`).Code)
+	assert.Equal(t, http.StatusOK, serve("DELETE", "/admin/v1/users/client1", "", `"3"`).Code)
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"iter"
+	"maps"
+	"path/filepath"
+	"slices"
 	"sync"
 
-	"kafka-soap-e2e-test/services/consumer/models" // Import UserData from consumer's models
+	"kafka-soap-e2e-test/services/providers/kyc/cdc"
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 // Action type for simulating different responses
@@ -16,51 +21,277 @@ const (
 	ActionNotFound      Action = "NotFound"
 )
 
+// Repository is the KYC storage used by the SOAP and admin handlers
+type Repository interface {
+	Create(userData kyc.Record) error
+	Read(clientID string) (kyc.Record, error)
+	Update(userData kyc.Record) error
+	Delete(clientID string) error
+	List() []kyc.Record
+	// Records yields every stored record ordered by ClientID, reading each one as it is reached, so
+	// that streaming them neither copies the store nor holds up writes. A record deleted meanwhile
+	// is skipped and one created meanwhile is not yielded.
+	Records() iter.Seq[kyc.Record]
+	// Page returns the page of records q selects, from an index of the store that is kept sorted
+	// between writes, so that a page starts at its cursor
+	Page(q userQuery) userPage
+
+	SetAction(clientID string, action Action) error
+	GetAction(clientID string) (Action, bool)
+	ClearAction(clientID string) error
+
+	// Apply stores the change each audit event describes and appends the events to the audit log,
+	// all in one atomic write, returning them with their sequence numbers set. An event's After
+	// replaces the record, or removes it when nil; its Before must be the stored record (compared by
+	// version), or nil for a record that must not exist yet. If any does not match, nothing is applied.
+	// The log is append-only; only Restore replaces it.
+	Apply(events ...kyc.AuditEvent) ([]kyc.AuditEvent, error)
+	History(clientID string) []kyc.AuditEvent
+
+	Snapshot() Snapshot
+	Restore(snapshot Snapshot) error
+
+	// CaptureChanges makes every Apply and Restore from now on also add the change events it causes,
+	// for tenant, to the repository's outbox in the same write, and call added after such a write.
+	// A Restore adds a single cdc.TypeReset event.
+	CaptureChanges(tenant string, added func())
+	// PendingChanges returns up to n of the oldest change events in the outbox, or all of them when n <= 0
+	PendingChanges(n int) []cdc.Event
+	// RemoveChanges drops the n oldest change events from the outbox once they have been published
+	RemoveChanges(n int) error
+}
+
+// Snapshot is a point-in-time copy of a repository's users, actions and audit log
+type Snapshot struct {
+	Users   map[string]kyc.Record `json:"users"`
+	Actions map[string]Action     `json:"actions"`
+	Events  []kyc.AuditEvent      `json:"events,omitempty"`
+	Outbox  []cdc.Event           `json:"outbox,omitempty"` // Only in a FileRepo's file: Snapshot leaves it out and Restore keeps the current one
+}
+
+// clone returns a Snapshot that shares no maps or slices with s
+func (s Snapshot) clone() Snapshot {
+	c := Snapshot{Users: maps.Clone(s.Users), Actions: maps.Clone(s.Actions), Events: slices.Clone(s.Events), Outbox: slices.Clone(s.Outbox)}
+	if c.Users == nil {
+		c.Users = make(map[string]kyc.Record)
+	}
+	if c.Actions == nil {
+		c.Actions = make(map[string]Action)
+	}
+	return c
+}
+
+// checkChanges checks every event against users, as left by the events before it, and returns
+// each ClientID the events change as they leave it, nil when deleted; users is not modified
+func checkChanges(users map[string]kyc.Record, events []kyc.AuditEvent) (map[string]*kyc.Record, error) {
+	changed := make(map[string]*kyc.Record, len(events)) // Each ClientID as left by the events checked so far
+	for _, event := range events {
+		stored, exists := users[event.ClientID]
+		if after, ok := changed[event.ClientID]; ok {
+			exists = after != nil
+			if exists {
+				stored = *after
+			}
+		}
+		switch {
+		case event.Before == nil && exists:
+			return nil, fmt.Errorf("user with ClientID '%s' already exists", event.ClientID)
+		case event.Before != nil && !exists:
+			return nil, fmt.Errorf("user with ClientID '%s' not found", event.ClientID)
+		case event.Before != nil:
+			if err := stored.CheckVersion(&event.Before.Version); err != nil {
+				return nil, err
+			}
+		}
+		changed[event.ClientID] = event.After
+	}
+	return changed, nil
+}
+
+// sequenceEvents returns copies of events numbered after the last event of auditLog
+func sequenceEvents(auditLog, events []kyc.AuditEvent) []kyc.AuditEvent {
+	next := int64(1)
+	if len(auditLog) > 0 {
+		next = auditLog[len(auditLog)-1].Sequence + 1
+	}
+	sequenced := make([]kyc.AuditEvent, len(events))
+	for i, event := range events {
+		event.Sequence = next + int64(i)
+		sequenced[i] = event
+	}
+	return sequenced
+}
+
+// applyChanges checks every event against users, as left by the events before it, and only then
+// stores the changes in users and appends the events to auditLog. It returns the new log and the
+// events with their sequence numbers set; on error users is untouched.
+func applyChanges(users map[string]kyc.Record, auditLog, events []kyc.AuditEvent) ([]kyc.AuditEvent, []kyc.AuditEvent, error) {
+	changed, err := checkChanges(users, events)
+	if err != nil {
+		return auditLog, nil, err
+	}
+	storeChanges(users, changed)
+	applied := sequenceEvents(auditLog, events)
+	return append(auditLog, applied...), applied, nil
+}
+
+// storeChanges stores each changed record in users, deleting the nil ones
+func storeChanges(users map[string]kyc.Record, changed map[string]*kyc.Record) {
+	for clientID, user := range changed {
+		if user == nil {
+			delete(users, clientID)
+		} else {
+			users[clientID] = *user
+		}
+	}
+}
+
+// changeCapture is how a repository that captures changes turns its writes into change events;
+// a nil *changeCapture captures nothing
+type changeCapture struct {
+	tenant string
+	added  func() // Called after a write that added events, if set
+}
+
+// changes returns the change events announcing the applied audit events
+func (c *changeCapture) changes(applied []kyc.AuditEvent) []cdc.Event {
+	if c == nil {
+		return nil
+	}
+	events := make([]cdc.Event, len(applied))
+	for i, event := range applied {
+		events[i] = cdc.FromAudit(event, c.tenant)
+	}
+	return events
+}
+
+// reset returns the change event announcing a Restore to a state with the given audit log
+func (c *changeCapture) reset(auditLog []kyc.AuditEvent) []cdc.Event {
+	if c == nil {
+		return nil
+	}
+	var sequence int64
+	if len(auditLog) > 0 {
+		sequence = auditLog[len(auditLog)-1].Sequence
+	}
+	return []cdc.Event{cdc.Reset(c.tenant, sequence, now().UTC())}
+}
+
+// notify reports a write that added change events
+func (c *changeCapture) notify() {
+	if c != nil && c.added != nil {
+		c.added()
+	}
+}
+
+// oldestChanges returns up to n of the oldest events of outbox, or all of them when n <= 0
+func oldestChanges(outbox []cdc.Event, n int) []cdc.Event {
+	if n <= 0 || n > len(outbox) {
+		n = len(outbox)
+	}
+	return slices.Clone(outbox[:n])
+}
+
+// readEach yields the records of clientIDs that read still finds, in order
+func readEach(clientIDs []string, read func(clientID string) (kyc.Record, error)) iter.Seq[kyc.Record] {
+	return func(yield func(kyc.Record) bool) {
+		for _, clientID := range clientIDs {
+			record, err := read(clientID)
+			if err != nil {
+				continue // Deleted since
+			}
+			if !yield(record) {
+				return
+			}
+		}
+	}
+}
+
+// historyOf returns the events of clientID in the order they were appended
+func historyOf(events []kyc.AuditEvent, clientID string) []kyc.AuditEvent {
+	var history []kyc.AuditEvent
+	for _, e := range events {
+		if e.ClientID == clientID {
+			history = append(history, e)
+		}
+	}
+	return history
+}
+
+// Storage backends selectable through KYC_STORE
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+// tenantStoreDir is the directory holding the file stores of the tenants next to the file store at path
+func tenantStoreDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "tenants")
+}
+
+// newRepository opens the backend named by kind; path is only used by the file backend
+func newRepository(kind, path string) (Repository, error) {
+	switch kind {
+	case "", StoreMemory:
+		return NewInMemoryRepo(), nil
+	case StoreFile:
+		return OpenFileRepo(path)
+	default:
+		return nil, fmt.Errorf("unknown repository backend '%s' (want %s or %s)", kind, StoreMemory, StoreFile)
+	}
+}
+
 // InMemoryRepo stores UserData in memory and simulated actions
 type InMemoryRepo struct {
 	mu      sync.RWMutex
-	users   map[string]models.UserData
+	users   map[string]kyc.Record
 	actions map[string]Action // New field to store simulated actions per ClientID
+	events  []kyc.AuditEvent
+	outbox  []cdc.Event
+	capture *changeCapture
+	index   recordIndex
 }
 
 // NewInMemoryRepo initializes a new InMemoryRepo
 func NewInMemoryRepo() *InMemoryRepo {
 	repo := &InMemoryRepo{
-		users:   make(map[string]models.UserData),
+		users:   make(map[string]kyc.Record),
 		actions: make(map[string]Action), // Initialize the actions map
 	}
 	return repo
 }
 
 // Create adds new UserData to the repository
-func (r *InMemoryRepo) Create(userData models.UserData) error {
+func (r *InMemoryRepo) Create(userData kyc.Record) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, exists := r.users[userData.ClientID]; exists {
 		return fmt.Errorf("user with ClientID '%s' already exists", userData.ClientID)
 	}
 	r.users[userData.ClientID] = userData
+	r.index.changed()
 	return nil
 }
 
 // Read retrieves UserData by ClientID
-func (r *InMemoryRepo) Read(clientID string) (models.UserData, error) {
+func (r *InMemoryRepo) Read(clientID string) (kyc.Record, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	if user, exists := r.users[clientID]; exists {
 		return user, nil
 	}
-	return models.UserData{}, fmt.Errorf("user with ClientID '%s' not found", clientID)
+	return kyc.Record{}, fmt.Errorf("user with ClientID '%s' not found", clientID)
 }
 
 // Update modifies existing UserData in the repository
-func (r *InMemoryRepo) Update(userData models.UserData) error {
+func (r *InMemoryRepo) Update(userData kyc.Record) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, exists := r.users[userData.ClientID]; !exists {
 		return fmt.Errorf("user with ClientID '%s' not found", userData.ClientID)
 	}
 	r.users[userData.ClientID] = userData
+	r.index.changed()
 	return nil
 }
 
@@ -72,14 +303,39 @@ func (r *InMemoryRepo) Delete(clientID string) error {
 		return fmt.Errorf("user with ClientID '%s' not found", clientID)
 	}
 	delete(r.users, clientID)
+	r.index.changed()
 	return nil
 }
 
+// List returns every stored UserData in no particular order
+func (r *InMemoryRepo) List() []kyc.Record {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	users := make([]kyc.Record, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// Records yields every stored UserData ordered by ClientID
+func (r *InMemoryRepo) Records() iter.Seq[kyc.Record] {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return readEach(slices.Sorted(maps.Keys(r.users)), r.Read)
+}
+
+// Page returns the page of UserData q selects
+func (r *InMemoryRepo) Page(q userQuery) userPage {
+	return r.index.page(q, r.List)
+}
+
 // SetAction sets a simulated action for a given ClientID
-func (r *InMemoryRepo) SetAction(clientID string, action Action) {
+func (r *InMemoryRepo) SetAction(clientID string, action Action) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.actions[clientID] = action
+	return nil
 }
 
 // GetAction retrieves the simulated action for a given ClientID
@@ -91,8 +347,72 @@ func (r *InMemoryRepo) GetAction(clientID string) (Action, bool) {
 }
 
 // ClearAction removes the simulated action for a given ClientID
-func (r *InMemoryRepo) ClearAction(clientID string) {
+func (r *InMemoryRepo) ClearAction(clientID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.actions, clientID)
+	return nil
+}
+
+// Apply stores the changes of events and appends them to the audit log, or applies nothing if any does not match
+func (r *InMemoryRepo) Apply(events ...kyc.AuditEvent) ([]kyc.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	auditLog, applied, err := applyChanges(r.users, r.events, events)
+	if err != nil {
+		return nil, err
+	}
+	r.events = auditLog
+	r.index.changed()
+	r.outbox = append(r.outbox, r.capture.changes(applied)...)
+	r.capture.notify()
+	return applied, nil
+}
+
+// History returns the audit events of clientID, oldest first
+func (r *InMemoryRepo) History(clientID string) []kyc.AuditEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return historyOf(r.events, clientID)
+}
+
+// Snapshot returns a copy of the current users, actions and audit log
+func (r *InMemoryRepo) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return Snapshot{Users: r.users, Actions: r.actions, Events: r.events}.clone()
+}
+
+// Restore replaces all users, actions and the audit log with those of snapshot
+func (r *InMemoryRepo) Restore(snapshot Snapshot) error {
+	c := snapshot.clone()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users, r.actions, r.events = c.Users, c.Actions, c.Events
+	r.index.changed()
+	r.outbox = append(r.outbox, r.capture.reset(c.Events)...)
+	r.capture.notify()
+	return nil
+}
+
+// CaptureChanges adds the change events of every later Apply and Restore to the outbox
+func (r *InMemoryRepo) CaptureChanges(tenant string, added func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.capture = &changeCapture{tenant: tenant, added: added}
+}
+
+// PendingChanges returns up to n of the oldest change events in the outbox
+func (r *InMemoryRepo) PendingChanges(n int) []cdc.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return oldestChanges(r.outbox, n)
+}
+
+// RemoveChanges drops the n oldest change events from the outbox
+func (r *InMemoryRepo) RemoveChanges(n int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outbox = slices.Delete(r.outbox, 0, min(n, len(r.outbox)))
+	return nil
 }
@@ -4,7 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"kafka-soap-e2e-test/services/consumer/models"
+	"github.com/stretchr/testify/require"
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 func TestNewInMemoryRepo(t *testing.T) {
@@ -15,7 +16,7 @@ func TestNewInMemoryRepo(t *testing.T) {
 
 func TestRepoCreate(t *testing.T) {
 	repo := NewInMemoryRepo()
-	user := models.UserData{ClientID: "client1", Risk: 0.5, Status: "Approved", Message: "Test message"}
+	user := kyc.Record{ClientID: "client1", Risk: 0.5, ReviewStatus: kyc.ReviewApproved}
 
 	err := repo.Create(user)
 	assert.NoError(t, err)
@@ -29,7 +30,7 @@ func TestRepoCreate(t *testing.T) {
 
 func TestRepoRead(t *testing.T) {
 	repo := NewInMemoryRepo()
-	user := models.UserData{ClientID: "client1", Risk: 0.5, Status: "Approved", Message: "Test message"}
+	user := kyc.Record{ClientID: "client1", Risk: 0.5, ReviewStatus: kyc.ReviewApproved}
 	assert.NoError(t, repo.Create(user))
 
 	// Read existing user
@@ -45,16 +46,16 @@ func TestRepoRead(t *testing.T) {
 
 func TestRepoUpdate(t *testing.T) {
 	repo := NewInMemoryRepo()
-	user := models.UserData{ClientID: "client1", Risk: 0.5, Status: "Approved", Message: "Test message"}
+	user := kyc.Record{ClientID: "client1", Risk: 0.5, ReviewStatus: kyc.ReviewApproved}
 	assert.NoError(t, repo.Create(user))
 
-	updatedUser := models.UserData{ClientID: "client1", Risk: 0.8, Status: "Pending", Message: "Updated message"}
+	updatedUser := kyc.Record{ClientID: "client1", Risk: 0.8, ReviewStatus: kyc.ReviewPending}
 	err := repo.Update(updatedUser)
 	assert.NoError(t, err)
 	assert.Equal(t, updatedUser, repo.users["client1"])
 
 	// Update non-existing user
-	nonExistentUser := models.UserData{ClientID: "nonexistent", Risk: 0.1}
+	nonExistentUser := kyc.Record{ClientID: "nonexistent", Risk: 0.1}
 	err = repo.Update(nonExistentUser)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
@@ -62,7 +63,7 @@ func TestRepoUpdate(t *testing.T) {
 
 func TestRepoDelete(t *testing.T) {
 	repo := NewInMemoryRepo()
-	user := models.UserData{ClientID: "client1", Risk: 0.5, Status: "Approved", Message: "Test message"}
+	user := kyc.Record{ClientID: "client1", Risk: 0.5, ReviewStatus: kyc.ReviewApproved}
 	assert.NoError(t, repo.Create(user))
 
 	// Delete existing user
@@ -75,3 +76,38 @@ func TestRepoDelete(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
+
+func TestInMemoryRepo_Apply(t *testing.T) {
+	repo := NewInMemoryRepo()
+	v1 := kyc.Record{ClientID: "client1", Risk: 0.1, Version: 1}
+	v2 := kyc.Record{ClientID: "client1", Risk: 0.2, Version: 2}
+	applied, err := repo.Apply(
+		kyc.AuditEvent{ClientID: "client1", Action: kyc.AuditCreate, After: &v1},
+		kyc.AuditEvent{ClientID: "client2", Action: kyc.AuditCreate, After: &kyc.Record{ClientID: "client2"}},
+		kyc.AuditEvent{ClientID: "client1", Action: kyc.AuditUpdate, Before: &v1, After: &v2},
+	)
+	require.NoError(t, err)
+	deleted, err := repo.Apply(kyc.AuditEvent{ClientID: "client2", Action: kyc.AuditDelete, Before: &kyc.Record{ClientID: "client2"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{1, 2, 3, 4}, []int64{applied[0].Sequence, applied[1].Sequence, applied[2].Sequence, deleted[0].Sequence})
+	assert.Equal(t, []kyc.AuditEvent{applied[0], applied[2]}, repo.History("client1"))
+	assert.Equal(t, []kyc.Record{v2}, repo.List())
+	assert.Empty(t, repo.History("client3"))
+
+	t.Run("applies nothing if any event does not match", func(t *testing.T) {
+		for _, stale := range [][]kyc.AuditEvent{
+			{{ClientID: "client3", After: &kyc.Record{ClientID: "client3"}}, {ClientID: "client1", Before: &v1, After: &v1}},
+			{{ClientID: "client3", After: &kyc.Record{ClientID: "client3"}}, {ClientID: "client1", After: &v1}},
+			{{ClientID: "client3", After: &kyc.Record{ClientID: "client3"}}, {ClientID: "client2", Before: &kyc.Record{ClientID: "client2"}}},
+		} {
+			_, err := repo.Apply(stale...)
+			assert.Error(t, err)
+		}
+		_, err := repo.Apply(kyc.AuditEvent{ClientID: "client1", Before: &v1, After: &v1})
+		var conflict *kyc.VersionConflictError
+		assert.ErrorAs(t, err, &conflict)
+		assert.Equal(t, []kyc.Record{v2}, repo.List())
+		assert.Len(t, repo.Snapshot().Events, 4)
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+)
+
+// adminListScenarios handles GET /admin/v1/scenarios
+func adminListScenarios(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, currentTenant(r).faults.Scenarios())
+}
+
+// adminAddScenario handles POST /admin/v1/scenarios. A scenario with the name of an active one
+// replaces it, starting again from the first step.
+func adminAddScenario(w http.ResponseWriter, r *http.Request) {
+	var scenario faults.Scenario
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&scenario); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	added, err := currentTenant(r).faults.AddScenario(scenario)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("KYC SOAP Server: Added scenario '%s' with %d steps for operation '%s' and ClientID '%s'", added.Name, len(added.Steps), added.Operation, added.ClientID)
+	writeJSONResponse(w, http.StatusCreated, added)
+}
+
+// adminClearScenarios handles DELETE /admin/v1/scenarios
+func adminClearScenarios(w http.ResponseWriter, r *http.Request) {
+	currentTenant(r).faults.ClearScenarios()
+	log.Printf("KYC SOAP Server: Cleared all scenarios")
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "All scenarios cleared"})
+}
+
+// adminScenario handles GET and DELETE /admin/v1/scenarios/{name}
+func adminScenario(name string, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		scenario, ok := currentTenant(r).faults.Scenario(name)
+		if !ok {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Scenario '%s' not found", name)})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, scenario)
+	case http.MethodDelete:
+		if !currentTenant(r).faults.RemoveScenario(name) {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Scenario '%s' not found", name)})
+			return
+		}
+		log.Printf("KYC SOAP Server: Removed scenario '%s'", name)
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Scenario '%s' removed", name)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// adminRewindScenario handles POST /admin/v1/scenarios/{name}/rewind
+func adminRewindScenario(name string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scenario, ok := currentTenant(r).faults.RewindScenario(name)
+	if !ok {
+		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Scenario '%s' not found", name)})
+		return
+	}
+	log.Printf("KYC SOAP Server: Rewound scenario '%s'", name)
+	writeJSONResponse(w, http.StatusOK, scenario)
+}
+
+// initScenarioRoutes registers the scenario admin routes
+func initScenarioRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/scenarios", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			adminListScenarios(w, r)
+		case http.MethodPost:
+			adminAddScenario(w, r)
+		case http.MethodDelete:
+			adminClearScenarios(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/admin/v1/scenarios/", func(w http.ResponseWriter, r *http.Request) { // Trailing slash to match {name}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/v1/scenarios/"), "/")
+		if len(parts) == 1 && parts[0] != "" { // Matches /admin/v1/scenarios/{name}
+			adminScenario(parts[0], w, r)
+		} else if len(parts) == 2 && parts[0] != "" && parts[1] == "rewind" { // Matches /admin/v1/scenarios/{name}/rewind
+			adminRewindScenario(parts[0], w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/providers/kyc/scoring"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// riskEngine computes Risk on every create and update. It is nil while KYC_SCORING_RULES is
+// unset, in which case Risk stays whatever the caller supplied.
+var riskEngine atomic.Pointer[scoring.Engine]
+
+// scoreRecord replaces record's Risk with the engine's score and returns the breakdown,
+// or returns nil and leaves record alone when scoring is disabled
+func scoreRecord(record *kyc.Record) *kyc.RiskScore {
+	engine := riskEngine.Load()
+	if engine == nil {
+		return nil
+	}
+	score := engine.Score(*record, now())
+	record.Risk = score.Score
+	return &score
+}
+
+// scoredRecord is the admin API's response to a create or update: the record plus, when the
+// scoring engine is enabled, the breakdown of its Risk
+type scoredRecord struct {
+	kyc.Record
+	RiskScore *kyc.RiskScore `json:"riskScore,omitempty"`
+}
+
+// handleScoreKYC serves ScoreKYC, scoring a record without storing it
+func handleScoreKYC(content []byte) (interface{}, *operationError) {
+	const operation = "ScoreKYC"
+	var req kycModels.ScoreKYCRequest
+	if err := xml.Unmarshal(content, &req); err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	if err := req.UserData.Validate(); err != nil {
+		log.Printf("KYC SOAP Server: Invalid UserData in %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	score := scoreRecord(&req.UserData)
+	if score == nil {
+		return nil, &operationError{operation: operation, code: ErrorCodeScoringUnavailable, message: "Risk scoring is not configured (set KYC_SCORING_RULES)", httpStatus: http.StatusServiceUnavailable}
+	}
+	return kycModels.ScoreKYCResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.ScoreKYCResponseBody{
+			ScoreKYCResult: kycModels.ScoreKYCResult{Status: "Success", Message: "User scored", ClientID: req.UserData.ClientID, RiskScore: score},
+		},
+	}, nil
+}
+
+// scoringStatus is the JSON body returned by the scoring admin endpoints
+type scoringStatus struct {
+	Enabled bool           `json:"enabled"`
+	Path    string         `json:"path,omitempty"`
+	Rules   *scoring.Rules `json:"rules,omitempty"`
+}
+
+func currentScoringStatus() scoringStatus {
+	engine := riskEngine.Load()
+	if engine == nil {
+		return scoringStatus{}
+	}
+	rules := engine.Rules()
+	return scoringStatus{Enabled: true, Path: engine.Path(), Rules: &rules}
+}
+
+// adminGetScoring handles GET /admin/v1/scoring
+func adminGetScoring(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, currentScoringStatus())
+}
+
+// adminReloadScoring handles POST /admin/v1/scoring/reload, re-reading the rules file without
+// waiting for the file watcher
+func adminReloadScoring(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	engine := riskEngine.Load()
+	if engine == nil {
+		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": "Risk scoring is not configured (set KYC_SCORING_RULES)"})
+		return
+	}
+	if err := engine.Reload(); err != nil {
+		writeJSONResponse(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, currentScoringStatus())
+}
+
+// initScoringRoutes registers the scoring admin routes
+func initScoringRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/scoring", adminGetScoring)
+	mux.HandleFunc("/admin/v1/scoring/reload", adminReloadScoring)
+}
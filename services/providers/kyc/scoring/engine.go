@@ -0,0 +1,123 @@
+package scoring
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+// Engine scores records with the rules loaded from a file. It is safe for concurrent use;
+// a reload that fails keeps the rules that were active before.
+type Engine struct {
+	path     string
+	provider *file.File
+
+	mu    sync.RWMutex
+	rules Rules
+}
+
+// load reads and validates the rules file behind provider
+func load(provider *file.File) (Rules, error) {
+	k := koanf.New(".")
+	if err := k.Load(provider, yaml.Parser()); err != nil {
+		return Rules{}, fmt.Errorf("error loading scoring rules: %w", err)
+	}
+	var rules Rules
+	if err := k.Unmarshal("", &rules); err != nil {
+		return Rules{}, fmt.Errorf("error unmarshaling scoring rules: %w", err)
+	}
+	rules.normalize()
+	if err := rules.Validate(); err != nil {
+		return Rules{}, fmt.Errorf("invalid scoring rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Open loads the rules file at path into a new Engine
+func Open(path string) (*Engine, error) {
+	e := &Engine{path: path, provider: file.Provider(path)}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEngine returns an Engine with fixed rules that is not backed by a file
+func NewEngine(rules Rules) *Engine {
+	rules.normalize()
+	return &Engine{rules: rules}
+}
+
+// Path returns the rules file the engine was opened with, or "" for a fixed engine
+func (e *Engine) Path() string {
+	return e.path
+}
+
+// Rules returns the active rules
+func (e *Engine) Rules() Rules {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Reload re-reads the rules file. On error the active rules stay in place.
+func (e *Engine) Reload() error {
+	if e.provider == nil {
+		return fmt.Errorf("scoring engine is not backed by a rules file")
+	}
+	rules, err := load(e.provider)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+	log.Printf("KYC SOAP Server: Loaded %d scoring rules from %s", len(rules.Rules), e.path)
+	return nil
+}
+
+// reloadDelay lets a burst of file events (truncate, write, rename) settle before reloading,
+// so a half-written file is not what gets loaded
+const reloadDelay = 100 * time.Millisecond
+
+// Watch reloads the rules whenever the file changes until Close is called
+func (e *Engine) Watch() error {
+	if e.provider == nil {
+		return fmt.Errorf("scoring engine is not backed by a rules file")
+	}
+	var pending *time.Timer
+	return e.provider.Watch(func(_ any, err error) {
+		if err != nil {
+			log.Printf("KYC SOAP Server: Stopped watching scoring rules %s: %v", e.path, err)
+			return
+		}
+		if pending != nil {
+			pending.Stop()
+		}
+		pending = time.AfterFunc(reloadDelay, func() {
+			if err := e.Reload(); err != nil {
+				log.Printf("KYC SOAP Server: Keeping previous scoring rules: %v", err)
+			}
+		})
+	})
+}
+
+// Close stops watching the rules file
+func (e *Engine) Close() error {
+	if e.provider == nil {
+		return nil
+	}
+	return e.provider.Unwatch()
+}
+
+// Score evaluates the active rules against record
+func (e *Engine) Score(record kyc.Record, now time.Time) kyc.RiskScore {
+	return e.Rules().Score(record, now)
+}
@@ -0,0 +1,236 @@
+// Package scoring computes KYC risk scores from configurable rules. Rules are loaded from a YAML
+// or JSON file (JSON being a subset of YAML, both go through the same parser) and can be reloaded
+// while the provider runs.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// RuleType selects what a rule looks at
+type RuleType string
+
+const (
+	RuleCountry    RuleType = "country"    // Nationality, address or document country is listed
+	RulePEP        RuleType = "pep"        // Client is a politically exposed person
+	RuleSanctioned RuleType = "sanctioned" // Client is on a sanctions list
+	RuleDocument   RuleType = "document"   // Identity documents are missing, expired or about to expire
+	RuleField      RuleType = "field"      // A custom field has a given value or lies in a range
+)
+
+// Country scopes of a country rule
+const (
+	ScopeNationality = "nationality"
+	ScopeAddress     = "address"
+	ScopeDocument    = "document"
+)
+
+// Rule adds Weight to the score of every record it matches. Which of the condition fields
+// apply depends on Type.
+type Rule struct {
+	Name   string   `koanf:"name" json:"name"`
+	Type   RuleType `koanf:"type" json:"type"`
+	Weight float64  `koanf:"weight" json:"weight"` // May be negative to lower the score
+
+	// country: matches if a country in Scope (all scopes when empty) is one of Countries
+	Countries []string `koanf:"countries" json:"countries,omitempty"`
+	Scope     []string `koanf:"scope" json:"scope,omitempty"`
+
+	// document: exactly one of Missing, Expired or ExpiresWithinDays
+	Missing           bool `koanf:"missing" json:"missing,omitempty"`
+	Expired           bool `koanf:"expired" json:"expired,omitempty"`
+	ExpiresWithinDays int  `koanf:"expiresWithinDays" json:"expiresWithinDays,omitempty"`
+
+	// field: the custom field named Field must satisfy every condition given
+	Field  string   `koanf:"field" json:"field,omitempty"`
+	Equals string   `koanf:"equals" json:"equals,omitempty"` // Case-insensitive
+	In     []string `koanf:"in" json:"in,omitempty"`         // Case-insensitive
+	Above  *float64 `koanf:"above" json:"above,omitempty"`   // Value parsed as a number, exclusive
+	Below  *float64 `koanf:"below" json:"below,omitempty"`   // Value parsed as a number, exclusive
+}
+
+// Rules is a complete scoring configuration. A score starts at Base, every matching rule adds
+// its weight and the total is clamped to [Min, Max].
+type Rules struct {
+	Base  float64 `koanf:"base" json:"base"`
+	Min   float64 `koanf:"min" json:"min"`
+	Max   float64 `koanf:"max" json:"max"` // 0 means 1
+	Rules []Rule  `koanf:"rules" json:"rules"`
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// normalize applies defaults
+func (rs *Rules) normalize() {
+	if rs.Max == 0 {
+		rs.Max = 1
+	}
+}
+
+// Validate checks the configuration and returns every problem found
+func (rs Rules) Validate() error {
+	var errs []error
+	if rs.Min > rs.Max {
+		errs = append(errs, fmt.Errorf("min %g is above max %g", rs.Min, rs.Max))
+	}
+	names := make(map[string]bool, len(rs.Rules))
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("rules[%d]: name is required", i))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("rules[%d]: duplicate name %q", i, r.Name))
+		}
+		names[r.Name] = true
+		if err := r.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d] (%s): %w", i, r.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Rule) validate() error {
+	switch r.Type {
+	case RuleCountry:
+		if len(r.Countries) == 0 {
+			return errors.New("countries is required")
+		}
+		for _, c := range r.Countries {
+			if !countryCode.MatchString(c) {
+				return fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code", c)
+			}
+		}
+		for _, s := range r.Scope {
+			if s != ScopeNationality && s != ScopeAddress && s != ScopeDocument {
+				return fmt.Errorf("unknown scope %q (want nationality, address or document)", s)
+			}
+		}
+	case RulePEP, RuleSanctioned:
+	case RuleDocument:
+		conditions := 0
+		for _, set := range []bool{r.Missing, r.Expired, r.ExpiresWithinDays > 0} {
+			if set {
+				conditions++
+			}
+		}
+		if conditions != 1 {
+			return errors.New("exactly one of missing, expired or expiresWithinDays is required")
+		}
+	case RuleField:
+		if r.Field == "" {
+			return errors.New("field is required")
+		}
+		if r.Equals == "" && len(r.In) == 0 && r.Above == nil && r.Below == nil {
+			return errors.New("at least one of equals, in, above or below is required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q (want country, pep, sanctioned, document or field)", r.Type)
+	}
+	return nil
+}
+
+// Score evaluates every rule against record. now is the reference time for document expiry.
+func (rs Rules) Score(record kyc.Record, now time.Time) kyc.RiskScore {
+	score := kyc.RiskScore{Base: rs.Base}
+	total := rs.Base
+	for _, r := range rs.Rules {
+		if detail, ok := r.match(record, now); ok {
+			score.Contributions = append(score.Contributions, kyc.RiskContribution{Rule: r.Name, Weight: r.Weight, Detail: detail})
+			total += r.Weight
+		}
+	}
+	total = math.Max(rs.Min, math.Min(rs.Max, total))
+	score.Score = math.Round(total*1e4) / 1e4 // Hide float noise from summing weights
+	return score
+}
+
+// match reports whether r applies to record and describes what matched
+func (r Rule) match(record kyc.Record, now time.Time) (string, bool) {
+	switch r.Type {
+	case RuleCountry:
+		return r.matchCountry(record)
+	case RulePEP:
+		return "politically exposed person", record.PEP
+	case RuleSanctioned:
+		return "listed on a sanctions list", record.Sanctioned
+	case RuleDocument:
+		return r.matchDocument(record, now)
+	case RuleField:
+		return r.matchField(record)
+	}
+	return "", false
+}
+
+func (r Rule) inScope(scope string) bool {
+	return len(r.Scope) == 0 || slices.Contains(r.Scope, scope)
+}
+
+func (r Rule) matchCountry(record kyc.Record) (string, bool) {
+	if r.inScope(ScopeNationality) && slices.Contains(r.Countries, record.Nationality) {
+		return "nationality " + record.Nationality, true
+	}
+	if r.inScope(ScopeAddress) {
+		for _, a := range record.Addresses {
+			if slices.Contains(r.Countries, a.Country) {
+				return "address in " + a.Country, true
+			}
+		}
+	}
+	if r.inScope(ScopeDocument) {
+		for _, d := range record.Documents {
+			if slices.Contains(r.Countries, d.IssuingCountry) {
+				return fmt.Sprintf("%s issued by %s", d.Type, d.IssuingCountry), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r Rule) matchDocument(record kyc.Record, now time.Time) (string, bool) {
+	switch {
+	case r.Missing:
+		return "no identity document", len(record.Documents) == 0
+	case r.Expired:
+		for _, d := range record.Documents {
+			if d.Expired(now) {
+				return fmt.Sprintf("%s %s expired on %s", d.Type, d.Number, d.ExpiryDate), true
+			}
+		}
+	default:
+		horizon := now.AddDate(0, 0, r.ExpiresWithinDays)
+		for _, d := range record.Documents {
+			if !d.Expired(now) && d.Expired(horizon) {
+				return fmt.Sprintf("%s %s expires on %s", d.Type, d.Number, d.ExpiryDate), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r Rule) matchField(record kyc.Record) (string, bool) {
+	value, ok := record.CustomField(r.Field)
+	if !ok {
+		return "", false
+	}
+	if r.Equals != "" && !strings.EqualFold(value, r.Equals) {
+		return "", false
+	}
+	if len(r.In) > 0 && !slices.ContainsFunc(r.In, func(v string) bool { return strings.EqualFold(v, value) }) {
+		return "", false
+	}
+	if r.Above != nil || r.Below != nil {
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || (r.Above != nil && n <= *r.Above) || (r.Below != nil && n >= *r.Below) {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("%s=%s", r.Field, value), true
+}
@@ -0,0 +1,112 @@
+package scoring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/knadh/koanf/providers/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func TestRules_Score(t *testing.T) {
+	rules, err := load(file.Provider("rules.yaml"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		record kyc.Record
+		score  float64
+		rules  []string
+	}{
+		{
+			name:   "Clean record",
+			record: kyc.Record{ClientID: "c1", Nationality: "DE", Documents: []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "P1", ExpiryDate: "2030-01-01"}}},
+			score:  0.1,
+		},
+		{
+			name: "High-risk nationality, PEP and expired document",
+			record: kyc.Record{ClientID: "c2", Nationality: "IR", PEP: true, Documents: []kyc.IdentityDocument{
+				{Type: kyc.DocumentPassport, Number: "P2", ExpiryDate: "2025-06-30"},
+			}},
+			score: 1,
+			rules: []string{"politically-exposed", "high-risk-country", "expired-document"},
+		},
+		{
+			name:   "Address scope ignores nationality",
+			record: kyc.Record{ClientID: "c3", Nationality: "KY", Addresses: []kyc.Address{{Line1: "1 Bay St", City: "Road Town", Country: "VG"}}},
+			score:  0.5,
+			rules:  []string{"offshore-address", "no-identity-document"},
+		},
+		{
+			name: "Custom fields and expiring document",
+			record: kyc.Record{ClientID: "c4", Documents: []kyc.IdentityDocument{{Type: kyc.DocumentNationalID, Number: "N1", ExpiryDate: "2026-03-01"}},
+				CustomFields: []kyc.CustomField{{Key: "creditScore", Value: "420"}, {Key: "riskFactor", Value: "LOW"}}},
+			score: 0.25,
+			rules: []string{"document-expiring-soon", "low-credit-score", "declared-risk-low"},
+		},
+		{
+			name:   "Non-numeric field does not match a range",
+			record: kyc.Record{ClientID: "c5", Documents: []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "P5"}}, CustomFields: []kyc.CustomField{{Key: "creditScore", Value: "n/a"}}},
+			score:  0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := rules.Score(tt.record, now)
+			assert.Equal(t, tt.score, score.Score)
+			assert.Equal(t, 0.1, score.Base)
+			var matched []string
+			for _, c := range score.Contributions {
+				matched = append(matched, c.Rule)
+				assert.NotEmpty(t, c.Detail)
+			}
+			assert.Equal(t, tt.rules, matched)
+		})
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	rules := Rules{Min: 2, Max: 1, Rules: []Rule{
+		{Name: "a", Type: RuleCountry, Countries: []string{"iran"}},
+		{Name: "a", Type: RuleDocument, Missing: true, Expired: true},
+		{Type: RuleField, Field: "x"},
+		{Name: "b", Type: "age"},
+	}}
+	err := rules.Validate()
+	require.Error(t, err)
+	for _, want := range []string{"min 2 is above max 1", "not an ISO 3166-1", "duplicate name", "exactly one of missing", "name is required", "at least one of equals", "unknown type"} {
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestEngine_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base": 0.2, "rules": [{"name": "pep", "type": "pep", "weight": 0.5}]}`), 0o644))
+
+	engine, err := Open(path)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Watch())
+	pep := kyc.Record{ClientID: "c1", PEP: true}
+	assert.Equal(t, 0.7, engine.Score(pep, now).Score)
+	assert.Equal(t, 1.0, engine.Rules().Max)
+
+	// An invalid file keeps the previous rules
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"name": "pep", "type": "unknown"}]}`), 0o644))
+	assert.Error(t, engine.Reload())
+	assert.Equal(t, 0.7, engine.Score(pep, now).Score)
+
+	// The watcher picks up a valid change
+	require.NoError(t, os.WriteFile(path, []byte(`{"base": 0.2, "rules": [{"name": "pep", "type": "pep", "weight": 0.1}]}`), 0o644))
+	assert.Eventually(t, func() bool { return engine.Score(pep, now).Score == 0.3 }, 2*time.Second, 10*time.Millisecond)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/providers/kyc/scoring"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testScoringRules() scoring.Rules {
+	return scoring.Rules{Base: 0.1, Rules: []scoring.Rule{
+		{Name: "politically-exposed", Type: scoring.RulePEP, Weight: 0.3},
+		{Name: "high-risk-country", Type: scoring.RuleCountry, Countries: []string{"IR"}, Weight: 0.4},
+	}}
+}
+
+func scoreKYCRequest(record kyc.Record) string {
+	return strings.Replace(createSOAPRequest("CreateKYC", record.ClientID, &record), "CreateKYC", "ScoreKYC", 2)
+}
+
+func TestSOAPHandler_Scoring(t *testing.T) {
+	riskEngine.Store(scoring.NewEngine(testScoringRules()))
+	defer riskEngine.Store(nil)
+	repo := NewInMemoryRepo()
+
+	t.Run("Create computes the risk", func(t *testing.T) {
+		record := kyc.Record{ClientID: "client1", Risk: 0.05, Nationality: "IR", PEP: true}
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(createSOAPRequest("CreateKYC", "client1", &record))))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response kycModels.KYCResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		assert.Equal(t, 0.8, response.UserData.Risk)
+		require.NotNil(t, response.RiskScore)
+		assert.Equal(t, 0.8, response.RiskScore.Score)
+		assert.Equal(t, []kyc.RiskContribution{
+			{Rule: "politically-exposed", Weight: 0.3, Detail: "politically exposed person"},
+			{Rule: "high-risk-country", Weight: 0.4, Detail: "nationality IR"},
+		}, response.RiskScore.Contributions)
+
+		stored, err := repo.Read("client1")
+		require.NoError(t, err)
+		assert.Equal(t, 0.8, stored.Risk)
+
+		_, violations, err := contract.KYCSchema().ValidateEnvelope(rec.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Empty(t, violations)
+	})
+
+	t.Run("ScoreKYC does not store the record", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(scoreKYCRequest(kyc.Record{ClientID: "client2", PEP: true}))))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response kycModels.ScoreKYCResult
+		require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &response))
+		assert.Equal(t, "Success", response.Status)
+		assert.Equal(t, "client2", response.ClientID)
+		require.NotNil(t, response.RiskScore)
+		assert.Equal(t, 0.4, response.RiskScore.Score)
+		assert.Equal(t, 0.1, response.RiskScore.Base)
+		_, err := repo.Read("client2")
+		assert.Error(t, err)
+
+		_, violations, err := contract.KYCSchema().ValidateEnvelope(rec.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Empty(t, violations)
+	})
+}
+
+func TestSOAPHandler_ScoringDisabled(t *testing.T) {
+	defer settings.Set(defaultSettings())
+	repo := NewInMemoryRepo()
+
+	record := kyc.Record{ClientID: "client1", Risk: 0.05, PEP: true}
+	created, err := createRecord(repo, auditContext{}, record, true)
+	require.NoError(t, err)
+	assert.Equal(t, 0.05, created.Risk, "without an engine the caller's risk is kept")
+	assert.Nil(t, created.RiskScore)
+
+	for _, style := range []ErrorStyle{ErrorStyleLegacy, ErrorStyleFault} {
+		t.Run(fmt.Sprintf("ScoreKYC (%s)", style), func(t *testing.T) {
+			settings.Set(Settings{ValidationMode: ValidationLenient, ErrorStyle: style})
+			rec := httptest.NewRecorder()
+			soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(scoreKYCRequest(record))))
+
+			if style == ErrorStyleLegacy {
+				assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+				assert.Contains(t, rec.Body.String(), "<ScoreKYCResponse")
+				return
+			}
+			fault := unmarshalSOAPFault(t, rec.Body.Bytes())
+			assert.Equal(t, "soapenv:Server", fault.FaultCode)
+			require.NotNil(t, fault.Detail.KYCFault)
+			assert.Equal(t, string(ErrorCodeScoringUnavailable), fault.Detail.KYCFault.ErrorCode)
+		})
+	}
+}
+
+func TestAdminScoring(t *testing.T) {
+	repo := NewInMemoryRepo()
+	mux := http.NewServeMux()
+	initAdminRoutes(mux, repo)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve("GET", "/admin/v1/scoring", "")
+	assert.JSONEq(t, `{"enabled":false}`, rec.Body.String())
+	assert.Equal(t, http.StatusConflict, serve("POST", "/admin/v1/scoring/reload", "").Code)
+
+	riskEngine.Store(scoring.NewEngine(testScoringRules()))
+	defer riskEngine.Store(nil)
+
+	var status scoringStatus
+	require.NoError(t, json.Unmarshal(serve("GET", "/admin/v1/scoring", "").Body.Bytes(), &status))
+	assert.True(t, status.Enabled)
+	require.NotNil(t, status.Rules)
+	assert.Len(t, status.Rules.Rules, 2)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("POST", "/admin/v1/scoring/reload", "").Code, "a fixed engine has no file to reload")
+
+	// Admin creates return the breakdown next to the record
+	rec = serve("POST", "/admin/v1/users", `{"clientId":"client1","risk":0.9,"nationality":"IR"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created scoredRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "client1", created.ClientID)
+	assert.Equal(t, 0.5, created.Risk)
+	require.NotNil(t, created.RiskScore)
+	assert.Len(t, created.RiskScore.Contributions, 1)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+)
+
+// handleSearchKYC serves SearchKYC, returning a page of the records matching every criterion in
+// ClientID order. It runs the same query as GET /admin/v1/users, so a PageToken is a listing cursor.
+func handleSearchKYC(repo Repository, content []byte) (interface{}, *operationError) {
+	const operation = "SearchKYC"
+	var req kycModels.SearchKYCRequest
+	if err := xml.Unmarshal(content, &req); err != nil {
+		log.Printf("KYC SOAP Server: Failed to unmarshal %s request: %v", operation, err)
+		return nil, invalidRequestError(operation, err)
+	}
+	query, err := searchQuery(req)
+	if err != nil {
+		return nil, invalidRequestError(operation, err)
+	}
+
+	page := repo.Page(query)
+	log.Printf("KYC SOAP Server: %s returned %d of %d matching user(s)", operation, len(page.Users), page.Total)
+	return kycModels.SearchKYCResponseEnvelope{
+		XmlnsSoapenv: "http://schemas.xmlsoap.org/soap/envelope/",
+		Body: kycModels.SearchKYCResponseBody{
+			SearchKYCResult: kycModels.SearchKYCResult{
+				Status:        "Success",
+				Message:       fmt.Sprintf("%d of %d matching user(s)", len(page.Users), page.Total),
+				Total:         page.Total,
+				NextPageToken: page.NextCursor,
+				Users:         page.Users,
+			},
+		},
+	}, nil
+}
+
+// searchQuery converts the criteria and paging of a SearchKYC request to a userQuery
+func searchQuery(req kycModels.SearchKYCRequest) (userQuery, error) {
+	query := userQuery{
+		limit:          defaultPageSize,
+		minRisk:        req.MinRisk,
+		maxRisk:        req.MaxRisk,
+		nameContains:   strings.ToLower(strings.TrimSpace(req.NameContains)),
+		documentNumber: strings.TrimSpace(req.DocumentNumber),
+		createdAfter:   req.CreatedAfter,
+		sortField:      "clientId",
+	}
+	if req.PageSize != 0 {
+		if req.PageSize < 1 || req.PageSize > maxPageSize {
+			return userQuery{}, fmt.Errorf("invalid PageSize %d: want a number from 1 to %d", req.PageSize, maxPageSize)
+		}
+		query.limit = req.PageSize
+	}
+	if query.minRisk != nil && query.maxRisk != nil && *query.minRisk > *query.maxRisk {
+		return userQuery{}, fmt.Errorf("MinRisk %v is greater than MaxRisk %v", *query.minRisk, *query.maxRisk)
+	}
+	for _, status := range req.ReviewStatuses {
+		if !slices.Contains(kyc.ReviewStatuses, status) {
+			return userQuery{}, fmt.Errorf("invalid ReviewStatus '%s': want one of %v", status, kyc.ReviewStatuses)
+		}
+		query.statuses = append(query.statuses, status)
+	}
+	if req.PageToken != "" {
+		cursor, err := decodeCursor(req.PageToken)
+		if err != nil || cursor.Sort != query.sortString() {
+			return userQuery{}, fmt.Errorf("invalid PageToken '%s'", req.PageToken)
+		}
+		query.after = &cursor
+	}
+	return query, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kycModels "kafka-soap-e2e-test/services/providers/kyc/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSearchRequest builds a SearchKYC with the given criteria elements
+func createSearchRequest(criteria string) string {
+	return fmt.Sprintf(`%s%s<SearchKYC xmlns="%s">%s</SearchKYC>%s%s`, soapEnvelopeStart, soapBodyStart, kycNamespaceAttr, criteria, soapBodyEnd, soapEnvelopeEnd)
+}
+
+func TestSOAPHandler_SearchKYC(t *testing.T) {
+	repo := NewInMemoryRepo()
+	january, june := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1, LegalName: "Ada Lovelace", CreatedAt: &january}))
+	turing := kyc.Record{ClientID: "client2", Risk: 0.5, LegalName: "Alan Turing", ReviewStatus: kyc.ReviewApproved, CreatedAt: &january,
+		Documents: []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "PA123", ExpiryDate: "2030-01-01"}}}
+	require.NoError(t, repo.Create(turing))
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client3", Risk: 0.9, LegalName: "Grace Hopper", ReviewStatus: kyc.ReviewApproved, CreatedAt: &june}))
+	search := func(criteria string) (*httptest.ResponseRecorder, kycModels.SearchKYCResult) {
+		rec := httptest.NewRecorder()
+		soapHandler(repo, rec, httptest.NewRequest("POST", "/soap", strings.NewReader(createSearchRequest(criteria))))
+		var result kycModels.SearchKYCResult
+		if rec.Code == http.StatusOK {
+			require.NoError(t, unmarshalSOAPResponse(rec.Body.Bytes(), &result))
+		}
+		return rec, result
+	}
+	clientIDs := func(result kycModels.SearchKYCResult) []string {
+		ids := make([]string, len(result.Users))
+		for i, user := range result.Users {
+			ids[i] = user.ClientID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		criteria string
+		want     []string
+	}{
+		{"no criteria", "", []string{"client1", "client2", "client3"}},
+		{"risk range", "<MinRisk>0.2</MinRisk><MaxRisk>0.9</MaxRisk>", []string{"client2", "client3"}},
+		{"status", "<ReviewStatus>PENDING</ReviewStatus><ReviewStatus>REJECTED</ReviewStatus>", []string{"client1"}},
+		{"name contains", "<NameContains>  TUR </NameContains>", []string{"client2"}},
+		{"document number", "<DocumentNumber>PA123</DocumentNumber>", []string{"client2"}},
+		{"created after", "<CreatedAfter>2024-03-01T00:00:00Z</CreatedAfter>", []string{"client3"}},
+		{"all criteria", "<MinRisk>0.3</MinRisk><ReviewStatus>APPROVED</ReviewStatus><NameContains>a</NameContains>", []string{"client2", "client3"}},
+		{"no match", "<NameContains>nobody</NameContains>", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, result := search(tt.criteria)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, "Success", result.Status)
+			assert.Equal(t, tt.want, clientIDs(result))
+			assert.Equal(t, len(tt.want), result.Total)
+			assert.Empty(t, result.NextPageToken)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		_, first := search("<PageSize>2</PageSize>")
+		assert.Equal(t, []string{"client1", "client2"}, clientIDs(first))
+		assert.Equal(t, 3, first.Total)
+		assert.Equal(t, "2 of 3 matching user(s)", first.Message)
+		require.NotEmpty(t, first.NextPageToken)
+
+		require.NoError(t, repo.Delete("client2"))
+		t.Cleanup(func() { _ = repo.Create(turing) })
+		_, second := search("<PageSize>2</PageSize><PageToken>" + first.NextPageToken + "</PageToken>")
+		assert.Equal(t, []string{"client3"}, clientIDs(second), "paging continues after a deleted record")
+		assert.Empty(t, second.NextPageToken)
+	})
+
+	t.Run("rejects invalid criteria", func(t *testing.T) {
+		for criteria, want := range map[string]string{
+			"<PageSize>1001</PageSize>":                    "invalid PageSize 1001",
+			"<MinRisk>0.8</MinRisk><MaxRisk>0.2</MaxRisk>": "MinRisk 0.8 is greater than MaxRisk 0.2",
+			"<PageToken>not-a-token</PageToken>":           "invalid PageToken",
+		} {
+			rec, _ := search(criteria)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, criteria)
+			assert.Contains(t, rec.Body.String(), want)
+		}
+		rec, _ := search("<ReviewStatus>DONE</ReviewStatus>")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "ValidationFault", "the schema lists the statuses")
+	})
+
+	t.Run("conforms to the schema", func(t *testing.T) {
+		s := defaultSettings()
+		s.ValidationMode = ValidationStrict
+		settings.Set(s)
+		t.Cleanup(func() { settings.Set(defaultSettings()) })
+		rec, result := search("<MinRisk>0.2</MinRisk><ReviewStatus>APPROVED</ReviewStatus><NameContains>a</NameContains><PageSize>1</PageSize>")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{"client2"}, clientIDs(result))
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+)
+
+// Settings are provider behaviours that can be changed at runtime through the admin API
+type Settings struct {
+	ValidationMode ValidationMode  `json:"validationMode"`
+	ErrorStyle     ErrorStyle      `json:"errorStyle"`
+	CallbackDelay  faults.Duration `json:"callbackDelay,omitempty"` // How long an asynchronous request waits before it runs and its response is sent to ReplyTo
+}
+
+// validate rejects unknown setting values
+func (s Settings) validate() error {
+	switch s.ValidationMode {
+	case ValidationStrict, ValidationLenient, ValidationOff:
+	default:
+		return fmt.Errorf("invalid validation mode '%s' (want strict, lenient or off)", s.ValidationMode)
+	}
+	switch s.ErrorStyle {
+	case ErrorStyleLegacy, ErrorStyleFault:
+	default:
+		return fmt.Errorf("invalid error style '%s' (want legacy or fault)", s.ErrorStyle)
+	}
+	if s.CallbackDelay < 0 {
+		return fmt.Errorf("invalid callback delay %s (want 0 or more)", time.Duration(s.CallbackDelay))
+	}
+	return nil
+}
+
+// defaultSettings returns the settings used when no environment overrides are present
+func defaultSettings() Settings {
+	return Settings{ValidationMode: ValidationLenient, ErrorStyle: ErrorStyleLegacy}
+}
+
+// settingsFromEnv applies SOAP_VALIDATION_MODE, SOAP_ERROR_STYLE and SOAP_CALLBACK_DELAY on top of the defaults
+func settingsFromEnv() (Settings, error) {
+	s := defaultSettings()
+	if v := os.Getenv("SOAP_VALIDATION_MODE"); v != "" {
+		s.ValidationMode = ValidationMode(v)
+	}
+	if v := os.Getenv("SOAP_ERROR_STYLE"); v != "" {
+		s.ErrorStyle = ErrorStyle(v)
+	}
+	if v := os.Getenv("SOAP_CALLBACK_DELAY"); v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return s, fmt.Errorf("invalid SOAP_CALLBACK_DELAY '%s': %w", v, err)
+		}
+		s.CallbackDelay = faults.Duration(delay)
+	}
+	return s, s.validate()
+}
+
+// settingsStore guards the live Settings shared by the SOAP and admin handlers
+type settingsStore struct {
+	mu sync.RWMutex
+	s  Settings
+}
+
+var settings = &settingsStore{s: defaultSettings()}
+
+// Get returns a copy of the current settings
+func (st *settingsStore) Get() Settings {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.s
+}
+
+// Set replaces the current settings
+func (st *settingsStore) Set(s Settings) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.s = s
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// snapshotStore keeps named repository snapshots plus the baseline taken once the fixtures are loaded
+type snapshotStore struct {
+	mu       sync.RWMutex
+	named    map[string]Snapshot
+	baseline Snapshot
+}
+
+func newSnapshotStore() *snapshotStore {
+	return &snapshotStore{named: make(map[string]Snapshot), baseline: Snapshot{}.clone()}
+}
+
+// snapshots holds the default tenant's snapshots taken through the admin API; they are not
+// persisted across restarts
+var snapshots = newSnapshotStore()
+
+// SetBaseline records the state that POST /admin/v1/reset returns to
+func (s *snapshotStore) SetBaseline(snapshot Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseline = snapshot.clone()
+}
+
+// Baseline returns the state recorded by SetBaseline
+func (s *snapshotStore) Baseline() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseline.clone()
+}
+
+// Save stores snapshot under name, replacing any previous snapshot of that name
+func (s *snapshotStore) Save(name string, snapshot Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.named[name] = snapshot.clone()
+}
+
+// Get returns the snapshot stored under name
+func (s *snapshotStore) Get(name string) (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.named[name]
+	if !ok {
+		return Snapshot{}, false
+	}
+	return snapshot.clone(), true
+}
+
+// Delete removes the snapshot stored under name and reports whether it existed
+func (s *snapshotStore) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.named[name]
+	delete(s.named, name)
+	return ok
+}
+
+// Names returns the names of all stored snapshots in sorted order
+func (s *snapshotStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.named))
+	for name := range s.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snapshotSummary is the JSON body returned for a snapshot
+type snapshotSummary struct {
+	Name    string `json:"name"`
+	Users   int    `json:"users"`
+	Actions int    `json:"actions"`
+}
+
+func summarize(name string, snapshot Snapshot) snapshotSummary {
+	return snapshotSummary{Name: name, Users: len(snapshot.Users), Actions: len(snapshot.Actions)}
+}
+
+// adminListSnapshots handles GET /admin/v1/snapshots
+func adminListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store := currentTenant(r).snapshots
+	summaries := make([]snapshotSummary, 0)
+	for _, name := range store.Names() {
+		if snapshot, ok := store.Get(name); ok {
+			summaries = append(summaries, summarize(name, snapshot))
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, summaries)
+}
+
+// adminCreateSnapshot handles POST /admin/v1/snapshots
+func adminCreateSnapshot(repo Repository, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var requestBody struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if requestBody.Name == "" || strings.Contains(requestBody.Name, "/") {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Snapshot name is required and must not contain '/'"})
+		return
+	}
+
+	snapshot := repo.Snapshot()
+	currentTenant(r).snapshots.Save(requestBody.Name, snapshot)
+	log.Printf("KYC SOAP Server: Saved snapshot '%s' (%d users, %d actions)", requestBody.Name, len(snapshot.Users), len(snapshot.Actions))
+	writeJSONResponse(w, http.StatusCreated, summarize(requestBody.Name, snapshot))
+}
+
+// adminRestoreSnapshot handles POST /admin/v1/snapshots/{name}/restore
+func adminRestoreSnapshot(repo Repository, name string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	snapshot, ok := currentTenant(r).snapshots.Get(name)
+	if !ok {
+		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("snapshot '%s' not found", name)})
+		return
+	}
+	if err := repo.Restore(snapshot); err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("KYC SOAP Server: Restored snapshot '%s'", name)
+	writeJSONResponse(w, http.StatusOK, summarize(name, snapshot))
+}
+
+// adminDeleteSnapshot handles DELETE /admin/v1/snapshots/{name}
+func adminDeleteSnapshot(name string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !currentTenant(r).snapshots.Delete(name) {
+		writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("snapshot '%s' not found", name)})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Snapshot '%s' deleted", name)})
+}
+
+// adminReset handles POST /admin/v1/reset, restoring the state the tenant had when it was created
+// (for the default tenant, after loading its fixtures) and removing every fault rule, scenario and
+// recorded request
+func adminReset(repo Repository, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	t := currentTenant(r)
+	baseline := t.snapshots.Baseline()
+	if err := repo.Restore(baseline); err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	t.faults.Clear()
+	t.faults.ClearScenarios()
+	t.journal.Clear()
+	log.Printf("KYC SOAP Server: Reset repository to its initial state and cleared all fault rules, scenarios and recorded requests")
+	writeJSONResponse(w, http.StatusOK, summarize("baseline", baseline))
+}
+
+// initSnapshotRoutes registers the snapshot, restore and reset admin routes
+func initSnapshotRoutes(mux *http.ServeMux, repo Repository) {
+	mux.HandleFunc("/admin/v1/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			adminListSnapshots(w, r)
+		case http.MethodPost:
+			adminCreateSnapshot(repo, w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/admin/v1/snapshots/", func(w http.ResponseWriter, r *http.Request) { // Trailing slash to match {name}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/v1/snapshots/"), "/")
+		switch {
+		case len(parts) == 2 && parts[0] != "" && parts[1] == "restore": // Matches /admin/v1/snapshots/{name}/restore
+			adminRestoreSnapshot(repo, parts[0], w, r)
+		case len(parts) == 1 && parts[0] != "": // Matches /admin/v1/snapshots/{name}
+			adminDeleteSnapshot(parts[0], w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("/admin/v1/reset", func(w http.ResponseWriter, r *http.Request) {
+		adminReset(repo, w, r)
+	})
+}
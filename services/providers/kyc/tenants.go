@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+)
+
+// TenantHeader selects a tenant for a request that does not name one in its path
+const TenantHeader = "X-KYC-Tenant"
+
+// tenant is an isolated copy of the provider's test state, so that parallel test suites do not
+// see each other's ClientIDs. Its users are kept in the repository backend the provider is
+// configured with; its fault rules, scenarios, snapshots and journal in memory. Settings and risk
+// scoring are global, and fixtures are only loaded into the default tenant: a tenant starts with a
+// copy of the users the default tenant had after loading them and does not follow later reloads.
+type tenant struct {
+	name      string
+	createdAt time.Time
+	repo      Repository
+	faults    *faults.Engine
+	journal   *requestJournal
+	snapshots *snapshotStore
+	mux       *http.ServeMux // The SOAP and admin routes, bound to repo
+	detach    func()         // Detaches repo's change event outbox from the relay
+}
+
+// defaultTenant serves every request that names no tenant. Its repository is the one main opens,
+// so it is not set here.
+var defaultTenant = &tenant{faults: faultRules, journal: journal, snapshots: snapshots}
+
+// newTenant creates a tenant whose reset returns to what repo holds now
+func newTenant(name string, repo Repository) *tenant {
+	t := &tenant{
+		name:      name,
+		createdAt: now().UTC(),
+		repo:      repo,
+		faults:    faults.NewEngine(),
+		journal:   newRequestJournal(journal.Capacity()),
+		snapshots: newSnapshotStore(),
+		mux:       http.NewServeMux(),
+	}
+	t.snapshots.SetBaseline(repo.Snapshot())
+	t.mux.HandleFunc("/soap", t.journal.Record(func(w http.ResponseWriter, r *http.Request) {
+		soapHandler(repo, w, r)
+	}))
+	initAdminRoutes(t.mux, repo)
+	return t
+}
+
+// tenantRoute is what the request context holds about the tenant a request was routed to
+type tenantRoute struct {
+	tenant *tenant
+	prefix string // "/t/{name}" when the tenant was named in the path
+}
+
+type tenantContextKey struct{}
+
+// currentTenant returns the tenant a request was routed to
+func currentTenant(r *http.Request) *tenant {
+	if route, ok := r.Context().Value(tenantContextKey{}).(tenantRoute); ok {
+		return route.tenant
+	}
+	return defaultTenant
+}
+
+// tenantPrefix returns the path prefix that named the tenant of a request, or ""
+func tenantPrefix(r *http.Request) string {
+	route, _ := r.Context().Value(tenantContextKey{}).(tenantRoute)
+	return route.prefix
+}
+
+// errTenantExists is returned when creating a tenant under a name that is taken
+var errTenantExists = errors.New("tenant already exists")
+
+// tenantNamePattern keeps tenant names usable as a path segment
+var tenantNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// tenantRegistry holds the tenants created through the admin API
+type tenantRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]*tenant
+	store   string // Repository backend of new tenants, see newRepository
+	dir     string // With the file backend, the directory holding one store file per tenant
+}
+
+// tenants are the tenants besides the default one
+var tenants = newTenantRegistry()
+
+func newTenantRegistry() *tenantRegistry {
+	return &tenantRegistry{tenants: make(map[string]*tenant)}
+}
+
+// UseStore keeps the users of tenants created from now on in the repository backend store. With
+// the file backend each tenant is stored as {name}.json in dir, and the tenants stored there are
+// opened again; a reopened tenant resets to the users it held when it was opened.
+func (reg *tenantRegistry) UseStore(store, dir string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.store, reg.dir = store, dir
+	if store != StoreFile {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tenant directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || !tenantNamePattern.MatchString(name) {
+			continue // Also skips the temporary files of an interrupted write
+		}
+		repo, err := OpenFileRepo(reg.path(name))
+		if err != nil {
+			return fmt.Errorf("failed to open tenant '%s': %w", name, err)
+		}
+		t := newTenant(name, repo)
+		t.detach = captureChanges(name, repo)
+		reg.tenants[name] = t
+		log.Printf("KYC SOAP Server: Opened tenant '%s' with %d users", name, len(repo.List()))
+	}
+	return nil
+}
+
+// path is the store file of the tenant called name with the file backend
+func (reg *tenantRegistry) path(name string) string {
+	return filepath.Join(reg.dir, name+".json")
+}
+
+// Create adds a tenant called name holding a copy of seed, or no users when seed is nil
+func (reg *tenantRegistry) Create(name string, seed *Snapshot) (*tenant, error) {
+	if !tenantNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("tenant name %q must be 1 to 64 letters, digits, '-' or '_'", name)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, exists := reg.tenants[name]; exists {
+		return nil, fmt.Errorf("%w: '%s'", errTenantExists, name)
+	}
+	repo, err := newRepository(reg.store, reg.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tenant '%s': %w", name, err)
+	}
+	if seed == nil {
+		seed = &Snapshot{}
+	}
+	// Capturing before the seed is restored announces it with a reset event. Restoring also writes
+	// the store file of an empty tenant.
+	detach := captureChanges(name, repo)
+	if err := repo.Restore(*seed); err != nil {
+		detach()
+		return nil, fmt.Errorf("failed to seed tenant '%s': %w", name, err)
+	}
+	t := newTenant(name, repo)
+	t.detach = detach
+	reg.tenants[name] = t
+	return t, nil
+}
+
+// Get returns the tenant called name
+func (reg *tenantRegistry) Get(name string) (*tenant, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	t, ok := reg.tenants[name]
+	return t, ok
+}
+
+// Delete removes the tenant called name with all its state, including change events it has not
+// published yet, and reports whether it existed
+func (reg *tenantRegistry) Delete(name string) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	t, ok := reg.tenants[name]
+	delete(reg.tenants, name)
+	if ok {
+		t.detach()
+	}
+	if ok && reg.store == StoreFile {
+		for _, path := range []string{reg.path(name), reg.path(name) + ".log"} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("KYC SOAP Server: Failed to remove the store of tenant '%s': %v", name, err)
+			}
+		}
+	}
+	return ok
+}
+
+// List returns the tenants sorted by name
+func (reg *tenantRegistry) List() []*tenant {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	list := make([]*tenant, 0, len(reg.tenants))
+	for _, t := range reg.tenants {
+		list = append(list, t)
+	}
+	slices.SortFunc(list, func(a, b *tenant) int { return strings.Compare(a.name, b.name) })
+	return list
+}
+
+// Route serves requests for a tenant, named by a /t/{tenant} path prefix or the X-KYC-Tenant
+// header, from that tenant's routes, and every other request from next
+func (reg *tenantRegistry) Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, path, prefix := "", r.URL.Path, ""
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/t/"); ok {
+			name, path, _ = strings.Cut(rest, "/")
+			path, prefix = "/"+path, "/t/"+name
+		} else if header := r.Header.Get(TenantHeader); header != "" {
+			name = header
+		}
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		t, ok := reg.Get(name)
+		if !ok {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Tenant '%s' not found", name)})
+			return
+		}
+		routed := r.Clone(context.WithValue(r.Context(), tenantContextKey{}, tenantRoute{tenant: t, prefix: prefix}))
+		routed.URL.Path, routed.URL.RawPath = path, ""
+		t.mux.ServeHTTP(w, routed)
+	})
+}
+
+// tenantSummary describes a tenant in the tenant admin API
+type tenantSummary struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Users     int       `json:"users"`
+	Rules     int       `json:"rules"`
+	Scenarios int       `json:"scenarios"`
+	Requests  int       `json:"requests"`
+}
+
+func (t *tenant) summary() tenantSummary {
+	return tenantSummary{
+		Name:      t.name,
+		CreatedAt: t.createdAt,
+		Users:     len(t.repo.List()),
+		Rules:     len(t.faults.Rules()),
+		Scenarios: len(t.faults.Scenarios()),
+		Requests:  len(t.journal.Entries(journalFilter{})),
+	}
+}
+
+// adminCreateTenant handles POST /admin/v1/tenants. The tenant starts with the users the default
+// tenant had after loading its fixtures, or with none when the body sets "empty".
+func adminCreateTenant(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Name  string `json:"name"`
+		Empty bool   `json:"empty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var seed *Snapshot
+	if !requestBody.Empty {
+		baseline := defaultTenant.snapshots.Baseline()
+		seed = &baseline
+	}
+	t, err := tenants.Create(requestBody.Name, seed)
+	if errors.Is(err, errTenantExists) {
+		writeJSONResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	} else if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("KYC SOAP Server: Created tenant '%s' with %d users", t.name, len(t.repo.List()))
+	writeJSONResponse(w, http.StatusCreated, t.summary())
+}
+
+// adminTenant handles GET and DELETE /admin/v1/tenants/{name}
+func adminTenant(name string, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		t, ok := tenants.Get(name)
+		if !ok {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Tenant '%s' not found", name)})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, t.summary())
+	case http.MethodDelete:
+		if !tenants.Delete(name) {
+			writeJSONResponse(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Tenant '%s' not found", name)})
+			return
+		}
+		log.Printf("KYC SOAP Server: Deleted tenant '%s'", name)
+		writeJSONResponse(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Tenant '%s' deleted", name)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// initTenantRoutes registers the tenant admin routes. They belong to the default tenant only, so
+// tenants are managed without naming one.
+func initTenantRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/v1/tenants", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			summaries := make([]tenantSummary, 0)
+			for _, t := range tenants.List() {
+				summaries = append(summaries, t.summary())
+			}
+			writeJSONResponse(w, http.StatusOK, summaries)
+		case http.MethodPost:
+			adminCreateTenant(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/admin/v1/tenants/", func(w http.ResponseWriter, r *http.Request) { // Trailing slash to match {name}
+		name := strings.TrimPrefix(r.URL.Path, "/admin/v1/tenants/")
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		adminTenant(name, w, r)
+	})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+	"kafka-soap-e2e-test/services/shared/kyc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTenantServer serves a default tenant holding client1 the way main does, and removes every
+// tenant the test creates
+func newTenantServer(t *testing.T) (*httptest.Server, Repository) {
+	t.Helper()
+	repo := NewInMemoryRepo()
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client1", Risk: 0.1}))
+	baseline := snapshots.Baseline()
+	snapshots.SetBaseline(repo.Snapshot())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/soap", journal.Record(func(w http.ResponseWriter, r *http.Request) { soapHandler(repo, w, r) }))
+	initAdminRoutes(mux, repo)
+	initTenantRoutes(mux)
+	server := httptest.NewServer(tenants.Route(mux))
+	t.Cleanup(func() {
+		server.Close()
+		for _, tenant := range tenants.List() {
+			tenants.Delete(tenant.name)
+		}
+		snapshots.SetBaseline(baseline)
+		faultRules.Clear()
+		journal.Clear()
+	})
+	return server, repo
+}
+
+func createTenant(t *testing.T, server *httptest.Server, body string) (int, tenantSummary) {
+	t.Helper()
+	resp, err := server.Client().Post(server.URL+"/admin/v1/tenants", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var summary tenantSummary
+	if resp.StatusCode == http.StatusCreated {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	}
+	return resp.StatusCode, summary
+}
+
+// postTenantSOAP posts a SOAP call to target, setting the tenant header when tenant is not empty
+func postTenantSOAP(t *testing.T, server *httptest.Server, target, tenant, operation, clientID string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("POST", server.URL+target, strings.NewReader(createSOAPRequest(operation, clientID, nil)))
+	require.NoError(t, err)
+	if tenant != "" {
+		req.Header.Set(TenantHeader, tenant)
+	}
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTenantRegistry_Create(t *testing.T) {
+	reg := newTenantRegistry()
+	seed := Snapshot{Users: map[string]kyc.Record{"client1": {ClientID: "client1", Risk: 0.1}}}
+
+	seeded, err := reg.Create("suite-a", &seed)
+	require.NoError(t, err)
+	assert.Len(t, seeded.repo.List(), 1)
+	assert.Len(t, seeded.snapshots.Baseline().Users, 1, "a reset returns to the seed")
+	empty, err := reg.Create("suite_b", nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty.repo.List())
+
+	_, err = reg.Create("suite-a", nil)
+	assert.ErrorIs(t, err, errTenantExists)
+	for _, bad := range []string{"", "a/b", "a b", strings.Repeat("x", 65)} {
+		_, err := reg.Create(bad, nil)
+		assert.Error(t, err, "%q", bad)
+	}
+
+	assert.Equal(t, []*tenant{seeded, empty}, reg.List())
+	assert.True(t, reg.Delete("suite-a"))
+	assert.False(t, reg.Delete("suite-a"))
+	_, ok := reg.Get("suite-a")
+	assert.False(t, ok)
+}
+
+func TestTenantRegistry_FileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tenants")
+	reg := newTenantRegistry()
+	require.NoError(t, reg.UseStore(StoreFile, dir))
+	seed := Snapshot{Users: map[string]kyc.Record{"client1": {ClientID: "client1", Risk: 0.1}}}
+	seeded, err := reg.Create("suite-a", &seed)
+	require.NoError(t, err)
+	_, err = reg.Create("suite-b", nil)
+	require.NoError(t, err)
+	_, err = createRecord(seeded.repo, auditContext{}, kyc.Record{ClientID: "client2", Risk: 0.2}, false)
+	require.NoError(t, err)
+
+	reopened := newTenantRegistry()
+	require.NoError(t, reopened.UseStore(StoreFile, dir))
+	require.Len(t, reopened.List(), 2, "tenants survive a restart, including empty ones")
+	suiteA, ok := reopened.Get("suite-a")
+	require.True(t, ok)
+	assert.Len(t, suiteA.repo.List(), 2)
+	assert.Len(t, suiteA.repo.History("client2"), 1)
+	assert.Len(t, suiteA.snapshots.Baseline().Users, 2, "a reopened tenant resets to what it held when opened")
+
+	_, err = createRecord(suiteA.repo, auditContext{}, kyc.Record{ClientID: "client3", Risk: 0.3}, false)
+	require.NoError(t, err)
+	assert.True(t, reopened.Delete("suite-a"))
+	assert.NoFileExists(t, filepath.Join(dir, "suite-a.json"), "deleting a tenant removes its store")
+	assert.NoFileExists(t, filepath.Join(dir, "suite-a.json.log"))
+
+	memory := newTenantRegistry()
+	require.NoError(t, memory.UseStore(StoreMemory, dir))
+	assert.Empty(t, memory.List(), "with the memory backend tenants do not outlive the process")
+}
+
+func TestTenants_AdminAPI(t *testing.T) {
+	server, _ := newTenantServer(t)
+
+	code, summary := createTenant(t, server, `{"name":"suite-a"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "suite-a", summary.Name)
+	assert.Equal(t, 1, summary.Users, "a tenant starts from the default tenant's fixtures")
+	code, summary = createTenant(t, server, `{"name":"suite-b","empty":true}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 0, summary.Users)
+
+	code, _ = createTenant(t, server, `{"name":"suite-a"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = createTenant(t, server, `{"name":"bad/name"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	resp, err := server.Client().Get(server.URL + "/admin/v1/tenants")
+	require.NoError(t, err)
+	var summaries []tenantSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summaries))
+	resp.Body.Close()
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "suite-a", summaries[0].Name)
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/admin/v1/tenants/suite-a", nil)
+	resp, err = server.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = server.Client().Get(server.URL + "/admin/v1/tenants/suite-a")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, http.StatusNotFound, postTenantSOAP(t, server, "/t/suite-a/soap", "", "KYCQuery", "client1").StatusCode)
+	assert.Equal(t, http.StatusNotFound, postTenantSOAP(t, server, "/soap", "suite-a", "KYCQuery", "client1").StatusCode)
+}
+
+func TestTenants_Isolation(t *testing.T) {
+	server, repo := newTenantServer(t)
+	code, _ := createTenant(t, server, `{"name":"suite-a"}`)
+	require.Equal(t, http.StatusCreated, code)
+	tenantA, _ := tenants.Get("suite-a")
+
+	// A user created in the tenant, by path or by header, is not seen by the default tenant
+	require.NoError(t, tenantA.repo.Create(kyc.Record{ClientID: "client2", Risk: 0.2}))
+	assert.Equal(t, http.StatusOK, postTenantSOAP(t, server, "/t/suite-a/soap", "", "KYCQuery", "client2").StatusCode)
+	assert.Equal(t, http.StatusOK, postTenantSOAP(t, server, "/soap", "suite-a", "KYCQuery", "client2").StatusCode)
+	assert.Equal(t, http.StatusNotFound, postTenantSOAP(t, server, "/soap", "", "KYCQuery", "client2").StatusCode)
+	_, err := repo.Read("client2")
+	assert.Error(t, err)
+
+	// Fault rules and the journal belong to the tenant they were added through
+	rule, err := json.Marshal(faults.Rule{Operation: "KYCQuery", Fault: faults.TypeInternalError})
+	require.NoError(t, err)
+	resp, err := server.Client().Post(server.URL+"/t/suite-a/admin/v1/actions", "application/json", strings.NewReader(string(rule)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, postTenantSOAP(t, server, "/t/suite-a/soap", "", "KYCQuery", "client1").StatusCode)
+	assert.Equal(t, http.StatusOK, postTenantSOAP(t, server, "/soap", "", "KYCQuery", "client1").StatusCode)
+	assert.Empty(t, faultRules.Rules())
+
+	assert.Len(t, tenantA.journal.Entries(journalFilter{}), 3)
+	assert.Len(t, journal.Entries(journalFilter{}), 2)
+
+	// A reset through the tenant returns it to its seed and leaves the default tenant alone
+	require.NoError(t, repo.Create(kyc.Record{ClientID: "client3", Risk: 0.3}))
+	resp, err = server.Client().Post(server.URL+"/t/suite-a/admin/v1/reset", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = tenantA.repo.Read("client2")
+	assert.Error(t, err)
+	assert.Empty(t, tenantA.faults.Rules())
+	assert.Empty(t, tenantA.journal.Entries(journalFilter{}))
+	_, err = repo.Read("client3")
+	assert.NoError(t, err)
+}
+
+func TestTenants_WSDLAddress(t *testing.T) {
+	server, _ := newTenantServer(t)
+	code, _ := createTenant(t, server, `{"name":"suite-a"}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	resp, err := server.Client().Get(server.URL + "/t/suite-a/soap?wsdl")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), server.URL+"/t/suite-a/soap", "the tenant's clients keep calling it by path")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+)
+
+// ValidationMode controls how inbound SOAP requests are checked against the service's XSD
+type ValidationMode string
+
+const (
+	ValidationStrict  ValidationMode = "strict"  // Reject a request on any violation
+	ValidationLenient ValidationMode = "lenient" // Reject missing or mistyped values; only log unknown elements, ordering and namespace problems
+	ValidationOff     ValidationMode = "off"     // Skip validation
+)
+
+// validateRequest checks a raw SOAP envelope and returns the violations that reject it under mode.
+// Envelopes that are not well-formed are left to the handler's own parsing errors.
+func validateRequest(mode ValidationMode, body []byte) []contract.Violation {
+	if mode == ValidationOff {
+		return nil
+	}
+	root, violations, err := contract.KYCSchema().ValidateEnvelope(body)
+	if err != nil {
+		return nil
+	}
+
+	var rejected []contract.Violation
+	for _, v := range violations {
+		if mode == ValidationLenient && tolerated(v) {
+			log.Printf("KYC SOAP Server: Tolerating schema violation in %s request: %s", root.Local, v)
+			continue
+		}
+		rejected = append(rejected, v)
+	}
+	return rejected
+}
+
+// tolerated reports whether lenient mode lets a violation through. encoding/xml decoding copes with
+// extra elements, any element order and unqualified names, so only missing and mistyped values are fatal.
+func tolerated(v contract.Violation) bool {
+	switch v.Kind {
+	case contract.ViolationUnexpected, contract.ViolationOrder, contract.ViolationNamespace:
+		return true
+	}
+	return false
+}
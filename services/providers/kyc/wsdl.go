@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kafka-soap-e2e-test/services/providers/kyc/contract"
+)
+
+// serveContract answers GET /soap?wsdl and GET /soap?xsd=N. It reports false for any other request.
+func serveContract(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	query := r.URL.Query()
+
+	var doc []byte
+	switch {
+	case query.Has("wsdl"):
+		doc = contract.WSDL(serviceAddress(r))
+	case query.Has("xsd"):
+		n, err := strconv.Atoi(query.Get("xsd"))
+		schema, ok := contract.XSD(n)
+		if err != nil || !ok {
+			http.Error(w, "Unknown schema: "+query.Get("xsd"), http.StatusNotFound)
+			log.Printf("KYC SOAP Server: Requested unknown schema xsd=%s", query.Get("xsd"))
+			return true
+		}
+		doc = schema
+	default:
+		return false
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	if _, err := w.Write(doc); err != nil {
+		log.Printf("KYC SOAP Server: Failed to write contract: %v", err)
+	}
+	log.Printf("KYC SOAP Server: Served contract for %s", r.URL.RequestURI())
+	return true
+}
+
+// hostPattern matches a host[:port] fit to publish in the WSDL: a DNS name, an IPv4 address or a
+// bracketed IPv6 address, with an optional port
+var hostPattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9.-]{0,252})?|\[[0-9A-Fa-f:.]+\])(:[0-9]{1,5})?$`)
+
+// serviceAddress is the SOAP endpoint URL as seen by the caller, honouring a fronting proxy's X-Forwarded-* headers.
+// Forwarded values that are not a plain scheme or host[:port] are ignored, and the result is XML-escaped.
+func serviceAddress(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	host := "localhost"
+	if hostPattern.MatchString(r.Host) {
+		host = r.Host
+	}
+	if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" {
+		if hostPattern.MatchString(fwd) {
+			host = fwd
+		} else {
+			log.Printf("KYC SOAP Server: Ignoring invalid X-Forwarded-Host %q", fwd)
+		}
+	}
+	var address strings.Builder
+	_ = xml.EscapeText(&address, []byte(scheme+"://"+host+tenantPrefix(r)+r.URL.Path)) // strings.Builder never fails
+	return address.String()
+}
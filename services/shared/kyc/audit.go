@@ -0,0 +1,53 @@
+package kyc
+
+import "time"
+
+// AuditAction is the kind of change an AuditEvent records
+type AuditAction string
+
+const (
+	AuditCreate     AuditAction = "CREATE"
+	AuditUpdate     AuditAction = "UPDATE"
+	AuditDelete     AuditAction = "DELETE"
+	AuditTransition AuditAction = "TRANSITION" // Review status change through the lifecycle operations or the admin API
+)
+
+// AuditSource is the interface a change came in through
+type AuditSource string
+
+const (
+	SourceSOAP    AuditSource = "SOAP"
+	SourceAdmin   AuditSource = "ADMIN"
+	SourceFixture AuditSource = "FIXTURE"
+)
+
+// AuditEvent is one entry of a record's append-only history. Before is nil for a create and
+// After is nil for a delete.
+type AuditEvent struct {
+	Sequence      int64       `xml:"Sequence" json:"sequence"` // Increases by one per event across all records
+	ClientID      string      `xml:"ClientID" json:"clientId"`
+	Action        AuditAction `xml:"Action" json:"action"`
+	Actor         string      `xml:"Actor" json:"actor"`
+	Source        AuditSource `xml:"Source" json:"source"`
+	Operation     string      `xml:"Operation,omitempty" json:"operation,omitempty"` // SOAP operation or admin route
+	CorrelationID string      `xml:"CorrelationID,omitempty" json:"correlationId,omitempty"`
+	Timestamp     time.Time   `xml:"Timestamp" json:"timestamp"`
+	Before        *Record     `xml:"Before,omitempty" json:"before,omitempty"`
+	After         *Record     `xml:"After,omitempty" json:"after,omitempty"`
+}
+
+// StateAt replays history, oldest event first, and returns the record as it stood at t.
+// It reports false if the record did not exist at t.
+func StateAt(history []AuditEvent, t time.Time) (Record, bool) {
+	var state *Record
+	for _, e := range history {
+		if e.Timestamp.After(t) {
+			break
+		}
+		state = e.After
+	}
+	if state == nil {
+		return Record{}, false
+	}
+	return *state, true
+}
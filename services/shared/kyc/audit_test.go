@@ -0,0 +1,33 @@
+package kyc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateAt(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	created := Record{ClientID: "client1", Risk: 0.2}
+	updated := Record{ClientID: "client1", Risk: 0.7}
+	history := []AuditEvent{
+		{Sequence: 1, ClientID: "client1", Action: AuditCreate, Timestamp: t0, After: &created},
+		{Sequence: 2, ClientID: "client1", Action: AuditUpdate, Timestamp: t0.Add(time.Hour), Before: &created, After: &updated},
+		{Sequence: 3, ClientID: "client1", Action: AuditDelete, Timestamp: t0.Add(2 * time.Hour), Before: &updated},
+	}
+
+	_, ok := StateAt(history, t0.Add(-time.Second))
+	assert.False(t, ok, "before the create")
+
+	state, ok := StateAt(history, t0)
+	assert.True(t, ok)
+	assert.Equal(t, 0.2, state.Risk)
+
+	state, ok = StateAt(history, t0.Add(90*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 0.7, state.Risk)
+
+	_, ok = StateAt(history, t0.Add(3*time.Hour))
+	assert.False(t, ok, "after the delete")
+}
@@ -0,0 +1,63 @@
+package kyc
+
+import (
+	"fmt"
+	"slices"
+)
+
+// transitions lists the review statuses each status may move to:
+//
+//	PENDING -> IN_REVIEW -> APPROVED | REJECTED
+//	PENDING, IN_REVIEW, APPROVED -> EXPIRED
+//	REJECTED, EXPIRED -> PENDING (resubmission)
+var transitions = map[ReviewStatus][]ReviewStatus{
+	ReviewPending:  {ReviewInReview, ReviewExpired},
+	ReviewInReview: {ReviewApproved, ReviewRejected, ReviewExpired},
+	ReviewApproved: {ReviewExpired},
+	ReviewRejected: {ReviewPending},
+	ReviewExpired:  {ReviewPending},
+}
+
+// Next returns the statuses s may move to
+func (s ReviewStatus) Next() []ReviewStatus {
+	return slices.Clone(transitions[s])
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next
+func (s ReviewStatus) CanTransitionTo(next ReviewStatus) bool {
+	return slices.Contains(transitions[s], next)
+}
+
+// TransitionError is returned for a status change the lifecycle does not allow.
+// An empty From means the record is being created, which only PENDING may be.
+type TransitionError struct {
+	ClientID string
+	From, To ReviewStatus
+}
+
+func (e *TransitionError) Error() string {
+	if e.From == "" {
+		return fmt.Sprintf("KYC record '%s' cannot be created as %s; new records start as %s", e.ClientID, e.To, ReviewPending)
+	}
+	return fmt.Sprintf("KYC record '%s' cannot move from %s to %s", e.ClientID, e.From, e.To)
+}
+
+// CurrentStatus returns the record's review status, treating records stored before
+// the lifecycle existed as PENDING
+func (r Record) CurrentStatus() ReviewStatus {
+	if r.ReviewStatus == "" {
+		return ReviewPending
+	}
+	return r.ReviewStatus
+}
+
+// Transition moves the record to status to, recording note as the reason or comment.
+// It fails with a *TransitionError if the lifecycle does not allow the move.
+func (r *Record) Transition(to ReviewStatus, note string) error {
+	from := r.CurrentStatus()
+	if !from.CanTransitionTo(to) {
+		return &TransitionError{ClientID: r.ClientID, From: from, To: to}
+	}
+	r.ReviewStatus, r.ReviewNote = to, note
+	return nil
+}
@@ -0,0 +1,41 @@
+package kyc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewStatus_Transitions(t *testing.T) {
+	assert.True(t, ReviewPending.CanTransitionTo(ReviewInReview))
+	assert.True(t, ReviewInReview.CanTransitionTo(ReviewApproved))
+	assert.True(t, ReviewInReview.CanTransitionTo(ReviewRejected))
+	assert.True(t, ReviewApproved.CanTransitionTo(ReviewExpired))
+	assert.True(t, ReviewRejected.CanTransitionTo(ReviewPending))
+
+	assert.False(t, ReviewPending.CanTransitionTo(ReviewApproved), "approval requires a review")
+	assert.False(t, ReviewApproved.CanTransitionTo(ReviewRejected))
+	assert.False(t, ReviewInReview.CanTransitionTo(ReviewInReview))
+
+	for _, s := range ReviewStatuses {
+		assert.NotEmpty(t, s.Next(), "%s is a dead end", s)
+	}
+}
+
+func TestRecord_Transition(t *testing.T) {
+	r := Record{ClientID: "c"}
+	assert.Equal(t, ReviewPending, r.CurrentStatus())
+
+	require.NoError(t, r.Transition(ReviewInReview, ""))
+	require.NoError(t, r.Transition(ReviewRejected, "document unreadable"))
+	assert.Equal(t, ReviewRejected, r.ReviewStatus)
+	assert.Equal(t, "document unreadable", r.ReviewNote)
+
+	err := r.Transition(ReviewApproved, "")
+	var transitionErr *TransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, ReviewRejected, transitionErr.From)
+	assert.Equal(t, ReviewApproved, transitionErr.To)
+	assert.Equal(t, ReviewRejected, r.ReviewStatus, "a refused transition leaves the record unchanged")
+}
@@ -0,0 +1,211 @@
+// Package kyc defines the KYC record shared by the provider, the consumer and the test framework.
+// The same struct is carried in SOAP messages (element names match the contract's UserData type),
+// the provider's admin JSON API, the JSON fixtures in tests/usecases/kyc and the consumer's Kafka payloads.
+package kyc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// DateLayout is the xs:date layout used for dates of birth and document expiry
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date in DateLayout, kept as text so an unset date is simply empty
+type Date string
+
+// NewDate formats t as a Date
+func NewDate(t time.Time) Date {
+	return Date(t.Format(DateLayout))
+}
+
+// Time parses d; it fails for an empty or malformed date
+func (d Date) Time() (time.Time, error) {
+	return time.Parse(DateLayout, string(d))
+}
+
+// ReviewStatus is where a record stands in the KYC review
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "PENDING"
+	ReviewInReview ReviewStatus = "IN_REVIEW"
+	ReviewApproved ReviewStatus = "APPROVED"
+	ReviewRejected ReviewStatus = "REJECTED"
+	ReviewExpired  ReviewStatus = "EXPIRED"
+)
+
+// ReviewStatuses lists every valid ReviewStatus
+var ReviewStatuses = []ReviewStatus{ReviewPending, ReviewInReview, ReviewApproved, ReviewRejected, ReviewExpired}
+
+// Decided reports whether s is the outcome of a review rather than a step towards one
+func (s ReviewStatus) Decided() bool {
+	return s == ReviewApproved || s == ReviewRejected
+}
+
+// DocumentType is the kind of an identity document
+type DocumentType string
+
+const (
+	DocumentPassport        DocumentType = "PASSPORT"
+	DocumentNationalID      DocumentType = "NATIONAL_ID"
+	DocumentDrivingLicence  DocumentType = "DRIVING_LICENCE"
+	DocumentResidencePermit DocumentType = "RESIDENCE_PERMIT"
+)
+
+// DocumentTypes lists every valid DocumentType
+var DocumentTypes = []DocumentType{DocumentPassport, DocumentNationalID, DocumentDrivingLicence, DocumentResidencePermit}
+
+// Address is a postal address of the client
+type Address struct {
+	Line1      string `xml:"Line1" json:"line1"`
+	Line2      string `xml:"Line2,omitempty" json:"line2,omitempty"`
+	City       string `xml:"City" json:"city"`
+	PostalCode string `xml:"PostalCode,omitempty" json:"postalCode,omitempty"`
+	Country    string `xml:"Country" json:"country"` // ISO 3166-1 alpha-2
+}
+
+// IdentityDocument is a document the client's identity was verified with
+type IdentityDocument struct {
+	Type           DocumentType `xml:"Type" json:"type"`
+	Number         string       `xml:"Number" json:"number"`
+	IssuingCountry string       `xml:"IssuingCountry,omitempty" json:"issuingCountry,omitempty"` // ISO 3166-1 alpha-2
+	ExpiryDate     Date         `xml:"ExpiryDate,omitempty" json:"expiryDate,omitempty"`
+}
+
+// Expired reports whether the document's expiry date lies before now
+func (d IdentityDocument) Expired(now time.Time) bool {
+	expiry, err := d.ExpiryDate.Time()
+	return err == nil && expiry.Before(now)
+}
+
+// CustomField is a free-form attribute of the client, such as a credit score, that risk rules can refer to by key
+type CustomField struct {
+	Key   string `xml:"Key" json:"key"`
+	Value string `xml:"Value" json:"value"`
+}
+
+// Record is the KYC record of a single client. Operation outcomes such as a status or message
+// are not part of it; they travel in the enclosing response.
+type Record struct {
+	ClientID     string             `xml:"ClientID" json:"clientId"`
+	Risk         float64            `xml:"Risk" json:"risk"`
+	LegalName    string             `xml:"LegalName,omitempty" json:"legalName,omitempty"`
+	DateOfBirth  Date               `xml:"DateOfBirth,omitempty" json:"dateOfBirth,omitempty"`
+	Nationality  string             `xml:"Nationality,omitempty" json:"nationality,omitempty"` // ISO 3166-1 alpha-2
+	Addresses    []Address          `xml:"Address,omitempty" json:"addresses,omitempty"`
+	Documents    []IdentityDocument `xml:"Document,omitempty" json:"documents,omitempty"`
+	PEP          bool               `xml:"PEP,omitempty" json:"pep,omitempty"`               // Politically exposed person
+	Sanctioned   bool               `xml:"Sanctioned,omitempty" json:"sanctioned,omitempty"` // Listed on a sanctions list
+	CustomFields []CustomField      `xml:"CustomField,omitempty" json:"customFields,omitempty"`
+	ReviewStatus ReviewStatus       `xml:"ReviewStatus,omitempty" json:"reviewStatus,omitempty"`
+	ReviewNote   string             `xml:"ReviewNote,omitempty" json:"reviewNote,omitempty"` // Reason or comment given with the last status change
+	CreatedAt    *time.Time         `xml:"CreatedAt,omitempty" json:"createdAt,omitempty"`
+	UpdatedAt    *time.Time         `xml:"UpdatedAt,omitempty" json:"updatedAt,omitempty"`
+	ReviewedAt   *time.Time         `xml:"ReviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	Version      int64              `xml:"Version,omitempty" json:"version,omitempty"` // Incremented by every write, starting at 1
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Validate checks the record's fields for well-formedness and returns every problem found
+func (r Record) Validate() error {
+	var errs []error
+	if r.ClientID == "" {
+		errs = append(errs, errors.New("clientId is required"))
+	}
+	if r.DateOfBirth != "" {
+		if dob, err := r.DateOfBirth.Time(); err != nil {
+			errs = append(errs, fmt.Errorf("dateOfBirth %q is not a %s date", r.DateOfBirth, DateLayout))
+		} else if dob.After(time.Now()) {
+			errs = append(errs, fmt.Errorf("dateOfBirth %s lies in the future", r.DateOfBirth))
+		}
+	}
+	if r.Nationality != "" && !countryCode.MatchString(r.Nationality) {
+		errs = append(errs, fmt.Errorf("nationality %q is not an ISO 3166-1 alpha-2 code", r.Nationality))
+	}
+	for i, a := range r.Addresses {
+		if a.Line1 == "" || a.City == "" {
+			errs = append(errs, fmt.Errorf("addresses[%d]: line1 and city are required", i))
+		}
+		if !countryCode.MatchString(a.Country) {
+			errs = append(errs, fmt.Errorf("addresses[%d]: country %q is not an ISO 3166-1 alpha-2 code", i, a.Country))
+		}
+	}
+	for i, d := range r.Documents {
+		if !slices.Contains(DocumentTypes, d.Type) {
+			errs = append(errs, fmt.Errorf("documents[%d]: unknown type %q", i, d.Type))
+		}
+		if d.Number == "" {
+			errs = append(errs, fmt.Errorf("documents[%d]: number is required", i))
+		}
+		if d.IssuingCountry != "" && !countryCode.MatchString(d.IssuingCountry) {
+			errs = append(errs, fmt.Errorf("documents[%d]: issuingCountry %q is not an ISO 3166-1 alpha-2 code", i, d.IssuingCountry))
+		}
+		if d.ExpiryDate != "" {
+			if _, err := d.ExpiryDate.Time(); err != nil {
+				errs = append(errs, fmt.Errorf("documents[%d]: expiryDate %q is not a %s date", i, d.ExpiryDate, DateLayout))
+			}
+		}
+	}
+	seen := make(map[string]bool, len(r.CustomFields))
+	for i, f := range r.CustomFields {
+		if f.Key == "" {
+			errs = append(errs, fmt.Errorf("customFields[%d]: key is required", i))
+		} else if seen[f.Key] {
+			errs = append(errs, fmt.Errorf("customFields[%d]: duplicate key %q", i, f.Key))
+		}
+		seen[f.Key] = true
+	}
+	if r.ReviewStatus != "" && !slices.Contains(ReviewStatuses, r.ReviewStatus) {
+		errs = append(errs, fmt.Errorf("unknown reviewStatus %q", r.ReviewStatus))
+	}
+	return errors.Join(errs...)
+}
+
+// CustomField returns the value of the custom field with key and whether the record has one
+func (r Record) CustomField(key string) (string, bool) {
+	for _, f := range r.CustomFields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Touch sets the record's timestamps and version for a write at now. previous is the stored
+// record being replaced, or nil on create; caller-supplied timestamps and versions are ignored.
+func (r *Record) Touch(previous *Record, now time.Time) {
+	now = now.UTC()
+	r.CreatedAt, r.UpdatedAt, r.ReviewedAt = &now, &now, nil
+	r.Version = 1
+	if previous != nil {
+		r.CreatedAt, r.ReviewedAt = previous.CreatedAt, previous.ReviewedAt
+		r.Version = previous.Version + 1
+	}
+	if r.ReviewStatus.Decided() && (previous == nil || previous.ReviewStatus != r.ReviewStatus) {
+		r.ReviewedAt = &now
+	}
+}
+
+// VersionConflictError is returned for a write that expected a different version of the record
+// than the one stored
+type VersionConflictError struct {
+	ClientID         string
+	Expected, Actual int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("KYC record '%s' is at version %d, not the expected version %d", e.ClientID, e.Actual, e.Expected)
+}
+
+// CheckVersion returns a *VersionConflictError unless expected is nil or matches the record's Version
+func (r Record) CheckVersion(expected *int64) error {
+	if expected != nil && *expected != r.Version {
+		return &VersionConflictError{ClientID: r.ClientID, Expected: *expected, Actual: r.Version}
+	}
+	return nil
+}
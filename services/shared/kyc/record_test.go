@@ -0,0 +1,126 @@
+package kyc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRecord() Record {
+	return Record{
+		ClientID:    "client1",
+		Risk:        0.25,
+		LegalName:   "Jane Doe",
+		DateOfBirth: "1985-04-12",
+		Nationality: "DE",
+		Addresses:   []Address{{Line1: "Hauptstr. 1", City: "Berlin", PostalCode: "10115", Country: "DE"}},
+		Documents: []IdentityDocument{
+			{Type: DocumentPassport, Number: "C01X00T47", IssuingCountry: "DE", ExpiryDate: "2031-01-31"},
+		},
+		PEP:          true,
+		CustomFields: []CustomField{{Key: "creditScore", Value: "720"}},
+		ReviewStatus: ReviewApproved,
+	}
+}
+
+func TestRecord_Validate(t *testing.T) {
+	assert.NoError(t, sampleRecord().Validate())
+	assert.NoError(t, Record{ClientID: "minimal"}.Validate())
+
+	r := sampleRecord()
+	r.ClientID = ""
+	r.DateOfBirth = "12/04/1985"
+	r.Nationality = "Germany"
+	r.Addresses[0].Country = "de"
+	r.Documents[0].Type = "LIBRARY_CARD"
+	r.Documents[0].Number = ""
+	r.Documents[0].ExpiryDate = "soon"
+	r.CustomFields = append(r.CustomFields, CustomField{Key: "creditScore", Value: "1"}, CustomField{Value: "x"})
+	r.ReviewStatus = "MAYBE"
+	err := r.Validate()
+	require.Error(t, err)
+	for _, want := range []string{"clientId is required", "dateOfBirth", "nationality", "addresses[0]: country", "documents[0]: unknown type", "documents[0]: number", "documents[0]: expiryDate", "customFields[1]: duplicate key", "customFields[2]: key is required", "reviewStatus"} {
+		assert.ErrorContains(t, err, want)
+	}
+
+	value, ok := sampleRecord().CustomField("creditScore")
+	assert.True(t, ok)
+	assert.Equal(t, "720", value)
+	_, ok = sampleRecord().CustomField("riskFactor")
+	assert.False(t, ok)
+
+	future := Record{ClientID: "c", DateOfBirth: NewDate(time.Now().AddDate(1, 0, 0))}
+	assert.ErrorContains(t, future.Validate(), "future")
+}
+
+func TestRecord_Encoding(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := sampleRecord()
+	r.CreatedAt = &created
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"dateOfBirth":"1985-04-12"`)
+	assert.Contains(t, string(data), `"documents":[{"type":"PASSPORT"`)
+	assert.NotContains(t, string(data), "sanctioned")
+	var fromJSON Record
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Equal(t, r, fromJSON)
+
+	wrapped := struct {
+		XMLName xml.Name `xml:"UserData"`
+		Record
+	}{Record: r}
+	data, err = xml.Marshal(wrapped)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<Document><Type>PASSPORT</Type>")
+	assert.Contains(t, string(data), "<CreatedAt>2024-01-02T03:04:05Z</CreatedAt>")
+	var fromXML struct {
+		XMLName xml.Name `xml:"UserData"`
+		Record
+	}
+	require.NoError(t, xml.Unmarshal(data, &fromXML))
+	assert.Equal(t, r, fromXML.Record)
+}
+
+func TestRecord_Touch(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+
+	r := Record{ClientID: "c", ReviewStatus: ReviewPending, CreatedAt: &t2, Version: 9}
+	r.Touch(nil, t0)
+	assert.Equal(t, int64(1), r.Version)
+	assert.Equal(t, t0, *r.CreatedAt)
+	assert.Equal(t, t0, *r.UpdatedAt)
+	assert.Nil(t, r.ReviewedAt)
+
+	approved := Record{ClientID: "c", ReviewStatus: ReviewApproved}
+	approved.Touch(&r, t1)
+	assert.Equal(t, t0, *approved.CreatedAt)
+	assert.Equal(t, t1, *approved.UpdatedAt)
+	assert.Equal(t, t1, *approved.ReviewedAt)
+
+	edited := Record{ClientID: "c", Risk: 0.4, ReviewStatus: ReviewApproved}
+	edited.Touch(&approved, t2)
+	assert.Equal(t, t2, *edited.UpdatedAt)
+	assert.Equal(t, t1, *edited.ReviewedAt, "unchanged review status keeps its review time")
+	assert.Equal(t, int64(3), edited.Version)
+}
+
+func TestRecord_CheckVersion(t *testing.T) {
+	r := Record{ClientID: "c", Version: 3}
+	three, four := int64(3), int64(4)
+	assert.NoError(t, r.CheckVersion(nil))
+	assert.NoError(t, r.CheckVersion(&three))
+
+	err := r.CheckVersion(&four)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, VersionConflictError{ClientID: "c", Expected: 4, Actual: 3}, *conflict)
+	assert.EqualError(t, err, "KYC record 'c' is at version 3, not the expected version 4")
+}
@@ -0,0 +1,16 @@
+package kyc
+
+// RiskScore is a computed risk together with the breakdown of how it was reached:
+// Score is Base plus the weights of all Contributions, clamped to the configured range.
+type RiskScore struct {
+	Score         float64            `xml:"Score" json:"score"`
+	Base          float64            `xml:"Base" json:"base"`
+	Contributions []RiskContribution `xml:"Contribution,omitempty" json:"contributions,omitempty"`
+}
+
+// RiskContribution is a scoring rule that matched a record and the weight it added
+type RiskContribution struct {
+	Rule   string  `xml:"Rule" json:"rule"`
+	Weight float64 `xml:"Weight" json:"weight"`
+	Detail string  `xml:"Detail,omitempty" json:"detail,omitempty"` // What matched, e.g. "nationality IR"
+}
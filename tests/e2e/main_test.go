@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/shared/kyc"
 	"log"
 	"math/rand"
 		"strings"
@@ -108,6 +109,11 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 	})
 
 	Context("SOAP message processing via Kafka", func() {
+		BeforeEach(func() {
+			// Start every entry from the kyc-service's fixture state, whatever earlier entries changed
+			Expect(testFramework.KycClient.Reset()).To(Succeed(), "Admin API: Failed to reset kyc-service")
+		})
+
 		type TestCase struct {
 			TestName         string // Added for better logging in setup/teardown
 			KafkaMessageType string
@@ -131,11 +137,10 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 				if tc.KafkaMessageType == "READ" || tc.KafkaMessageType == "UPDATE" || tc.KafkaMessageType == "DELETE" || tc.TestName == "READ clientError InternalError" || tc.TestName == "READ clientTimeout Timeout" {
 					_, readErr := testFramework.KycClient.ReadUser(tc.KafkaClientID)
 					if readErr != nil && strings.Contains(readErr.Error(), "status: 404") {
-						defaultUserData := models.UserData{
-							ClientID: tc.KafkaClientID,
-							Risk:     0.1,
-							Status:   "Initial",
-							Message:  "Created by test setup",
+						defaultUserData := kyc.Record{
+							ClientID:     tc.KafkaClientID,
+							Risk:         0.1,
+							ReviewStatus: kyc.ReviewPending,
 						}
 						Expect(testFramework.KycClient.CreateUser(defaultUserData)).NotTo(HaveOccurred(), "Admin API: Failed to create user for test setup")
 						log.Printf("Admin API: Created client '%s' for test setup.", tc.KafkaClientID)
@@ -177,7 +182,7 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 				}
 
 				// Populate UserData only if provided in TestCase (for CREATE/UPDATE)
-				if tc.KafkaUserData.ClientID != "" || tc.KafkaUserData.Risk != 0 || tc.KafkaUserData.Status != "" || tc.KafkaUserData.Message != "" {
+				if tc.KafkaUserData.ClientID != "" || tc.KafkaUserData.Risk != 0 {
 					kafkaMsgToSend.UserData = tc.KafkaUserData
 				}
 
@@ -233,12 +238,15 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 				TestName:         "CREATE newClientXYZ",
 				KafkaMessageType: "CREATE",
 				KafkaClientID:    "newClientXYZ",
-				KafkaUserData: models.UserData{
-					ClientID: "newClientXYZ",
-					Risk:     0.5,
-					Status:   "Pending",
-					Message:  "Initial creation",
-				},
+				KafkaUserData: models.UserData{Record: kyc.Record{
+					ClientID:     "newClientXYZ",
+					Risk:         0.5,
+					LegalName:    "Xavier Young",
+					DateOfBirth:  "1990-02-14",
+					Nationality:  "NL",
+					Documents:    []kyc.IdentityDocument{{Type: kyc.DocumentPassport, Number: "NX1234567", IssuingCountry: "NL", ExpiryDate: "2032-02-14"}},
+					ReviewStatus: kyc.ReviewPending,
+				}},
 				ExpectedClientID: "newClientXYZ",
 				ExpectedStatus:   "Success",
 				ExpectedMessage:  "User created",
@@ -249,12 +257,11 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 				TestName:         "UPDATE clientA123",
 				KafkaMessageType: "UPDATE",
 				KafkaClientID:    "clientA123",
-				KafkaUserData: models.UserData{
-					ClientID: "clientA123",
-					Risk:     0.75, // Updated risk
-					Status:   "Approved",
-					Message:  "Risk updated",
-				},
+				KafkaUserData: models.UserData{Record: kyc.Record{
+					ClientID:     "clientA123",
+					Risk:         0.75, // Updated risk
+					ReviewStatus: kyc.ReviewApproved,
+				}},
 				ExpectedClientID: "clientA123",
 				ExpectedStatus:   "Success",
 				ExpectedMessage:  "User updated",
@@ -271,6 +278,16 @@ var _ = Describe("Kafka SOAP E2E Test Suite", Ordered, func() {
 				ExpectedRisk:     0.0, // Default for delete response
 			}),
 
+			Entry("GetKYCStatus for clientA123 (STATUS)", TestCase{
+				TestName:         "STATUS clientA123",
+				KafkaMessageType: "STATUS",
+				KafkaClientID:    "clientA123",
+				ExpectedClientID: "clientA123",
+				ExpectedStatus:   "Success",
+				ExpectedMessage:  "User status retrieved",
+				ExpectedRisk:     0.0, // GetKYCStatus carries only the review fields
+			}),
+
 			Entry("KYCQuery for clientError (READ) with simulated InternalError", TestCase{
 				TestName:         "READ clientError InternalError",
 				KafkaMessageType: "READ",
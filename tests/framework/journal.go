@@ -0,0 +1,218 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// RecordedRequest is a SOAP call as the kyc-service's request journal recorded it
+type RecordedRequest struct {
+	Seq       int64       `json:"seq"`
+	Time      time.Time   `json:"time"`
+	Operation string      `json:"operation"`
+	ClientID  string      `json:"clientId"`
+	Headers   http.Header `json:"headers"`
+	Body      string      `json:"body"`
+	Status    int         `json:"status"` // 0 when the connection was dropped without a response
+	LatencyMs float64     `json:"latencyMs"`
+}
+
+// RequestFilter selects recorded requests on the kyc-service. Zero fields apply no filter.
+type RequestFilter struct {
+	Operation string
+	ClientID  string
+	Status    int
+	Since     time.Time
+	After     int64  // Only requests with a higher sequence number
+	Contains  string // Substring of the raw request body
+	Limit     int    // Only the most recent ones
+}
+
+func (f RequestFilter) values() url.Values {
+	values := url.Values{}
+	if f.Operation != "" {
+		values.Set("operation", f.Operation)
+	}
+	if f.ClientID != "" {
+		values.Set("clientId", f.ClientID)
+	}
+	if f.Status != 0 {
+		values.Set("status", strconv.Itoa(f.Status))
+	}
+	if !f.Since.IsZero() {
+		values.Set("since", f.Since.Format(time.RFC3339Nano))
+	}
+	if f.After != 0 {
+		values.Set("after", strconv.FormatInt(f.After, 10))
+	}
+	if f.Contains != "" {
+		values.Set("contains", f.Contains)
+	}
+	if f.Limit != 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return values
+}
+
+// String describes the filter for verification failures
+func (f RequestFilter) String() string {
+	operation := f.Operation
+	if operation == "" {
+		operation = "SOAP"
+	}
+	description := operation + " request(s)"
+	if f.ClientID != "" {
+		description += fmt.Sprintf(" for ClientID '%s'", f.ClientID)
+	}
+	if f.Status != 0 {
+		description += fmt.Sprintf(" answered with %d", f.Status)
+	}
+	return description
+}
+
+// Requests returns the SOAP calls the kyc-service recorded that pass filter, oldest first, via Admin API
+func (a *AdminAPIClient) Requests(filter RequestFilter) ([]RecordedRequest, error) {
+	resp, err := a.client.Get(fmt.Sprintf("%s/requests?%s", a.baseURL, filter.values().Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call admin requests API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin requests API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var requests []RecordedRequest
+	if err := json.NewDecoder(resp.Body).Decode(&requests); err != nil {
+		return nil, fmt.Errorf("failed to decode admin requests response: %w", err)
+	}
+	return requests, nil
+}
+
+// ClearRequests empties the kyc-service's request journal via Admin API
+func (a *AdminAPIClient) ClearRequests() error {
+	return a.do(http.MethodDelete, "/requests", nil, "clear requests", http.StatusOK)
+}
+
+// CountMatcher is the number of requests VerifyRequests expects
+type CountMatcher struct {
+	description string
+	matches     func(n int) bool
+}
+
+// Exactly expects n requests
+func Exactly(n int) CountMatcher {
+	return CountMatcher{description: fmt.Sprintf("exactly %d", n), matches: func(got int) bool { return got == n }}
+}
+
+// AtLeast expects n or more requests
+func AtLeast(n int) CountMatcher {
+	return CountMatcher{description: fmt.Sprintf("at least %d", n), matches: func(got int) bool { return got >= n }}
+}
+
+// AtMost expects n or fewer requests
+func AtMost(n int) CountMatcher {
+	return CountMatcher{description: fmt.Sprintf("at most %d", n), matches: func(got int) bool { return got <= n }}
+}
+
+// Never expects no requests
+func Never() CountMatcher {
+	return CountMatcher{description: "no", matches: func(got int) bool { return got == 0 }}
+}
+
+// RequestMatcher checks the content of a recorded request
+type RequestMatcher struct {
+	description string
+	matches     func(RecordedRequest) bool
+}
+
+// WithElement matches requests whose body has an element called name, in any namespace, with
+// the text value. WithElement("Risk", "0.8") matches an UpdateKYC that sets Risk to 0.8.
+func WithElement(name, value string) RequestMatcher {
+	m := WithXPath(fmt.Sprintf("//*[local-name()='%s']", name), value)
+	m.description = fmt.Sprintf("with %s '%s'", name, value)
+	return m
+}
+
+// WithXPath matches requests whose body has an element at xpath with the text value
+func WithXPath(xpath, value string) RequestMatcher {
+	return RequestMatcher{
+		description: fmt.Sprintf("with '%s' at %s", value, xpath),
+		matches: func(r RecordedRequest) bool {
+			doc, err := xmlquery.Parse(strings.NewReader(r.Body))
+			if err != nil {
+				return false
+			}
+			for _, node := range xmlquery.Find(doc, xpath) {
+				if strings.TrimSpace(node.InnerText()) == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// WithHeader matches requests that carried the header with value
+func WithHeader(name, value string) RequestMatcher {
+	return RequestMatcher{
+		description: fmt.Sprintf("with header %s '%s'", name, value),
+		matches:     func(r RecordedRequest) bool { return r.Headers.Get(name) == value },
+	}
+}
+
+// WithBodyContaining matches requests whose raw body contains s
+func WithBodyContaining(s string) RequestMatcher {
+	return RequestMatcher{
+		description: fmt.Sprintf("with a body containing '%s'", s),
+		matches:     func(r RecordedRequest) bool { return strings.Contains(r.Body, s) },
+	}
+}
+
+// VerifyRequests checks that the kyc-service received count requests that pass filter and every
+// matcher, for example exactly one UpdateKYC for clientA123 with Risk 0.8:
+//
+//	client.VerifyRequests(RequestFilter{Operation: "UpdateKYC", ClientID: "clientA123"}, Exactly(1), WithElement("Risk", "0.8"))
+func (a *AdminAPIClient) VerifyRequests(filter RequestFilter, count CountMatcher, matchers ...RequestMatcher) error {
+	requests, err := a.Requests(filter)
+	if err != nil {
+		return err
+	}
+	matched := 0
+	for _, request := range requests {
+		if matchesAll(request, matchers) {
+			matched++
+		}
+	}
+	if count.matches(matched) {
+		return nil
+	}
+
+	description := filter.String()
+	for _, m := range matchers {
+		description += " " + m.description
+	}
+	var bodies strings.Builder
+	for _, request := range requests {
+		fmt.Fprintf(&bodies, "\n#%d %s %s (status %d):\n%s", request.Seq, request.Operation, request.ClientID, request.Status, request.Body)
+	}
+	return fmt.Errorf("expected %s %s, found %d; %d request(s) passed the filter:%s", count.description, description, matched, len(requests), bodies.String())
+}
+
+func matchesAll(request RecordedRequest, matchers []RequestMatcher) bool {
+	for _, m := range matchers {
+		if !m.matches(request) {
+			return false
+		}
+	}
+	return true
+}
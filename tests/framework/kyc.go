@@ -5,32 +5,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
-	"kafka-soap-e2e-test/services/consumer/models"
+	"kafka-soap-e2e-test/services/providers/kyc/cdc"
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+	"kafka-soap-e2e-test/services/shared/kyc"
 )
 
 // AdminAPIClient struct for interacting with the kyc-service admin API
 type AdminAPIClient struct {
 	client  *http.Client
+	rootURL string // The kyc-service root, where tenants are managed
 	baseURL string
 }
 
 // NewAdminAPIClient initializes a new AdminAPIClient
 func NewAdminAPIClient(kycServiceURL string) *AdminAPIClient {
-	adminURL := strings.TrimSuffix(kycServiceURL, "/soap") + "/admin/v1"
+	rootURL := strings.TrimSuffix(kycServiceURL, "/soap")
+	adminURL := rootURL + "/admin/v1"
 	log.Printf("Initializing Admin API client with base URL: %s", adminURL)
 	return &AdminAPIClient{
 		client:  &http.Client{Timeout: 5 * time.Second}, // Admin operations should be quick
+		rootURL: rootURL,
 		baseURL: adminURL,
 	}
 }
 
 // CreateUser via Admin API
-func (a *AdminAPIClient) CreateUser(userData models.UserData) error {
+func (a *AdminAPIClient) CreateUser(userData kyc.Record) error {
 	url := fmt.Sprintf("%s/users", a.baseURL)
 	body, err := json.Marshal(userData)
 	if err != nil {
@@ -55,32 +63,32 @@ func (a *AdminAPIClient) CreateUser(userData models.UserData) error {
 }
 
 // ReadUser via Admin API
-func (a *AdminAPIClient) ReadUser(clientID string) (models.UserData, error) {
+func (a *AdminAPIClient) ReadUser(clientID string) (kyc.Record, error) {
 	url := fmt.Sprintf("%s/users/%s", a.baseURL, clientID)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return models.UserData{}, fmt.Errorf("failed to create admin read user request: %w", err)
+		return kyc.Record{}, fmt.Errorf("failed to create admin read user request: %w", err)
 	}
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return models.UserData{}, fmt.Errorf("failed to call admin read user API: %w", err)
+		return kyc.Record{}, fmt.Errorf("failed to call admin read user API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return models.UserData{}, fmt.Errorf("admin read user API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+		return kyc.Record{}, fmt.Errorf("admin read user API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
 	}
 
-	var userData models.UserData
+	var userData kyc.Record
 	if err := json.NewDecoder(resp.Body).Decode(&userData); err != nil {
-		return models.UserData{}, fmt.Errorf("failed to decode user data from admin read response: %w", err)
+		return kyc.Record{}, fmt.Errorf("failed to decode user data from admin read response: %w", err)
 	}
 	return userData, nil
 }
 
 // UpdateUser via Admin API
-func (a *AdminAPIClient) UpdateUser(userData models.UserData) error {
+func (a *AdminAPIClient) UpdateUser(userData kyc.Record) error {
 	url := fmt.Sprintf("%s/users/%s", a.baseURL, userData.ClientID)
 	body, err := json.Marshal(userData)
 	if err != nil {
@@ -168,3 +176,347 @@ func (a *AdminAPIClient) ClearAction(clientID string) error {
 	}
 	return nil
 }
+
+// SetValidationMode switches the kyc-service's inbound XSD validation to "strict", "lenient" or "off" via Admin API
+func (a *AdminAPIClient) SetValidationMode(mode string) error {
+	return a.updateSettings(map[string]string{"validationMode": mode})
+}
+
+// SetErrorStyle switches how the kyc-service reports failed operations, "legacy" or "fault", via Admin API
+func (a *AdminAPIClient) SetErrorStyle(style string) error {
+	return a.updateSettings(map[string]string{"errorStyle": style})
+}
+
+// updateSettings changes the given kyc-service settings, leaving the others untouched
+func (a *AdminAPIClient) updateSettings(changes map[string]string) error {
+	url := fmt.Sprintf("%s/settings", a.baseURL)
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create admin update settings request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call admin update settings API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("admin update settings API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// CreateSnapshot saves the kyc-service's current users and actions under name via Admin API
+func (a *AdminAPIClient) CreateSnapshot(name string) error {
+	return a.do(http.MethodPost, "/snapshots", map[string]string{"name": name}, "create snapshot", http.StatusCreated)
+}
+
+// RestoreSnapshot replaces the kyc-service's users and actions with the snapshot saved under name via Admin API
+func (a *AdminAPIClient) RestoreSnapshot(name string) error {
+	return a.do(http.MethodPost, fmt.Sprintf("/snapshots/%s/restore", url.PathEscape(name)), nil, "restore snapshot", http.StatusOK)
+}
+
+// DeleteSnapshot removes the snapshot saved under name via Admin API
+func (a *AdminAPIClient) DeleteSnapshot(name string) error {
+	return a.do(http.MethodDelete, fmt.Sprintf("/snapshots/%s", url.PathEscape(name)), nil, "delete snapshot", http.StatusOK)
+}
+
+// Reset returns the kyc-service to the users and actions it had after loading its fixtures, and
+// removes every fault rule, scenario and recorded request, via Admin API
+func (a *AdminAPIClient) Reset() error {
+	return a.do(http.MethodPost, "/reset", nil, "reset", http.StatusOK)
+}
+
+// ForceTransition moves clientID to review status to via Admin API, bypassing the lifecycle rules
+func (a *AdminAPIClient) ForceTransition(clientID, to, reason string) error {
+	payload := map[string]string{"to": to, "reason": reason}
+	return a.do(http.MethodPost, fmt.Sprintf("/users/%s/transition", url.PathEscape(clientID)), payload, "transition", http.StatusOK)
+}
+
+// ReloadScoring makes the kyc-service re-read its risk scoring rules file via Admin API
+func (a *AdminAPIClient) ReloadScoring() error {
+	return a.do(http.MethodPost, "/scoring/reload", nil, "reload scoring", http.StatusOK)
+}
+
+// ReloadFixtures makes the kyc-service apply changes to its fixture directory without waiting for
+// the file watcher via Admin API. With overwrite every fixture is applied again, replacing records
+// created or changed at runtime.
+func (a *AdminAPIClient) ReloadFixtures(overwrite bool) error {
+	return a.do(http.MethodPost, fmt.Sprintf("/fixtures/reload?overwrite=%t", overwrite), nil, "reload fixtures", http.StatusOK)
+}
+
+// FlushChangeEvents makes the kyc-service publish the change events still in its outbox now,
+// instead of when its relay next retries, via Admin API
+func (a *AdminAPIClient) FlushChangeEvents() error {
+	return a.do(http.MethodPost, "/cdc/flush", nil, "flush change events", http.StatusOK)
+}
+
+// PendingChangeEvents returns the change events the kyc-service has not yet published, oldest
+// first, via Admin API
+func (a *AdminAPIClient) PendingChangeEvents() ([]cdc.Event, error) {
+	resp, err := a.client.Get(a.baseURL + "/cdc?outbox=true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call admin change events API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin change events API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var status struct {
+		Outbox []cdc.Event `json:"outbox"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode admin change events response: %w", err)
+	}
+	return status.Outbox, nil
+}
+
+// AddFaultRule makes the kyc-service inject a fault into the requests the rule matches via Admin
+// API and returns the rule as activated, with its ID
+func (a *AdminAPIClient) AddFaultRule(rule faults.Rule) (faults.Rule, error) {
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return faults.Rule{}, fmt.Errorf("failed to marshal fault rule: %w", err)
+	}
+	resp, err := a.client.Post(a.baseURL+"/actions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return faults.Rule{}, fmt.Errorf("failed to call admin add fault rule API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return faults.Rule{}, fmt.Errorf("admin add fault rule API returned non-201 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var added faults.Rule
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		return faults.Rule{}, fmt.Errorf("failed to decode admin add fault rule response: %w", err)
+	}
+	return added, nil
+}
+
+// FaultRules returns the kyc-service's active fault rules with their hit counts via Admin API
+func (a *AdminAPIClient) FaultRules() ([]faults.Rule, error) {
+	resp, err := a.client.Get(a.baseURL + "/actions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call admin list fault rules API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin list fault rules API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var rules []faults.Rule
+	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode admin list fault rules response: %w", err)
+	}
+	return rules, nil
+}
+
+// RemoveFaultRule deactivates the fault rule with id via Admin API
+func (a *AdminAPIClient) RemoveFaultRule(id string) error {
+	return a.do(http.MethodDelete, fmt.Sprintf("/actions/rules/%s", url.PathEscape(id)), nil, "remove fault rule", http.StatusOK)
+}
+
+// ClearFaultRules deactivates every fault rule via Admin API, leaving per-ClientID actions alone
+func (a *AdminAPIClient) ClearFaultRules() error {
+	return a.do(http.MethodDelete, "/actions", nil, "clear fault rules", http.StatusOK)
+}
+
+// History returns every recorded change of clientID, oldest first, via Admin API
+func (a *AdminAPIClient) History(clientID string) ([]kyc.AuditEvent, error) {
+	resp, err := a.client.Get(fmt.Sprintf("%s/users/%s/history", a.baseURL, url.PathEscape(clientID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call admin history API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin history API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var history []kyc.AuditEvent
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("failed to decode admin history response: %w", err)
+	}
+	return history, nil
+}
+
+// UserQuery selects and orders the users returned by ListUsers. Zero fields apply no filter.
+type UserQuery struct {
+	Limit    int // Users per page; the kyc-service defaults to 100 and allows up to 1000
+	MinRisk  *float64
+	MaxRisk  *float64
+	Statuses []kyc.ReviewStatus
+	Prefix   string // ClientID prefix
+	Sort     string // clientId (default), risk, createdAt or updatedAt; prefix with '-' for descending order
+}
+
+// values encodes q as the query parameters of GET /admin/v1/users
+func (q UserQuery) values() url.Values {
+	values := url.Values{}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.MinRisk != nil {
+		values.Set("minRisk", strconv.FormatFloat(*q.MinRisk, 'f', -1, 64))
+	}
+	if q.MaxRisk != nil {
+		values.Set("maxRisk", strconv.FormatFloat(*q.MaxRisk, 'f', -1, 64))
+	}
+	for _, status := range q.Statuses {
+		values.Add("status", string(status))
+	}
+	if q.Prefix != "" {
+		values.Set("prefix", q.Prefix)
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	return values
+}
+
+// UserPage is one page of ListUsers
+type UserPage struct {
+	Users      []kyc.Record `json:"users"`
+	Total      int          `json:"total"` // Users matching the query across all pages
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// ListUsers returns an iterator over the pages of users matching query via Admin API. Each page is
+// fetched when the loop reaches it; iteration stops after the last page or the first error.
+func (a *AdminAPIClient) ListUsers(query UserQuery) iter.Seq2[UserPage, error] {
+	return func(yield func(UserPage, error) bool) {
+		values := query.values()
+		for {
+			page, err := a.listUsersPage(values)
+			if !yield(page, err) || err != nil || page.NextCursor == "" {
+				return
+			}
+			values.Set("cursor", page.NextCursor)
+		}
+	}
+}
+
+// listUsersPage fetches the single page of users selected by values
+func (a *AdminAPIClient) listUsersPage(values url.Values) (UserPage, error) {
+	resp, err := a.client.Get(fmt.Sprintf("%s/users?%s", a.baseURL, values.Encode()))
+	if err != nil {
+		return UserPage{}, fmt.Errorf("failed to call admin list users API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return UserPage{}, fmt.Errorf("admin list users API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var page UserPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return UserPage{}, fmt.Errorf("failed to decode admin list users response: %w", err)
+	}
+	return page, nil
+}
+
+// ImportReport is the kyc-service's account of an ImportUsers call
+type ImportReport struct {
+	Mode    string `json:"mode"`
+	Rows    int    `json:"rows"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	Deleted int    `json:"deleted"`
+	Failed  int    `json:"failed"`
+	Errors  []struct {
+		Row      int    `json:"row"`
+		ClientID string `json:"clientId,omitempty"`
+		Error    string `json:"error"`
+	} `json:"errors,omitempty"`
+}
+
+// ImportUsers loads the records in data, encoded as format ("ndjson", "json" or "csv"), into the
+// kyc-service via Admin API. mode is "upsert", "create-only" or "replace-all". Rejected rows are
+// listed in the report rather than returned as an error, except for an aborted replace-all.
+func (a *AdminAPIClient) ImportUsers(format, mode string, data io.Reader) (ImportReport, error) {
+	query := url.Values{"format": {format}, "mode": {mode}}
+	resp, err := a.client.Post(fmt.Sprintf("%s/import?%s", a.baseURL, query.Encode()), "", data)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to call admin import API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+		respBody, _ := io.ReadAll(resp.Body)
+		return ImportReport{}, fmt.Errorf("admin import API returned non-200/422 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var report ImportReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return ImportReport{}, fmt.Errorf("failed to decode admin import response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		return report, fmt.Errorf("admin import API applied nothing: %d of %d rows are invalid", report.Failed, report.Rows)
+	}
+	return report, nil
+}
+
+// ExportUsers writes every record of the kyc-service, encoded as format ("ndjson", "json" or
+// "csv"), to w via Admin API
+func (a *AdminAPIClient) ExportUsers(format string, w io.Writer) error {
+	resp, err := a.client.Get(fmt.Sprintf("%s/export?format=%s", a.baseURL, url.QueryEscape(format)))
+	if err != nil {
+		return fmt.Errorf("failed to call admin export API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("admin export API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read admin export response: %w", err)
+	}
+	return nil
+}
+
+// do sends an admin request with an optional JSON body and expects wantStatus in return
+func (a *AdminAPIClient) do(method, path string, payload any, operation string, wantStatus int) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal admin %s request: %w", operation, err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequest(method, a.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create admin %s request: %w", operation, err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call admin %s API: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("admin %s API returned non-%d status: %d, body: %s", operation, wantStatus, resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"kafka-soap-e2e-test/services/providers/kyc/faults"
+)
+
+// ScenarioBuilder assembles a kyc-service scenario, the responses it gives the calls it matches
+// one call at a time, for AdminAPIClient.UploadScenario:
+//
+//	NewScenario("retry").ForClient("client1").ForOperation("KYCQuery").
+//		Fail(faults.TypeServiceUnavailable, 2).Succeed(1)
+type ScenarioBuilder struct {
+	scenario faults.Scenario
+}
+
+// NewScenario starts a scenario called name that matches every call until narrowed down
+func NewScenario(name string) *ScenarioBuilder {
+	return &ScenarioBuilder{scenario: faults.Scenario{Name: name}}
+}
+
+// ForClient limits the scenario to calls about clientID, which may be a wildcard pattern
+func (b *ScenarioBuilder) ForClient(clientID string) *ScenarioBuilder {
+	b.scenario.ClientID = clientID
+	return b
+}
+
+// ForOperation limits the scenario to calls of the SOAP operation, which may be a wildcard pattern
+func (b *ScenarioBuilder) ForOperation(operation string) *ScenarioBuilder {
+	b.scenario.Operation = operation
+	return b
+}
+
+// Fail answers the next times calls with fault
+func (b *ScenarioBuilder) Fail(fault faults.Type, times int) *ScenarioBuilder {
+	return b.Then(faults.Step{Fault: fault, Times: times})
+}
+
+// Succeed answers the next times calls with the real response
+func (b *ScenarioBuilder) Succeed(times int) *ScenarioBuilder {
+	return b.Then(faults.Step{Times: times})
+}
+
+// SucceedAfter answers the next times calls with the real response, each delay late
+func (b *ScenarioBuilder) SucceedAfter(delay time.Duration, times int) *ScenarioBuilder {
+	latency := &faults.Latency{Distribution: faults.DistributionFixed, Value: faults.Duration(delay)}
+	return b.Then(faults.Step{Latency: latency, Times: times})
+}
+
+// Then appends step as is, for faults that need more than Fail offers such as a Retry-After
+func (b *ScenarioBuilder) Then(step faults.Step) *ScenarioBuilder {
+	b.scenario.Steps = append(b.scenario.Steps, step)
+	return b
+}
+
+// Cycle starts the scenario over after its last step instead of letting the real responses through
+func (b *ScenarioBuilder) Cycle() *ScenarioBuilder {
+	b.scenario.Cycle = true
+	return b
+}
+
+// Build returns the scenario
+func (b *ScenarioBuilder) Build() faults.Scenario {
+	return b.scenario
+}
+
+// UploadScenario activates the built scenario from its first step, replacing an active scenario
+// with the same name, via Admin API
+func (a *AdminAPIClient) UploadScenario(scenario *ScenarioBuilder) (faults.Scenario, error) {
+	body, err := json.Marshal(scenario.Build())
+	if err != nil {
+		return faults.Scenario{}, fmt.Errorf("failed to marshal scenario: %w", err)
+	}
+	resp, err := a.client.Post(a.baseURL+"/scenarios", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return faults.Scenario{}, fmt.Errorf("failed to call admin upload scenario API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return faults.Scenario{}, fmt.Errorf("admin upload scenario API returned non-201 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var uploaded faults.Scenario
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return faults.Scenario{}, fmt.Errorf("failed to decode admin upload scenario response: %w", err)
+	}
+	return uploaded, nil
+}
+
+// Scenario returns the active scenario called name with its current position via Admin API
+func (a *AdminAPIClient) Scenario(name string) (faults.Scenario, error) {
+	resp, err := a.client.Get(fmt.Sprintf("%s/scenarios/%s", a.baseURL, url.PathEscape(name)))
+	if err != nil {
+		return faults.Scenario{}, fmt.Errorf("failed to call admin get scenario API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return faults.Scenario{}, fmt.Errorf("admin get scenario API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var scenario faults.Scenario
+	if err := json.NewDecoder(resp.Body).Decode(&scenario); err != nil {
+		return faults.Scenario{}, fmt.Errorf("failed to decode admin get scenario response: %w", err)
+	}
+	return scenario, nil
+}
+
+// RewindScenario sends the scenario called name back to its first step via Admin API
+func (a *AdminAPIClient) RewindScenario(name string) error {
+	return a.do(http.MethodPost, fmt.Sprintf("/scenarios/%s/rewind", url.PathEscape(name)), nil, "rewind scenario", http.StatusOK)
+}
+
+// RemoveScenario deactivates the scenario called name via Admin API
+func (a *AdminAPIClient) RemoveScenario(name string) error {
+	return a.do(http.MethodDelete, fmt.Sprintf("/scenarios/%s", url.PathEscape(name)), nil, "remove scenario", http.StatusOK)
+}
+
+// ClearScenarios deactivates every scenario via Admin API
+func (a *AdminAPIClient) ClearScenarios() error {
+	return a.do(http.MethodDelete, "/scenarios", nil, "clear scenarios", http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Tenant is one of the kyc-service's isolated namespaces, as the tenant admin API describes it
+type Tenant struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Users     int       `json:"users"`
+	Rules     int       `json:"rules"`
+	Scenarios int       `json:"scenarios"`
+	Requests  int       `json:"requests"`
+}
+
+// ForTenant returns a client whose users, fault rules, scenarios, snapshots and recorded requests
+// are those of the kyc-service tenant called name. Tenants are managed through the unscoped client.
+func (a *AdminAPIClient) ForTenant(name string) *AdminAPIClient {
+	return &AdminAPIClient{
+		client:  a.client,
+		rootURL: a.rootURL,
+		baseURL: fmt.Sprintf("%s/t/%s/admin/v1", a.rootURL, url.PathEscape(name)),
+	}
+}
+
+// TenantSOAPURL is the SOAP endpoint of the kyc-service tenant called name
+func (a *AdminAPIClient) TenantSOAPURL(name string) string {
+	return fmt.Sprintf("%s/t/%s/soap", a.rootURL, url.PathEscape(name))
+}
+
+// CreateTenant creates a kyc-service tenant holding the users loaded from the fixtures, or no users
+// when empty is set, via Admin API
+func (a *AdminAPIClient) CreateTenant(name string, empty bool) (Tenant, error) {
+	body, err := json.Marshal(map[string]any{"name": name, "empty": empty})
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to marshal create tenant request: %w", err)
+	}
+	resp, err := a.client.Post(a.rootURL+"/admin/v1/tenants", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to call admin create tenant API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Tenant{}, fmt.Errorf("admin create tenant API returned non-201 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var created Tenant
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return Tenant{}, fmt.Errorf("failed to decode admin create tenant response: %w", err)
+	}
+	return created, nil
+}
+
+// Tenants returns the kyc-service's tenants, sorted by name, via Admin API
+func (a *AdminAPIClient) Tenants() ([]Tenant, error) {
+	resp, err := a.client.Get(a.rootURL + "/admin/v1/tenants")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call admin list tenants API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin list tenants API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var tenants []Tenant
+	if err := json.NewDecoder(resp.Body).Decode(&tenants); err != nil {
+		return nil, fmt.Errorf("failed to decode admin list tenants response: %w", err)
+	}
+	return tenants, nil
+}
+
+// DeleteTenant removes a kyc-service tenant with all its state via Admin API
+func (a *AdminAPIClient) DeleteTenant(name string) error {
+	unscoped := &AdminAPIClient{client: a.client, rootURL: a.rootURL, baseURL: a.rootURL + "/admin/v1"}
+	return unscoped.do(http.MethodDelete, "/tenants/"+url.PathEscape(name), nil, "delete tenant", http.StatusOK)
+}
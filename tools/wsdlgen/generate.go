@@ -0,0 +1,607 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtinTypes maps XML Schema built-in types to Go types
+var builtinTypes = map[string]string{
+	"string":             "string",
+	"normalizedString":   "string",
+	"token":              "string",
+	"anyURI":             "string",
+	"ID":                 "string",
+	"IDREF":              "string",
+	"NMTOKEN":            "string",
+	"language":           "string",
+	"QName":              "string",
+	"date":               "string",
+	"time":               "string",
+	"duration":           "string",
+	"dateTime":           "time.Time",
+	"boolean":            "bool",
+	"double":             "float64",
+	"decimal":            "float64",
+	"float":              "float32",
+	"long":               "int64",
+	"integer":            "int64",
+	"int":                "int",
+	"short":              "int16",
+	"byte":               "int8",
+	"nonNegativeInteger": "uint64",
+	"positiveInteger":    "uint64",
+	"unsignedLong":       "uint64",
+	"unsignedInt":        "uint32",
+	"unsignedShort":      "uint16",
+	"unsignedByte":       "uint8",
+	"base64Binary":       "[]byte",
+}
+
+// goStruct is a Go struct emitted for a complex type or element
+type goStruct struct {
+	Name    string
+	Origin  string // Schema construct the struct was generated from, for its doc comment
+	Doc     string
+	XMLName *qname // Set for top-level elements
+	Embed   string // Named complex type a top-level element is declared with
+	Fields  []goField
+}
+
+type goField struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+// goEnum is a named Go type emitted for a simple type
+type goEnum struct {
+	Name   string
+	Origin string
+	Base   string
+	Doc    string
+	Values []string
+}
+
+// goOperation is a single client method
+type goOperation struct {
+	Name       string
+	Doc        string
+	SOAPAction string
+	Input      string
+	Output     string
+}
+
+// goService is a port type rendered as a client interface
+type goService struct {
+	Name       string
+	Operations []goOperation
+}
+
+// generator turns a loaded contract into Go source
+type generator struct {
+	c         *contract
+	pkg       string
+	structs   []*goStruct
+	enums     []*goEnum
+	services  []goService
+	names     map[string]bool
+	types     map[qname]string // Named complex and simple types
+	elements  map[qname]string // Top-level elements
+	usesTime  bool
+	namespace string
+}
+
+// generate renders the Go source for c in package pkg
+func generate(c *contract, pkg string) ([]byte, error) {
+	g := &generator{
+		c:        c,
+		pkg:      pkg,
+		names:    make(map[string]bool),
+		types:    make(map[qname]string),
+		elements: make(map[qname]string),
+	}
+	g.namespace = c.defs.TargetNamespace
+
+	// Register every global name first so forward references resolve
+	for _, s := range c.schemas {
+		for _, ct := range s.ComplexTypes {
+			g.types[qname{s.TargetNamespace, ct.Name}] = g.reserve(goName(ct.Name))
+		}
+		for _, st := range s.SimpleTypes {
+			g.types[qname{s.TargetNamespace, st.Name}] = g.reserve(goName(st.Name))
+		}
+	}
+	for _, s := range c.schemas {
+		for _, el := range s.Elements {
+			g.elements[qname{s.TargetNamespace, el.Name}] = g.reserve(goName(el.Name))
+		}
+	}
+
+	for _, s := range c.schemas {
+		for _, st := range s.SimpleTypes {
+			if err := g.simpleType(s, st); err != nil {
+				return nil, err
+			}
+		}
+		for _, ct := range s.ComplexTypes {
+			st := &goStruct{Name: g.types[qname{s.TargetNamespace, ct.Name}], Origin: "the " + ct.Name + " complex type", Doc: ct.Documentation}
+			if err := g.complexType(s, st, ct); err != nil {
+				return nil, err
+			}
+			g.structs = append(g.structs, st)
+		}
+		for _, el := range s.Elements {
+			if err := g.topLevelElement(s, el); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := g.operations(); err != nil {
+		return nil, err
+	}
+	return g.render()
+}
+
+// reserve returns a unique Go identifier based on name
+func (g *generator) reserve(name string) string {
+	candidate := name
+	for i := 2; g.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[candidate] = true
+	return candidate
+}
+
+func (g *generator) simpleType(s loadedSchema, st xsdSimpleType) error {
+	base, err := g.resolveType(s, st.Restriction.Base)
+	if err != nil {
+		return fmt.Errorf("simple type %s: %w", st.Name, err)
+	}
+	enum := &goEnum{Name: g.types[qname{s.TargetNamespace, st.Name}], Origin: "the " + st.Name + " simple type", Base: base, Doc: st.Documentation}
+	for _, e := range st.Restriction.Enumerations {
+		enum.Values = append(enum.Values, e.Value)
+	}
+	g.enums = append(g.enums, enum)
+	return nil
+}
+
+func (g *generator) topLevelElement(s loadedSchema, el xsdElement) error {
+	name := g.elements[qname{s.TargetNamespace, el.Name}]
+	st := &goStruct{Name: name, Origin: "the " + el.Name + " element", Doc: el.Documentation, XMLName: &qname{s.TargetNamespace, el.Name}}
+	switch {
+	case el.ComplexType != nil:
+		if err := g.complexType(s, st, *el.ComplexType); err != nil {
+			return err
+		}
+	case el.Type != "":
+		typ, err := g.resolveType(s, el.Type)
+		if err != nil {
+			return fmt.Errorf("element %s: %w", el.Name, err)
+		}
+		if g.isComplex(s, el.Type) {
+			st.Embed = typ
+		} else {
+			st.Fields = append(st.Fields, goField{Name: "Value", Type: typ, Tag: `xml:",chardata"`})
+		}
+	}
+	g.structs = append(g.structs, st)
+	return nil
+}
+
+// complexType fills st with the fields of ct
+func (g *generator) complexType(s loadedSchema, st *goStruct, ct xsdComplexType) error {
+	if st.Doc == "" {
+		st.Doc = ct.Documentation
+	}
+	attrs := ct.Attributes
+	var groups []*xsdGroup
+	if ct.ComplexContent != nil {
+		ext := ct.ComplexContent.Extension
+		base, err := g.resolveType(s, ext.Base)
+		if err != nil {
+			return fmt.Errorf("complex type %s: %w", st.Name, err)
+		}
+		st.Embed = base
+		groups = append(groups, ext.Sequence)
+		attrs = append(attrs, ext.Attributes...)
+	}
+	groups = append(groups, ct.Sequence, ct.All)
+
+	for _, grp := range groups {
+		if err := g.group(s, st, grp, false); err != nil {
+			return err
+		}
+	}
+	if ct.Choice != nil {
+		if err := g.group(s, st, ct.Choice, true); err != nil {
+			return err
+		}
+	}
+	for _, a := range attrs {
+		typ := "string"
+		if a.Type != "" {
+			var err error
+			if typ, err = g.resolveType(s, a.Type); err != nil {
+				return fmt.Errorf("attribute %s.%s: %w", st.Name, a.Name, err)
+			}
+		}
+		tag := a.Name + ",attr"
+		if a.Use != "required" {
+			tag += ",omitempty"
+		}
+		st.Fields = append(st.Fields, goField{Name: goName(a.Name), Type: typ, Tag: fmt.Sprintf(`xml:"%s"`, tag)})
+	}
+	return nil
+}
+
+// group adds the elements of a sequence, all or choice group to st
+func (g *generator) group(s loadedSchema, st *goStruct, grp *xsdGroup, optional bool) error {
+	if grp == nil {
+		return nil
+	}
+	for _, el := range grp.Elements {
+		f, err := g.field(s, st.Name, el, optional)
+		if err != nil {
+			return err
+		}
+		st.Fields = append(st.Fields, f)
+	}
+	for i := range grp.Choices {
+		if err := g.group(s, st, &grp.Choices[i], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field renders a local element as a struct field
+func (g *generator) field(s loadedSchema, parent string, el xsdElement, optional bool) (goField, error) {
+	name, space := el.Name, ""
+	if s.ElementFormDefault == "qualified" {
+		space = s.TargetNamespace
+	}
+
+	var typ string
+	complex := false
+	switch {
+	case el.Ref != "":
+		ref := resolve(el.Ref, s.ns, s.TargetNamespace)
+		goType, ok := g.elements[ref]
+		if !ok {
+			return goField{}, fmt.Errorf("element %s.%s references unknown element %s", parent, el.Ref, el.Ref)
+		}
+		name, space, typ, complex = ref.Local, ref.Space, goType, true
+	case el.ComplexType != nil:
+		nested := &goStruct{Name: g.reserve(parent + goName(el.Name)), Origin: "the anonymous type of " + parent + "." + el.Name}
+		if err := g.complexType(s, nested, *el.ComplexType); err != nil {
+			return goField{}, err
+		}
+		g.structs = append(g.structs, nested)
+		typ, complex = nested.Name, true
+	case el.SimpleType != nil:
+		base, err := g.resolveType(s, el.SimpleType.Restriction.Base)
+		if err != nil {
+			return goField{}, fmt.Errorf("element %s.%s: %w", parent, el.Name, err)
+		}
+		typ = base
+	case el.Type != "":
+		var err error
+		if typ, err = g.resolveType(s, el.Type); err != nil {
+			return goField{}, fmt.Errorf("element %s.%s: %w", parent, el.Name, err)
+		}
+		complex = g.isComplex(s, el.Type)
+	default:
+		typ = "string"
+	}
+
+	tag := name
+	if space != "" {
+		tag = space + " " + name
+	}
+	repeated := el.MaxOccurs == "unbounded"
+	if n, err := strconv.Atoi(el.MaxOccurs); err == nil && n > 1 {
+		repeated = true
+	}
+	switch {
+	case repeated:
+		typ = "[]" + typ
+		tag += ",omitempty"
+	case optional || el.MinOccurs == "0":
+		tag += ",omitempty"
+		if complex || typ == "time.Time" {
+			typ = "*" + typ
+		}
+	}
+	return goField{Name: goName(el.Name), Type: typ, Tag: fmt.Sprintf(`xml:"%s"`, tag)}, nil
+}
+
+// resolveType maps a type reference to a Go type
+func (g *generator) resolveType(s loadedSchema, ref string) (string, error) {
+	q := resolve(ref, s.ns, s.TargetNamespace)
+	if q.Space == xsdNamespace {
+		typ, ok := builtinTypes[q.Local]
+		if !ok {
+			return "", fmt.Errorf("unsupported XML Schema type %s", ref)
+		}
+		if typ == "time.Time" {
+			g.usesTime = true
+		}
+		return typ, nil
+	}
+	if typ, ok := g.types[q]; ok {
+		return typ, nil
+	}
+	return "", fmt.Errorf("unknown type %s", ref)
+}
+
+// isComplex reports whether ref names a complex type
+func (g *generator) isComplex(s loadedSchema, ref string) bool {
+	q := resolve(ref, s.ns, s.TargetNamespace)
+	for _, schema := range g.c.schemas {
+		if schema.TargetNamespace != q.Space {
+			continue
+		}
+		for _, ct := range schema.ComplexTypes {
+			if ct.Name == q.Local {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// operations builds one client interface per port type from the bindings
+func (g *generator) operations() error {
+	defs := g.c.defs
+	messages := make(map[string]string)
+	for _, m := range defs.Messages {
+		if len(m.Parts) != 1 {
+			return fmt.Errorf("message %s: only single-part document/literal messages are supported", m.Name)
+		}
+		el := resolve(m.Parts[0].Element, g.c.ns, defs.TargetNamespace)
+		typ, ok := g.elements[el]
+		if !ok {
+			return fmt.Errorf("message %s references unknown element %s", m.Name, m.Parts[0].Element)
+		}
+		messages[m.Name] = typ
+	}
+
+	actions := make(map[string]map[string]string)
+	for _, b := range defs.Bindings {
+		portType := resolve(b.Type, g.c.ns, defs.TargetNamespace).Local
+		if actions[portType] == nil {
+			actions[portType] = make(map[string]string)
+		}
+		for _, op := range b.Operations {
+			actions[portType][op.Name] = op.SOAPOperation.SOAPAction
+		}
+	}
+
+	for _, pt := range defs.PortTypes {
+		svc := goService{Name: goName(pt.Name)}
+		for _, op := range pt.Operations {
+			in := messages[resolve(op.Input.Message, g.c.ns, defs.TargetNamespace).Local]
+			out := messages[resolve(op.Output.Message, g.c.ns, defs.TargetNamespace).Local]
+			if in == "" || out == "" {
+				return fmt.Errorf("operation %s.%s has an unknown input or output message", pt.Name, op.Name)
+			}
+			svc.Operations = append(svc.Operations, goOperation{
+				Name:       goName(op.Name),
+				Doc:        strings.TrimSpace(op.Documentation),
+				SOAPAction: actions[pt.Name][op.Name],
+				Input:      in,
+				Output:     out,
+			})
+		}
+		g.services = append(g.services, svc)
+	}
+	return nil
+}
+
+// render writes the collected declarations as gofmt'ed Go source
+func (g *generator) render() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by wsdlgen from %s. DO NOT EDIT.\n\n", g.c.source)
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	b.WriteString("import (\n\t\"bytes\"\n\t\"encoding/xml\"\n\t\"fmt\"\n")
+	if g.usesTime {
+		b.WriteString("\t\"time\"\n")
+	}
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(&b, "// Namespace is the target namespace of the %s contract\n", g.c.defs.Name)
+	fmt.Fprintf(&b, "const Namespace = %q\n\n", g.namespace)
+
+	for _, e := range g.enums {
+		writeDoc(&b, e.Name, e.Origin, e.Doc)
+		fmt.Fprintf(&b, "type %s %s\n\n", e.Name, e.Base)
+		if len(e.Values) > 0 && e.Base == "string" {
+			b.WriteString("const (\n")
+			for _, v := range e.Values {
+				fmt.Fprintf(&b, "\t%s%s %s = %q\n", e.Name, enumValueName(v), e.Name, v)
+			}
+			b.WriteString(")\n\n")
+		}
+	}
+
+	for _, st := range g.structs {
+		writeDoc(&b, st.Name, st.Origin, st.Doc)
+		fmt.Fprintf(&b, "type %s struct {\n", st.Name)
+		if st.XMLName != nil {
+			fmt.Fprintf(&b, "\tXMLName xml.Name `xml:\"%s %s\"`\n", st.XMLName.Space, st.XMLName.Local)
+		}
+		if st.Embed != "" {
+			fmt.Fprintf(&b, "\t%s\n", st.Embed)
+		}
+		for _, f := range st.Fields {
+			fmt.Fprintf(&b, "\t%s %s `%s`\n", f.Name, f.Type, f.Tag)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString(clientPreamble)
+	for _, svc := range g.services {
+		impl := unexport(svc.Name) + "Client"
+		fmt.Fprintf(&b, "// %s is the client interface for the %s port type\n", svc.Name, svc.Name)
+		fmt.Fprintf(&b, "type %s interface {\n", svc.Name)
+		for _, op := range svc.Operations {
+			if op.Doc != "" {
+				fmt.Fprintf(&b, "\t// %s\n", op.Doc)
+			}
+			fmt.Fprintf(&b, "\t%s(req *%s) (*%s, error)\n", op.Name, op.Input, op.Output)
+		}
+		b.WriteString("}\n\n")
+
+		fmt.Fprintf(&b, "// New%sClient returns a %s that sends requests over t\n", svc.Name, svc.Name)
+		fmt.Fprintf(&b, "func New%sClient(t Transport) %s {\n\treturn &%s{transport: t}\n}\n\n", svc.Name, svc.Name, impl)
+		fmt.Fprintf(&b, "type %s struct {\n\ttransport Transport\n}\n\n", impl)
+
+		for _, op := range svc.Operations {
+			fmt.Fprintf(&b, "// %s calls the %s operation (SOAPAction %s)\n", op.Name, op.Name, op.SOAPAction)
+			fmt.Fprintf(&b, "func (c *%s) %s(req *%s) (*%s, error) {\n", impl, op.Name, op.Input, op.Output)
+			fmt.Fprintf(&b, "\tresp := new(%s)\n", op.Output)
+			fmt.Fprintf(&b, "\tif err := call(c.transport, %q, req, resp); err != nil {\n\t\treturn nil, err\n\t}\n\treturn resp, nil\n}\n\n", op.SOAPAction)
+		}
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w\n%s", err, b.String())
+	}
+	return src, nil
+}
+
+// writeDoc writes a doc comment naming the schema origin, followed by the schema documentation
+func writeDoc(b *bytes.Buffer, name, origin, doc string) {
+	fmt.Fprintf(b, "// %s is generated from %s.\n", name, origin)
+	if doc = strings.Join(strings.Fields(doc), " "); doc != "" {
+		fmt.Fprintf(b, "// %s\n", doc)
+	}
+}
+
+// goName turns an XML name into an exported Go identifier
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+// enumValueName is goName for enumeration values. Upper-case values such as NOT_FOUND become NotFound.
+func enumValueName(value string) string {
+	if strings.ToUpper(value) == value {
+		value = strings.ToLower(value)
+	}
+	return goName(value)
+}
+
+// unexport lowercases the leading acronym or letter of an identifier
+func unexport(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// clientPreamble is emitted once per generated file
+const clientPreamble = `// Transport sends a SOAP envelope for soapAction and returns the raw response envelope.
+type Transport interface {
+	Call(soapAction string, envelope []byte) ([]byte, error)
+}
+
+// Fault is a SOAP 1.1 fault returned in place of an operation's response
+type Fault struct {
+	XMLName xml.Name ` + "`xml:\"http://schemas.xmlsoap.org/soap/envelope/ Fault\"`" + `
+	Code    string   ` + "`xml:\"faultcode\"`" + `
+	String  string   ` + "`xml:\"faultstring\"`" + `
+	Actor   string   ` + "`xml:\"faultactor,omitempty\"`" + `
+	Detail  struct {
+		Content []byte ` + "`xml:\",innerxml\"`" + `
+	} ` + "`xml:\"detail\"`" + `
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("SOAP fault %s: %s", f.Code, f.String)
+}
+
+type requestEnvelope struct {
+	XMLName      xml.Name ` + "`xml:\"soapenv:Envelope\"`" + `
+	XmlnsSoapenv string   ` + "`xml:\"xmlns:soapenv,attr\"`" + `
+	Body         struct {
+		XMLName xml.Name ` + "`xml:\"soapenv:Body\"`" + `
+		Content any
+	}
+}
+
+const soapEnvNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+
+// call wraps req in a SOAP envelope, sends it and decodes the body into resp
+func call(t Transport, soapAction string, req, resp any) error {
+	env := requestEnvelope{XmlnsSoapenv: soapEnvNamespace}
+	env.Body.Content = req
+	body, err := xml.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SOAP request: %w", err)
+	}
+
+	raw, err := t.Call(soapAction, append([]byte(xml.Header), body...))
+	if err != nil {
+		return err
+	}
+
+	// Decode the first element inside soapenv:Body in place, so prefixes declared on the Envelope stay in scope
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	inBody := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to parse SOAP response: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !inBody {
+			inBody = start.Name.Space == soapEnvNamespace && start.Name.Local == "Body"
+			continue
+		}
+		if start.Name.Space == soapEnvNamespace && start.Name.Local == "Fault" {
+			var fault Fault
+			if err := dec.DecodeElement(&fault, &start); err != nil {
+				return fmt.Errorf("failed to parse SOAP fault: %w", err)
+			}
+			return &fault
+		}
+		if err := dec.DecodeElement(resp, &start); err != nil {
+			return fmt.Errorf("failed to parse SOAP response body: %w", err)
+		}
+		return nil
+	}
+}
+`
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kycWSDL = "../../services/providers/kyc/contract/kyc.wsdl"
+
+// TestGenerate_KYCContractIsUpToDate fails when kyc_gen.go was not regenerated after a contract change
+func TestGenerate_KYCContractIsUpToDate(t *testing.T) {
+	c, err := loadContract(kycWSDL)
+	require.NoError(t, err)
+	src, err := generate(c, "kycsoap")
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile("../../services/consumer/clients/soapclient/kycsoap/kyc_gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(src), "run go generate ./services/consumer/clients/soapclient/kycsoap")
+}
+
+func TestGenerate_SchemaFeatures(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.xsd", `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test" targetNamespace="urn:test" elementFormDefault="qualified">
+  <xs:simpleType name="Level">
+    <xs:restriction base="xs:string">
+      <xs:enumeration value="low"/>
+      <xs:enumeration value="high"/>
+      <xs:enumeration value="VERY_HIGH"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:complexType name="Item">
+    <xs:sequence>
+      <xs:element name="Level" type="t:Level"/>
+      <xs:element name="Tags" type="xs:string" maxOccurs="unbounded"/>
+      <xs:element name="Seen" type="xs:dateTime" minOccurs="0"/>
+      <xs:element name="Nested" minOccurs="0">
+        <xs:complexType><xs:sequence><xs:element name="Count" type="xs:int"/></xs:sequence></xs:complexType>
+      </xs:element>
+    </xs:sequence>
+    <xs:attribute name="id" type="xs:string" use="required"/>
+  </xs:complexType>
+  <xs:element name="Get"><xs:complexType><xs:sequence><xs:element name="ID" type="xs:string"/></xs:sequence></xs:complexType></xs:element>
+  <xs:element name="GetResult" type="t:Item"/>
+</xs:schema>`)
+	writeFile(t, dir, "svc.wsdl", `<wsdl:definitions name="Svc" targetNamespace="urn:test" xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test">
+  <wsdl:types><xs:schema><xs:import namespace="urn:test" schemaLocation="svc.xsd"/></xs:schema></wsdl:types>
+  <wsdl:message name="GetIn"><wsdl:part name="p" element="t:Get"/></wsdl:message>
+  <wsdl:message name="GetOut"><wsdl:part name="p" element="t:GetResult"/></wsdl:message>
+  <wsdl:portType name="SvcPort"><wsdl:operation name="Get"><wsdl:input message="t:GetIn"/><wsdl:output message="t:GetOut"/></wsdl:operation></wsdl:portType>
+  <wsdl:binding name="SvcBinding" type="t:SvcPort"><wsdl:operation name="Get"><soap:operation soapAction="urn:test/Get"/></wsdl:operation></wsdl:binding>
+</wsdl:definitions>`)
+
+	c, err := loadContract(filepath.Join(dir, "svc.wsdl"))
+	require.NoError(t, err)
+	out, err := generate(c, "svc")
+	require.NoError(t, err)
+	// Collapse gofmt column alignment so the assertions do not depend on neighbouring fields
+	src := strings.Join(strings.Fields(string(out)), " ")
+	for _, want := range []string{
+		`LevelLow Level = "low"`,
+		`LevelVeryHigh Level = "VERY_HIGH"`,
+		"Level Level `xml:\"urn:test Level\"`",
+		"Tags []string `xml:\"urn:test Tags,omitempty\"`",
+		"Seen *time.Time `xml:\"urn:test Seen,omitempty\"`",
+		"Nested *ItemNested `xml:\"urn:test Nested,omitempty\"`",
+		"Id string `xml:\"id,attr\"`",
+		"type GetResult struct { XMLName xml.Name `xml:\"urn:test GetResult\"` Item }",
+		"Get(req *Get) (*GetResult, error)",
+		`call(c.transport, "urn:test/Get", req, resp)`,
+	} {
+		assert.Contains(t, src, want)
+	}
+}
+
+func TestGenerate_UnknownType(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "bad.wsdl", `<wsdl:definitions targetNamespace="urn:test" xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test">
+  <wsdl:types><xs:schema targetNamespace="urn:test"><xs:element name="X" type="t:Missing"/></xs:schema></wsdl:types>
+</wsdl:definitions>`)
+
+	c, err := loadContract(filepath.Join(dir, "bad.wsdl"))
+	require.NoError(t, err)
+	_, err = generate(c, "bad")
+	assert.ErrorContains(t, err, "unknown type t:Missing")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
@@ -0,0 +1,46 @@
+// Command wsdlgen generates typed SOAP client code from a WSDL 1.1 document and its XML Schemas.
+//
+// It supports document/literal bindings: every operation becomes a method on a client interface,
+// and every schema type and element used by the messages becomes a Go struct. The generated client
+// sends requests through a Transport, which *soapclient.SOAPClient implements.
+//
+// Usage:
+//
+//	//go:generate go run kafka-soap-e2e-test/tools/wsdlgen -wsdl path/to/service.wsdl -package name -out service_gen.go
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	wsdlPath := flag.String("wsdl", "", "path to the WSDL document")
+	pkg := flag.String("package", "", "Go package name of the generated file")
+	out := flag.String("out", "", "output file (stdout if empty)")
+	flag.Parse()
+
+	if *wsdlPath == "" || *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c, err := loadContract(*wsdlPath)
+	if err != nil {
+		log.Fatalf("wsdlgen: %v", err)
+	}
+	src, err := generate(c, *pkg)
+	if err != nil {
+		log.Fatalf("wsdlgen: %v", err)
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+	if err != nil {
+		log.Fatalf("wsdlgen: failed to write output: %v", err)
+	}
+}
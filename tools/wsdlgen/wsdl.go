@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+)
+
+// --- WSDL 1.1 documents ---
+
+type wsdlDefinitions struct {
+	XMLName         xml.Name       `xml:"definitions"`
+	Name            string         `xml:"name,attr"`
+	TargetNamespace string         `xml:"targetNamespace,attr"`
+	Attrs           []xml.Attr     `xml:",any,attr"`
+	Schemas         []xsdSchema    `xml:"types>schema"`
+	Messages        []wsdlMessage  `xml:"message"`
+	PortTypes       []wsdlPortType `xml:"portType"`
+	Bindings        []wsdlBinding  `xml:"binding"`
+}
+
+type wsdlMessage struct {
+	Name  string `xml:"name,attr"`
+	Parts []struct {
+		Name    string `xml:"name,attr"`
+		Element string `xml:"element,attr"`
+	} `xml:"part"`
+}
+
+type wsdlPortType struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name          string `xml:"name,attr"`
+		Documentation string `xml:"documentation"`
+		Input         struct {
+			Message string `xml:"message,attr"`
+		} `xml:"input"`
+		Output struct {
+			Message string `xml:"message,attr"`
+		} `xml:"output"`
+	} `xml:"operation"`
+}
+
+type wsdlBinding struct {
+	Name       string `xml:"name,attr"`
+	Type       string `xml:"type,attr"`
+	Operations []struct {
+		Name          string `xml:"name,attr"`
+		SOAPOperation struct {
+			SOAPAction string `xml:"soapAction,attr"`
+		} `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	} `xml:"operation"`
+}
+
+// --- XML Schema documents (the subset used by document/literal services) ---
+
+type xsdSchema struct {
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Attrs              []xml.Attr       `xml:",any,attr"`
+	Imports            []xsdImport      `xml:"import"`
+	Includes           []xsdImport      `xml:"include"`
+	Elements           []xsdElement     `xml:"element"`
+	ComplexTypes       []xsdComplexType `xml:"complexType"`
+	SimpleTypes        []xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdImport struct {
+	Namespace      string `xml:"namespace,attr"`
+	SchemaLocation string `xml:"schemaLocation,attr"`
+}
+
+type xsdElement struct {
+	Name          string          `xml:"name,attr"`
+	Type          string          `xml:"type,attr"`
+	Ref           string          `xml:"ref,attr"`
+	MinOccurs     string          `xml:"minOccurs,attr"`
+	MaxOccurs     string          `xml:"maxOccurs,attr"`
+	Documentation string          `xml:"annotation>documentation"`
+	ComplexType   *xsdComplexType `xml:"complexType"`
+	SimpleType    *xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdAttribute struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	Use  string `xml:"use,attr"`
+}
+
+type xsdGroup struct {
+	Elements []xsdElement `xml:"element"`
+	Choices  []xsdGroup   `xml:"choice"`
+}
+
+type xsdComplexType struct {
+	Name           string         `xml:"name,attr"`
+	Documentation  string         `xml:"annotation>documentation"`
+	Sequence       *xsdGroup      `xml:"sequence"`
+	All            *xsdGroup      `xml:"all"`
+	Choice         *xsdGroup      `xml:"choice"`
+	Attributes     []xsdAttribute `xml:"attribute"`
+	ComplexContent *struct {
+		Extension struct {
+			Base       string         `xml:"base,attr"`
+			Sequence   *xsdGroup      `xml:"sequence"`
+			Attributes []xsdAttribute `xml:"attribute"`
+		} `xml:"extension"`
+	} `xml:"complexContent"`
+}
+
+type xsdSimpleType struct {
+	Name          string `xml:"name,attr"`
+	Documentation string `xml:"annotation>documentation"`
+	Restriction   struct {
+		Base         string `xml:"base,attr"`
+		Enumerations []struct {
+			Value string `xml:"value,attr"`
+		} `xml:"enumeration"`
+	} `xml:"restriction"`
+}
+
+// prefixes returns the namespace declared for every prefix on an element
+func prefixes(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string)
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" {
+			m[a.Name.Local] = a.Value
+		}
+	}
+	return m
+}
+
+// qname is a resolved XML qualified name
+type qname struct {
+	Space string
+	Local string
+}
+
+// resolve turns a prefixed attribute value such as "xs:string" into a qname
+func resolve(value string, ns map[string]string, defaultNS string) qname {
+	if prefix, local, ok := strings.Cut(value, ":"); ok {
+		if space, found := ns[prefix]; found {
+			return qname{Space: space, Local: local}
+		}
+		return qname{Space: defaultNS, Local: local}
+	}
+	return qname{Space: defaultNS, Local: value}
+}
+
+// loadedSchema is a parsed schema along with the prefixes in scope for it
+type loadedSchema struct {
+	xsdSchema
+	ns map[string]string
+}
+
+// contract is a WSDL with every referenced schema loaded
+type contract struct {
+	defs    wsdlDefinitions
+	ns      map[string]string
+	schemas []loadedSchema
+	source  string
+}
+
+// loadContract parses the WSDL at path and the schemas it imports, relative to the WSDL
+func loadContract(path string) (*contract, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WSDL %s: %w", path, err)
+	}
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("failed to parse WSDL %s: %w", path, err)
+	}
+
+	c := &contract{defs: defs, ns: prefixes(defs.Attrs), source: filepath.Base(path)}
+	seen := make(map[string]bool)
+	for _, s := range defs.Schemas {
+		// Inline schemas inherit the prefixes declared on wsdl:definitions
+		ns := prefixes(defs.Attrs)
+		for k, v := range prefixes(s.Attrs) {
+			ns[k] = v
+		}
+		if err := c.addSchema(loadedSchema{xsdSchema: s, ns: ns}, filepath.Dir(path), seen); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// addSchema registers s and recursively loads its imports and includes
+func (c *contract) addSchema(s loadedSchema, dir string, seen map[string]bool) error {
+	c.schemas = append(c.schemas, s)
+	for _, imp := range append(s.Imports, s.Includes...) {
+		if imp.SchemaLocation == "" {
+			continue
+		}
+		location := filepath.Join(dir, imp.SchemaLocation)
+		if seen[location] {
+			continue
+		}
+		seen[location] = true
+
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return fmt.Errorf("failed to read schema %s: %w", location, err)
+		}
+		var child xsdSchema
+		if err := xml.Unmarshal(data, &child); err != nil {
+			return fmt.Errorf("failed to parse schema %s: %w", location, err)
+		}
+		if child.TargetNamespace == "" {
+			child.TargetNamespace = s.TargetNamespace // Chameleon include
+		}
+		if err := c.addSchema(loadedSchema{xsdSchema: child, ns: prefixes(child.Attrs)}, filepath.Dir(location), seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}